@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/nyasuto/moz/internal/api"
+	"github.com/nyasuto/moz/internal/engine"
 )
 
 func main() {
 	var (
-		port     = flag.String("port", "8080", "Port to run the server on")
-		dataPath = flag.String("data", "moz.bin", "Path to the data file")
-		help     = flag.Bool("help", false, "Show help")
+		port       = flag.String("port", "8080", "Port to run the server on")
+		dataPath   = flag.String("data", "moz.bin", "Path to the data file")
+		engineName = flag.String("engine", engine.Log, "Storage engine: log, lsm, partitioned, or async")
+		format     = flag.String("format", "text", "Storage format for the log engine: text or binary")
+		indexType  = flag.String("index", "none", "Index type for the log engine: hash, btree, radix, or none")
+		help       = flag.Bool("help", false, "Show help")
 	)
 	flag.Parse()
 
@@ -26,7 +32,32 @@ func main() {
 		os.Exit(0)
 	}
 
-	server := api.NewServer(*dataPath, *port)
+	_ = *dataPath // Data location is controlled by MOZ_DATA_DIR
+
+	opts := engine.DefaultOptions()
+	opts.Engine = *engineName
+	opts.Format = *format
+	opts.IndexType = *indexType
+
+	namespaces, err := engine.OpenNamespaces(opts)
+	if err != nil {
+		log.Fatalf("Failed to open storage engine: %v", err)
+	}
+
+	server := api.NewServerWithNamespaces(namespaces, *port)
+	fmt.Printf("Using %s engine\n", namespaces.Engine())
+
+	// Close the store on shutdown so buffered writes are flushed
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		if err := server.Close(); err != nil {
+			log.Printf("Warning: Failed to close store: %v", err)
+		}
+		os.Exit(0)
+	}()
+
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
@@ -1,29 +1,38 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/nyasuto/moz/internal/batch"
 	"github.com/nyasuto/moz/internal/daemon"
+	"github.com/nyasuto/moz/internal/engine"
 	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
 	"github.com/nyasuto/moz/internal/pool"
 	"github.com/nyasuto/moz/internal/query"
 )
 
 func main() {
 	var format = flag.String("format", "text", "Storage format: text or binary")
-	var indexType = flag.String("index", "none", "Index type: hash, btree, or none")
+	var indexType = flag.String("index", "none", "Index type: hash, btree, radix, or none")
 	var help = flag.Bool("help", false, "Show help message")
 	var useDaemon = flag.Bool("daemon", false, "Use daemon mode for high performance")
 	var forceLocal = flag.Bool("local", false, "Force local execution (bypass daemon)")
 	var partitions = flag.Int("partitions", 1, "Number of partitions for parallel writes (1-16)")
+	var engineName = flag.String("engine", engine.Log, "Storage engine: log, lsm, partitioned, or async")
+	var namespace = flag.String("ns", "", "Namespace to operate on (default: the default namespace)")
+	var outputFormat = flag.String("output", "", "Output format of query, list, range, prefix, sorted and stats: table, json, jsonl, csv or tsv")
 	flag.Parse()
 
 	// Handle help flag
@@ -40,33 +49,77 @@ func main() {
 
 	command := args[0]
 
+	// --output may also follow the command, as in moz query --output json "..."
+	output := *outputFormat
+	if outputCommands[command] {
+		rest, format, err := extractOutputFlag(args[1:], output)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		args, output = append(args[:1], rest...), format
+	} else if output != "" {
+		fmt.Println("❌ --output is supported by query, list, range, prefix, sorted and stats only")
+		os.Exit(1)
+	}
+
+	opts, err := engineOptions(*engineName, *format, *indexType, *partitions, *namespace)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
 	// Handle daemon-specific commands first
 	switch command {
 	case "daemon":
-		handleDaemonCommands(args[1:], *format, *indexType)
+		handleDaemonCommands(args[1:], opts)
 		return
 	case "batch":
-		handleBatchCommand(args[1:], *format, *indexType, *useDaemon || daemon.IsDaemonRunning())
+		handleBatchCommand(args[1:], opts, *useDaemon || daemon.IsDaemonRunning())
 		return
 	case "pool":
-		handlePoolCommands(args[1:], *format, *indexType)
+		handlePoolCommands(args[1:], opts)
+		return
+	case "migrate":
+		handleMigrateCommand(args[1:], opts)
+		return
+	case "ingest":
+		handleIngestCommand(args[1:], opts)
+		return
+	case "lsm":
+		handleLSMCommand(args[1:], opts)
+		return
+	case "sst":
+		handleSSTCommand(args[1:])
+		return
+	case "ns":
+		handleNamespaceCommand(args[1:], opts, *forceLocal)
+		return
+	case "index":
+		handleIndexCommand(args[1:], opts)
 		return
 	}
 
 	// Auto-optimization: try daemon first unless forced local
 	if !*forceLocal && daemon.IsDaemonRunning() {
-		if err := executeThroughDaemon(command, args[1:]); err == nil {
+		running := checkDaemonEngine(opts.Engine)
+		if err := executeThroughDaemon(command, args[1:], opts.Namespace, output); err == nil {
 			return
 		}
 		// If daemon execution fails, fall back to local execution
+		if running != "" && running != engine.Log {
+			fmt.Printf("Warning: running %s locally; writes buffered by the %s daemon may not be visible\n", command, running)
+		}
 	}
 
-	// Create store with partition support
-	store := createStoreOrPartitioned(*format, *indexType, *partitions)
+	// Only writes create the namespace they target
+	opts.CreateNamespace = writesData(command, args[1:])
+	store := openStore(opts)
+	defer closeStore(store)
 
 	// Show partition info if using partitions
-	if *partitions > 1 {
-		fmt.Printf("🔄 Using %d partitions for parallel processing\n", *partitions)
+	if store.Engine() == engine.Partitioned {
+		fmt.Printf("🔄 Using %d partitions for parallel processing\n", opts.Partitions)
 	}
 
 	switch command {
@@ -79,7 +132,7 @@ func main() {
 		if err := store.Put(key, value); err != nil {
 			log.Fatalf("Error putting key-value: %v", err)
 		}
-		if *partitions > 1 {
+		if store.Engine() == engine.Partitioned {
 			fmt.Printf("✅ Stored (partition): %s = %s\n", key, value)
 		} else {
 			fmt.Printf("✅ Stored: %s = %s\n", key, value)
@@ -98,8 +151,12 @@ func main() {
 		fmt.Printf("%s\n", value)
 
 	case "del", "delete":
+		if len(args) >= 2 && (args[1] == "--prefix" || args[1] == "--range") {
+			handleRangeDelete(store, args[1:])
+			break
+		}
 		if len(args) != 2 {
-			fmt.Println("Usage: moz del <key>")
+			fmt.Println("Usage: moz del <key> | moz del --prefix <prefix> | moz del --range <start> <end>")
 			os.Exit(1)
 		}
 		key := args[1]
@@ -113,7 +170,10 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error listing keys: %v", err)
 		}
-		if len(keys) == 0 {
+		if output != outputText {
+			sort.Strings(keys)
+			printResultSet(output, storeRows(store, keys))
+		} else if len(keys) == 0 {
 			fmt.Println("No keys found")
 		} else {
 			for _, key := range keys {
@@ -133,10 +193,20 @@ func main() {
 		fmt.Println("✅ Store compacted")
 
 	case "stats":
+		if output != outputText {
+			printResultSet(output, statsRows(store, opts))
+			break
+		}
 		fmt.Printf("📊 Storage Statistics:\n")
 
-		// Try to get extended stats if available
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
+		fmt.Printf("  Engine: %s\n", store.Engine())
+		if opts.Namespace != "" {
+			fmt.Printf("  Namespace: %s\n", opts.Namespace)
+		}
+
+		// Log engine stores report detailed compaction and index statistics
+		if baseStore, ok := store.(engine.BaseStore); ok {
+			extStore := baseStore.Base()
 			stats, err := extStore.GetCompactionStats()
 			if err != nil {
 				log.Fatalf("Error getting compaction stats: %v", err)
@@ -147,8 +217,8 @@ func main() {
 				log.Fatalf("Error getting index stats: %v", err)
 			}
 
-			fmt.Printf("  Format: %s\n", *format)
-			fmt.Printf("  Index: %s\n", *indexType)
+			fmt.Printf("  Format: %s\n", opts.Format)
+			fmt.Printf("  Index: %s\n", opts.IndexType)
 			fmt.Printf("  Auto-compaction: %v\n", stats.Enabled)
 			fmt.Printf("  Operations since last compaction: %d\n", stats.OperationCount)
 			fmt.Printf("  File size: %d bytes\n", stats.FileSize)
@@ -165,11 +235,18 @@ func main() {
 			fmt.Printf("  Index type: %s\n", indexStats["type"])
 			fmt.Printf("  Index size: %d entries\n", indexStats["size"])
 			fmt.Printf("  Index memory usage: %d bytes\n", indexStats["memory_usage"])
+			for _, def := range extStore.SecondaryIndexes() {
+				fmt.Printf("  Secondary index %s: %v\n", def.Name, indexStats["secondary"].(map[string]interface{})[def.Name])
+			}
+			if fulltextStats, ok := indexStats["fulltext"]; ok {
+				fmt.Printf("  Full-text index: %v\n", fulltextStats)
+			}
 		} else {
-			// For partitioned stores, show basic info
-			fmt.Printf("  Format: %s\n", *format)
-			fmt.Printf("  Partitions: %d\n", *partitions)
-			fmt.Printf("  Basic statistics available\n")
+			stats, err := store.Stats()
+			if err != nil {
+				log.Fatalf("Error getting stats: %v", err)
+			}
+			printStatsMap(stats)
 		}
 
 	case "convert":
@@ -230,22 +307,20 @@ func main() {
 		}
 		startKey, endKey := args[1], args[2]
 
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
-			results, err := extStore.GetRange(startKey, endKey)
-			if err != nil {
-				log.Fatalf("Error performing range query: %v", err)
-			}
+		results, err := store.GetRange(startKey, endKey)
+		if err != nil {
+			log.Fatalf("Error performing range query: %v", err)
+		}
 
-			if len(results) == 0 {
-				fmt.Printf("No keys found in range [%s, %s]\n", startKey, endKey)
-			} else {
-				fmt.Printf("🔍 Range query [%s, %s] (%d results):\n", startKey, endKey, len(results))
-				for key, value := range results {
-					fmt.Printf("  %s: %s\n", key, value)
-				}
-			}
+		if output != outputText {
+			printResultSet(output, keyValueRows(sortedKeys(results), results))
+		} else if len(results) == 0 {
+			fmt.Printf("No keys found in range [%s, %s]\n", startKey, endKey)
 		} else {
-			fmt.Println("❌ Range queries not supported with partitioned stores")
+			fmt.Printf("🔍 Range query [%s, %s] (%d results):\n", startKey, endKey, len(results))
+			for _, key := range sortedKeys(results) {
+				fmt.Printf("  %s: %s\n", key, results[key])
+			}
 		}
 
 	case "prefix":
@@ -255,32 +330,32 @@ func main() {
 		}
 		prefix := args[1]
 
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
-			results, err := extStore.PrefixSearch(prefix)
-			if err != nil {
-				log.Fatalf("Error performing prefix search: %v", err)
-			}
+		results, err := store.PrefixSearch(prefix)
+		if err != nil {
+			log.Fatalf("Error performing prefix search: %v", err)
+		}
 
-			if len(results) == 0 {
-				fmt.Printf("No keys found with prefix '%s'\n", prefix)
-			} else {
-				fmt.Printf("🔍 Prefix search '%s' (%d results):\n", prefix, len(results))
-				for key, value := range results {
-					fmt.Printf("  %s: %s\n", key, value)
-				}
-			}
+		if output != outputText {
+			printResultSet(output, keyValueRows(sortedKeys(results), results))
+		} else if len(results) == 0 {
+			fmt.Printf("No keys found with prefix '%s'\n", prefix)
 		} else {
-			fmt.Println("❌ Prefix search not supported with partitioned stores")
+			fmt.Printf("🔍 Prefix search '%s' (%d results):\n", prefix, len(results))
+			for _, key := range sortedKeys(results) {
+				fmt.Printf("  %s: %s\n", key, results[key])
+			}
 		}
 
 	case "sorted":
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
-			keys, err := extStore.ListSorted()
+		if sortedStore, ok := store.(engine.SortedLister); ok {
+			keys, err := sortedStore.ListSorted()
 			if err != nil {
 				log.Fatalf("Error getting sorted keys: %v", err)
 			}
 
-			if len(keys) == 0 {
+			if output != outputText {
+				printResultSet(output, storeRows(store, keys))
+			} else if len(keys) == 0 {
 				fmt.Println("No keys found")
 			} else {
 				fmt.Printf("📋 Sorted keys (%d total):\n", len(keys))
@@ -294,11 +369,16 @@ func main() {
 				}
 			}
 		} else {
-			// Fall back to regular list for partitioned stores
+			// Other engines have no sorted index, so sort the full key list
 			keys, err := store.List()
 			if err != nil {
 				log.Fatalf("Error listing keys: %v", err)
 			}
+			sort.Strings(keys)
+			if output != outputText {
+				printResultSet(output, storeRows(store, keys))
+				break
+			}
 			fmt.Printf("📋 Keys (%d total):\n", len(keys))
 			for _, key := range keys {
 				value, err := store.Get(key)
@@ -311,82 +391,152 @@ func main() {
 		}
 
 	case "rebuild-index":
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
-			if err := extStore.RebuildIndex(); err != nil {
+		if baseStore, ok := store.(engine.BaseStore); ok {
+			if err := baseStore.Base().RebuildIndex(); err != nil {
 				log.Fatalf("Error rebuilding index: %v", err)
 			}
 			fmt.Println("✅ Index rebuilt successfully")
 		} else {
-			fmt.Println("❌ Index operations not supported with partitioned stores")
+			exitUnsupported(store, "Index operations")
 		}
 
 	case "validate-index":
-		if extStore, ok := store.(ExtendedStoreInterface); ok {
-			if err := extStore.ValidateIndex(); err != nil {
+		if baseStore, ok := store.(engine.BaseStore); ok {
+			if err := baseStore.Base().ValidateIndex(); err != nil {
 				log.Fatalf("Index validation failed: %v", err)
 			}
 			fmt.Println("✅ Index validation passed")
 		} else {
-			fmt.Println("❌ Index operations not supported with partitioned stores")
+			exitUnsupported(store, "Index operations")
 		}
 
-	case "query":
+	case "search":
 		if len(args) < 2 {
-			fmt.Println("Usage: moz query \"SELECT * FROM moz WHERE key = 'value'\"")
+			fmt.Println("Usage: moz search \"terms\"")
 			os.Exit(1)
 		}
-		queryStr := strings.Join(args[1:], " ")
+		terms := strings.Join(args[1:], " ")
 
-		// Parse and execute query
-		lexer := query.NewLexer(queryStr)
-		parser := query.NewParser(lexer)
-		stmt := parser.ParseQuery()
+		if baseStore, ok := store.(engine.BaseStore); ok {
+			hits, err := baseStore.Base().Search(terms)
+			if err != nil {
+				log.Fatalf("Error searching: %v", err)
+			}
 
-		if len(parser.Errors()) > 0 {
-			fmt.Printf("❌ Query parsing errors:\n")
-			for _, err := range parser.Errors() {
-				fmt.Printf("  - %s\n", err)
+			if len(hits) == 0 {
+				fmt.Printf("No values match '%s'\n", terms)
+			} else {
+				fmt.Printf("🔍 Search '%s' (%d results):\n", terms, len(hits))
+				for i, hit := range hits {
+					value, err := store.Get(hit.Key)
+					if err != nil {
+						fmt.Printf("%d. %s (score %d): <error: %v>\n", i+1, hit.Key, hit.Score, err)
+					} else {
+						fmt.Printf("%d. %s (score %d): %s\n", i+1, hit.Key, hit.Score, value)
+					}
+				}
 			}
+		} else {
+			exitUnsupported(store, "Full-text search")
+		}
+
+	case "query":
+		queryStr, params, dryRun := parseQueryArgs(args[1:])
+		if queryStr == "" {
+			fmt.Println("Usage: moz query [--dry-run] [--param <value>]... \"SELECT * FROM moz WHERE key = ?\"")
 			os.Exit(1)
 		}
 
-		if kvStore, ok := store.(*kvstore.KVStore); ok {
-			executor := query.NewExecutor(kvStore)
-			result := executor.Execute(stmt)
+		executor := query.NewExecutor(store)
+		executor.SetDryRun(dryRun)
+		prepared, err := executor.Prepare(queryStr)
+		if err != nil {
+			fmt.Printf("❌ Query parsing error: %v\n", err)
+			os.Exit(1)
+		}
+		stmt := prepared.Statement()
+		result := prepared.Execute(params...)
 
-			if result.Error != nil {
-				log.Fatalf("Query execution error: %v", result.Error)
-			}
+		if result.Error != nil {
+			log.Fatalf("Query execution error: %v", result.Error)
+		}
+
+		if output != outputText {
+			printResultSet(output, queryRows(stmt, result))
+			return
+		}
+
+		if result.Explain != nil {
+			fmt.Println("📋 Query plan:")
+			fmt.Print(result.Explain.String())
+			return
+		}
 
-			// Display results
-			selectStmt, ok := stmt.(*query.SelectStatement)
-			if !ok {
-				fmt.Println("❌ Invalid statement type")
-				os.Exit(1)
+		// Data-modifying statements report the rows they changed
+		var action string
+		switch stmt.(type) {
+		case *query.InsertStatement:
+			action = "Inserted"
+		case *query.UpdateStatement:
+			action = "Updated"
+		case *query.DeleteStatement:
+			action = "Deleted"
+		}
+		if action != "" {
+			if dryRun {
+				fmt.Printf("🧪 Dry run: %d rows would be %s:\n", result.Affected, strings.ToLower(action))
+			} else {
+				fmt.Printf("✅ %s %d rows:\n", action, result.Affected)
+			}
+			for i, row := range result.Rows {
+				fmt.Printf("%d. %s: %s\n", i+1, row["key"], row["value"])
 			}
+			return
+		}
 
-			if len(selectStmt.Fields) > 0 {
-				if _, ok := selectStmt.Fields[0].(*query.FunctionExpression); ok {
-					// Aggregation query
-					fmt.Printf("Count: %d\n", result.Count)
+		// Display results
+		selectStmt, ok := stmt.(*query.SelectStatement)
+		if !ok {
+			fmt.Println("❌ Invalid statement type")
+			os.Exit(1)
+		}
+
+		if len(selectStmt.Fields) > 0 {
+			if result.Aggregated {
+				// Aggregation query: one row per group, columns in SELECT order
+				if len(result.Rows) == 1 && len(result.Columns) == 1 && len(selectStmt.GroupBy) == 0 {
+					fmt.Printf("%s: %s\n", result.Columns[0], result.Rows[0][result.Columns[0]])
 				} else {
-					// Regular SELECT query
-					if len(result.Rows) == 0 {
-						fmt.Println("No results found")
-					} else {
-						fmt.Printf("🔍 Query results (%d rows):\n", len(result.Rows))
-						for i, row := range result.Rows {
-							fmt.Printf("%d. ", i+1)
-							for field, value := range row {
-								fmt.Printf("%s: %s  ", field, value)
+					fmt.Printf("📊 Aggregate results (%d groups, %d rows matched):\n", len(result.Rows), result.Count)
+					for i, row := range result.Rows {
+						fmt.Printf("%d. ", i+1)
+						for _, column := range result.Columns {
+							if value, exists := row[column]; exists {
+								fmt.Printf("%s: %s  ", column, value)
+							} else {
+								fmt.Printf("%s: null  ", column)
+							}
+						}
+						fmt.Println()
+					}
+				}
+			} else {
+				// Regular SELECT query
+				if len(result.Rows) == 0 {
+					fmt.Println("No results found")
+				} else {
+					fmt.Printf("🔍 Query results (%d rows):\n", len(result.Rows))
+					for i, row := range result.Rows {
+						fmt.Printf("%d. ", i+1)
+						for _, column := range result.Columns {
+							if value, exists := row[column]; exists {
+								fmt.Printf("%s: %s  ", column, value)
 							}
-							fmt.Println()
 						}
+						fmt.Println()
 					}
 				}
 			}
-		} else {
-			fmt.Println("❌ Query language not supported with partitioned stores")
 		}
 
 	case "help":
@@ -400,8 +550,8 @@ func main() {
 }
 
 // executeThroughDaemon executes command through daemon for high performance
-func executeThroughDaemon(command string, args []string) error {
-	client := daemon.NewClient()
+func executeThroughDaemon(command string, args []string, namespace, output string) error {
+	client := daemon.NewClient().WithNamespace(namespace)
 
 	switch command {
 	case "put":
@@ -425,6 +575,20 @@ func executeThroughDaemon(command string, args []string) error {
 		return err
 
 	case "del", "delete":
+		if len(args) == 2 && args[0] == "--prefix" {
+			err := client.DeletePrefix(args[1])
+			if err == nil {
+				fmt.Printf("✅ Deleted keys with prefix '%s'\n", args[1])
+			}
+			return err
+		}
+		if len(args) == 3 && args[0] == "--range" {
+			err := client.DeleteRange(args[1], args[2])
+			if err == nil {
+				fmt.Printf("✅ Deleted keys in range [%s, %s]\n", args[1], args[2])
+			}
+			return err
+		}
 		if len(args) != 1 {
 			return fmt.Errorf("delete requires exactly 1 argument")
 		}
@@ -439,7 +603,9 @@ func executeThroughDaemon(command string, args []string) error {
 		if err != nil {
 			return err
 		}
-		if len(entries) == 0 {
+		if output != outputText {
+			printResultSet(output, keyValueRows(sortedKeys(entries), entries))
+		} else if len(entries) == 0 {
 			fmt.Println("No keys found")
 		} else {
 			for key, value := range entries {
@@ -460,6 +626,14 @@ func executeThroughDaemon(command string, args []string) error {
 		if err != nil {
 			return err
 		}
+		if statsMap, ok := stats.(map[string]interface{}); ok && output != outputText {
+			rs := resultSet{Columns: []string{"name", "value"}}
+			for _, key := range append([]string{"engine"}, sortedStatKeys(statsMap)...) {
+				rs.Rows = append(rs.Rows, map[string]string{"name": key, "value": statValue(statsMap[key])})
+			}
+			printResultSet(output, rs)
+			return nil
+		}
 		fmt.Printf("📊 Storage Statistics (via daemon):\n")
 		fmt.Printf("%+v\n", stats)
 		return nil
@@ -470,7 +644,7 @@ func executeThroughDaemon(command string, args []string) error {
 }
 
 // handleDaemonCommands handles daemon management commands
-func handleDaemonCommands(args []string, format, indexType string) {
+func handleDaemonCommands(args []string, opts engine.Options) {
 	if len(args) < 1 {
 		fmt.Println("Usage: moz daemon <start|stop|status|restart>")
 		os.Exit(1)
@@ -485,11 +659,18 @@ func handleDaemonCommands(args []string, format, indexType string) {
 			return
 		}
 
-		// Create store
-		store := createStore(format, indexType)
+		if opts.Namespace != "" {
+			fmt.Println("Warning: the daemon serves every namespace; --ns is ignored")
+		}
+
+		// Open the default namespace; others are opened on first request
+		namespaces, err := engine.OpenNamespaces(opts)
+		if err != nil {
+			log.Fatalf("Failed to open %s engine: %v", opts.Engine, err)
+		}
 
 		// Create and start daemon
-		dm := daemon.NewDaemonManager(store)
+		dm := daemon.NewDaemonManager(namespaces)
 		if err := dm.Start(); err != nil {
 			log.Fatalf("Failed to start daemon: %v", err)
 		}
@@ -500,6 +681,7 @@ func handleDaemonCommands(args []string, format, indexType string) {
 		}
 
 		fmt.Println("🚀 Daemon started successfully")
+		fmt.Printf("Engine: %s\n", namespaces.Engine())
 		fmt.Printf("Socket: %s\n", dm.GetSocketPath())
 
 		// Set up signal handling for graceful shutdown
@@ -540,14 +722,17 @@ func handleDaemonCommands(args []string, format, indexType string) {
 		if daemon.IsDaemonRunning() {
 			pid, _ := daemon.GetDaemonPID()
 			fmt.Printf("✅ Daemon is running (PID: %d)\n", pid)
+			if name, err := daemon.NewClient().Engine(); err == nil {
+				fmt.Printf("Engine: %s\n", name)
+			}
 		} else {
 			fmt.Println("❌ Daemon is not running")
 		}
 
 	case "restart":
-		handleDaemonCommands([]string{"stop"}, format, indexType)
+		handleDaemonCommands([]string{"stop"}, opts)
 		time.Sleep(1 * time.Second)
-		handleDaemonCommands([]string{"start"}, format, indexType)
+		handleDaemonCommands([]string{"start"}, opts)
 
 	default:
 		fmt.Printf("Unknown daemon command: %s\n", subcommand)
@@ -556,8 +741,288 @@ func handleDaemonCommands(args []string, format, indexType string) {
 	}
 }
 
+// handleMigrateCommand handles "moz migrate --to <lsm|binary>"
+func handleMigrateCommand(args []string, opts engine.Options) {
+	var target string
+	switch {
+	case len(args) == 2 && args[0] == "--to":
+		target = args[1]
+	case len(args) == 1 && strings.HasPrefix(args[0], "--to="):
+		target = strings.TrimPrefix(args[0], "--to=")
+	default:
+		fmt.Println("Usage: moz migrate --to <lsm|binary>")
+		os.Exit(1)
+	}
+
+	if opts.Namespace != "" {
+		fmt.Println("❌ Migration only applies to the default namespace; drop the --ns flag")
+		os.Exit(1)
+	}
+
+	// The daemon would keep writing to the legacy log without the migration seeing it
+	if daemon.IsDaemonRunning() {
+		fmt.Println("❌ Stop the daemon before migrating: moz daemon stop")
+		os.Exit(1)
+	}
+
+	migrationOpts := kvstore.DefaultMigrationOptions()
+	migrationOpts.Progress = func(p kvstore.MigrationProgress) {
+		percent := 100.0
+		if p.Total > 0 {
+			percent = float64(p.Done) * 100 / float64(p.Total)
+		}
+		fmt.Printf("\r🔄 %-6s %d/%d keys (%.0f%%)", p.Phase, p.Done, p.Total, percent)
+		if p.Done == p.Total {
+			fmt.Println()
+		}
+	}
+
+	fmt.Printf("🚚 Migrating %s log store to %s...\n", opts.Format, target)
+	result, err := engine.Migrate(opts, target, migrationOpts)
+	if err != nil {
+		fmt.Println()
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	if result.ResumedFrom != "" {
+		fmt.Printf("  Resumed after checkpoint: %s\n", result.ResumedFrom)
+	}
+	fmt.Printf("  Keys: %d total, %d copied, %d repaired during verification\n", result.TotalKeys, result.Copied, result.Repaired)
+	fmt.Printf("  Time: %v\n", result.Duration)
+	if result.RetiredLog != "" {
+		fmt.Printf("  Legacy log retired to: %s\n", result.RetiredLog)
+	}
+	fmt.Printf("✅ Migration to %s completed and verified\n", target)
+
+	switch target {
+	case engine.MigrateToLSM:
+		fmt.Println("   Use --engine=lsm to access the migrated data")
+	case engine.MigrateToBinary:
+		fmt.Println("   Use --format=binary to access the migrated data")
+	}
+}
+
+// handleIngestCommand handles "moz ingest <file.sst>..." for the LSM engine
+func handleIngestCommand(args []string, opts engine.Options) {
+	if len(args) < 1 {
+		fmt.Println("Usage: moz --engine=lsm ingest <file.sst> [file.sst...]")
+		os.Exit(1)
+	}
+
+	// A running daemon would not see SSTables added behind its back
+	if daemon.IsDaemonRunning() {
+		fmt.Println("❌ Stop the daemon before ingesting: moz daemon stop")
+		os.Exit(1)
+	}
+
+	opts.CreateNamespace = true
+	store := openStore(opts)
+	defer closeStore(store)
+
+	ingester, ok := store.(engine.Ingester)
+	if !ok {
+		exitUnsupported(store, "ingest")
+	}
+
+	fmt.Printf("📥 Ingesting %d SSTable files...\n", len(args))
+	start := time.Now()
+	ingested, err := ingester.IngestExternalFiles(args)
+	if err != nil {
+		closeStore(store)
+		log.Fatalf("Ingestion failed: %v", err)
+	}
+
+	var entries uint64
+	for _, file := range ingested {
+		fmt.Printf("  %s → L%d (%s, %d entries)\n", file.Path, file.Level, file.ID, file.Entries)
+		entries += file.Entries
+	}
+	fmt.Printf("✅ Ingested %d files with %d entries in %v\n", len(ingested), entries, time.Since(start))
+}
+
+// handleLSMCommand handles "moz lsm levels"
+func handleLSMCommand(args []string, opts engine.Options) {
+	if len(args) != 1 || args[0] != "levels" {
+		fmt.Println("Usage: moz lsm levels")
+		os.Exit(1)
+	}
+
+	if daemon.IsDaemonRunning() {
+		fmt.Println("Warning: the daemon is running; writes it has not flushed yet are not shown")
+	}
+
+	opts.Engine = engine.LSM
+	store := openStore(opts)
+	defer closeStore(store)
+
+	reporter, ok := store.(engine.LevelReporter)
+	if !ok {
+		exitUnsupported(store, "Level inspection")
+	}
+
+	fmt.Println("📊 LSM-Tree levels:")
+	for _, level := range reporter.GetLevelInfo() {
+		fmt.Printf("L%v: %v SSTables, %v / %v bytes", level["level"], level["sstable_count"], level["total_size"], level["max_size"])
+		if keyRange, ok := level["key_range"].([]string); ok && len(keyRange) == 2 {
+			fmt.Printf(", keys [%q .. %q]", keyRange[0], keyRange[1])
+		}
+		fmt.Println()
+
+		sstables, _ := level["sstables"].([]map[string]interface{})
+		for _, sstable := range sstables {
+			fmt.Printf("  %v: %v bytes, %v entries, keys [%q .. %q]",
+				sstable["id"], sstable["size"], sstable["entries"], sstable["min_key"], sstable["max_key"])
+			if tombstones, ok := sstable["range_tombstones"].(int); ok && tombstones > 0 {
+				fmt.Printf(", %d range tombstones", tombstones)
+			}
+			if bloom, ok := sstable["bloom"].(lsm.BloomFilterStats); ok {
+				fmt.Printf(", bloom FPR %.2f%% (expected %.2f%%, %d bits, %d hashes)",
+					bloom.EstimatedFPR*100, bloom.ExpectedFPR*100, bloom.Size, bloom.NumHashFunctions)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// handleSSTCommand handles "moz sst dump <file>" and "moz sst verify <file>..."
+func handleSSTCommand(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: moz sst dump <file.sst> [--limit N]")
+		fmt.Println("       moz sst verify <file.sst>...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "dump":
+		limit := 0
+		if len(args) == 4 && args[2] == "--limit" {
+			n, err := strconv.Atoi(args[3])
+			if err != nil || n < 0 {
+				log.Fatalf("Invalid limit: %s", args[3])
+			}
+			limit = n
+		} else if len(args) != 2 {
+			fmt.Println("Usage: moz sst dump <file.sst> [--limit N]")
+			os.Exit(1)
+		}
+		dumpSSTable(args[1], limit)
+
+	case "verify":
+		failed := 0
+		for _, path := range args[1:] {
+			if !verifySSTable(path) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("❌ %d of %d SSTables failed verification\n", failed, len(args)-1)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Printf("Unknown sst command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// dumpSSTable prints the metadata, range tombstones and entries of an SSTable
+func dumpSSTable(path string, limit int) {
+	sstable, err := lsm.OpenSSTableFile(path)
+	if err != nil {
+		log.Fatalf("Failed to open SSTable: %v", err)
+	}
+	defer sstable.Close()
+
+	metadata := sstable.Metadata()
+	fmt.Printf("📄 SSTable %s\n", path)
+	fmt.Printf("  Version: %d\n", metadata.Version)
+	fmt.Printf("  Level: %d\n", metadata.Level)
+	fmt.Printf("  Entries: %d\n", metadata.NumEntries)
+	fmt.Printf("  File size: %d bytes\n", sstable.FileSize)
+	fmt.Printf("  Key range: [%q .. %q]\n", metadata.MinKey, metadata.MaxKey)
+	if newest := sstable.NewestEntryTime(); !newest.IsZero() {
+		fmt.Printf("  Newest entry: %s\n", newest.Format(time.RFC3339Nano))
+	}
+	if bf := sstable.BloomFilter(); bf != nil {
+		bloom := bf.GetStats()
+		fmt.Printf("  Bloom filter: %d bits, %d hashes, %d keys, FPR %.2f%% (expected %.2f%%)\n",
+			bloom.Size, bloom.NumHashFunctions, bloom.NumItems, bloom.EstimatedFPR*100, bloom.ExpectedFPR*100)
+	}
+	if pf, prefixLen := sstable.PrefixBloomFilter(); pf != nil {
+		fmt.Printf("  Prefix bloom filter: %d-byte prefixes, %d bits\n", prefixLen, pf.GetStats().Size)
+	}
+
+	tombstones := sstable.RangeTombstones()
+	if len(tombstones) > 0 {
+		fmt.Printf("  Range tombstones (%d):\n", len(tombstones))
+		for _, rt := range tombstones {
+			fmt.Printf("    %s\n", rt)
+		}
+	}
+
+	index := sstable.Index()
+	fmt.Printf("  Entries (offset+length, timestamp, key, value):\n")
+	iter := sstable.Iterator()
+	for i := 0; iter.HasNext(); i++ {
+		if limit > 0 && i >= limit {
+			fmt.Printf("    ... %d more entries\n", len(index)-i)
+			break
+		}
+		entry, err := iter.Next()
+		if err != nil {
+			fmt.Printf("    %d+%d %q: ❌ %v\n", index[i].Offset, index[i].Length, index[i].Key, err)
+			continue
+		}
+
+		value := fmt.Sprintf("%q", truncateValue(entry.Value, 64))
+		switch {
+		case entry.Deleted:
+			value = "<deleted>"
+		case entry.BlobIndex:
+			value = "<blob " + entry.BlobLocation() + ">"
+		}
+		timestamp := time.Unix(0, entry.Timestamp).Format(time.RFC3339Nano)
+		fmt.Printf("    %d+%d %s %q = %s\n", index[i].Offset, index[i].Length, timestamp, entry.Key, value)
+	}
+}
+
+// verifySSTable verifies an SSTable and prints the result
+func verifySSTable(path string) bool {
+	sstable, err := lsm.OpenSSTableFile(path)
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", path, err)
+		return false
+	}
+	defer sstable.Close()
+
+	report := sstable.Verify()
+	if !report.OK() {
+		fmt.Printf("❌ %s: %d problems\n", path, len(report.Problems))
+		for i, problem := range report.Problems {
+			if i == 20 {
+				fmt.Printf("  ... %d more\n", len(report.Problems)-i)
+				break
+			}
+			fmt.Printf("  %s\n", problem)
+		}
+		return false
+	}
+
+	fmt.Printf("✅ %s: %d entries (%d deletions, %d blob pointers), %d range tombstones verified\n",
+		path, report.Entries, report.Deletions, report.BlobIndexes, report.RangeTombstones)
+	return true
+}
+
+// truncateValue shortens long values for display
+func truncateValue(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return fmt.Sprintf("%s... (%d bytes)", value[:max], len(value))
+}
+
 // handleBatchCommand handles batch operations
-func handleBatchCommand(args []string, format, indexType string, useDaemon bool) {
+func handleBatchCommand(args []string, opts engine.Options, useDaemon bool) {
 	if len(args) < 1 {
 		fmt.Println("Usage: moz batch <operation1> [args...] <operation2> [args...] ...")
 		fmt.Println("Example: moz batch put user1 alice put user2 bob get user1")
@@ -579,8 +1044,9 @@ func handleBatchCommand(args []string, format, indexType string, useDaemon bool)
 
 	// Try daemon first if available and requested
 	if useDaemon && daemon.IsDaemonRunning() {
+		checkDaemonEngine(opts.Engine)
 		fmt.Println("📡 Using daemon for high-performance batch execution")
-		client := daemon.NewClient()
+		client := daemon.NewClient().WithNamespace(opts.Namespace)
 
 		start := time.Now()
 		successCount := 0
@@ -607,7 +1073,9 @@ func handleBatchCommand(args []string, format, indexType string, useDaemon bool)
 	}
 
 	// Local batch execution
-	store := createStore(format, indexType)
+	opts.CreateNamespace = true
+	store := openStore(opts)
+	defer closeStore(store)
 	executor := batch.NewBatchExecutor(store)
 
 	results := executor.Execute(operations)
@@ -633,13 +1101,14 @@ func handleBatchCommand(args []string, format, indexType string, useDaemon bool)
 }
 
 // handlePoolCommands handles process pool commands
-func handlePoolCommands(args []string, format, indexType string) {
+func handlePoolCommands(args []string, opts engine.Options) {
 	if len(args) < 1 {
 		fmt.Println("Usage: moz pool <start|status|test> [workers] [jobs]")
 		os.Exit(1)
 	}
 
 	subcommand := args[0]
+	opts.CreateNamespace = true // Pool jobs write to the namespace
 
 	switch subcommand {
 	case "start":
@@ -657,7 +1126,8 @@ func handlePoolCommands(args []string, format, indexType string) {
 			}
 		}
 
-		store := createStore(format, indexType)
+		store := openStore(opts)
+		defer closeStore(store)
 		pool := pool.NewProcessPool(workerSize, queueSize, store)
 
 		if err := pool.Start(); err != nil {
@@ -695,7 +1165,8 @@ func handlePoolCommands(args []string, format, indexType string) {
 			}
 		}
 
-		store := createStore(format, indexType)
+		store := openStore(opts)
+		defer closeStore(store)
 		pool := pool.NewProcessPool(workerSize, 1000, store)
 
 		if err := pool.Start(); err != nil {
@@ -742,50 +1213,189 @@ func handlePoolCommands(args []string, format, indexType string) {
 	}
 }
 
-// createStore creates a KVStore with the specified configuration
-func createStore(format, indexType string) *kvstore.KVStore {
-	storageConfig := kvstore.StorageConfig{
-		Format:     format,
-		TextFile:   "moz.log",
-		BinaryFile: "moz.bin",
-		IndexType:  indexType,
-		IndexFile:  "moz.idx",
+// handleIndexCommand handles "moz index create <name> [field]", "moz index drop <name>",
+// "moz index fulltext [field]", "moz index drop-fulltext" and "moz index list"
+func handleIndexCommand(args []string, opts engine.Options) {
+	if len(args) < 1 ||
+		(args[0] == "create" && len(args) != 2 && len(args) != 3) ||
+		(args[0] == "drop" && len(args) != 2) ||
+		(args[0] == "fulltext" && len(args) > 2) ||
+		(args[0] == "drop-fulltext" && len(args) != 1) ||
+		(args[0] == "list" && len(args) != 1) {
+		fmt.Println("Usage: moz index <create <name> [json.field]|drop <name>|fulltext [json.field]|drop-fulltext|list>")
+		os.Exit(1)
 	}
 
-	compactionConfig := kvstore.CompactionConfig{
-		Enabled:         true,
-		MaxFileSize:     1024 * 1024, // 1MB
-		MaxOperations:   1000,
-		CompactionRatio: 0.5,
+	// The daemon keeps its own copy of the index definitions
+	if args[0] != "list" && daemon.IsDaemonRunning() {
+		fmt.Println("❌ Stop the daemon before changing indexes: moz daemon stop")
+		os.Exit(1)
 	}
 
-	return kvstore.NewWithConfig(compactionConfig, storageConfig)
+	store := openStore(opts)
+	defer closeStore(store)
+
+	baseStore, ok := store.(engine.BaseStore)
+	if !ok {
+		exitUnsupported(store, "Secondary indexes")
+	}
+	kv := baseStore.Base()
+
+	switch args[0] {
+	case "create":
+		field := ""
+		if len(args) == 3 {
+			field = args[2]
+		}
+		if err := kv.CreateSecondaryIndex(args[1], field); err != nil {
+			closeStore(store)
+			log.Fatalf("Error creating index: %v", err)
+		}
+		if field == "" {
+			fmt.Printf("✅ Created index %s on value\n", args[1])
+		} else {
+			fmt.Printf("✅ Created index %s on value.%s\n", args[1], field)
+		}
+
+	case "drop":
+		if err := kv.DropSecondaryIndex(args[1]); err != nil {
+			closeStore(store)
+			log.Fatalf("Error dropping index: %v", err)
+		}
+		fmt.Printf("✅ Dropped index: %s\n", args[1])
+
+	case "fulltext":
+		field := ""
+		if len(args) == 2 {
+			field = args[1]
+		}
+		if err := kv.CreateFullTextIndex(field); err != nil {
+			closeStore(store)
+			log.Fatalf("Error creating full-text index: %v", err)
+		}
+		if field == "" {
+			fmt.Println("✅ Created full-text index on value")
+		} else {
+			fmt.Printf("✅ Created full-text index on value.%s\n", field)
+		}
+
+	case "drop-fulltext":
+		if err := kv.DropFullTextIndex(); err != nil {
+			closeStore(store)
+			log.Fatalf("Error dropping full-text index: %v", err)
+		}
+		fmt.Println("✅ Dropped full-text index")
+
+	case "list":
+		if field, ok := kv.FullTextField(); ok {
+			if field == "" {
+				fmt.Println("📝 Full-text index: value")
+			} else {
+				fmt.Printf("📝 Full-text index: value.%s\n", field)
+			}
+		}
+		defs := kv.SecondaryIndexes()
+		if len(defs) == 0 {
+			fmt.Println("No secondary indexes")
+			return
+		}
+		fmt.Printf("🔍 Secondary indexes (%d total):\n", len(defs))
+		for _, def := range defs {
+			target := "value"
+			if def.Field != "" {
+				target = "value." + def.Field
+			}
+			fmt.Printf("  %s: %s\n", def.Name, target)
+		}
+
+	default:
+		fmt.Printf("Unknown index command: %s\n", args[0])
+		fmt.Println("Available commands: create, drop, fulltext, drop-fulltext, list")
+		os.Exit(1)
+	}
 }
 
-// StoreInterface defines the common interface for both regular and partitioned stores
-type StoreInterface interface {
-	Put(key, value string) error
-	Get(key string) (string, error)
-	Delete(key string) error
-	List() ([]string, error)
-	Compact() error
+// handleNamespaceCommand handles "moz ns list" and "moz ns drop <name>"
+func handleNamespaceCommand(args []string, opts engine.Options, forceLocal bool) {
+	if len(args) < 1 || (args[0] == "list" && len(args) != 1) || (args[0] == "drop" && len(args) != 2) {
+		fmt.Println("Usage: moz ns <list|drop <name>>")
+		os.Exit(1)
+	}
+
+	if !forceLocal && daemon.IsDaemonRunning() {
+		checkDaemonEngine(opts.Engine)
+		client := daemon.NewClient()
+		switch args[0] {
+		case "list":
+			names, err := client.Namespaces()
+			if err != nil {
+				log.Fatalf("Error listing namespaces: %v", err)
+			}
+			printNamespaces(names)
+			return
+		case "drop":
+			if err := client.DropNamespace(args[1]); err != nil {
+				log.Fatalf("Error dropping namespace: %v", err)
+			}
+			fmt.Printf("✅ Dropped namespace: %s\n", args[1])
+			return
+		}
+	}
+
+	namespaces, err := engine.OpenNamespaces(opts)
+	if err != nil {
+		log.Fatalf("Failed to open %s engine: %v", opts.Engine, err)
+	}
+	defer func() {
+		if err := namespaces.Close(); err != nil {
+			log.Printf("Warning: Failed to close store: %v", err)
+		}
+	}()
+
+	switch args[0] {
+	case "list":
+		names, err := namespaces.List()
+		if err != nil {
+			log.Fatalf("Error listing namespaces: %v", err)
+		}
+		printNamespaces(names)
+
+	case "drop":
+		if err := namespaces.Drop(args[1]); err != nil {
+			_ = namespaces.Close()
+			log.Fatalf("Error dropping namespace: %v", err)
+		}
+		fmt.Printf("✅ Dropped namespace: %s\n", args[1])
+
+	default:
+		fmt.Printf("Unknown ns command: %s\n", args[0])
+		fmt.Println("Available commands: list, drop")
+		os.Exit(1)
+	}
 }
 
-// ExtendedStoreInterface extends StoreInterface with additional methods
-type ExtendedStoreInterface interface {
-	StoreInterface
-	GetRange(start, end string) (map[string]string, error)
-	PrefixSearch(prefix string) (map[string]string, error)
-	ListSorted() ([]string, error)
-	GetCompactionStats() (kvstore.CompactionStats, error)
-	GetIndexStats() (map[string]interface{}, error)
-	RebuildIndex() error
-	ValidateIndex() error
+// printNamespaces prints namespace names, one per line
+func printNamespaces(names []string) {
+	fmt.Printf("📁 Namespaces (%d total):\n", len(names))
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
 }
 
-func createStoreOrPartitioned(format, indexType string, partitions int) StoreInterface {
-	if partitions <= 1 {
-		return createStore(format, indexType)
+// engineOptions builds engine options from the command line flags.
+// --partitions > 1 with the default log engine selects the partitioned engine.
+func engineOptions(name, format, indexType string, partitions int, namespace string) (engine.Options, error) {
+	if err := engine.Validate(name); err != nil {
+		return engine.Options{}, err
+	}
+	if err := engine.ValidateNamespace(namespace); err != nil {
+		return engine.Options{}, err
+	}
+	if namespace == engine.DefaultNamespace {
+		namespace = ""
+	}
+	if name == engine.Log && partitions > 1 {
+		name = engine.Partitioned
 	}
 
 	// Validate partition count
@@ -793,32 +1403,304 @@ func createStoreOrPartitioned(format, indexType string, partitions int) StoreInt
 		log.Printf("Warning: partition count %d exceeds maximum 16, using 16", partitions)
 		partitions = 16
 	}
+	if name == engine.Partitioned && partitions <= 1 {
+		partitions = engine.DefaultOptions().Partitions
+	}
 
-	config := kvstore.DefaultPartitionConfig()
-	config.NumPartitions = partitions
+	return engine.Options{
+		Engine:     name,
+		Format:     format,
+		IndexType:  indexType,
+		Partitions: partitions,
+		Namespace:  namespace,
+	}, nil
+}
 
-	store, err := kvstore.NewPartitionedKVStore(config)
-	if err != nil {
-		log.Fatalf("Failed to create partitioned store: %v", err)
+// writesData reports whether a command changes data; only those create a missing namespace
+func writesData(command string, args []string) bool {
+	switch command {
+	case "put", "del", "delete":
+		return true
+	case "query":
+		queryStr, _, dryRun := parseQueryArgs(args)
+		return !dryRun && query.IsWrite(queryStr)
+	default:
+		return false
 	}
+}
+
+// parseQueryArgs splits the arguments of moz query into the query text, the --param values and --dry-run
+func parseQueryArgs(args []string) (string, []interface{}, bool) {
+	var queryArgs []string
+	var params []interface{}
+	dryRun := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dry-run":
+			dryRun = true
+		case args[i] == "--param" && i+1 < len(args):
+			i++
+			params = append(params, parseQueryParam(args[i]))
+		default:
+			queryArgs = append(queryArgs, args[i])
+		}
+	}
+	return strings.Join(queryArgs, " "), params, dryRun
+}
 
+// openStore opens the store for the selected engine
+func openStore(opts engine.Options) engine.Store {
+	store, err := engine.Open(opts)
+	if err != nil {
+		log.Fatalf("Failed to open %s engine: %v", opts.Engine, err)
+	}
 	return store
 }
 
+// closeStore closes the store, flushing any buffered writes
+func closeStore(store engine.Store) {
+	if err := store.Close(); err != nil {
+		log.Printf("Warning: Failed to close store: %v", err)
+	}
+}
+
+// exitUnsupported reports an operation the selected engine does not support and exits
+func exitUnsupported(store engine.Store, operation string) {
+	fmt.Printf("❌ %v\n", engine.Unsupported(store, operation))
+	os.Exit(1)
+}
+
+// checkDaemonEngine returns the daemon's engine, exiting if an explicitly
+// selected engine differs from it
+func checkDaemonEngine(name string) string {
+	running, err := daemon.NewClient().Engine()
+	if err != nil {
+		return ""
+	}
+
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "engine" || f.Name == "partitions" {
+			explicit = true
+		}
+	})
+	if explicit && running != name {
+		fmt.Printf("❌ Daemon is running with the %s engine, but --engine=%s was requested\n", running, name)
+		fmt.Println("   Restart the daemon with the same --engine or use --local")
+		os.Exit(1)
+	}
+	return running
+}
+
+// printStatsMap prints engine statistics in key order
+func printStatsMap(stats map[string]interface{}) {
+	for _, key := range sortedStatKeys(stats) {
+		fmt.Printf("  %s: %v\n", key, stats[key])
+	}
+}
+
+// handleRangeDelete handles "moz del --prefix <prefix>" and "moz del --range <start> <end>"
+func handleRangeDelete(store engine.Store, args []string) {
+	rangeStore, ok := store.(engine.RangeDeleter)
+	if !ok {
+		exitUnsupported(store, "Range deletes")
+	}
+
+	switch {
+	case args[0] == "--prefix" && len(args) == 2:
+		if err := rangeStore.DeletePrefix(args[1]); err != nil {
+			log.Fatalf("Error deleting prefix: %v", err)
+		}
+		fmt.Printf("✅ Deleted keys with prefix '%s'\n", args[1])
+
+	case args[0] == "--range" && len(args) == 3:
+		if err := rangeStore.DeleteRange(args[1], args[2]); err != nil {
+			log.Fatalf("Error deleting range: %v", err)
+		}
+		fmt.Printf("✅ Deleted keys in range [%s, %s]\n", args[1], args[2])
+
+	default:
+		fmt.Println("Usage: moz del --prefix <prefix> | moz del --range <start> <end>")
+		os.Exit(1)
+	}
+}
+
+// printResultSet prints the rows of a command in an --output format
+func printResultSet(format string, rs resultSet) {
+	if err := writeResultSet(os.Stdout, format, rs); err != nil {
+		log.Fatalf("Error writing output: %v", err)
+	}
+}
+
+// storeRows reads the values of keys into key and value rows, in the order of keys
+func storeRows(store engine.Store, keys []string) resultSet {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if err != nil {
+			log.Fatalf("Error getting key %s: %v", key, err)
+		}
+		values[key] = value
+	}
+	return keyValueRows(keys, values)
+}
+
+func sortedKeys(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// queryRows returns the result of a statement as rows: the selected or changed rows,
+// or the stages of an EXPLAIN plan
+func queryRows(stmt query.Statement, result *query.ExecuteResult) resultSet {
+	if ex := result.Explain; ex != nil {
+		rs := resultSet{Columns: []string{"stage", "detail"}, Explain: ex}
+		if ex.Analyzed {
+			rs.Columns = append(rs.Columns, "rows", "duration_ms")
+		}
+		for _, stage := range ex.Stages {
+			rs.Rows = append(rs.Rows, map[string]string{
+				"stage":       stage.Name,
+				"detail":      stage.Detail,
+				"rows":        strconv.Itoa(stage.Rows),
+				"duration_ms": strconv.FormatFloat(stage.DurationMs, 'f', 3, 64),
+			})
+		}
+		return rs
+	}
+
+	rs := resultSet{Columns: result.Columns, Rows: result.Rows}
+	switch stmt.(type) {
+	case *query.InsertStatement, *query.UpdateStatement, *query.DeleteStatement:
+		rs.Affected = &result.Affected
+	}
+	return rs
+}
+
+// statsRows returns storage statistics as name and value rows
+func statsRows(store engine.Store, opts engine.Options) resultSet {
+	rs := resultSet{Columns: []string{"name", "value"}}
+	add := func(name string, value interface{}) {
+		rs.Rows = append(rs.Rows, map[string]string{"name": name, "value": statValue(value)})
+	}
+
+	add("engine", store.Engine())
+	if opts.Namespace != "" {
+		add("namespace", opts.Namespace)
+	}
+
+	baseStore, ok := store.(engine.BaseStore)
+	if !ok {
+		stats, err := store.Stats()
+		if err != nil {
+			log.Fatalf("Error getting stats: %v", err)
+		}
+		for _, key := range sortedStatKeys(stats) {
+			add(key, stats[key])
+		}
+		return rs
+	}
+
+	extStore := baseStore.Base()
+	stats, err := extStore.GetCompactionStats()
+	if err != nil {
+		log.Fatalf("Error getting compaction stats: %v", err)
+	}
+	indexStats, err := extStore.GetIndexStats()
+	if err != nil {
+		log.Fatalf("Error getting index stats: %v", err)
+	}
+
+	add("format", opts.Format)
+	add("index", opts.IndexType)
+	add("auto_compaction", stats.Enabled)
+	add("operation_count", stats.OperationCount)
+	add("file_size", stats.FileSize)
+	add("deleted_ratio", stats.DeletedRatio)
+	add("next_compaction_at", stats.NextCompactionAt)
+	if stats.LastCompaction > 0 {
+		add("last_compaction", time.Unix(stats.LastCompaction, 0).UTC().Format(time.RFC3339))
+	} else {
+		rs.Rows = append(rs.Rows, map[string]string{"name": "last_compaction"})
+	}
+	add("index_enabled", indexStats["enabled"])
+	add("index_type", indexStats["type"])
+	add("index_size", indexStats["size"])
+	add("index_memory_usage", indexStats["memory_usage"])
+	for _, def := range extStore.SecondaryIndexes() {
+		add("secondary_index."+def.Name, indexStats["secondary"].(map[string]interface{})[def.Name])
+	}
+	if fulltextStats, ok := indexStats["fulltext"]; ok {
+		add("fulltext_index", fulltextStats)
+	}
+	return rs
+}
+
+// statValue formats a statistic; maps, lists and structs are encoded as JSON
+func statValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// sortedStatKeys returns the names of engine statistics in order, without the engine name
+func sortedStatKeys(stats map[string]interface{}) []string {
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		if key != "engine" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseQueryParam converts a --param value to a query argument: JSON numbers, true,
+// false and null bind as their types, anything else as a string
+func parseQueryParam(arg string) interface{} {
+	decoder := json.NewDecoder(strings.NewReader(arg))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil && !decoder.More() {
+		switch value.(type) {
+		case json.Number, bool, nil:
+			return value
+		}
+	}
+	return arg
+}
+
 func printUsage() {
 	fmt.Println("🔨 Moz KVストア - コマンドライン使用法:")
 	fmt.Println("")
 	fmt.Println("Global Flags:")
 	fmt.Println("  --format <text|binary>  - ストレージフォーマット指定 (default: text)")
-	fmt.Println("  --index <hash|btree|none> - インデックス方式指定 (default: none)")
+	fmt.Println("  --index <hash|btree|radix|none> - インデックス方式指定 (default: none)")
+	fmt.Println("  --engine <log|lsm|partitioned|async> - ストレージエンジン指定 (default: log)")
+	fmt.Println("  --partitions <n>        - パーティション数指定 (1-16, partitionedエンジン)")
+	fmt.Println("  --ns <name>             - ネームスペース指定 (default: default)")
 	fmt.Println("  --daemon                - デーモンモード使用（高性能）")
 	fmt.Println("  --local                 - ローカル実行強制（デーモンバイパス）")
+	fmt.Println("  --output <table|json|jsonl|csv|tsv> - query・list・range・prefix・sorted・statsの出力形式（コマンドの後にも指定可）")
 	fmt.Println("  --help                  - ヘルプメッセージ表示")
 	fmt.Println("")
 	fmt.Println("基本操作:")
 	fmt.Println("  moz put <key> <value>  - キー・バリューの保存")
 	fmt.Println("  moz get <key>          - キーの値を取得")
 	fmt.Println("  moz del <key>          - キーを削除")
+	fmt.Println("  moz del --prefix <prefix>     - プレフィックス一致キーを一括削除")
+	fmt.Println("  moz del --range <start> <end> - 範囲内のキーを一括削除")
 	fmt.Println("  moz list               - 全キー・バリューを表示")
 	fmt.Println("  moz help               - ヘルプメッセージ表示")
 	fmt.Println("")
@@ -834,21 +1716,48 @@ func printUsage() {
 	fmt.Println("  moz range <start> <end> - 範囲検索")
 	fmt.Println("  moz prefix <prefix>     - プレフィックス検索")
 	fmt.Println("  moz sorted              - ソート済み一覧")
+	fmt.Println("  moz search \"terms\"      - 値の全文検索（出現頻度順）")
 	fmt.Println("")
 	fmt.Println("クエリ言語:")
 	fmt.Println("  moz query \"SELECT * FROM moz WHERE key = 'value'\"")
 	fmt.Println("  moz query \"SELECT * FROM moz WHERE key LIKE 'user%'\"")
 	fmt.Println("  moz query \"SELECT COUNT(*) FROM moz WHERE value CONTAINS 'admin'\"")
+	fmt.Println("  moz query \"SELECT key FROM moz WHERE value MATCH 'red shoes'\"")
+	fmt.Println("  moz query \"SELECT value.user.name FROM moz WHERE value.age >= 30\"")
+	fmt.Println("  moz query \"SELECT SPLIT_PART(key, ':', 1), COUNT(*), AVG(value.age) FROM moz GROUP BY SPLIT_PART(key, ':', 1) HAVING COUNT(*) > 1\"")
+	fmt.Println("  moz query \"SELECT key, value.price * value.qty AS total FROM moz WHERE LOWER(value.name) LIKE 'a%' ORDER BY total DESC\"")
+	fmt.Println("  moz query \"INSERT INTO moz (key, value) VALUES ('user:1', 'Alice')\"")
+	fmt.Println("  moz query \"UPDATE moz SET value = 'done' WHERE key LIKE 'task:%'\"")
+	fmt.Println("  moz query --dry-run \"DELETE FROM moz WHERE key LIKE 'tmp:%'\"  - 変更せずに対象行を表示")
+	fmt.Println("  moz query --param user:1 --param 30 \"SELECT * FROM moz WHERE key = ? AND value.age >= ?\"")
+	fmt.Println("  moz query \"EXPLAIN ANALYZE SELECT * FROM moz WHERE key LIKE 'user:%'\"  - 実行計画と各段階の行数・時間")
 	fmt.Println("")
 	fmt.Println("管理操作:")
 	fmt.Println("  moz compact            - ストレージ最適化")
 	fmt.Println("  moz stats              - ストレージ統計表示")
-	fmt.Println("  moz rebuild-index      - インデックス再構築")
+	fmt.Println("  moz rebuild-index      - インデックス再構築（セカンダリインデックス含む）")
+	fmt.Println("  moz index create <name> [field] - 値（またはJSONフィールド）のセカンダリインデックス作成")
+	fmt.Println("  moz index drop <name>  - セカンダリインデックス削除")
+	fmt.Println("  moz index fulltext [field] - 値（またはJSONフィールド）の全文検索インデックス作成")
+	fmt.Println("  moz index drop-fulltext - 全文検索インデックス削除")
+	fmt.Println("  moz index list         - セカンダリインデックス一覧")
 	fmt.Println("  moz validate-index     - インデックス検証")
 	fmt.Println("")
+	fmt.Println("ネームスペース:")
+	fmt.Println("  moz --ns <name> <command> - 指定ネームスペースで操作（初回の書き込みで作成、未作成なら読み取りはエラー）")
+	fmt.Println("  moz ns list               - ネームスペース一覧")
+	fmt.Println("  moz ns drop <name>        - ネームスペースと全データを削除")
+	fmt.Println("")
 	fmt.Println("フォーマット操作:")
 	fmt.Println("  moz convert <from> <to> - フォーマット変換 (text ↔ binary)")
 	fmt.Println("  moz validate <format>   - ファイル整合性検証")
+	fmt.Println("  moz migrate --to <lsm|binary> - ログストアの移行（再開可能・検証付き）")
+	fmt.Println("  moz --engine=lsm ingest <file.sst>... - 外部SSTableの一括取り込み")
+	fmt.Println("")
+	fmt.Println("LSM診断:")
+	fmt.Println("  moz lsm levels                 - レベル別SSTable・キー範囲・Bloom FPR表示")
+	fmt.Println("  moz sst dump <file.sst> [--limit N] - SSTableのメタデータ・エントリ表示")
+	fmt.Println("  moz sst verify <file.sst>...   - SSTableの整合性検証")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  moz daemon start                    # デーモン開始")
@@ -857,7 +1766,10 @@ func printUsage() {
 	fmt.Println("  moz --format=binary put key value   # バイナリ形式で保存")
 	fmt.Println("  moz --index=hash put user alice     # Hash Index使用")
 	fmt.Println("  moz --index=btree range a z         # B-Tree Index範囲検索")
+	fmt.Println("  moz --engine=lsm put user alice     # LSM-Treeエンジンで保存")
+	fmt.Println("  moz --ns billing put invoice:1 100  # billingネームスペースに保存")
 	fmt.Println("  moz query \"SELECT * FROM moz WHERE key LIKE 'user%'\" # SQLライククエリ")
+	fmt.Println("  moz query --output csv \"SELECT key, value.age FROM moz\"  # CSV出力（列はSELECTの順）")
 	fmt.Println("")
 	fmt.Println("🎯 Performance Tips:")
 	fmt.Println("  • デーモンモードで9倍高速化: moz daemon start")
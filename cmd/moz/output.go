@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats of --output. Without the flag, commands print their usual text.
+const (
+	outputText  = ""
+	outputTable = "table"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+// outputCommands are the commands that accept --output
+var outputCommands = map[string]bool{
+	"query": true, "list": true, "range": true, "prefix": true, "sorted": true, "stats": true,
+}
+
+// validateOutput checks an --output value
+func validateOutput(format string) error {
+	switch format {
+	case outputText, outputTable, outputJSON, outputJSONL, outputCSV, outputTSV:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q: expected table, json, jsonl, csv or tsv", format)
+	}
+}
+
+// extractOutputFlag removes --output <format> or --output=<format> from command
+// arguments and returns the remaining arguments and the format, or def if absent
+func extractOutputFlag(args []string, def string) ([]string, string, error) {
+	format := def
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--output" || args[i] == "-output":
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("--output requires a format")
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(args[i], "--output="):
+			format = strings.TrimPrefix(args[i], "--output=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, format, validateOutput(format)
+}
+
+// resultSet is the tabular output of a command. Rows lack the columns they have no
+// value for, which the formats print as null (json, jsonl, table) or empty (csv, tsv).
+type resultSet struct {
+	Columns  []string
+	Rows     []map[string]string
+	Affected *int        // Rows changed by INSERT, UPDATE and DELETE
+	Explain  interface{} // Plan of EXPLAIN statements
+}
+
+// keyValueRows returns key and value rows in the order of keys
+func keyValueRows(keys []string, values map[string]string) resultSet {
+	rs := resultSet{Columns: []string{"key", "value"}, Rows: make([]map[string]string, 0, len(keys))}
+	for _, key := range keys {
+		rs.Rows = append(rs.Rows, map[string]string{"key": key, "value": values[key]})
+	}
+	return rs
+}
+
+// writeResultSet prints a result set in a machine-readable format
+func writeResultSet(w io.Writer, format string, rs resultSet) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, rs)
+	case outputJSONL:
+		for _, row := range rs.Rows {
+			line, err := orderedRow{rs.Columns, row}.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(rs.Columns); err != nil {
+			return err
+		}
+		for _, row := range rs.Rows {
+			record := make([]string, len(rs.Columns))
+			for i, column := range rs.Columns {
+				record[i] = row[column]
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case outputTSV:
+		lines := []string{tsvLine(rs.Columns)}
+		for _, row := range rs.Rows {
+			record := make([]string, len(rs.Columns))
+			for i, column := range rs.Columns {
+				record[i] = row[column]
+			}
+			lines = append(lines, tsvLine(record))
+		}
+		_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+		return err
+	default:
+		return writeTable(w, rs)
+	}
+}
+
+// jsonDocument is the schema of --output json
+type jsonDocument struct {
+	Columns  []string     `json:"columns"`
+	Rows     []orderedRow `json:"rows"`
+	Count    int          `json:"count"`
+	Affected *int         `json:"affected,omitempty"`
+	Explain  interface{}  `json:"explain,omitempty"`
+}
+
+func writeJSON(w io.Writer, rs resultSet) error {
+	doc := jsonDocument{Columns: rs.Columns, Rows: make([]orderedRow, len(rs.Rows)), Count: len(rs.Rows), Affected: rs.Affected, Explain: rs.Explain}
+	for i, row := range rs.Rows {
+		doc.Rows[i] = orderedRow{rs.Columns, row}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// orderedRow encodes a row as a JSON object with its members in column order
+type orderedRow struct {
+	columns []string
+	values  map[string]string
+}
+
+// MarshalJSON implements json.Marshaler
+func (r orderedRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeJSONString(&buf, column); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		value, ok := r.values[column]
+		if !ok {
+			buf.WriteString("null")
+			continue
+		}
+		if err := encodeJSONString(&buf, value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeJSONString writes a JSON string without escaping HTML characters
+func encodeJSONString(buf *bytes.Buffer, s string) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+	return nil
+}
+
+// tsvLine joins fields with tabs, escaping backslashes, tabs and line breaks as
+// \\, \t, \n and \r so that every record stays on one line
+func tsvLine(fields []string) string {
+	escaped := make([]string, len(fields))
+	for i, field := range fields {
+		escaped[i] = tsvEscaper.Replace(field)
+	}
+	return strings.Join(escaped, "\t")
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeTable prints aligned columns under a header, followed by the row count
+func writeTable(w io.Writer, rs resultSet) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(rs.Columns))
+	rule := make([]string, len(rs.Columns))
+	for i, column := range rs.Columns {
+		header[i] = tableCell(column)
+		rule[i] = strings.Repeat("-", len([]rune(header[i])))
+	}
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	fmt.Fprintln(table, strings.Join(rule, "\t"))
+	for _, row := range rs.Rows {
+		cells := make([]string, len(rs.Columns))
+		for i, column := range rs.Columns {
+			if value, ok := row[column]; ok {
+				cells[i] = tableCell(value)
+			} else {
+				cells[i] = "null"
+			}
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("(%d rows)", len(rs.Rows))
+	if rs.Affected != nil {
+		summary = fmt.Sprintf("(%d rows affected)", *rs.Affected)
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+// tableCell keeps a value on one table line
+func tableCell(value string) string {
+	return tsvEscaper.Replace(value)
+}
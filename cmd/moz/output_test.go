@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteResultSet(t *testing.T) {
+	rs := resultSet{
+		Columns: []string{"value", "key"},
+		Rows: []map[string]string{
+			{"key": "user:1", "value": `Alice, "A"`},
+			{"key": "user:2", "value": "tab\there\nline <b>"},
+			{"key": "user:3"},
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{outputCSV, "value,key\n\"Alice, \"\"A\"\"\",user:1\n\"tab\there\nline <b>\",user:2\n,user:3\n"},
+		{outputTSV, "value\tkey\nAlice, \"A\"\tuser:1\ntab\\there\\nline <b>\tuser:2\n\tuser:3\n"},
+		{outputJSONL, `{"value":"Alice, \"A\"","key":"user:1"}` + "\n" +
+			`{"value":"tab\there\nline <b>","key":"user:2"}` + "\n" +
+			`{"value":null,"key":"user:3"}` + "\n"},
+		{outputTable, "value                key\n-----                ---\n" +
+			"Alice, \"A\"           user:1\ntab\\there\\nline <b>  user:2\nnull                 user:3\n(3 rows)\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writeResultSet(&out, tt.format, rs); err != nil {
+			t.Fatalf("%s: write failed: %v", tt.format, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", tt.format, tt.expected, out.String())
+		}
+	}
+}
+
+func TestWriteResultSet_JSON(t *testing.T) {
+	affected := 2
+	rs := resultSet{
+		Columns:  []string{"key", "value"},
+		Rows:     []map[string]string{{"key": "b", "value": "2"}, {"key": "a"}},
+		Affected: &affected,
+	}
+
+	var out bytes.Buffer
+	if err := writeResultSet(&out, outputJSON, rs); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	// Rows keep their order and their members follow the column order
+	compact := strings.Join(strings.Fields(out.String()), "")
+	expected := `{"columns":["key","value"],"rows":[{"key":"b","value":"2"},{"key":"a","value":null}],"count":2,"affected":2}`
+	if compact != expected {
+		t.Errorf("Expected %s, got %s", expected, compact)
+	}
+
+	var doc struct {
+		Columns []string             `json:"columns"`
+		Rows    []map[string]*string `json:"rows"`
+		Count   int                  `json:"count"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Count != 2 || doc.Rows[1]["value"] != nil {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+}
+
+func TestExtractOutputFlag(t *testing.T) {
+	args, format, err := extractOutputFlag([]string{"--output", "csv", "SELECT", "*", "--output=jsonl"}, "")
+	if err != nil || format != outputJSONL || strings.Join(args, " ") != "SELECT *" {
+		t.Errorf("Expected jsonl and the query, got %q %v %v", format, args, err)
+	}
+	if _, format, _ := extractOutputFlag([]string{"a", "z"}, outputTSV); format != outputTSV {
+		t.Errorf("Expected the default format, got %q", format)
+	}
+	if _, _, err := extractOutputFlag([]string{"--output", "xml"}, ""); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, _, err := extractOutputFlag([]string{"--output"}, ""); err == nil {
+		t.Error("Expected an error for a missing format")
+	}
+}
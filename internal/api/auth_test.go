@@ -10,6 +10,7 @@ import (
 )
 
 func TestLogin(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -60,6 +61,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestUnauthorizedAccess(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -84,6 +86,7 @@ func TestUnauthorizedAccess(t *testing.T) {
 }
 
 func TestHealthCheckNoAuth(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -98,6 +101,7 @@ func TestHealthCheckNoAuth(t *testing.T) {
 }
 
 func TestInvalidCredentials(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
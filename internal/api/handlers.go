@@ -1,14 +1,21 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyasuto/moz/internal/engine"
+	"github.com/nyasuto/moz/internal/query"
 )
 
 func (s *Server) putKey(c *gin.Context) {
 	start := time.Now()
+	store, ok := s.storeFor(c, true)
+	if !ok {
+		return
+	}
 	key := c.Param("key")
 
 	var req PutRequest
@@ -22,7 +29,7 @@ func (s *Server) putKey(c *gin.Context) {
 		return
 	}
 
-	if err := s.store.Put(key, req.Value); err != nil {
+	if err := store.Put(key, req.Value); err != nil {
 		s.errorResponse(c, http.StatusInternalServerError, "PUT_FAILED", err.Error())
 		return
 	}
@@ -36,6 +43,10 @@ func (s *Server) putKey(c *gin.Context) {
 
 func (s *Server) getKey(c *gin.Context) {
 	start := time.Now()
+	store, ok := s.storeFor(c, false)
+	if !ok {
+		return
+	}
 	key := c.Param("key")
 
 	if key == "" {
@@ -43,7 +54,7 @@ func (s *Server) getKey(c *gin.Context) {
 		return
 	}
 
-	value, err := s.store.Get(key)
+	value, err := store.Get(key)
 	if err != nil {
 		s.errorResponse(c, http.StatusNotFound, "KEY_NOT_FOUND", err.Error())
 		return
@@ -57,6 +68,10 @@ func (s *Server) getKey(c *gin.Context) {
 
 func (s *Server) deleteKey(c *gin.Context) {
 	start := time.Now()
+	store, ok := s.storeFor(c, true)
+	if !ok {
+		return
+	}
 	key := c.Param("key")
 
 	if key == "" {
@@ -64,7 +79,7 @@ func (s *Server) deleteKey(c *gin.Context) {
 		return
 	}
 
-	if err := s.store.Delete(key); err != nil {
+	if err := store.Delete(key); err != nil {
 		s.errorResponse(c, http.StatusNotFound, "KEY_NOT_FOUND", err.Error())
 		return
 	}
@@ -75,10 +90,57 @@ func (s *Server) deleteKey(c *gin.Context) {
 	}, time.Since(start))
 }
 
+// deleteKeys removes every key matching ?prefix= or the inclusive range ?start=&end=
+func (s *Server) deleteKeys(c *gin.Context) {
+	start := time.Now()
+	store, ok := s.storeFor(c, true)
+	if !ok {
+		return
+	}
+	prefix := c.Query("prefix")
+	rangeStart, rangeEnd := c.Query("start"), c.Query("end")
+
+	rangeStore, ok := store.(engine.RangeDeleter)
+	if !ok {
+		s.errorResponse(c, http.StatusNotImplemented, "NOT_SUPPORTED", engine.Unsupported(store, "Range deletes").Error())
+		return
+	}
+
+	switch {
+	case prefix != "":
+		if err := rangeStore.DeletePrefix(prefix); err != nil {
+			s.errorResponse(c, http.StatusInternalServerError, "DELETE_FAILED", err.Error())
+			return
+		}
+		s.successResponse(c, http.StatusOK, gin.H{
+			"prefix":  prefix,
+			"deleted": true,
+		}, time.Since(start))
+
+	case rangeStart != "" && rangeEnd != "":
+		if err := rangeStore.DeleteRange(rangeStart, rangeEnd); err != nil {
+			s.errorResponse(c, http.StatusBadRequest, "DELETE_FAILED", err.Error())
+			return
+		}
+		s.successResponse(c, http.StatusOK, gin.H{
+			"start":   rangeStart,
+			"end":     rangeEnd,
+			"deleted": true,
+		}, time.Since(start))
+
+	default:
+		s.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "Either prefix or both start and end are required")
+	}
+}
+
 func (s *Server) listKeys(c *gin.Context) {
 	start := time.Now()
+	store, ok := s.storeFor(c, false)
+	if !ok {
+		return
+	}
 
-	keys, err := s.store.List()
+	keys, err := store.List()
 	if err != nil {
 		s.errorResponse(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
 		return
@@ -86,7 +148,7 @@ func (s *Server) listKeys(c *gin.Context) {
 
 	entries := make([]KVEntry, 0, len(keys))
 	for _, key := range keys {
-		if value, err := s.store.Get(key); err == nil {
+		if value, err := store.Get(key); err == nil {
 			entries = append(entries, KVEntry{
 				Key:   key,
 				Value: value,
@@ -101,6 +163,106 @@ func (s *Server) listKeys(c *gin.Context) {
 	}, time.Since(start))
 }
 
+// runQuery executes a query language statement, including EXPLAIN and data-modifying statements,
+// binding the request's params to its placeholders
+func (s *Server) runQuery(c *gin.Context) {
+	start := time.Now()
+
+	var req QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	store, ok := s.storeFor(c, query.IsWrite(req.Query) && !req.DryRun)
+	if !ok {
+		return
+	}
+
+	executor := query.NewExecutor(store)
+	executor.SetDryRun(req.DryRun)
+	stmt, err := executor.Prepare(req.Query)
+	if err != nil {
+		s.errorResponse(c, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+
+	result := stmt.Execute(req.Params...)
+	if result.Error != nil {
+		s.errorResponse(c, http.StatusBadRequest, "QUERY_FAILED", result.Error.Error())
+		return
+	}
+
+	response := QueryResponse{
+		Rows:     result.Rows,
+		Columns:  result.Columns,
+		Count:    len(result.Rows),
+		Affected: result.Affected,
+		Explain:  result.Explain,
+	}
+	if result.Explain != nil {
+		response.Plan = result.Explain.String()
+	}
+	s.successResponse(c, http.StatusOK, response, time.Since(start))
+}
+
+// storeFor returns the store of the request's namespace, the default one for routes without :ns.
+// Only writes create a missing namespace; other requests get 404.
+func (s *Server) storeFor(c *gin.Context, write bool) (engine.Store, bool) {
+	var store engine.Store
+	var err error
+	if write {
+		store, err = s.namespaces.Create(c.Param("ns"))
+	} else {
+		store, err = s.namespaces.Store(c.Param("ns"))
+	}
+	if errors.Is(err, engine.ErrNamespaceNotFound) {
+		s.errorResponse(c, http.StatusNotFound, "NAMESPACE_NOT_FOUND", err.Error())
+		return nil, false
+	}
+	if err != nil {
+		s.errorResponse(c, http.StatusBadRequest, "INVALID_NAMESPACE", err.Error())
+		return nil, false
+	}
+	return store, true
+}
+
+func (s *Server) listNamespaces(c *gin.Context) {
+	start := time.Now()
+
+	names, err := s.namespaces.List()
+	if err != nil {
+		s.errorResponse(c, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+		return
+	}
+
+	s.successResponse(c, http.StatusOK, gin.H{
+		"namespaces": names,
+		"count":      len(names),
+	}, time.Since(start))
+}
+
+// dropNamespace deletes a namespace and all of its data
+func (s *Server) dropNamespace(c *gin.Context) {
+	start := time.Now()
+	name := c.Param("ns")
+
+	if err := engine.ValidateNamespace(name); err != nil || name == engine.DefaultNamespace {
+		s.errorResponse(c, http.StatusBadRequest, "INVALID_NAMESPACE", "The default namespace cannot be dropped and names may only contain letters, digits, '_' and '-'")
+		return
+	}
+
+	if err := s.namespaces.Drop(name); err != nil {
+		s.errorResponse(c, http.StatusNotFound, "NAMESPACE_NOT_FOUND", err.Error())
+		return
+	}
+
+	s.successResponse(c, http.StatusOK, gin.H{
+		"namespace": name,
+		"dropped":   true,
+	}, time.Since(start))
+}
+
 func (s *Server) successResponse(c *gin.Context, status int, data interface{}, duration time.Duration) {
 	c.JSON(status, APIResponse{
 		Status: "success",
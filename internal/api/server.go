@@ -1,22 +1,34 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyasuto/moz/internal/engine"
 	"github.com/nyasuto/moz/internal/kvstore"
 )
 
 type Server struct {
-	store  *kvstore.KVStore
-	port   string
-	router *gin.Engine
-	auth   *AuthManager
+	namespaces *engine.Namespaces
+	port       string
+	router     *gin.Engine
+	auth       *AuthManager
 }
 
 func NewServer(dataPath, port string) *Server {
-	store := kvstore.New()
+	return NewServerWithStore(engine.NewLogStore(kvstore.New()), port)
+}
+
+// NewServerWithStore creates a server backed by the given storage engine.
+// Other namespaces are opened with the engine's default options.
+func NewServerWithStore(store engine.Store, port string) *Server {
+	return NewServerWithNamespaces(engine.NewNamespaces(store, engine.DefaultOptions()), port)
+}
+
+// NewServerWithNamespaces creates a server serving every namespace of a storage engine
+func NewServerWithNamespaces(namespaces *engine.Namespaces, port string) *Server {
 	auth := NewAuthManager()
 
 	gin.SetMode(gin.ReleaseMode)
@@ -24,10 +36,10 @@ func NewServer(dataPath, port string) *Server {
 	router.Use(gin.Logger(), gin.Recovery())
 
 	s := &Server{
-		store:  store,
-		port:   port,
-		router: router,
-		auth:   auth,
+		namespaces: namespaces,
+		port:       port,
+		router:     router,
+		auth:       auth,
 	}
 
 	s.setupRoutes()
@@ -45,23 +57,41 @@ func (s *Server) setupRoutes() {
 		protected.Use(s.AuthMiddleware())
 		{
 			protected.GET("/stats", s.getStats)
+			protected.POST("/query", s.runQuery)
+			s.setupKVRoutes(protected.Group("/kv"))
 
-			kv := protected.Group("/kv")
+			// Namespaced routes; /kv, /stats and /query above use the default namespace
+			protected.GET("/ns", s.listNamespaces)
+			ns := protected.Group("/ns/:ns")
 			{
-				kv.PUT("/:key", s.putKey)
-				kv.GET("/:key", s.getKey)
-				kv.DELETE("/:key", s.deleteKey)
-				kv.GET("", s.listKeys)
+				ns.DELETE("", s.dropNamespace)
+				ns.GET("/stats", s.getStats)
+				ns.POST("/query", s.runQuery)
+				s.setupKVRoutes(ns.Group("/kv"))
 			}
 		}
 	}
 }
 
+// setupKVRoutes registers the key-value routes of a namespace
+func (s *Server) setupKVRoutes(kv *gin.RouterGroup) {
+	kv.PUT("/:key", s.putKey)
+	kv.GET("/:key", s.getKey)
+	kv.DELETE("/:key", s.deleteKey)
+	kv.GET("", s.listKeys)
+	kv.DELETE("", s.deleteKeys)
+}
+
 func (s *Server) Start() error {
 	fmt.Printf("Starting moz-server on port %s\n", s.port)
 	return http.ListenAndServe(":"+s.port, s.router)
 }
 
+// Close closes the stores of every open namespace
+func (s *Server) Close() error {
+	return s.namespaces.Close()
+}
+
 func (s *Server) healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
@@ -70,7 +100,11 @@ func (s *Server) healthCheck(c *gin.Context) {
 }
 
 func (s *Server) getStats(c *gin.Context) {
-	stats, err := s.store.GetStats()
+	stats, err := s.namespaces.Stats(c.Param("ns"))
+	if errors.Is(err, engine.ErrNamespaceNotFound) {
+		s.errorResponse(c, http.StatusNotFound, "NAMESPACE_NOT_FOUND", err.Error())
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, APIResponse{
 			Status: "error",
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyasuto/moz/internal/engine"
 )
 
 func getAuthToken(t *testing.T, server *Server) string {
@@ -50,6 +51,7 @@ func TestMain(m *testing.M) {
 }
 
 func TestHealthCheck(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -72,6 +74,7 @@ func TestHealthCheck(t *testing.T) {
 }
 
 func TestPutAndGet(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -112,6 +115,7 @@ func TestPutAndGet(t *testing.T) {
 }
 
 func TestGetNonExistentKey(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -137,6 +141,7 @@ func TestGetNonExistentKey(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -172,7 +177,83 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestDeletePrefix(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
+	server := NewServer("test.bin", "8080")
+	defer os.Remove("test.bin")
+
+	token := getAuthToken(t, server)
+
+	for _, key := range []string{"tenant-a:1", "tenant-a:2", "tenant-b:1"} {
+		putBody, _ := json.Marshal(PutRequest{Value: "value-" + key})
+		req, _ := http.NewRequest("PUT", "/api/v1/kv/"+key, bytes.NewBuffer(putBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Missing parameters are rejected
+	req, _ := http.NewRequest("DELETE", "/api/v1/kv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("DELETE without parameters: Expected status 400, got %d", resp.Code)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/kv?prefix=tenant-a:", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp = httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Errorf("DELETE prefix: Expected status 200, got %d", resp.Code)
+	}
+
+	expected := map[string]int{
+		"tenant-a:1": http.StatusNotFound,
+		"tenant-a:2": http.StatusNotFound,
+		"tenant-b:1": http.StatusOK,
+	}
+	for key, status := range expected {
+		req, _ = http.NewRequest("GET", "/api/v1/kv/"+key, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp = httptest.NewRecorder()
+		server.router.ServeHTTP(resp, req)
+
+		if resp.Code != status {
+			t.Errorf("GET %s after DELETE prefix: Expected status %d, got %d", key, status, resp.Code)
+		}
+	}
+}
+
+func TestDeletePrefixUnsupportedEngine(t *testing.T) {
+	t.Setenv("MOZ_PARTITION_DIR", t.TempDir())
+
+	opts := engine.DefaultOptions()
+	opts.Engine = engine.Partitioned
+	store, err := engine.Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open partitioned engine: %v", err)
+	}
+	server := NewServerWithStore(store, "8080")
+	defer server.Close()
+
+	token := getAuthToken(t, server)
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/kv?prefix=tenant-a:", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	server.router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusNotImplemented {
+		t.Errorf("DELETE prefix on partitioned engine: Expected status 501, got %d", resp.Code)
+	}
+}
+
 func TestList(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	server := NewServer("test.bin", "8080")
 	defer os.Remove("test.bin")
 
@@ -209,3 +290,166 @@ func TestList(t *testing.T) {
 		t.Errorf("Expected success status, got %s", response.Status)
 	}
 }
+
+func TestNamespaces(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
+
+	namespaces, err := engine.OpenNamespaces(engine.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to open namespaces: %v", err)
+	}
+	server := NewServerWithNamespaces(namespaces, "8080")
+	defer server.Close()
+
+	token := getAuthToken(t, server)
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var reader *bytes.Buffer
+		if body != nil {
+			data, _ := json.Marshal(body)
+			reader = bytes.NewBuffer(data)
+		} else {
+			reader = bytes.NewBuffer(nil)
+		}
+		req, _ := http.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		server.router.ServeHTTP(resp, req)
+		return resp
+	}
+	value := func(resp *httptest.ResponseRecorder) interface{} {
+		var response APIResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		data, _ := response.Data.(map[string]interface{})
+		return data["value"]
+	}
+
+	do("PUT", "/api/v1/kv/user", PutRequest{Value: "default-value"})
+	if resp := do("PUT", "/api/v1/ns/billing/kv/user", PutRequest{Value: "billing-value"}); resp.Code != http.StatusOK {
+		t.Fatalf("PUT in namespace: Expected status 200, got %d", resp.Code)
+	}
+
+	if resp := do("GET", "/api/v1/ns/billing/kv/user", nil); resp.Code != http.StatusOK || value(resp) != "billing-value" {
+		t.Errorf("GET in namespace: Expected billing-value, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := do("GET", "/api/v1/kv/user", nil); value(resp) != "default-value" {
+		t.Errorf("GET in default namespace: Expected default-value, got %s", resp.Body.String())
+	}
+	if resp := do("GET", "/api/v1/ns/bad.name/kv/user", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("Invalid namespace: Expected status 400, got %d", resp.Code)
+	}
+
+	resp := do("GET", "/api/v1/ns/billing/stats", nil)
+	if resp.Code != http.StatusOK || !bytes.Contains(resp.Body.Bytes(), []byte(`"namespace":"billing"`)) {
+		t.Errorf("Namespace stats: Expected billing stats, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	// Reads never create a namespace
+	if resp := do("GET", "/api/v1/ns/ghost/kv/user", nil); resp.Code != http.StatusNotFound || !bytes.Contains(resp.Body.Bytes(), []byte("NAMESPACE_NOT_FOUND")) {
+		t.Errorf("GET in missing namespace: Expected NAMESPACE_NOT_FOUND, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := do("GET", "/api/v1/ns/ghost/stats", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("Stats of missing namespace: Expected status 404, got %d", resp.Code)
+	}
+	if resp := do("POST", "/api/v1/ns/ghost/query", QueryRequest{Query: "SELECT * FROM moz"}); resp.Code != http.StatusNotFound {
+		t.Errorf("SELECT in missing namespace: Expected status 404, got %d", resp.Code)
+	}
+	resp = do("GET", "/api/v1/ns", nil)
+	if !bytes.Contains(resp.Body.Bytes(), []byte(`"namespaces":["billing","default"]`)) {
+		t.Errorf("List namespaces: Unexpected response %s", resp.Body.String())
+	}
+
+	if resp := do("DELETE", "/api/v1/ns/default", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("Drop default namespace: Expected status 400, got %d", resp.Code)
+	}
+	if resp := do("DELETE", "/api/v1/ns/billing", nil); resp.Code != http.StatusOK {
+		t.Errorf("Drop namespace: Expected status 200, got %d", resp.Code)
+	}
+	if resp := do("GET", "/api/v1/ns/billing/kv/user", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("GET after drop: Expected status 404, got %d", resp.Code)
+	}
+	if resp := do("GET", "/api/v1/kv/user", nil); value(resp) != "default-value" {
+		t.Errorf("Default namespace after drop: Expected default-value, got %s", resp.Body.String())
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
+
+	namespaces, err := engine.OpenNamespaces(engine.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to open namespaces: %v", err)
+	}
+	server := NewServerWithNamespaces(namespaces, "8080")
+	defer server.Close()
+
+	token := getAuthToken(t, server)
+	run := func(path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		server.router.ServeHTTP(resp, req)
+
+		var response APIResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		result, _ := response.Data.(map[string]interface{})
+		return resp, result
+	}
+
+	resp, result := run("/api/v1/ns/shop/query", QueryRequest{Query: "INSERT INTO moz VALUES ('user:1', 'Alice'), ('user:2', 'Bob'), ('item:1', 'Pen')"})
+	if resp.Code != http.StatusOK || result["affected"] != float64(3) {
+		t.Fatalf("INSERT: Expected 3 affected rows, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp, result = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELECT key FROM moz WHERE key LIKE 'user:%'"})
+	if resp.Code != http.StatusOK || result["count"] != float64(2) {
+		t.Errorf("SELECT: Expected 2 rows, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp, result = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELECT key FROM moz WHERE key LIKE $1 AND value != $2", Params: []interface{}{"user:%", "Bob"}})
+	if resp.Code != http.StatusOK || result["count"] != float64(1) {
+		t.Errorf("SELECT with params: Expected 1 row, got %d %s", resp.Code, resp.Body.String())
+	}
+	resp, _ = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELECT key FROM moz WHERE key LIKE ? AND value != ?", Params: []interface{}{"user:%", "Bob"}})
+	if resp.Code != http.StatusOK {
+		t.Errorf("SELECT with params: Expected status 200, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp, _ = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELECT key FROM moz WHERE key = ?"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("Missing params: Expected status 400, got %d", resp.Code)
+	}
+
+	// The default namespace does not see the rows
+	if _, result = run("/api/v1/query", QueryRequest{Query: "SELECT key FROM moz"}); result["count"] != float64(0) {
+		t.Errorf("SELECT in default namespace: Expected 0 rows, got %v", result)
+	}
+
+	resp, result = run("/api/v1/ns/shop/query", QueryRequest{Query: "EXPLAIN ANALYZE SELECT key FROM moz WHERE key LIKE 'user:%' AND value != 'Bob'"})
+	explain, _ := result["explain"].(map[string]interface{})
+	if resp.Code != http.StatusOK || explain["access"] != "KEY PREFIX" || explain["rows_scanned"] != float64(2) || explain["rows_returned"] != float64(1) {
+		t.Errorf("EXPLAIN ANALYZE: Unexpected response %d %s", resp.Code, resp.Body.String())
+	}
+	if plan, _ := result["plan"].(string); !bytes.Contains([]byte(plan), []byte("Access: KEY PREFIX")) {
+		t.Errorf("EXPLAIN ANALYZE: Expected a text plan, got %q", plan)
+	}
+
+	resp, result = run("/api/v1/ns/shop/query", QueryRequest{Query: "DELETE FROM moz WHERE key LIKE 'user:%'", DryRun: true})
+	if resp.Code != http.StatusOK || result["affected"] != float64(2) {
+		t.Errorf("Dry-run DELETE: Expected 2 affected rows, got %d %s", resp.Code, resp.Body.String())
+	}
+	if _, result = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELECT COUNT(*) FROM moz"}); result["count"] != float64(1) {
+		t.Errorf("COUNT after dry run: Unexpected result %v", result)
+	}
+
+	if resp, _ = run("/api/v1/ns/shop/query", QueryRequest{Query: "SELEKT *"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("Invalid query: Expected status 400, got %d", resp.Code)
+	}
+	if resp, _ = run("/api/v1/ns/shop/query", QueryRequest{Query: "INSERT INTO moz VALUES ('item:1', 'again')"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("Duplicate INSERT: Expected status 400, got %d", resp.Code)
+	}
+}
@@ -1,5 +1,7 @@
 package api
 
+import "github.com/nyasuto/moz/internal/query"
+
 // APIResponse represents a standard API response
 type APIResponse struct {
 	Status   string      `json:"status"`
@@ -45,3 +47,20 @@ type BatchOperation struct {
 	Key   string `json:"key" binding:"required"`
 	Value string `json:"value,omitempty"`
 }
+
+// QueryRequest represents a query language request body
+type QueryRequest struct {
+	Query  string        `json:"query" binding:"required"`
+	Params []interface{} `json:"params,omitempty"`  // Arguments for the ? or $1 placeholders, in order
+	DryRun bool          `json:"dry_run,omitempty"` // Report INSERT, UPDATE and DELETE changes without writing
+}
+
+// QueryResponse represents the result of a query language statement
+type QueryResponse struct {
+	Rows     []map[string]string `json:"rows"`
+	Columns  []string            `json:"columns,omitempty"` // Column order of the rows
+	Count    int                 `json:"count"`
+	Affected int                 `json:"affected,omitempty"` // Rows changed by INSERT, UPDATE and DELETE
+	Explain  *query.Explanation  `json:"explain,omitempty"`  // Plan of EXPLAIN statements
+	Plan     string              `json:"plan,omitempty"`     // Text form of the EXPLAIN plan
+}
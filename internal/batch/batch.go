@@ -5,7 +5,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/engine"
 )
 
 // Operation represents a single batch operation
@@ -25,11 +25,11 @@ type BatchResult struct {
 
 // BatchExecutor executes batch operations
 type BatchExecutor struct {
-	store *kvstore.KVStore
+	store engine.Store
 }
 
 // NewBatchExecutor creates a new batch executor
-func NewBatchExecutor(store *kvstore.KVStore) *BatchExecutor {
+func NewBatchExecutor(store engine.Store) *BatchExecutor {
 	return &BatchExecutor{
 		store: store,
 	}
@@ -237,7 +237,7 @@ func (be *BatchExecutor) executeOperation(op Operation) BatchResult {
 		}
 
 	case "stats":
-		stats, err := be.store.GetCompactionStats()
+		stats, err := be.store.Stats()
 		if err != nil {
 			return BatchResult{
 				Success: false,
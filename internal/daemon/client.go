@@ -13,6 +13,7 @@ import (
 type Client struct {
 	socketPath string
 	timeout    time.Duration
+	namespace  string // Namespace sent with every request; empty selects the default
 }
 
 // NewClient creates a new daemon client
@@ -23,8 +24,20 @@ func NewClient() *Client {
 	}
 }
 
+// WithNamespace returns a copy of the client whose requests target the given namespace
+func (c *Client) WithNamespace(namespace string) *Client {
+	clone := *c
+	clone.namespace = namespace
+	return &clone
+}
+
 // ExecuteCommand executes a command via the daemon
 func (c *Client) ExecuteCommand(command string, args ...string) (interface{}, error) {
+	return c.send(Request{Command: command, Arguments: args})
+}
+
+// send sends a request to the daemon and returns its result
+func (c *Client) send(req Request) (interface{}, error) {
 	// Connect to daemon
 	conn, err := net.DialTimeout("unix", c.socketPath, c.timeout)
 	if err != nil {
@@ -39,13 +52,10 @@ func (c *Client) ExecuteCommand(command string, args ...string) (interface{}, er
 		return nil, fmt.Errorf("failed to set deadline: %w", err)
 	}
 
-	// Create request
-	req := Request{
-		ID:        fmt.Sprintf("client-%d", time.Now().UnixNano()),
-		Command:   command,
-		Arguments: args,
-		Timestamp: time.Now(),
-	}
+	// Fill in the request envelope
+	req.ID = fmt.Sprintf("client-%d", time.Now().UnixNano())
+	req.Namespace = c.namespace
+	req.Timestamp = time.Now()
 
 	// Send request
 	encoder := json.NewEncoder(conn)
@@ -95,6 +105,18 @@ func (c *Client) Delete(key string) error {
 	return err
 }
 
+// DeletePrefix executes a DELETE-PREFIX command via daemon
+func (c *Client) DeletePrefix(prefix string) error {
+	_, err := c.ExecuteCommand("delete-prefix", prefix)
+	return err
+}
+
+// DeleteRange executes a DELETE-RANGE command via daemon
+func (c *Client) DeleteRange(start, end string) error {
+	_, err := c.ExecuteCommand("delete-range", start, end)
+	return err
+}
+
 // List executes a LIST command via daemon
 func (c *Client) List() (map[string]string, error) {
 	result, err := c.ExecuteCommand("list")
@@ -118,6 +140,26 @@ func (c *Client) List() (map[string]string, error) {
 	return entries, nil
 }
 
+// Query executes a query language statement via daemon. The params are bound to the
+// ? or $1 placeholders of the query in order, never parsed as query text.
+func (c *Client) Query(queryStr string, params ...interface{}) (*QueryResult, error) {
+	result, err := c.send(Request{Command: "query", Arguments: []string{queryStr}, Parameters: params})
+	if err != nil {
+		return nil, err
+	}
+
+	// The result arrives as generic JSON; decode it again into its type
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected response type: %T", result)
+	}
+	var queryResult QueryResult
+	if err := json.Unmarshal(data, &queryResult); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	return &queryResult, nil
+}
+
 // Compact executes a COMPACT command via daemon
 func (c *Client) Compact() error {
 	_, err := c.ExecuteCommand("compact")
@@ -129,6 +171,48 @@ func (c *Client) Stats() (interface{}, error) {
 	return c.ExecuteCommand("stats")
 }
 
+// Namespaces returns the names of all namespaces served by the daemon
+func (c *Client) Namespaces() ([]string, error) {
+	result, err := c.ExecuteCommand("namespaces")
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type: %T", result)
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		if name, ok := item.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// DropNamespace deletes a namespace and all of its data via daemon
+func (c *Client) DropNamespace(namespace string) error {
+	_, err := c.ExecuteCommand("drop-namespace", namespace)
+	return err
+}
+
+// Engine returns the storage engine the daemon is serving
+func (c *Client) Engine() (string, error) {
+	result, err := c.ExecuteCommand("engine")
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := result.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected response type: %T", result)
+	}
+
+	return name, nil
+}
+
 // Ping checks if daemon is responsive
 func (c *Client) Ping() error {
 	result, err := c.ExecuteCommand("ping")
@@ -12,12 +12,13 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/engine"
+	"github.com/nyasuto/moz/internal/query"
 )
 
 // DaemonManager manages the background daemon process
 type DaemonManager struct {
-	store      *kvstore.KVStore
+	namespaces *engine.Namespaces
 	listener   net.Listener
 	socketPath string
 	ctx        context.Context
@@ -29,10 +30,20 @@ type DaemonManager struct {
 
 // Request represents a client request to the daemon
 type Request struct {
-	ID        string    `json:"id"`
-	Command   string    `json:"command"`
-	Arguments []string  `json:"arguments"`
-	Timestamp time.Time `json:"timestamp"`
+	ID         string        `json:"id"`
+	Command    string        `json:"command"`
+	Arguments  []string      `json:"arguments"`
+	Parameters []interface{} `json:"parameters,omitempty"` // Arguments for the placeholders of a query
+	Namespace  string        `json:"namespace,omitempty"`  // Empty selects the default namespace
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// QueryResult is the result of the query command
+type QueryResult struct {
+	Rows     []map[string]string `json:"rows"`
+	Columns  []string            `json:"columns,omitempty"` // Column order of the rows
+	Affected int                 `json:"affected,omitempty"`
+	Plan     string              `json:"plan,omitempty"` // Text form of an EXPLAIN plan
 }
 
 // Response represents a daemon response to the client
@@ -44,15 +55,15 @@ type Response struct {
 	Duration time.Duration `json:"duration"`
 }
 
-// NewDaemonManager creates a new daemon manager
-func NewDaemonManager(store *kvstore.KVStore) *DaemonManager {
+// NewDaemonManager creates a new daemon manager serving every namespace of an engine
+func NewDaemonManager(namespaces *engine.Namespaces) *DaemonManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Use temp directory for socket
 	socketPath := filepath.Join(os.TempDir(), "moz-daemon.sock")
 
 	return &DaemonManager{
-		store:      store,
+		namespaces: namespaces,
 		socketPath: socketPath,
 		ctx:        ctx,
 		cancel:     cancel,
@@ -111,6 +122,10 @@ func (d *DaemonManager) Stop() error {
 	_ = os.Remove(d.socketPath)
 
 	d.running = false
+
+	if err := d.namespaces.Close(); err != nil {
+		return fmt.Errorf("failed to close store: %w", err)
+	}
 	return nil
 }
 
@@ -199,13 +214,32 @@ func (d *DaemonManager) processRequest(req Request) Response {
 		ID: req.ID,
 	}
 
+	// Key-value commands run against the request's namespace
+	var store engine.Store
+	switch req.Command {
+	case "namespaces", "drop-namespace", "engine", "ping", "help":
+	default:
+		var err error
+		if writesData(req) {
+			store, err = d.namespaces.Create(req.Namespace)
+		} else {
+			store, err = d.namespaces.Store(req.Namespace)
+		}
+		if err != nil {
+			response.Success = false
+			response.Error = err.Error()
+			response.Duration = time.Since(start)
+			return response
+		}
+	}
+
 	switch req.Command {
 	case "put":
 		if len(req.Arguments) != 2 {
 			response.Success = false
 			response.Error = "put requires exactly 2 arguments: key and value"
 		} else {
-			err := d.store.Put(req.Arguments[0], req.Arguments[1])
+			err := store.Put(req.Arguments[0], req.Arguments[1])
 			if err != nil {
 				response.Success = false
 				response.Error = err.Error()
@@ -220,7 +254,7 @@ func (d *DaemonManager) processRequest(req Request) Response {
 			response.Success = false
 			response.Error = "get requires exactly 1 argument: key"
 		} else {
-			value, err := d.store.Get(req.Arguments[0])
+			value, err := store.Get(req.Arguments[0])
 			if err != nil {
 				response.Success = false
 				response.Error = err.Error()
@@ -235,7 +269,43 @@ func (d *DaemonManager) processRequest(req Request) Response {
 			response.Success = false
 			response.Error = "delete requires exactly 1 argument: key"
 		} else {
-			err := d.store.Delete(req.Arguments[0])
+			err := store.Delete(req.Arguments[0])
+			if err != nil {
+				response.Success = false
+				response.Error = err.Error()
+			} else {
+				response.Success = true
+				response.Result = "OK"
+			}
+		}
+
+	case "delete-prefix":
+		if len(req.Arguments) != 1 {
+			response.Success = false
+			response.Error = "delete-prefix requires exactly 1 argument: prefix"
+		} else if rangeStore, ok := store.(engine.RangeDeleter); !ok {
+			response.Success = false
+			response.Error = engine.Unsupported(store, "delete-prefix").Error()
+		} else {
+			err := rangeStore.DeletePrefix(req.Arguments[0])
+			if err != nil {
+				response.Success = false
+				response.Error = err.Error()
+			} else {
+				response.Success = true
+				response.Result = "OK"
+			}
+		}
+
+	case "delete-range":
+		if len(req.Arguments) != 2 {
+			response.Success = false
+			response.Error = "delete-range requires exactly 2 arguments: start and end"
+		} else if rangeStore, ok := store.(engine.RangeDeleter); !ok {
+			response.Success = false
+			response.Error = engine.Unsupported(store, "delete-range").Error()
+		} else {
+			err := rangeStore.DeleteRange(req.Arguments[0], req.Arguments[1])
 			if err != nil {
 				response.Success = false
 				response.Error = err.Error()
@@ -246,7 +316,7 @@ func (d *DaemonManager) processRequest(req Request) Response {
 		}
 
 	case "list":
-		entries, err := d.store.List()
+		entries, err := store.List()
 		if err != nil {
 			response.Success = false
 			response.Error = err.Error()
@@ -255,8 +325,20 @@ func (d *DaemonManager) processRequest(req Request) Response {
 			response.Result = entries
 		}
 
+	case "query":
+		if len(req.Arguments) != 1 {
+			response.Success = false
+			response.Error = "query requires exactly 1 argument: query"
+		} else if result, err := runQuery(store, req.Arguments[0], req.Parameters); err != nil {
+			response.Success = false
+			response.Error = err.Error()
+		} else {
+			response.Success = true
+			response.Result = result
+		}
+
 	case "compact":
-		err := d.store.Compact()
+		err := store.Compact()
 		if err != nil {
 			response.Success = false
 			response.Error = err.Error()
@@ -266,7 +348,7 @@ func (d *DaemonManager) processRequest(req Request) Response {
 		}
 
 	case "stats":
-		stats, err := d.store.GetCompactionStats()
+		stats, err := d.namespaces.Stats(req.Namespace)
 		if err != nil {
 			response.Success = false
 			response.Error = err.Error()
@@ -275,13 +357,39 @@ func (d *DaemonManager) processRequest(req Request) Response {
 			response.Result = stats
 		}
 
+	case "namespaces":
+		names, err := d.namespaces.List()
+		if err != nil {
+			response.Success = false
+			response.Error = err.Error()
+		} else {
+			response.Success = true
+			response.Result = names
+		}
+
+	case "drop-namespace":
+		if len(req.Arguments) != 1 {
+			response.Success = false
+			response.Error = "drop-namespace requires exactly 1 argument: namespace"
+		} else if err := d.namespaces.Drop(req.Arguments[0]); err != nil {
+			response.Success = false
+			response.Error = err.Error()
+		} else {
+			response.Success = true
+			response.Result = "OK"
+		}
+
+	case "engine":
+		response.Success = true
+		response.Result = d.namespaces.Engine()
+
 	case "ping":
 		response.Success = true
 		response.Result = "pong"
 
 	case "help":
 		response.Success = true
-		response.Result = "Available commands: put, get, delete, list, compact, stats, ping, help"
+		response.Result = "Available commands: put, get, delete, delete-prefix, delete-range, list, query, compact, stats, namespaces, drop-namespace, engine, ping, help"
 
 	default:
 		response.Success = false
@@ -292,6 +400,36 @@ func (d *DaemonManager) processRequest(req Request) Response {
 	return response
 }
 
+// writesData reports whether a request changes data; only those create its namespace
+func writesData(req Request) bool {
+	switch req.Command {
+	case "put", "delete", "delete-prefix", "delete-range":
+		return true
+	case "query":
+		return len(req.Arguments) == 1 && query.IsWrite(req.Arguments[0])
+	default:
+		return false
+	}
+}
+
+// runQuery executes a query language statement with its placeholders bound to params
+func runQuery(store engine.Store, queryStr string, params []interface{}) (*QueryResult, error) {
+	stmt, err := query.NewExecutor(store).Prepare(queryStr)
+	if err != nil {
+		return nil, err
+	}
+	result := stmt.Execute(params...)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	queryResult := &QueryResult{Rows: result.Rows, Columns: result.Columns, Affected: result.Affected}
+	if result.Explain != nil {
+		queryResult.Plan = result.Explain.String()
+	}
+	return queryResult, nil
+}
+
 // IsDaemonRunning checks if daemon is already running by trying to connect
 func IsDaemonRunning() bool {
 	socketPath := filepath.Join(os.TempDir(), "moz-daemon.sock")
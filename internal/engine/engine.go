@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
+	"github.com/nyasuto/moz/internal/query"
+)
+
+// Storage engine names accepted by --engine
+const (
+	Log         = "log"         // Single append-only log file (text or binary)
+	LSM         = "lsm"         // LSM-Tree with SSTables
+	Partitioned = "partitioned" // Hash-partitioned log files with batched writes
+	Async       = "async"       // Log file fronted by a WAL and MemTable
+)
+
+// Names returns the supported engine names
+func Names() []string {
+	return []string{Log, LSM, Partitioned, Async}
+}
+
+// Store is the set of operations every engine supports. It includes the reads
+// of query.Store, so the query language runs on every engine. Optional capabilities
+// are exposed through the SortedLister, RangeDeleter, Ingester, LevelReporter
+// and BaseStore interfaces.
+type Store interface {
+	Put(key, value string) error
+	Get(key string) (string, error)
+	Delete(key string) error
+	List() ([]string, error)
+	GetRange(start, end string) (map[string]string, error)
+	PrefixSearch(prefix string) (map[string]string, error)
+	Compact() error
+	Stats() (map[string]interface{}, error)
+	Close() error
+	Engine() string
+}
+
+// Every engine's store can run queries
+var _ query.Store = Store(nil)
+
+// SortedLister is implemented by engines that list keys in sorted order without a full sort
+type SortedLister interface {
+	ListSorted() ([]string, error)
+}
+
+// RangeDeleter is implemented by engines that can delete many keys with a single record
+type RangeDeleter interface {
+	DeleteRange(start, end string) error
+	DeletePrefix(prefix string) error
+}
+
+// Ingester is implemented by engines that can bulk load externally built SSTables
+type Ingester interface {
+	IngestExternalFiles(paths []string) ([]lsm.IngestedFile, error)
+}
+
+// LevelReporter is implemented by engines that organize data in levels
+type LevelReporter interface {
+	GetLevelInfo() []map[string]interface{}
+}
+
+// BaseStore is implemented by engines backed by a single KVStore, which
+// provides secondary indexes, full-text search and compaction statistics
+type BaseStore interface {
+	Base() *kvstore.KVStore
+}
+
+// UnsupportedError reports an operation the selected engine does not support
+type UnsupportedError struct {
+	Engine    string
+	Operation string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s not supported by the %s engine", e.Operation, e.Engine)
+}
+
+// Unsupported returns an UnsupportedError for the store's engine
+func Unsupported(store Store, operation string) error {
+	return &UnsupportedError{Engine: store.Engine(), Operation: operation}
+}
+
+// Options selects and configures a storage engine
+type Options struct {
+	Engine          string // log, lsm, partitioned or async
+	Format          string // Log engine file format: text or binary
+	IndexType       string // Log engine index: hash, btree, radix or none
+	Partitions      int    // Partition count for the partitioned engine (1-16)
+	Namespace       string // Namespace to open; empty selects the default namespace
+	CreateNamespace bool   // Create a missing namespace instead of failing with ErrNamespaceNotFound; set for writes
+}
+
+// DefaultOptions returns options for the default log engine
+func DefaultOptions() Options {
+	return Options{
+		Engine:     Log,
+		Format:     "text",
+		IndexType:  "none",
+		Partitions: 4,
+	}
+}
+
+// Validate checks that the engine name is supported
+func Validate(name string) error {
+	for _, known := range Names() {
+		if name == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown engine: %s (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Open creates a store for the selected engine
+func Open(opts Options) (Store, error) {
+	if opts.Engine == "" {
+		opts.Engine = Log
+	}
+	if err := Validate(opts.Engine); err != nil {
+		return nil, err
+	}
+	if err := ValidateNamespace(opts.Namespace); err != nil {
+		return nil, err
+	}
+	namespace := namespaceName(opts.Namespace)
+
+	// Namespaces of the file-based engines exist once their directory does
+	if namespace != DefaultNamespace && opts.Engine != LSM && !opts.CreateNamespace {
+		if _, err := os.Stat(filepath.Join(namespaceRoot(opts.Engine), namespace)); os.IsNotExist(err) {
+			return nil, namespaceNotFound(namespace)
+		}
+	}
+
+	switch opts.Engine {
+	case LSM:
+		config := lsm.DefaultLSMKVStoreConfig()
+		config.DataDir = lsmDataDir()
+		config.EnableMigration = false
+		store, err := lsm.NewLSMKVStore(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open LSM engine: %w", err)
+		}
+		// An unfinished migration keeps serving reads from the legacy log
+		if _, migrating := store.MigrationCheckpoint(); migrating {
+			store.AttachLegacyStore(newLogKVStore(Options{Format: opts.Format, IndexType: opts.IndexType}))
+		}
+		if namespace == DefaultNamespace {
+			return &lsmStore{store: store}, nil
+		}
+		nsStore, err := openLSMNamespace(store, namespace, true, opts.CreateNamespace)
+		if err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+		return nsStore, nil
+
+	case Partitioned:
+		config := kvstore.DefaultPartitionConfig()
+		if opts.Partitions > 0 {
+			config.NumPartitions = opts.Partitions
+		}
+		if namespace != DefaultNamespace {
+			config.DataDir = filepath.Join(config.DataDir, namespaceDirName, namespace)
+		}
+		store, err := kvstore.NewPartitionedKVStore(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open partitioned engine: %w", err)
+		}
+		return &partitionedStore{store: store}, nil
+
+	case Async:
+		config := kvstore.DefaultAsyncConfig()
+		config.WALConfig.DataDir = dataDir()
+		if namespace != DefaultNamespace {
+			config.WALConfig.DataDir = namespaceDir(namespace)
+			config.DataDir = config.WALConfig.DataDir
+		}
+		store, err := kvstore.NewAsyncKVStore(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open async engine: %w", err)
+		}
+		return &asyncStore{store: store}, nil
+
+	default:
+		return &logStore{KVStore: newLogKVStore(opts)}, nil
+	}
+}
+
+// dataDir returns the base data directory shared by the engines
+func dataDir() string {
+	if envDir := os.Getenv("MOZ_DATA_DIR"); envDir != "" {
+		return envDir
+	}
+	return kvstore.DefaultDataDir
+}
+
+// lsmDataDir returns the directory holding the LSM engine's SSTables
+func lsmDataDir() string {
+	return filepath.Join(dataDir(), "lsm")
+}
+
+// newLogKVStore creates the KVStore used by the log engine
+func newLogKVStore(opts Options) *kvstore.KVStore {
+	format := opts.Format
+	if format == "" {
+		format = "text"
+	}
+	indexType := opts.IndexType
+	if indexType == "" {
+		indexType = "none"
+	}
+
+	storageConfig := kvstore.StorageConfig{
+		Format:     format,
+		TextFile:   kvstore.LogFileName,
+		BinaryFile: "moz.bin",
+		IndexType:  indexType,
+		IndexFile:  "moz.idx",
+	}
+	if namespace := namespaceName(opts.Namespace); namespace != DefaultNamespace {
+		storageConfig.DataDir = namespaceDir(namespace)
+	}
+
+	compactionConfig := kvstore.CompactionConfig{
+		Enabled:         true,
+		MaxFileSize:     1024 * 1024, // 1MB
+		MaxOperations:   1000,
+		CompactionRatio: 0.5,
+	}
+
+	return kvstore.NewWithConfig(compactionConfig, storageConfig)
+}
+
+// logStore is the log engine; it exposes every KVStore capability
+type logStore struct {
+	*kvstore.KVStore
+}
+
+// NewLogStore wraps an existing KVStore as a log engine store
+func NewLogStore(store *kvstore.KVStore) Store {
+	return &logStore{KVStore: store}
+}
+
+func (s *logStore) Engine() string { return Log }
+
+func (s *logStore) Close() error { return s.KVStore.Close() }
+
+func (s *logStore) Base() *kvstore.KVStore { return s.KVStore }
+
+func (s *logStore) Stats() (map[string]interface{}, error) {
+	stats, err := s.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	compaction, err := s.GetCompactionStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"engine":          Log,
+		"memory_map_size": stats.MemoryMapSize,
+		"is_loaded":       stats.IsLoaded,
+		"compaction":      compaction,
+	}, nil
+}
+
+// lsmStore is the LSM-Tree engine
+type lsmStore struct {
+	store *lsm.LSMKVStore
+}
+
+func (s *lsmStore) Engine() string                      { return LSM }
+func (s *lsmStore) Put(key, value string) error         { return s.store.Put(key, value) }
+func (s *lsmStore) Get(key string) (string, error)      { return s.store.Get(key) }
+func (s *lsmStore) Delete(key string) error             { return s.store.Delete(key) }
+func (s *lsmStore) Compact() error                      { return s.store.Compact() }
+func (s *lsmStore) Close() error                        { return s.store.Close() }
+func (s *lsmStore) DeleteRange(start, end string) error { return s.store.DeleteRange(start, end) }
+func (s *lsmStore) DeletePrefix(prefix string) error    { return s.store.DeletePrefix(prefix) }
+
+func (s *lsmStore) List() ([]string, error) {
+	keys, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *lsmStore) GetRange(start, end string) (map[string]string, error) {
+	return s.store.GetRange(start, end)
+}
+
+func (s *lsmStore) PrefixSearch(prefix string) (map[string]string, error) {
+	return s.store.PrefixSearch(prefix)
+}
+
+func (s *lsmStore) IngestExternalFiles(paths []string) ([]lsm.IngestedFile, error) {
+	return s.store.IngestExternalFiles(paths)
+}
+
+func (s *lsmStore) GetLevelInfo() []map[string]interface{} {
+	return s.store.GetLevelInfo()
+}
+
+func (s *lsmStore) Stats() (map[string]interface{}, error) {
+	stats := s.store.Stats()
+	stats["engine"] = LSM
+	return stats, nil
+}
+
+// partitionedStore is the hash-partitioned engine
+type partitionedStore struct {
+	store *kvstore.PartitionedKVStore
+}
+
+func (s *partitionedStore) Engine() string                 { return Partitioned }
+func (s *partitionedStore) Put(key, value string) error    { return s.store.Put(key, value) }
+func (s *partitionedStore) Get(key string) (string, error) { return s.store.Get(key) }
+func (s *partitionedStore) Delete(key string) error        { return s.store.Delete(key) }
+func (s *partitionedStore) List() ([]string, error)        { return s.store.List() }
+func (s *partitionedStore) Compact() error                 { return s.store.Compact() }
+func (s *partitionedStore) Close() error                   { return s.store.Close() }
+
+func (s *partitionedStore) GetRange(start, end string) (map[string]string, error) {
+	return s.store.GetRange(start, end)
+}
+
+func (s *partitionedStore) PrefixSearch(prefix string) (map[string]string, error) {
+	return s.store.PrefixSearch(prefix)
+}
+
+func (s *partitionedStore) ListSorted() ([]string, error) { return s.store.ListSorted() }
+
+func (s *partitionedStore) Stats() (map[string]interface{}, error) {
+	stats, err := s.store.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	stats["engine"] = Partitioned
+	return stats, nil
+}
+
+// asyncStore is the WAL-backed asynchronous engine.
+// Writes wait for their WAL append; range and prefix reads flush the MemTable first.
+type asyncStore struct {
+	store *kvstore.AsyncKVStore
+}
+
+func (s *asyncStore) Engine() string                 { return Async }
+func (s *asyncStore) Put(key, value string) error    { return s.store.AsyncPut(key, value).Wait() }
+func (s *asyncStore) Get(key string) (string, error) { return s.store.Get(key) }
+func (s *asyncStore) Delete(key string) error        { return s.store.AsyncDelete(key).Wait() }
+func (s *asyncStore) List() ([]string, error)        { return s.store.List() }
+func (s *asyncStore) Close() error                   { return s.store.Close() }
+
+func (s *asyncStore) Compact() error {
+	if err := s.store.ForceFlush(); err != nil {
+		return err
+	}
+	return s.store.Compact()
+}
+
+func (s *asyncStore) GetRange(start, end string) (map[string]string, error) {
+	return s.store.GetRange(start, end)
+}
+
+func (s *asyncStore) PrefixSearch(prefix string) (map[string]string, error) {
+	return s.store.PrefixSearch(prefix)
+}
+
+func (s *asyncStore) Stats() (map[string]interface{}, error) {
+	stats := s.store.GetAsyncStats()
+	stats["engine"] = Async
+	return stats, nil
+}
@@ -0,0 +1,439 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
+	"github.com/nyasuto/moz/internal/query"
+)
+
+func openTestStore(t *testing.T, name string) Store {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("MOZ_DATA_DIR", dir)
+	t.Setenv("MOZ_PARTITION_DIR", dir)
+
+	opts := DefaultOptions()
+	opts.Engine = name
+	store, err := Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open %s engine: %v", name, err)
+	}
+	return store
+}
+
+func TestOpen_UnknownEngine(t *testing.T) {
+	if _, err := Open(Options{Engine: "rocks"}); err == nil {
+		t.Error("Expected error for unknown engine")
+	}
+}
+
+func TestEngines_BasicOperations(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			store := openTestStore(t, name)
+			defer store.Close()
+
+			if store.Engine() != name {
+				t.Errorf("Expected engine %s, got %s", name, store.Engine())
+			}
+
+			for _, key := range []string{"user:2", "user:1", "item:1"} {
+				if err := store.Put(key, "value-"+key); err != nil {
+					t.Fatalf("Put %s failed: %v", key, err)
+				}
+			}
+			if err := store.Delete("item:1"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+
+			value, err := store.Get("user:1")
+			if err != nil || value != "value-user:1" {
+				t.Errorf("Expected user:1=value-user:1, got %q (err=%v)", value, err)
+			}
+			if _, err := store.Get("item:1"); err == nil {
+				t.Error("Expected error when getting deleted key")
+			}
+
+			keys, err := store.List()
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(keys) != 2 {
+				t.Errorf("Expected 2 keys, got %v", keys)
+			}
+
+			if err := store.Compact(); err != nil {
+				t.Fatalf("Compact failed: %v", err)
+			}
+
+			stats, err := store.Stats()
+			if err != nil {
+				t.Fatalf("Stats failed: %v", err)
+			}
+			if stats["engine"] != name {
+				t.Errorf("Expected stats engine %s, got %v", name, stats["engine"])
+			}
+
+			results, err := store.PrefixSearch("user:")
+			if err != nil {
+				t.Fatalf("PrefixSearch failed: %v", err)
+			}
+			if len(results) != 2 {
+				t.Errorf("Expected 2 prefix results, got %v", results)
+			}
+
+			results, err = store.GetRange("item:", "user:1")
+			if err != nil {
+				t.Fatalf("GetRange failed: %v", err)
+			}
+			if !reflect.DeepEqual(results, map[string]string{"user:1": "value-user:1"}) {
+				t.Errorf("Expected range [item:, user:1] to hold only user:1, got %v", results)
+			}
+		})
+	}
+}
+
+func TestEngines_Capabilities(t *testing.T) {
+	tests := []struct {
+		name        string
+		rangeDelete bool
+		ingest      bool
+		levels      bool
+		base        bool
+	}{
+		{Log, true, false, false, true},
+		{LSM, true, true, true, false},
+		{Partitioned, false, false, false, false},
+		{Async, false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := openTestStore(t, tt.name)
+			defer store.Close()
+
+			_, rangeDelete := store.(RangeDeleter)
+			_, ingest := store.(Ingester)
+			_, levels := store.(LevelReporter)
+			_, base := store.(BaseStore)
+			got := []bool{rangeDelete, ingest, levels, base}
+			want := []bool{tt.rangeDelete, tt.ingest, tt.levels, tt.base}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected capabilities %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestEngines_Query(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			store := openTestStore(t, name)
+			defer store.Close()
+
+			executor := query.NewExecutor(store)
+			run := func(q string, args ...interface{}) *query.ExecuteResult {
+				t.Helper()
+				stmt, err := executor.Prepare(q)
+				if err != nil {
+					t.Fatalf("Prepare %q failed: %v", q, err)
+				}
+				result := stmt.Execute(args...)
+				if result.Error != nil {
+					t.Fatalf("Query %q failed: %v", q, result.Error)
+				}
+				return result
+			}
+
+			run("INSERT INTO moz (key, value) VALUES ('user:1', 'alice'), ('user:2', 'bob'), ('item:1', 'book')")
+			if value, err := store.Get("user:2"); err != nil || value != "bob" {
+				t.Errorf("Expected user:2=bob, got %q (err=%v)", value, err)
+			}
+
+			tests := []struct {
+				query string
+				args  []interface{}
+				keys  []string
+			}{
+				{"SELECT * FROM moz WHERE key = ?", []interface{}{"user:1"}, []string{"user:1"}},
+				{"SELECT * FROM moz WHERE key LIKE 'user:%'", nil, []string{"user:1", "user:2"}},
+				{"SELECT * FROM moz WHERE key BETWEEN 'item:' AND 'user:1'", nil, []string{"item:1", "user:1"}},
+				{"SELECT * FROM moz WHERE value = 'book'", nil, []string{"item:1"}},
+			}
+			for _, tt := range tests {
+				result := run(tt.query, tt.args...)
+				var keys []string
+				for _, row := range result.Rows {
+					keys = append(keys, row["key"])
+				}
+				sort.Strings(keys)
+				if !reflect.DeepEqual(keys, tt.keys) {
+					t.Errorf("%s: expected keys %v, got %v", tt.query, tt.keys, keys)
+				}
+			}
+
+			if result := run("DELETE FROM moz WHERE key LIKE 'user:%'"); result.Affected != 2 {
+				t.Errorf("Expected DELETE to affect 2 rows, got %d", result.Affected)
+			}
+			if result := run("SELECT COUNT(*) FROM moz"); result.Rows[0]["COUNT(*)"] != "1" {
+				t.Errorf("Expected 1 key after DELETE, got %v", result.Rows)
+			}
+		})
+	}
+}
+
+func TestLSMEngine_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MOZ_DATA_DIR", dir)
+
+	store, err := Open(Options{Engine: LSM})
+	if err != nil {
+		t.Fatalf("Failed to open LSM engine: %v", err)
+	}
+	if err := store.Put("persistent", "value"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := Open(Options{Engine: LSM})
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM engine: %v", err)
+	}
+	defer reopened.Close()
+
+	value, err := reopened.Get("persistent")
+	if err != nil || value != "value" {
+		t.Errorf("Expected persistent=value after reopen, got %q (err=%v)", value, err)
+	}
+}
+
+func TestNamespaces_IsolationAndDrop(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("MOZ_DATA_DIR", dir)
+			t.Setenv("MOZ_PARTITION_DIR", dir)
+
+			opts := DefaultOptions()
+			opts.Engine = name
+			namespaces, err := OpenNamespaces(opts)
+			if err != nil {
+				t.Fatalf("Failed to open namespaces: %v", err)
+			}
+
+			// Reads never create a namespace
+			if _, err := namespaces.Store("billing"); !errors.Is(err, ErrNamespaceNotFound) {
+				t.Errorf("Expected ErrNamespaceNotFound before the first write, got %v", err)
+			}
+			if _, err := namespaces.Stats("billing"); !errors.Is(err, ErrNamespaceNotFound) {
+				t.Errorf("Expected ErrNamespaceNotFound for stats, got %v", err)
+			}
+			if names, _ := namespaces.List(); !reflect.DeepEqual(names, []string{DefaultNamespace}) {
+				t.Errorf("Expected reads to leave only the default namespace, got %v", names)
+			}
+
+			billing, err := namespaces.Create("billing")
+			if err != nil {
+				t.Fatalf("Failed to create namespace: %v", err)
+			}
+			if err := namespaces.Default().Put("key", "default-value"); err != nil {
+				t.Fatalf("Put to default namespace failed: %v", err)
+			}
+			if err := billing.Put("key", "billing-value"); err != nil {
+				t.Fatalf("Put to billing namespace failed: %v", err)
+			}
+			if err := billing.Put("invoice:1", "100"); err != nil {
+				t.Fatalf("Put to billing namespace failed: %v", err)
+			}
+
+			if value, err := namespaces.Default().Get("key"); err != nil || value != "default-value" {
+				t.Errorf("Expected default key=default-value, got %q (err=%v)", value, err)
+			}
+			if _, err := namespaces.Default().Get("invoice:1"); err == nil {
+				t.Error("Expected billing key to be invisible in the default namespace")
+			}
+			keys, err := billing.List()
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, []string{"invoice:1", "key"}) {
+				t.Errorf("Unexpected billing keys: %v", keys)
+			}
+			invoices, err := billing.GetRange("invoice:", "invoice:~")
+			if err != nil {
+				t.Fatalf("GetRange failed: %v", err)
+			}
+			if !reflect.DeepEqual(invoices, map[string]string{"invoice:1": "100"}) {
+				t.Errorf("Unexpected billing range: %v", invoices)
+			}
+
+			stats, err := namespaces.Stats("billing")
+			if err != nil || stats["namespace"] != "billing" {
+				t.Errorf("Expected billing stats, got %v (err=%v)", stats, err)
+			}
+			if err := namespaces.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			// Namespaces survive a restart
+			namespaces, err = OpenNamespaces(opts)
+			if err != nil {
+				t.Fatalf("Failed to reopen namespaces: %v", err)
+			}
+			defer namespaces.Close()
+
+			names, err := namespaces.List()
+			if err != nil || !reflect.DeepEqual(names, []string{"billing", DefaultNamespace}) {
+				t.Errorf("Expected [billing default], got %v (err=%v)", names, err)
+			}
+			billing, err = namespaces.Store("billing")
+			if err != nil {
+				t.Fatalf("Failed to open namespace: %v", err)
+			}
+			if value, err := billing.Get("key"); err != nil || value != "billing-value" {
+				t.Errorf("Expected billing key=billing-value after reopen, got %q (err=%v)", value, err)
+			}
+
+			if err := namespaces.Drop(DefaultNamespace); err == nil {
+				t.Error("Expected error when dropping the default namespace")
+			}
+			if err := namespaces.Drop("billing"); err != nil {
+				t.Fatalf("Drop failed: %v", err)
+			}
+			if names, _ := namespaces.List(); !reflect.DeepEqual(names, []string{DefaultNamespace}) {
+				t.Errorf("Expected only the default namespace after drop, got %v", names)
+			}
+			if _, err := namespaces.Store("billing"); !errors.Is(err, ErrNamespaceNotFound) {
+				t.Errorf("Expected ErrNamespaceNotFound after drop, got %v", err)
+			}
+			if value, err := namespaces.Default().Get("key"); err != nil || value != "default-value" {
+				t.Errorf("Expected default namespace to be untouched, got %q (err=%v)", value, err)
+			}
+		})
+	}
+}
+
+func TestOpen_MissingNamespace(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("MOZ_DATA_DIR", dir)
+			t.Setenv("MOZ_PARTITION_DIR", dir)
+
+			opts := DefaultOptions()
+			opts.Engine = name
+			opts.Namespace = "ghost"
+			if _, err := Open(opts); !errors.Is(err, ErrNamespaceNotFound) {
+				t.Fatalf("Expected ErrNamespaceNotFound, got %v", err)
+			}
+
+			opts.CreateNamespace = true
+			store, err := Open(opts)
+			if err != nil {
+				t.Fatalf("Failed to create namespace: %v", err)
+			}
+			if err := store.Put("key", "value"); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			opts.CreateNamespace = false
+			store, err = Open(opts)
+			if err != nil {
+				t.Fatalf("Failed to open created namespace: %v", err)
+			}
+			defer store.Close()
+			if value, err := store.Get("key"); err != nil || value != "value" {
+				t.Errorf("Expected key=value, got %q (err=%v)", value, err)
+			}
+		})
+	}
+}
+
+func TestNamespaces_InvalidName(t *testing.T) {
+	if _, err := Open(Options{Engine: Log, Namespace: "../escape"}); err == nil {
+		t.Error("Expected error for invalid namespace name")
+	}
+}
+
+func TestMigrate_ToLSMResumesAndRetiresLog(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MOZ_DATA_DIR", dir)
+
+	legacy, err := Open(Options{Engine: Log})
+	if err != nil {
+		t.Fatalf("Failed to open log engine: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		legacy.Put(key, "value-"+key)
+	}
+
+	// Simulate a migration interrupted after copying "a" and "b"
+	config := lsm.DefaultLSMKVStoreConfig()
+	config.DataDir = lsmDataDir()
+	config.EnableMigration = false
+	partial, err := lsm.NewLSMKVStore(config)
+	if err != nil {
+		t.Fatalf("Failed to open LSM store: %v", err)
+	}
+	partial.Put("a", "value-a")
+	partial.Put("b", "value-b")
+	partial.Put(kvstore.MigrationCheckpointKey, "b")
+	partial.Close()
+
+	// Until the migration finishes, the LSM engine also serves legacy keys
+	store, err := Open(Options{Engine: LSM})
+	if err != nil {
+		t.Fatalf("Failed to open LSM engine: %v", err)
+	}
+	if value, err := store.Get("d"); err != nil || value != "value-d" {
+		t.Errorf("Expected legacy key d during migration, got %q (err=%v)", value, err)
+	}
+	keys, _ := store.List()
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c", "d"}) {
+		t.Errorf("Expected keys from both stores without the checkpoint, got %v", keys)
+	}
+	store.Close()
+
+	result, err := Migrate(Options{Engine: Log}, MigrateToLSM, kvstore.DefaultMigrationOptions())
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.ResumedFrom != "b" {
+		t.Errorf("Expected resume after b, got %q", result.ResumedFrom)
+	}
+	if result.RetiredLog != filepath.Join(dir, kvstore.LogFileName)+retiredSuffix {
+		t.Errorf("Unexpected retired log path: %s", result.RetiredLog)
+	}
+
+	migrated, err := Open(Options{Engine: LSM})
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM engine: %v", err)
+	}
+	defer migrated.Close()
+
+	stats, _ := migrated.Stats()
+	if mode := stats["migration"].(map[string]interface{})["migration_mode"]; mode != false {
+		t.Error("Expected LSM engine to leave migration mode after migrating")
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if value, err := migrated.Get(key); err != nil || value != "value-"+key {
+			t.Errorf("Expected %s=value-%s after migration, got %q (err=%v)", key, key, value, err)
+		}
+	}
+
+	if _, err := Migrate(Options{Engine: Log}, MigrateToLSM, kvstore.DefaultMigrationOptions()); err == nil {
+		t.Error("Expected error when the legacy log has already been retired")
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
+)
+
+// Migration targets accepted by Migrate
+const (
+	MigrateToLSM    = "lsm"    // Copy the log store into the LSM engine
+	MigrateToBinary = "binary" // Rewrite the text log store in binary format
+)
+
+// retiredSuffix is appended to a legacy log file once its data has been migrated
+const retiredSuffix = ".migrated"
+
+// MigrationResult summarizes a migration run
+type MigrationResult struct {
+	kvstore.MigrationResult
+	RetiredLog string // Path the legacy log file was moved to ("" if there was none)
+}
+
+// Migrate moves the log engine's data (read in opts.Format) to the target, resuming
+// an interrupted run from its checkpoint. The legacy log file is retired by renaming
+// it only after every key/value pair has been verified in the target.
+func Migrate(opts Options, target string, migrationOpts kvstore.MigrationOptions) (MigrationResult, error) {
+	var result MigrationResult
+
+	source := newLogKVStore(opts)
+	if _, err := os.Stat(source.LogFilePath()); os.IsNotExist(err) {
+		return result, fmt.Errorf("no log store to migrate at %s", source.LogFilePath())
+	}
+
+	switch target {
+	case MigrateToLSM:
+		config := lsm.DefaultLSMKVStoreConfig()
+		config.DataDir = lsmDataDir()
+		config.EnableMigration = false
+		store, err := lsm.NewLSMKVStore(config)
+		if err != nil {
+			return result, fmt.Errorf("failed to open LSM engine: %w", err)
+		}
+
+		store.AttachLegacyStore(source)
+		migrated, err := store.Migrate(migrationOpts)
+		result.MigrationResult = migrated
+		if closeErr := store.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close LSM engine: %w", closeErr)
+		}
+		if err != nil {
+			return result, err
+		}
+
+	case MigrateToBinary:
+		if opts.Format == "binary" {
+			return result, fmt.Errorf("log store is already in binary format")
+		}
+		binaryOpts := opts
+		binaryOpts.Format = "binary"
+
+		migrated, err := kvstore.Migrate(source, newLogKVStore(binaryOpts), migrationOpts)
+		result.MigrationResult = migrated
+		if err != nil {
+			return result, err
+		}
+
+	default:
+		return result, fmt.Errorf("unknown migration target: %s (supported: %s, %s)", target, MigrateToLSM, MigrateToBinary)
+	}
+
+	retired, err := retireLogFile(source.LogFilePath())
+	if err != nil {
+		return result, err
+	}
+	result.RetiredLog = retired
+	return result, nil
+}
+
+// retireLogFile renames a migrated log file out of the way, returning its new path
+func retireLogFile(path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	retired := path + retiredSuffix
+	if err := os.Rename(path, retired); err != nil {
+		return "", fmt.Errorf("failed to retire legacy log: %w", err)
+	}
+	return retired, nil
+}
@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
+)
+
+// DefaultNamespace is the namespace used when none is given; it is the engine's original keyspace
+const DefaultNamespace = "default"
+
+// namespaceDirName is the subdirectory holding the data of non-default namespaces
+const namespaceDirName = "ns"
+
+// ErrNamespaceNotFound is returned when reading from a namespace that no write has created
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
+// namespaceNotFound returns ErrNamespaceNotFound for a namespace
+func namespaceNotFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrNamespaceNotFound, name)
+}
+
+// ValidateNamespace accepts an empty name (the default namespace) or a name made of
+// letters, digits, '_' and '-'
+func ValidateNamespace(name string) error {
+	if len(name) > 64 {
+		return fmt.Errorf("invalid namespace %q: longer than 64 characters", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("invalid namespace %q: only letters, digits, '_' and '-' are allowed", name)
+		}
+	}
+	return nil
+}
+
+// namespaceName maps the empty name to DefaultNamespace
+func namespaceName(name string) string {
+	if name == "" {
+		return DefaultNamespace
+	}
+	return name
+}
+
+// namespaceDir returns the directory of a namespace of the log and async engines
+func namespaceDir(name string) string {
+	return filepath.Join(dataDir(), namespaceDirName, name)
+}
+
+// namespaceRoot returns the directory holding the namespaces of a file-based engine
+func namespaceRoot(engineName string) string {
+	if engineName == Partitioned {
+		return filepath.Join(kvstore.DefaultPartitionConfig().DataDir, namespaceDirName)
+	}
+	return filepath.Join(dataDir(), namespaceDirName)
+}
+
+// Namespaces gives every namespace of an engine its own store: a separate log
+// directory for the log, partitioned and async engines and a column family for LSM.
+// Namespaces are created by the first write and stay open until Close.
+type Namespaces struct {
+	mu     sync.Mutex
+	opts   Options
+	root   Store            // The default namespace
+	stores map[string]Store // Open non-default namespaces
+}
+
+// OpenNamespaces opens the default namespace of the selected engine
+func OpenNamespaces(opts Options) (*Namespaces, error) {
+	opts.Namespace = ""
+	root, err := Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewNamespaces(root, opts), nil
+}
+
+// NewNamespaces serves namespaces alongside an already open default store.
+// Other namespaces are opened with opts.
+func NewNamespaces(root Store, opts Options) *Namespaces {
+	opts.Engine = root.Engine()
+	opts.Namespace = ""
+	return &Namespaces{
+		opts:   opts,
+		root:   root,
+		stores: make(map[string]Store),
+	}
+}
+
+// Engine returns the engine serving the namespaces
+func (n *Namespaces) Engine() string {
+	return n.root.Engine()
+}
+
+// Default returns the store of the default namespace
+func (n *Namespaces) Default() Store {
+	return n.root
+}
+
+// Store returns the store of an existing namespace. It fails with ErrNamespaceNotFound
+// for a namespace that has not been created, so that reads never create one.
+func (n *Namespaces) Store(name string) (Store, error) {
+	return n.open(name, false)
+}
+
+// Create returns the store of a namespace for writing, creating the namespace if needed
+func (n *Namespaces) Create(name string) (Store, error) {
+	return n.open(name, true)
+}
+
+// open returns the store of a namespace, creating a missing namespace only if create is set
+func (n *Namespaces) open(name string, create bool) (Store, error) {
+	if err := ValidateNamespace(name); err != nil {
+		return nil, err
+	}
+	name = namespaceName(name)
+	if name == DefaultNamespace {
+		return n.root, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if store, ok := n.stores[name]; ok {
+		return store, nil
+	}
+
+	var store Store
+	var err error
+	if root, ok := n.root.(*lsmStore); ok {
+		store, err = openLSMNamespace(root.store, name, false, create)
+	} else {
+		opts := n.opts
+		opts.Namespace = name
+		opts.CreateNamespace = create
+		store, err = Open(opts)
+	}
+	if errors.Is(err, ErrNamespaceNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open namespace %q: %w", name, err)
+	}
+	n.stores[name] = store
+	return store, nil
+}
+
+// Stats returns the statistics of a namespace, tagged with its name
+func (n *Namespaces) Stats(name string) (map[string]interface{}, error) {
+	store, err := n.Store(name)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := store.Stats()
+	if err != nil {
+		return nil, err
+	}
+	stats["namespace"] = namespaceName(name)
+	return stats, nil
+}
+
+// List returns the names of all namespaces, including the default one, in sorted order
+func (n *Namespaces) List() ([]string, error) {
+	names := map[string]bool{DefaultNamespace: true}
+
+	if root, ok := n.root.(*lsmStore); ok {
+		for _, name := range root.store.ColumnFamilies() {
+			names[name] = true
+		}
+	} else {
+		entries, err := os.ReadDir(namespaceRoot(n.opts.Engine))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read namespaces: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && ValidateNamespace(entry.Name()) == nil {
+				names[entry.Name()] = true
+			}
+		}
+
+		n.mu.Lock()
+		for name := range n.stores {
+			names[name] = true
+		}
+		n.mu.Unlock()
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Drop closes a namespace and deletes all of its data. The default namespace cannot be dropped.
+func (n *Namespaces) Drop(name string) error {
+	if err := ValidateNamespace(name); err != nil {
+		return err
+	}
+	if namespaceName(name) == DefaultNamespace {
+		return fmt.Errorf("cannot drop the default namespace")
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if store, ok := n.stores[name]; ok {
+		delete(n.stores, name)
+		if err := store.Close(); err != nil {
+			return fmt.Errorf("failed to close namespace %q: %w", name, err)
+		}
+	}
+
+	if root, ok := n.root.(*lsmStore); ok {
+		if err := root.store.DropColumnFamily(name); err != nil {
+			return fmt.Errorf("failed to drop namespace %q: %w", name, err)
+		}
+		return nil
+	}
+
+	dir := filepath.Join(namespaceRoot(n.opts.Engine), name)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return namespaceNotFound(name)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to drop namespace %q: %w", name, err)
+	}
+	return nil
+}
+
+// Close closes every open namespace, the default one last
+func (n *Namespaces) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var firstErr error
+	for name, store := range n.stores {
+		if err := store.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close namespace %q: %w", name, err)
+		}
+	}
+	n.stores = make(map[string]Store)
+
+	if err := n.root.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// lsmNamespaceStore is a namespace of the LSM engine, backed by a column family
+type lsmNamespaceStore struct {
+	root  *lsm.LSMKVStore
+	cf    *lsm.ColumnFamily
+	owner bool // Close the root store when the namespace is closed
+}
+
+// openLSMNamespace returns the column family of a namespace, creating it if needed and allowed
+func openLSMNamespace(root *lsm.LSMKVStore, name string, owner, create bool) (*lsmNamespaceStore, error) {
+	cf, err := root.ColumnFamily(name)
+	if err != nil {
+		if !create {
+			return nil, namespaceNotFound(name)
+		}
+		if cf, err = root.CreateColumnFamily(name, lsm.DefaultLSMConfig()); err != nil {
+			return nil, fmt.Errorf("failed to create namespace %q: %w", name, err)
+		}
+	}
+	return &lsmNamespaceStore{root: root, cf: cf, owner: owner}, nil
+}
+
+func (s *lsmNamespaceStore) Engine() string                      { return LSM }
+func (s *lsmNamespaceStore) Put(key, value string) error         { return s.cf.Put(key, value) }
+func (s *lsmNamespaceStore) Get(key string) (string, error)      { return s.cf.Get(key) }
+func (s *lsmNamespaceStore) Delete(key string) error             { return s.cf.Delete(key) }
+func (s *lsmNamespaceStore) Compact() error                      { return s.cf.Compact() }
+func (s *lsmNamespaceStore) DeleteRange(start, end string) error { return s.cf.DeleteRange(start, end) }
+func (s *lsmNamespaceStore) DeletePrefix(prefix string) error    { return s.cf.DeletePrefix(prefix) }
+
+func (s *lsmNamespaceStore) GetRange(start, end string) (map[string]string, error) {
+	return s.cf.GetRange(start, end)
+}
+
+func (s *lsmNamespaceStore) PrefixSearch(prefix string) (map[string]string, error) {
+	return s.cf.PrefixSearch(prefix)
+}
+
+func (s *lsmNamespaceStore) List() ([]string, error) {
+	iter, err := s.cf.NewIterator("")
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for iter.HasNext() {
+		key, _ := iter.Next()
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *lsmNamespaceStore) Stats() (map[string]interface{}, error) {
+	stats, err := s.root.ColumnFamilyStats(s.cf.Name())
+	if err != nil {
+		return nil, err
+	}
+	stats["engine"] = LSM
+	return stats, nil
+}
+
+func (s *lsmNamespaceStore) Close() error {
+	if s.owner {
+		return s.root.Close()
+	}
+	return nil
+}
@@ -84,6 +84,26 @@ func BenchmarkBTreeIndex_Insert(b *testing.B) {
 	}
 }
 
+// BenchmarkRadixIndex_Insert benchmarks radix index insertion
+func BenchmarkRadixIndex_Insert(b *testing.B) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	entries := generateBenchmarkData(b.N)
+	keys := make([]string, 0, b.N)
+	values := make([]IndexEntry, 0, b.N)
+
+	for key, entry := range entries {
+		keys = append(keys, key)
+		values = append(values, entry)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ri.Insert(keys[i], values[i])
+	}
+}
+
 // BenchmarkHashIndex_Get benchmarks hash index lookup
 func BenchmarkHashIndex_Get(b *testing.B) {
 	hi, err := NewHashIndex(DefaultHashIndexConfig())
@@ -130,6 +150,26 @@ func BenchmarkBTreeIndex_Get(b *testing.B) {
 	}
 }
 
+// BenchmarkRadixIndex_Get benchmarks radix index lookup
+func BenchmarkRadixIndex_Get(b *testing.B) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	// Pre-populate with data
+	entries := generateBenchmarkData(benchmarkDataSize)
+	for key, entry := range entries {
+		ri.Insert(key, entry)
+	}
+
+	// Generate random lookup keys
+	lookupKeys := generateRandomKeys(b.N, benchmarkDataSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ri.Get(lookupKeys[i])
+	}
+}
+
 // BenchmarkHashIndex_Range benchmarks hash index range queries
 func BenchmarkHashIndex_Range(b *testing.B) {
 	hi, err := NewHashIndex(DefaultHashIndexConfig())
@@ -174,6 +214,25 @@ func BenchmarkBTreeIndex_Range(b *testing.B) {
 	}
 }
 
+// BenchmarkRadixIndex_Range benchmarks radix index range queries
+func BenchmarkRadixIndex_Range(b *testing.B) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	// Pre-populate with data
+	entries := generateBenchmarkData(benchmarkDataSize)
+	for key, entry := range entries {
+		ri.Insert(key, entry)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		startKey := fmt.Sprintf("%s%06d", keyPrefix, i%1000)
+		endKey := fmt.Sprintf("%s%06d", keyPrefix, (i%1000)+100)
+		ri.Range(startKey, endKey)
+	}
+}
+
 // BenchmarkHashIndex_Keys benchmarks getting all keys from hash index
 func BenchmarkHashIndex_Keys(b *testing.B) {
 	hi, err := NewHashIndex(DefaultHashIndexConfig())
@@ -214,6 +273,23 @@ func BenchmarkBTreeIndex_Keys(b *testing.B) {
 	}
 }
 
+// BenchmarkRadixIndex_Keys benchmarks getting all keys from radix index
+func BenchmarkRadixIndex_Keys(b *testing.B) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	// Pre-populate with data
+	entries := generateBenchmarkData(1000) // Smaller dataset for Keys() benchmark
+	for key, entry := range entries {
+		ri.Insert(key, entry)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ri.Keys()
+	}
+}
+
 // BenchmarkHashIndex_BatchInsert benchmarks hash index batch insertion
 func BenchmarkHashIndex_BatchInsert(b *testing.B) {
 	entries := generateBenchmarkData(b.N)
@@ -242,7 +318,18 @@ func BenchmarkBTreeIndex_BatchInsert(b *testing.B) {
 	bt.BatchInsert(entries)
 }
 
-// BenchmarkIndexManager_HashVsBTree compares hash vs B-tree through manager
+// BenchmarkRadixIndex_BatchInsert benchmarks radix index batch insertion
+func BenchmarkRadixIndex_BatchInsert(b *testing.B) {
+	entries := generateBenchmarkData(b.N)
+
+	b.ResetTimer()
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	ri.BatchInsert(entries)
+}
+
+// BenchmarkIndexManager_HashVsBTree compares hash vs B-tree vs radix through manager
 func BenchmarkIndexManager_HashVsBTree(b *testing.B) {
 	testData := generateBenchmarkData(1000)
 
@@ -287,6 +374,27 @@ func BenchmarkIndexManager_HashVsBTree(b *testing.B) {
 			im.Get(lookupKeys[i])
 		}
 	})
+
+	b.Run("Radix", func(b *testing.B) {
+		im, err := NewIndexManager(IndexTypeRadix)
+		if err != nil {
+			b.Fatalf("Failed to create radix index manager: %v", err)
+		}
+		defer im.Close()
+
+		// Insert data
+		for key, entry := range testData {
+			im.Insert(key, entry)
+		}
+
+		// Benchmark lookups
+		lookupKeys := generateRandomKeys(b.N, 1000)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			im.Get(lookupKeys[i])
+		}
+	})
 }
 
 // BenchmarkMemoryUsage_Comparison compares memory usage between index types
@@ -320,6 +428,20 @@ func BenchmarkMemoryUsage_Comparison(b *testing.B) {
 
 		b.ReportMetric(float64(im.MemoryUsage()), "bytes")
 	})
+
+	b.Run("Radix_Memory", func(b *testing.B) {
+		im, err := NewIndexManager(IndexTypeRadix)
+		if err != nil {
+			b.Fatalf("Failed to create radix index manager: %v", err)
+		}
+		defer im.Close()
+
+		for key, entry := range testData {
+			im.Insert(key, entry)
+		}
+
+		b.ReportMetric(float64(im.MemoryUsage()), "bytes")
+	})
 }
 
 // BenchmarkPrefixSearch compares prefix search performance
@@ -374,4 +496,55 @@ func BenchmarkPrefixSearch(b *testing.B) {
 			im.Prefix(prefix)
 		}
 	})
+
+	b.Run("Radix_Prefix", func(b *testing.B) {
+		im, err := NewIndexManager(IndexTypeRadix)
+		if err != nil {
+			b.Fatalf("Failed to create radix index manager: %v", err)
+		}
+		defer im.Close()
+
+		for key, entry := range testData {
+			im.Insert(key, entry)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			prefix := prefixes[i%len(prefixes)]
+			im.Prefix(prefix)
+		}
+	})
+}
+
+// BenchmarkHierarchicalPrefixSearch compares prefix search on long hierarchical keys
+// such as "tenant:12:user:345:session:6", narrowing to a single tenant's user
+func BenchmarkHierarchicalPrefixSearch(b *testing.B) {
+	testData := make(map[string]IndexEntry)
+	for tenant := 0; tenant < 50; tenant++ {
+		for user := 0; user < 20; user++ {
+			for session := 0; session < 10; session++ {
+				key := fmt.Sprintf("tenant:%d:user:%d:session:%d", tenant, user, session)
+				testData[key] = IndexEntry{Key: key, Size: 50}
+			}
+		}
+	}
+
+	for _, indexType := range []IndexType{IndexTypeHash, IndexTypeBTree, IndexTypeRadix} {
+		b.Run(string(indexType), func(b *testing.B) {
+			im, err := NewIndexManager(indexType)
+			if err != nil {
+				b.Fatalf("Failed to create %s index manager: %v", indexType, err)
+			}
+			defer im.Close()
+
+			for key, entry := range testData {
+				im.Insert(key, entry)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				im.Prefix(fmt.Sprintf("tenant:%d:user:%d:", i%50, i%20))
+			}
+		})
+	}
 }
@@ -0,0 +1,255 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Tokenize splits text into lower-case words of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// termFrequencies counts the occurrences of each token in text
+func termFrequencies(text string) map[string]int {
+	freqs := make(map[string]int)
+	for _, term := range Tokenize(text) {
+		freqs[term]++
+	}
+	return freqs
+}
+
+// SearchHit is a key matching a full-text query
+type SearchHit struct {
+	Key   string
+	Score int // Total occurrences of the query terms in the indexed text
+}
+
+// MatchScore scores text against query terms without an index. It returns false
+// unless every term occurs in the text.
+func MatchScore(terms []string, text string) (int, bool) {
+	if len(terms) == 0 {
+		return 0, false
+	}
+	freqs := termFrequencies(text)
+	score := 0
+	for _, term := range terms {
+		if freqs[term] == 0 {
+			return 0, false
+		}
+		score += freqs[term]
+	}
+	return score, true
+}
+
+// RankHits orders hits by descending score, then by key
+func RankHits(hits []SearchHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Key < hits[j].Key
+	})
+}
+
+// FullTextIndex is an inverted index from words to the keys whose values contain them
+type FullTextIndex struct {
+	mu       sync.RWMutex
+	field    string                    // JSON field path; empty indexes the whole value
+	docs     map[string]map[string]int // Key -> term -> frequency
+	postings map[string]map[string]int // Term -> key -> frequency
+	stale    bool                      // The persisted entries do not match the log and must be rebuilt
+}
+
+// NewFullTextIndex creates an empty full-text index over values, or over a JSON field of values
+func NewFullTextIndex(field string) *FullTextIndex {
+	return &FullTextIndex{
+		field:    field,
+		docs:     make(map[string]map[string]int),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Field returns the JSON field path the index covers; empty means the whole value
+func (ft *FullTextIndex) Field() string {
+	return ft.field
+}
+
+// Update indexes the value stored under key, replacing its previous entry
+func (ft *FullTextIndex) Update(key, value string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.update(key, value)
+}
+
+func (ft *FullTextIndex) update(key, value string) {
+	ft.remove(key)
+
+	text, ok := ExtractField(value, ft.field)
+	if !ok {
+		return
+	}
+	freqs := termFrequencies(text)
+	if len(freqs) == 0 {
+		return
+	}
+	ft.addDoc(key, freqs)
+}
+
+func (ft *FullTextIndex) addDoc(key string, freqs map[string]int) {
+	ft.docs[key] = freqs
+	for term, freq := range freqs {
+		if ft.postings[term] == nil {
+			ft.postings[term] = make(map[string]int)
+		}
+		ft.postings[term][key] = freq
+	}
+}
+
+// Remove drops the entry of a deleted key
+func (ft *FullTextIndex) Remove(key string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.remove(key)
+}
+
+func (ft *FullTextIndex) remove(key string) {
+	freqs, exists := ft.docs[key]
+	if !exists {
+		return
+	}
+	delete(ft.docs, key)
+	for term := range freqs {
+		delete(ft.postings[term], key)
+		if len(ft.postings[term]) == 0 {
+			delete(ft.postings, term)
+		}
+	}
+}
+
+// Rebuild replaces the entries with ones built from the current data
+func (ft *FullTextIndex) Rebuild(data map[string]string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	ft.docs = make(map[string]map[string]int)
+	ft.postings = make(map[string]map[string]int)
+	for key, value := range data {
+		ft.update(key, value)
+	}
+	ft.stale = false
+}
+
+// Stale reports whether the loaded entries must be rebuilt before use
+func (ft *FullTextIndex) Stale() bool {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	return ft.stale
+}
+
+// Search returns the keys containing every word of query, ranked by how often the words occur
+func (ft *FullTextIndex) Search(query string) []SearchHit {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return []SearchHit{}
+	}
+
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+
+	// Walk the shortest posting list and check the others
+	shortest := ft.postings[terms[0]]
+	for _, term := range terms[1:] {
+		if len(ft.postings[term]) < len(shortest) {
+			shortest = ft.postings[term]
+		}
+	}
+
+	hits := []SearchHit{}
+	for key := range shortest {
+		score := 0
+		for _, term := range terms {
+			freq := ft.postings[term][key]
+			if freq == 0 {
+				score = 0
+				break
+			}
+			score += freq
+		}
+		if score > 0 {
+			hits = append(hits, SearchHit{Key: key, Score: score})
+		}
+	}
+	RankHits(hits)
+	return hits
+}
+
+// Stats returns the field, document count and distinct term count of the index
+func (ft *FullTextIndex) Stats() map[string]interface{} {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+
+	return map[string]interface{}{
+		"field":     ft.field,
+		"documents": len(ft.docs),
+		"terms":     len(ft.postings),
+	}
+}
+
+// fullTextIndexFile is the persisted form of a FullTextIndex
+type fullTextIndexFile struct {
+	LogSize int64                     `json:"log_size"` // Size of the log the entries were built from
+	Field   string                    `json:"field,omitempty"`
+	Docs    map[string]map[string]int `json:"docs"` // Key -> term -> frequency
+}
+
+// Save writes the index to filename, recording the size of the log it reflects
+func (ft *FullTextIndex) Save(filename string, logSize int64) error {
+	ft.mu.RLock()
+	data, err := json.Marshal(fullTextIndexFile{LogSize: logSize, Field: ft.field, Docs: ft.docs})
+	ft.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode full-text index: %w", err)
+	}
+
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write full-text index: %w", err)
+	}
+	if err := os.Rename(tempFile, filename); err != nil {
+		_ = os.Remove(tempFile) // Best effort cleanup
+		return fmt.Errorf("failed to replace full-text index file: %w", err)
+	}
+	return nil
+}
+
+// LoadFullTextIndex reads the index saved in filename. A missing file yields nil;
+// entries saved for a different log size are marked stale.
+func LoadFullTextIndex(filename string, logSize int64) (*FullTextIndex, error) {
+	data, err := os.ReadFile(filename) // #nosec G304 - path is built from the store's data directory
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read full-text index: %w", err)
+	}
+
+	var file fullTextIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode full-text index: %w", err)
+	}
+
+	ft := NewFullTextIndex(file.Field)
+	for key, freqs := range file.Docs {
+		ft.addDoc(key, freqs)
+	}
+	ft.stale = file.LogSize != logSize
+	return ft, nil
+}
@@ -0,0 +1,105 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Red running-shoes, size 42! Ünïcode")
+	want := []string{"red", "running", "shoes", "size", "42", "ünïcode"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestFullTextIndex_Search(t *testing.T) {
+	ft := NewFullTextIndex("")
+	ft.Rebuild(map[string]string{
+		"p1": "red shoes",
+		"p2": "red red shoes for running",
+		"p3": "blue shoes",
+		"p4": "red hat",
+	})
+
+	tests := []struct {
+		query string
+		want  []SearchHit
+	}{
+		{"red shoes", []SearchHit{{Key: "p2", Score: 3}, {Key: "p1", Score: 2}}},
+		{"SHOES", []SearchHit{{Key: "p1", Score: 1}, {Key: "p2", Score: 1}, {Key: "p3", Score: 1}}},
+		{"green", []SearchHit{}},
+		{"  ", []SearchHit{}},
+	}
+	for _, tt := range tests {
+		if got := ft.Search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Incremental updates replace and remove postings
+	ft.Update("p3", "red shoes")
+	ft.Remove("p2")
+	want := []SearchHit{{Key: "p1", Score: 2}, {Key: "p3", Score: 2}}
+	if got := ft.Search("red shoes"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search after updates = %v, want %v", got, want)
+	}
+	if stats := ft.Stats(); stats["documents"] != 3 || stats["terms"] != 3 {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+}
+
+func TestFullTextIndex_Field(t *testing.T) {
+	ft := NewFullTextIndex("description")
+	ft.Update("p1", `{"name":"shoes","description":"comfortable running shoes"}`)
+	ft.Update("p2", `{"name":"running hat"}`)
+	ft.Update("p3", "not json running")
+
+	want := []SearchHit{{Key: "p1", Score: 1}}
+	if got := ft.Search("running"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+}
+
+func TestFullTextIndex_Persistence(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "moz.ftidx")
+
+	if ft, err := LoadFullTextIndex(filename, 0); err != nil || ft != nil {
+		t.Fatalf("Expected no index for a missing file, got %v, %v", ft, err)
+	}
+
+	ft := NewFullTextIndex("")
+	ft.Update("p1", "red shoes")
+	if err := ft.Save(filename, 100); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadFullTextIndex(filename, 100)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Stale() {
+		t.Error("Expected index saved for the same log size to be fresh")
+	}
+	if got := loaded.Search("shoes"); !reflect.DeepEqual(got, []SearchHit{{Key: "p1", Score: 1}}) {
+		t.Errorf("Search after load = %v", got)
+	}
+
+	stale, err := LoadFullTextIndex(filename, 200)
+	if err != nil || !stale.Stale() {
+		t.Errorf("Expected stale index for a different log size, got %v", err)
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	if score, ok := MatchScore(Tokenize("red shoes"), "Red shoes, red laces"); !ok || score != 3 {
+		t.Errorf("MatchScore = %d, %v; want 3, true", score, ok)
+	}
+	if _, ok := MatchScore(Tokenize("red boots"), "red shoes"); ok {
+		t.Error("Expected no match when a term is missing")
+	}
+	if _, ok := MatchScore(nil, "red shoes"); ok {
+		t.Error("Expected no match for an empty query")
+	}
+}
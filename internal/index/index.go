@@ -11,6 +11,7 @@ type IndexType string
 const (
 	IndexTypeHash  IndexType = "hash"
 	IndexTypeBTree IndexType = "btree"
+	IndexTypeRadix IndexType = "radix"
 	IndexTypeNone  IndexType = "none"
 )
 
@@ -91,6 +92,8 @@ func NewIndexManagerWithPool(indexType IndexType, entryPool IndexEntryPool) (*In
 		} else {
 			idx, err = NewBTreeIndex(DefaultBTreeIndexConfig())
 		}
+	case IndexTypeRadix:
+		idx = NewRadixIndexWithPool(entryPool)
 	case IndexTypeNone:
 		idx = NewNoIndex()
 	default:
@@ -105,6 +105,38 @@ func TestIndexManager_BTreeIndex(t *testing.T) {
 	}
 }
 
+func TestIndexManager_RadixIndex(t *testing.T) {
+	// Test radix index through manager
+	im, err := NewIndexManager(IndexTypeRadix)
+	if err != nil {
+		t.Fatalf("Failed to create index manager: %v", err)
+	}
+	defer im.Close()
+
+	if im.GetIndexType() != IndexTypeRadix || !im.IsEnabled() {
+		t.Errorf("Expected enabled index type %s, got %s", IndexTypeRadix, im.GetIndexType())
+	}
+
+	for _, key := range []string{"tenant:1:user:1", "tenant:1:user:2", "tenant:2:user:1"} {
+		if err := im.Insert(key, IndexEntry{Key: key}); err != nil {
+			t.Fatalf("Failed to insert key %s: %v", key, err)
+		}
+	}
+
+	// Test prefix search (radix's strength)
+	entries, err := im.Prefix("tenant:1:")
+	if err != nil {
+		t.Fatalf("Failed to perform prefix search: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Key != "tenant:1:user:1" || entries[1].Key != "tenant:1:user:2" {
+		t.Errorf("Expected tenant:1 users in order, got %v", entries)
+	}
+
+	if err := im.Validate(); err != nil {
+		t.Errorf("Validation failed: %v", err)
+	}
+}
+
 func TestIndexManager_NoIndex(t *testing.T) {
 	// Test disabled index
 	im, err := NewIndexManager(IndexTypeNone)
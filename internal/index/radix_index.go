@@ -0,0 +1,486 @@
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// radixNode is a node of a compressed radix trie. The key of a node is the
+// concatenation of the labels on the path from the root.
+type radixNode struct {
+	label    string       // Edge label from the parent; empty only for the root
+	children []*radixNode // Sorted by the first byte of their labels
+	entry    IndexEntry
+	hasEntry bool
+}
+
+// child returns the position of the child whose label starts with b, and whether it exists
+func (n *radixNode) child(b byte) (int, bool) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].label[0] >= b })
+	return i, i < len(n.children) && n.children[i].label[0] == b
+}
+
+// RadixIndex implements a compressed radix trie index. Shared key prefixes are
+// stored once, which suits long hierarchical keys such as "tenant:1:user:2:...",
+// and prefix and range queries only visit the matching subtrees.
+type RadixIndex struct {
+	root      *radixNode
+	count     int64
+	nodes     int64 // Number of nodes excluding the root
+	mu        sync.RWMutex
+	entryPool IndexEntryPool // Optional memory pool for IndexEntry objects
+}
+
+// NewRadixIndex creates a new radix trie index
+func NewRadixIndex() *RadixIndex {
+	return NewRadixIndexWithPool(nil)
+}
+
+// NewRadixIndexWithPool creates a new radix trie index with optional memory pool
+func NewRadixIndexWithPool(entryPool IndexEntryPool) *RadixIndex {
+	return &RadixIndex{
+		root:      &radixNode{},
+		entryPool: entryPool,
+	}
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// Insert adds an entry to the radix index
+func (ri *RadixIndex) Insert(key string, entry IndexEntry) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.insert(key, entry)
+	return nil
+}
+
+func (ri *RadixIndex) insert(key string, entry IndexEntry) {
+	node := ri.root
+	rest := key
+	for rest != "" {
+		i, found := node.child(rest[0])
+		if !found {
+			// No edge shares a byte with the rest of the key: add a leaf
+			leaf := &radixNode{label: rest, entry: entry, hasEntry: true}
+			node.children = append(node.children, nil)
+			copy(node.children[i+1:], node.children[i:])
+			node.children[i] = leaf
+			ri.nodes++
+			ri.count++
+			return
+		}
+
+		next := node.children[i]
+		common := commonPrefixLen(rest, next.label)
+		if common < len(next.label) {
+			// Split the edge at the end of the shared part
+			split := &radixNode{label: next.label[:common], children: []*radixNode{next}}
+			next.label = next.label[common:]
+			node.children[i] = split
+			ri.nodes++
+			next = split
+		}
+		node = next
+		rest = rest[common:]
+	}
+
+	if !node.hasEntry {
+		ri.count++
+	}
+	node.entry = entry
+	node.hasEntry = true
+}
+
+// Delete removes an entry from the radix index
+func (ri *RadixIndex) Delete(key string) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	// Record the path so emptied nodes can be pruned on the way back
+	path := []*radixNode{ri.root}
+	node := ri.root
+	rest := key
+	for rest != "" {
+		i, found := node.child(rest[0])
+		if !found || !strings.HasPrefix(rest, node.children[i].label) {
+			return fmt.Errorf("key not found: %s", key)
+		}
+		node = node.children[i]
+		rest = rest[len(node.label):]
+		path = append(path, node)
+	}
+	if !node.hasEntry {
+		return fmt.Errorf("key not found: %s", key)
+	}
+
+	node.entry = IndexEntry{}
+	node.hasEntry = false
+	ri.count--
+
+	// Remove or merge nodes that no longer carry an entry, bottom up. Removing a
+	// leaf can leave its parent with one child, which is merged on the next step.
+	for depth := len(path) - 1; depth > 0; depth-- {
+		node, parent := path[depth], path[depth-1]
+		if node.hasEntry || len(node.children) > 1 {
+			break
+		}
+		if len(node.children) == 1 {
+			// Merge the only child into this node
+			only := node.children[0]
+			node.label += only.label
+			node.children = only.children
+			node.entry = only.entry
+			node.hasEntry = only.hasEntry
+			ri.nodes--
+			break
+		}
+		i, _ := parent.child(node.label[0])
+		parent.children = append(parent.children[:i], parent.children[i+1:]...)
+		ri.nodes--
+	}
+
+	return nil
+}
+
+// find returns the node holding key, or nil
+func (ri *RadixIndex) find(key string) *radixNode {
+	node := ri.root
+	rest := key
+	for rest != "" {
+		i, found := node.child(rest[0])
+		if !found || !strings.HasPrefix(rest, node.children[i].label) {
+			return nil
+		}
+		node = node.children[i]
+		rest = rest[len(node.label):]
+	}
+	return node
+}
+
+// Get retrieves an entry from the radix index
+func (ri *RadixIndex) Get(key string) (IndexEntry, error) {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	node := ri.find(key)
+	if node == nil || !node.hasEntry {
+		return IndexEntry{}, fmt.Errorf("key not found: %s", key)
+	}
+	return node.entry, nil
+}
+
+// Exists checks if a key exists in the radix index
+func (ri *RadixIndex) Exists(key string) bool {
+	_, err := ri.Get(key)
+	return err == nil
+}
+
+// BatchInsert adds multiple entries efficiently
+func (ri *RadixIndex) BatchInsert(entries map[string]IndexEntry) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	for key, entry := range entries {
+		ri.insert(key, entry)
+	}
+	return nil
+}
+
+// BatchDelete removes multiple entries efficiently
+func (ri *RadixIndex) BatchDelete(keys []string) error {
+	for _, key := range keys {
+		if err := ri.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete key %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// walk visits the entries below node in key order. key holds the key of node and
+// is reused as a buffer; skip reports whether the subtree below a node key can be
+// pruned, and visit returns false to stop the walk.
+func (ri *RadixIndex) walk(node *radixNode, key []byte, skip func([]byte) bool, visit func([]byte, IndexEntry) bool) bool {
+	if node.hasEntry && !visit(key, node.entry) {
+		return false
+	}
+	for _, child := range node.children {
+		childKey := append(key, child.label...)
+		if skip != nil && skip(childKey) {
+			continue
+		}
+		if !ri.walk(child, childKey, skip, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns all keys in sorted order
+func (ri *RadixIndex) Keys() []string {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	keys := make([]string, 0, ri.count)
+	ri.walk(ri.root, nil, nil, func(key []byte, _ IndexEntry) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	return keys
+}
+
+// Range returns entries in the specified key range [start, end] in key order
+func (ri *RadixIndex) Range(start, end string) ([]IndexEntry, error) {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	var entries []IndexEntry
+	// Every key below a node is at least the node's key, so a node past end ends
+	// its subtree; a node before start that is not a prefix of start is wholly before it
+	skip := func(prefix []byte) bool {
+		return string(prefix) > end || (string(prefix) < start && !strings.HasPrefix(start, string(prefix)))
+	}
+	ri.walk(ri.root, nil, skip, func(key []byte, entry IndexEntry) bool {
+		if string(key) > end {
+			return false
+		}
+		if string(key) >= start {
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	return entries, nil
+}
+
+// Prefix returns entries with the specified key prefix in key order
+func (ri *RadixIndex) Prefix(prefix string) ([]IndexEntry, error) {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	// Descend to the node whose key first extends the prefix
+	node := ri.root
+	matched := 0
+	for matched < len(prefix) {
+		rest := prefix[matched:]
+		i, found := node.child(rest[0])
+		if !found {
+			return nil, nil
+		}
+		child := node.children[i]
+		if !strings.HasPrefix(child.label, rest) && !strings.HasPrefix(rest, child.label) {
+			return nil, nil
+		}
+		node = child
+		matched += len(child.label)
+	}
+
+	// The entries below the node all match; their keys are not needed
+	var entries []IndexEntry
+	var collect func(node *radixNode)
+	collect = func(node *radixNode) {
+		if node.hasEntry {
+			entries = append(entries, node.entry)
+		}
+		for _, child := range node.children {
+			collect(child)
+		}
+	}
+	collect(node)
+	return entries, nil
+}
+
+// Size returns the number of entries in the index
+func (ri *RadixIndex) Size() int64 {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+	return ri.count
+}
+
+// MemoryUsage estimates memory usage in bytes
+func (ri *RadixIndex) MemoryUsage() int64 {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	size := int64(128) // Struct overhead
+
+	var visit func(node *radixNode)
+	visit = func(node *radixNode) {
+		size += 48 + int64(len(node.label)) + 8*int64(len(node.children)) // Node, label and child pointers
+		if node.hasEntry {
+			size += 64 + int64(len(node.entry.Key)) // IndexEntry struct
+		}
+		for _, child := range node.children {
+			visit(child)
+		}
+	}
+	visit(ri.root)
+
+	return size
+}
+
+// Validate checks the integrity of the radix index
+func (ri *RadixIndex) Validate() error {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	var entries, nodes int64
+	var check func(node *radixNode, key string) error
+	check = func(node *radixNode, key string) error {
+		if node.hasEntry {
+			entries++
+		}
+		if node != ri.root {
+			nodes++
+			if node.label == "" {
+				return fmt.Errorf("empty edge label below %q", key)
+			}
+			if !node.hasEntry && len(node.children) < 2 {
+				return fmt.Errorf("uncompressed node at %q", key)
+			}
+		}
+		for i, child := range node.children {
+			if child.label == "" {
+				return fmt.Errorf("empty edge label below %q", key)
+			}
+			if i > 0 && node.children[i-1].label[0] >= child.label[0] {
+				return fmt.Errorf("children not sorted below %q", key)
+			}
+			if err := check(child, key+child.label); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(ri.root, ""); err != nil {
+		return err
+	}
+
+	if entries != ri.count {
+		return fmt.Errorf("count mismatch: expected %d, found %d", ri.count, entries)
+	}
+	if nodes != ri.nodes {
+		return fmt.Errorf("node count mismatch: expected %d, found %d", ri.nodes, nodes)
+	}
+	return nil
+}
+
+// Rebuild rebuilds the entire index from scratch
+func (ri *RadixIndex) Rebuild(entries map[string]IndexEntry) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ri.root = &radixNode{}
+	ri.count = 0
+	ri.nodes = 0
+	for key, entry := range entries {
+		ri.insert(key, entry)
+	}
+	return nil
+}
+
+// radixRecord is a persisted key and entry
+type radixRecord struct {
+	Key   string
+	Entry IndexEntry
+}
+
+// Save persists the radix index to a file as its entries in key order
+func (ri *RadixIndex) Save(filename string) error {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	// Validate filename to prevent directory traversal
+	if err := validateFilePath(filename); err != nil {
+		return fmt.Errorf("invalid filename: %w", err)
+	}
+
+	file, err := os.Create(filename) // #nosec G304 - filename validated above
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	records := make([]radixRecord, 0, ri.count)
+	ri.walk(ri.root, nil, nil, func(key []byte, entry IndexEntry) bool {
+		records = append(records, radixRecord{Key: string(key), Entry: entry})
+		return true
+	})
+
+	encoder := gob.NewEncoder(file)
+	if err := encoder.Encode(ri.count); err != nil {
+		return fmt.Errorf("failed to encode count: %w", err)
+	}
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to encode entries: %w", err)
+	}
+
+	return nil
+}
+
+// Load restores the radix index from a file
+func (ri *RadixIndex) Load(filename string) error {
+	// Validate filename to prevent directory traversal
+	if err := validateFilePath(filename); err != nil {
+		return fmt.Errorf("invalid filename: %w", err)
+	}
+
+	file, err := os.Open(filename) // #nosec G304 - filename validated above
+	if err != nil {
+		return fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	decoder := gob.NewDecoder(file)
+
+	var count int64
+	if err := decoder.Decode(&count); err != nil {
+		return fmt.Errorf("failed to decode count: %w", err)
+	}
+
+	var records []radixRecord
+	if err := decoder.Decode(&records); err != nil {
+		return fmt.Errorf("failed to decode entries: %w", err)
+	}
+	if int64(len(records)) != count {
+		return fmt.Errorf("count mismatch: expected %d, found %d", count, len(records))
+	}
+
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ri.root = &radixNode{}
+	ri.count = 0
+	ri.nodes = 0
+	for _, record := range records {
+		ri.insert(record.Key, record.Entry)
+	}
+	return nil
+}
+
+// Close cleans up resources
+func (ri *RadixIndex) Close() error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	// Clear all data
+	ri.root = &radixNode{}
+	ri.count = 0
+	ri.nodes = 0
+
+	return nil
+}
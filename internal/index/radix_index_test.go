@@ -0,0 +1,195 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func radixEntry(key string) IndexEntry {
+	return IndexEntry{Key: key, Offset: int64(len(key)), Size: 10}
+}
+
+func entryKeys(entries []IndexEntry) []string {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+func TestRadixIndex_BasicOperations(t *testing.T) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	keys := []string{"tenant:1:user:1", "tenant:1:user:10", "tenant:1:user:2", "tenant:2", "tenant", "team"}
+	for _, key := range keys {
+		if err := ri.Insert(key, radixEntry(key)); err != nil {
+			t.Fatalf("Failed to insert %s: %v", key, err)
+		}
+	}
+	if err := ri.Validate(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+
+	for _, key := range keys {
+		entry, err := ri.Get(key)
+		if err != nil || entry.Key != key {
+			t.Errorf("Get(%s) = %v, %v", key, entry, err)
+		}
+	}
+	for _, key := range []string{"tenant:", "tenant:1:user:3", "te", "tenants"} {
+		if ri.Exists(key) {
+			t.Errorf("Expected %s to not exist", key)
+		}
+	}
+
+	// Overwriting does not change the size
+	_ = ri.Insert("tenant", IndexEntry{Key: "tenant", Offset: 99})
+	if entry, _ := ri.Get("tenant"); entry.Offset != 99 || ri.Size() != int64(len(keys)) {
+		t.Errorf("Expected updated entry and size %d, got %v and %d", len(keys), entry, ri.Size())
+	}
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	if got := ri.Keys(); !reflect.DeepEqual(got, sorted) {
+		t.Errorf("Keys() = %v, want %v", got, sorted)
+	}
+
+	// Deleting merges nodes that no longer branch
+	for _, key := range []string{"tenant", "tenant:1:user:1", "team"} {
+		if err := ri.Delete(key); err != nil {
+			t.Fatalf("Failed to delete %s: %v", key, err)
+		}
+		if err := ri.Validate(); err != nil {
+			t.Fatalf("Validation failed after deleting %s: %v", key, err)
+		}
+	}
+	if err := ri.Delete("tenant:1"); err == nil {
+		t.Error("Expected error deleting a key that is only a prefix")
+	}
+	want := []string{"tenant:1:user:10", "tenant:1:user:2", "tenant:2"}
+	if got := ri.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() after delete = %v, want %v", got, want)
+	}
+}
+
+func TestRadixIndex_PrefixAndRange(t *testing.T) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	var keys []string
+	for tenant := 0; tenant < 3; tenant++ {
+		for user := 0; user < 12; user++ {
+			keys = append(keys, fmt.Sprintf("tenant:%d:user:%d", tenant, user))
+		}
+	}
+	keys = append(keys, "tenant", "tenant:1", "zebra")
+	for _, key := range keys {
+		_ = ri.Insert(key, radixEntry(key))
+	}
+	sort.Strings(keys)
+
+	filter := func(match func(string) bool) []string {
+		var result []string
+		for _, key := range keys {
+			if match(key) {
+				result = append(result, key)
+			}
+		}
+		return result
+	}
+
+	for _, prefix := range []string{"", "tenant:1", "tenant:1:user:1", "tenant:1:u", "ten", "tenant:9", "zz"} {
+		entries, err := ri.Prefix(prefix)
+		if err != nil {
+			t.Fatalf("Prefix(%q) failed: %v", prefix, err)
+		}
+		want := filter(func(key string) bool { return len(key) >= len(prefix) && key[:len(prefix)] == prefix })
+		if got := entryKeys(entries); len(got)+len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Errorf("Prefix(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+
+	ranges := [][2]string{
+		{"tenant:1", "tenant:1:user:5"},
+		{"tenant:0:user:11", "tenant:2"},
+		{"a", "tenant"},
+		{"tenant:2:user:9", "zzz"},
+		{"u", "y"},
+	}
+	for _, r := range ranges {
+		entries, err := ri.Range(r[0], r[1])
+		if err != nil {
+			t.Fatalf("Range(%q, %q) failed: %v", r[0], r[1], err)
+		}
+		want := filter(func(key string) bool { return key >= r[0] && key <= r[1] })
+		if got := entryKeys(entries); len(got)+len(want) > 0 && !reflect.DeepEqual(got, want) {
+			t.Errorf("Range(%q, %q) = %v, want %v", r[0], r[1], got, want)
+		}
+	}
+}
+
+func TestRadixIndex_RandomizedAgainstMap(t *testing.T) {
+	ri := NewRadixIndex()
+	defer ri.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	expected := make(map[string]bool)
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("t:%d:u:%d", rng.Intn(5), rng.Intn(50))
+		if rng.Intn(3) == 0 {
+			err := ri.Delete(key)
+			if (err == nil) != expected[key] {
+				t.Fatalf("Delete(%s) = %v, exists = %v", key, err, expected[key])
+			}
+			delete(expected, key)
+		} else {
+			_ = ri.Insert(key, radixEntry(key))
+			expected[key] = true
+		}
+	}
+
+	if err := ri.Validate(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	want := make([]string, 0, len(expected))
+	for key := range expected {
+		want = append(want, key)
+	}
+	sort.Strings(want)
+	if got := ri.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+}
+
+func TestRadixIndex_Persistence(t *testing.T) {
+	tmpFile := "test_radix_index.gob"
+	defer os.Remove(tmpFile)
+
+	ri1 := NewRadixIndex()
+	for _, key := range []string{"a:1", "a:2", "b"} {
+		_ = ri1.Insert(key, radixEntry(key))
+	}
+	if err := ri1.Save(tmpFile); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	ri2 := NewRadixIndex()
+	if err := ri2.Load(tmpFile); err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if !reflect.DeepEqual(ri2.Keys(), ri1.Keys()) || ri2.Size() != 3 {
+		t.Errorf("Loaded keys %v, want %v", ri2.Keys(), ri1.Keys())
+	}
+	if ri2.MemoryUsage() != ri1.MemoryUsage() {
+		t.Errorf("Expected equal memory usage, got %d and %d", ri2.MemoryUsage(), ri1.MemoryUsage())
+	}
+
+	if err := ri2.Save("../escape.gob"); err == nil {
+		t.Error("Expected error for a path with directory traversal")
+	}
+}
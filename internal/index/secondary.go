@@ -0,0 +1,344 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SecondaryIndexDef declares a secondary index over values
+type SecondaryIndexDef struct {
+	Name  string `json:"name"`
+	Field string `json:"field,omitempty"` // JSON field path such as "status" or "user.name"; empty indexes the whole value
+}
+
+// SecondaryIndex maps indexed values to the keys holding them
+type SecondaryIndex struct {
+	def    SecondaryIndexDef
+	values map[string]string              // Key -> indexed value
+	keys   map[string]map[string]struct{} // Indexed value -> keys
+}
+
+func newSecondaryIndex(def SecondaryIndexDef) *SecondaryIndex {
+	return &SecondaryIndex{
+		def:    def,
+		values: make(map[string]string),
+		keys:   make(map[string]map[string]struct{}),
+	}
+}
+
+// update indexes the value stored under key, replacing its previous entry
+func (si *SecondaryIndex) update(key, value string) {
+	si.remove(key)
+
+	indexed, ok := ExtractField(value, si.def.Field)
+	if !ok {
+		return
+	}
+	si.values[key] = indexed
+	if si.keys[indexed] == nil {
+		si.keys[indexed] = make(map[string]struct{})
+	}
+	si.keys[indexed][key] = struct{}{}
+}
+
+// remove drops the entry of key
+func (si *SecondaryIndex) remove(key string) {
+	indexed, exists := si.values[key]
+	if !exists {
+		return
+	}
+	delete(si.values, key)
+	delete(si.keys[indexed], key)
+	if len(si.keys[indexed]) == 0 {
+		delete(si.keys, indexed)
+	}
+}
+
+// lookup returns the keys whose indexed value equals value, in sorted order
+func (si *SecondaryIndex) lookup(value string) []string {
+	keys := make([]string, 0, len(si.keys[value]))
+	for key := range si.keys[value] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExtractField returns the indexed form of a value for a field path. The whole value
+// is used when field is empty; otherwise the value must be a JSON document whose
+// field holds a string, number or boolean.
+func ExtractField(value, field string) (string, bool) {
+	if field == "" {
+		return value, true
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return "", false
+	}
+
+	for _, part := range strings.Split(field, ".") {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, exists := node[part]
+			if !exists {
+				return "", false
+			}
+			doc = child
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			doc = node[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := doc.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false // Objects, arrays and null are not indexed
+	}
+}
+
+// ValidateSecondaryIndexDef checks an index name and field path
+func ValidateSecondaryIndexDef(def SecondaryIndexDef) error {
+	if def.Name == "" {
+		return fmt.Errorf("index name cannot be empty")
+	}
+	for _, r := range def.Name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("invalid index name %q: only letters, digits, '_' and '-' are allowed", def.Name)
+		}
+	}
+	if def.Field != "" {
+		for _, part := range strings.Split(def.Field, ".") {
+			if part == "" {
+				return fmt.Errorf("invalid field path %q", def.Field)
+			}
+		}
+	}
+	return nil
+}
+
+// SecondaryIndexSet holds the secondary indexes of a store
+type SecondaryIndexSet struct {
+	mu      sync.RWMutex
+	indexes map[string]*SecondaryIndex
+	stale   bool // The persisted entries do not match the log and must be rebuilt
+}
+
+// NewSecondaryIndexSet creates an empty set
+func NewSecondaryIndexSet() *SecondaryIndexSet {
+	return &SecondaryIndexSet{indexes: make(map[string]*SecondaryIndex)}
+}
+
+// Len returns the number of secondary indexes
+func (s *SecondaryIndexSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.indexes)
+}
+
+// Definitions returns the index definitions sorted by name
+func (s *SecondaryIndexSet) Definitions() []SecondaryIndexDef {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	defs := make([]SecondaryIndexDef, 0, len(s.indexes))
+	for _, si := range s.indexes {
+		defs = append(defs, si.def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Create adds an index and builds it from the current data
+func (s *SecondaryIndexSet) Create(def SecondaryIndexDef, data map[string]string) error {
+	if err := ValidateSecondaryIndexDef(def); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.indexes[def.Name]; exists {
+		return fmt.Errorf("index %q already exists", def.Name)
+	}
+	si := newSecondaryIndex(def)
+	for key, value := range data {
+		si.update(key, value)
+	}
+	s.indexes[def.Name] = si
+	return nil
+}
+
+// Drop removes an index
+func (s *SecondaryIndexSet) Drop(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.indexes[name]; !exists {
+		return fmt.Errorf("index %q does not exist", name)
+	}
+	delete(s.indexes, name)
+	return nil
+}
+
+// Update indexes a new or changed value in every index
+func (s *SecondaryIndexSet) Update(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, si := range s.indexes {
+		si.update(key, value)
+	}
+}
+
+// Remove drops a deleted key from every index
+func (s *SecondaryIndexSet) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, si := range s.indexes {
+		si.remove(key)
+	}
+}
+
+// Lookup returns the sorted keys whose field equals value, using the first index
+// (by name) on the field. It returns false if no index covers the field.
+func (s *SecondaryIndexSet) Lookup(field, value string) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match *SecondaryIndex
+	for _, si := range s.indexes {
+		if si.def.Field == field && (match == nil || si.def.Name < match.def.Name) {
+			match = si
+		}
+	}
+	if match == nil {
+		return nil, false
+	}
+	return match.lookup(value), true
+}
+
+// Rebuild rebuilds every index from the current data
+func (s *SecondaryIndexSet) Rebuild(data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, si := range s.indexes {
+		rebuilt := newSecondaryIndex(si.def)
+		for key, value := range data {
+			rebuilt.update(key, value)
+		}
+		s.indexes[name] = rebuilt
+	}
+	s.stale = false
+}
+
+// Stale reports whether the loaded entries must be rebuilt before use
+func (s *SecondaryIndexSet) Stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stale
+}
+
+// Stats returns the field, entry count and distinct value count of each index
+func (s *SecondaryIndexSet) Stats() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make(map[string]interface{}, len(s.indexes))
+	for name, si := range s.indexes {
+		stats[name] = map[string]interface{}{
+			"field":           si.def.Field,
+			"entries":         len(si.values),
+			"distinct_values": len(si.keys),
+		}
+	}
+	return stats
+}
+
+// secondaryIndexFile is the persisted form of a SecondaryIndexSet
+type secondaryIndexFile struct {
+	LogSize int64                 `json:"log_size"` // Size of the log the entries were built from
+	Indexes []secondaryIndexEntry `json:"indexes"`
+}
+
+type secondaryIndexEntry struct {
+	SecondaryIndexDef
+	Entries map[string]string `json:"entries"` // Key -> indexed value
+}
+
+// Save writes the definitions and entries to filename, recording the size of the log they reflect
+func (s *SecondaryIndexSet) Save(filename string, logSize int64) error {
+	s.mu.RLock()
+	file := secondaryIndexFile{LogSize: logSize}
+	for _, si := range s.indexes {
+		file.Indexes = append(file.Indexes, secondaryIndexEntry{SecondaryIndexDef: si.def, Entries: si.values})
+	}
+	data, err := json.Marshal(file)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode secondary indexes: %w", err)
+	}
+
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secondary indexes: %w", err)
+	}
+	if err := os.Rename(tempFile, filename); err != nil {
+		_ = os.Remove(tempFile) // Best effort cleanup
+		return fmt.Errorf("failed to replace secondary index file: %w", err)
+	}
+	return nil
+}
+
+// LoadSecondaryIndexSet reads the indexes saved in filename. A missing file yields an
+// empty set; entries saved for a different log size are marked stale.
+func LoadSecondaryIndexSet(filename string, logSize int64) (*SecondaryIndexSet, error) {
+	s := NewSecondaryIndexSet()
+
+	data, err := os.ReadFile(filename) // #nosec G304 - path is built from the store's data directory
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secondary indexes: %w", err)
+	}
+
+	var file secondaryIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode secondary indexes: %w", err)
+	}
+
+	for _, entry := range file.Indexes {
+		si := newSecondaryIndex(entry.SecondaryIndexDef)
+		for key, indexed := range entry.Entries {
+			si.values[key] = indexed
+			if si.keys[indexed] == nil {
+				si.keys[indexed] = make(map[string]struct{})
+			}
+			si.keys[indexed][key] = struct{}{}
+		}
+		s.indexes[entry.Name] = si
+	}
+	s.stale = file.LogSize != logSize
+	return s, nil
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtractField(t *testing.T) {
+	doc := `{"status":"active","age":30,"admin":true,"user":{"name":"alice","tags":["a","b"]},"none":null}`
+
+	tests := []struct {
+		value string
+		field string
+		want  string
+		ok    bool
+	}{
+		{"plain", "", "plain", true},
+		{doc, "status", "active", true},
+		{doc, "age", "30", true},
+		{doc, "admin", "true", true},
+		{doc, "user.name", "alice", true},
+		{doc, "user.tags.1", "b", true},
+		{doc, "user", "", false},
+		{doc, "none", "", false},
+		{doc, "missing", "", false},
+		{"not json", "status", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ExtractField(tt.value, tt.field)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ExtractField(%q) = %q, %v; want %q, %v", tt.field, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSecondaryIndexSet_Maintenance(t *testing.T) {
+	set := NewSecondaryIndexSet()
+	data := map[string]string{
+		"u1": `{"status":"active"}`,
+		"u2": `{"status":"inactive"}`,
+	}
+	if err := set.Create(SecondaryIndexDef{Name: "by_status", Field: "status"}, data); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := set.Create(SecondaryIndexDef{Name: "by_status"}, data); err == nil {
+		t.Error("Expected error for duplicate index name")
+	}
+	if err := set.Create(SecondaryIndexDef{Name: "bad name"}, data); err == nil {
+		t.Error("Expected error for invalid index name")
+	}
+
+	set.Update("u3", `{"status":"active"}`)
+	set.Update("u2", `{"status":"active"}`)
+	set.Remove("u1")
+
+	keys, ok := set.Lookup("status", "active")
+	if !ok || !reflect.DeepEqual(keys, []string{"u2", "u3"}) {
+		t.Errorf("Expected [u2 u3], got %v (ok=%v)", keys, ok)
+	}
+	if keys, _ := set.Lookup("status", "inactive"); len(keys) != 0 {
+		t.Errorf("Expected no inactive keys, got %v", keys)
+	}
+	if _, ok := set.Lookup("", "active"); ok {
+		t.Error("Expected no index on the whole value")
+	}
+
+	if err := set.Drop("by_status"); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if set.Len() != 0 {
+		t.Errorf("Expected no indexes after drop, got %d", set.Len())
+	}
+}
+
+func TestSecondaryIndexSet_SaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "moz.sidx")
+
+	set := NewSecondaryIndexSet()
+	if err := set.Create(SecondaryIndexDef{Name: "by_value"}, map[string]string{"k1": "v", "k2": "v"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := set.Save(filename, 42); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadSecondaryIndexSet(filename, 42)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Stale() {
+		t.Error("Expected loaded indexes to be current")
+	}
+	if keys, ok := loaded.Lookup("", "v"); !ok || !reflect.DeepEqual(keys, []string{"k1", "k2"}) {
+		t.Errorf("Expected [k1 k2] after load, got %v (ok=%v)", keys, ok)
+	}
+
+	// Entries saved for another log size must be rebuilt
+	stale, err := LoadSecondaryIndexSet(filename, 100)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !stale.Stale() {
+		t.Error("Expected indexes saved for a different log size to be stale")
+	}
+
+	empty, err := LoadSecondaryIndexSet(filepath.Join(t.TempDir(), "missing.sidx"), 0)
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("Expected empty set for missing file, got %d indexes (err=%v)", empty.Len(), err)
+	}
+}
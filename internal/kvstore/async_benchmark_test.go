@@ -258,6 +258,7 @@ func TestAsyncPerformanceImprovement(t *testing.T) {
 	const numOperations = 100
 
 	// Measure sync performance
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 	defer store.Compact()
 
@@ -275,6 +276,7 @@ func TestAsyncPerformanceImprovement(t *testing.T) {
 	// Measure async performance
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	config.WALConfig.BufferSize = 1000
 
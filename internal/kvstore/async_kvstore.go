@@ -33,7 +33,8 @@ type AsyncConfig struct {
 	MemTableConfig  MemTableConfig
 	FlushInterval   time.Duration
 	CompactInterval time.Duration
-	EnableAsync     bool // If false, falls back to sync behavior
+	EnableAsync     bool   // If false, falls back to sync behavior
+	DataDir         string // Directory of the base log (default: MOZ_DATA_DIR or the current directory)
 }
 
 // DefaultAsyncConfig returns default async configuration
@@ -90,6 +91,7 @@ func NewAsyncKVStore(config AsyncConfig) (*AsyncKVStore, error) {
 			BinaryFile: "moz.bin",
 			IndexType:  "none",
 			IndexFile:  "moz.idx",
+			DataDir:    config.DataDir,
 		},
 	)
 
@@ -202,9 +204,12 @@ func (as *AsyncKVStore) AsyncDelete(key string) *AsyncResult {
 
 // Get reads from MemTable first, then falls back to disk
 func (as *AsyncKVStore) Get(key string) (string, error) {
-	// 1. Check MemTable first (most recent data)
-	if value, found := as.memTable.Get(key); found {
-		return value, nil
+	// 1. Check MemTable first (most recent data); a deletion marker hides older values on disk
+	if entry, found := as.memTable.Lookup(key); found {
+		if entry.Deleted {
+			return "", fmt.Errorf("key not found: %s", key)
+		}
+		return entry.Value, nil
 	}
 
 	// 2. Fall back to base KVStore (disk)
@@ -279,6 +284,24 @@ func (as *AsyncKVStore) ForceFlush() error {
 	return nil
 }
 
+// GetRange returns the key-value pairs in the inclusive range [start, end].
+// The MemTable is flushed first so that the disk store holds every write.
+func (as *AsyncKVStore) GetRange(start, end string) (map[string]string, error) {
+	if err := as.ForceFlush(); err != nil {
+		return nil, err
+	}
+	return as.KVStore.GetRange(start, end)
+}
+
+// PrefixSearch returns the key-value pairs whose keys start with prefix.
+// The MemTable is flushed first so that the disk store holds every write.
+func (as *AsyncKVStore) PrefixSearch(prefix string) (map[string]string, error) {
+	if err := as.ForceFlush(); err != nil {
+		return nil, err
+	}
+	return as.KVStore.PrefixSearch(prefix)
+}
+
 // GetAsyncStats returns statistics about async operations
 func (as *AsyncKVStore) GetAsyncStats() map[string]interface{} {
 	stats := make(map[string]interface{})
@@ -351,6 +374,11 @@ func (as *AsyncKVStore) Close() error {
 
 		// Wait for all operations to complete
 		as.wg.Wait()
+
+		// Persist indexes maintained by the flushed writes
+		if err := as.KVStore.Close(); err != nil {
+			fmt.Printf("Warning: index save during shutdown failed: %v\n", err)
+		}
 	})
 
 	return nil
@@ -11,6 +11,7 @@ func TestAsyncKVStore_BasicAsyncOperations(t *testing.T) {
 	// Setup
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	config.WALConfig.BufferSize = 1000   // Larger buffer for tests
 	config.MemTableConfig.MaxSize = 1024 // Small size for testing
@@ -60,6 +61,7 @@ func TestAsyncKVStore_SyncFallback(t *testing.T) {
 	// Setup with async disabled
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	config.EnableAsync = false // Disable async
 
@@ -90,6 +92,7 @@ func TestAsyncKVStore_SyncFallback(t *testing.T) {
 func TestAsyncKVStore_ConcurrentOperations(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	config.WALConfig.BufferSize = 1000 // Large buffer for concurrent tests
 
@@ -152,6 +155,7 @@ func TestAsyncKVStore_ConcurrentOperations(t *testing.T) {
 func TestAsyncKVStore_MemTableFlush(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	// Use large limits to keep all entries in memtable (no auto-flush)
 	config.MemTableConfig.MaxSize = 1024 * 1024
@@ -202,6 +206,7 @@ func TestAsyncKVStore_MemTableFlush(t *testing.T) {
 func TestAsyncKVStore_Statistics(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 
 	store, err := NewAsyncKVStore(config)
@@ -269,6 +274,7 @@ func TestAsyncKVStore_Statistics(t *testing.T) {
 func TestAsyncKVStore_ListOperations(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 
 	store, err := NewAsyncKVStore(config)
@@ -323,6 +329,7 @@ func TestAsyncKVStore_ListOperations(t *testing.T) {
 func TestAsyncKVStore_ErrorHandling(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 	config.WALConfig.BufferSize = 2 // Very small buffer
 
@@ -367,6 +374,7 @@ func TestAsyncKVStore_ErrorHandling(t *testing.T) {
 func TestAsyncKVStore_GracefulShutdown(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultAsyncConfig()
+	config.DataDir = tempDir
 	config.WALConfig.DataDir = tempDir
 
 	store, err := NewAsyncKVStore(config)
@@ -0,0 +1,132 @@
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nyasuto/moz/internal/index"
+)
+
+// WriteOp is one write of a batch: a put, or a deletion when Delete is set
+type WriteOp struct {
+	Key    string
+	Value  string
+	Delete bool
+}
+
+// ApplyBatch applies writes in order as one unit. Every key is validated and every
+// deleted key must exist before anything is written; the records are then appended
+// with a single write under the store lock, so readers see all of the batch or none.
+func (kv *KVStore) ApplyBatch(ops []WriteOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	for _, op := range ops {
+		if err := ValidateKey(op.Key); err != nil {
+			return err
+		}
+	}
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return err
+	}
+
+	// Check the whole batch against the state each write leaves behind
+	var records strings.Builder
+	sizes := make([]int, len(ops))
+	for i, op := range ops {
+		var logEntry string
+		if op.Delete {
+			if _, exists := data[op.Key]; !exists {
+				return fmt.Errorf("key not found: %s", op.Key)
+			}
+			delete(data, op.Key)
+			logEntry = fmt.Sprintf("%s\t__DELETED__\n", op.Key)
+		} else {
+			data[op.Key] = op.Value
+			logEntry = fmt.Sprintf("%s\t%s\n", op.Key, op.Value)
+		}
+		sizes[i] = len(logEntry)
+		records.WriteString(logEntry)
+	}
+
+	file, err := os.OpenFile(kv.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			// Log close error but don't override main error
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+
+	if _, err := file.WriteString(records.String()); err != nil {
+		return fmt.Errorf("failed to write to log: %w", err)
+	}
+
+	// Update memory map after successful write
+	for _, op := range ops {
+		value := op.Value
+		if op.Delete {
+			value = "__DELETED__"
+		}
+		if err := kv.updateMemoryMap(op.Key, value); err != nil {
+			return fmt.Errorf("failed to update memory map: %w", err)
+		}
+	}
+
+	// Update index if enabled
+	if kv.indexManager.IsEnabled() {
+		fileInfo, _ := file.Stat()
+		offset := fileInfo.Size() - int64(records.Len())
+		for i, op := range ops {
+			var indexErr error
+			if op.Delete {
+				indexErr = kv.indexManager.Delete(op.Key)
+			} else {
+				indexErr = kv.indexManager.Insert(op.Key, index.IndexEntry{
+					Key:       op.Key,
+					Offset:    offset,
+					Size:      int32(sizes[i]), // #nosec G115 - entries are far smaller than 2GB
+					Timestamp: time.Now().UnixNano(),
+				})
+			}
+			if indexErr != nil {
+				// Log error but don't fail the operation
+				fmt.Printf("Warning: failed to update index: %v\n", indexErr)
+			}
+			offset += int64(sizes[i])
+		}
+	}
+	kv.maintainSecondaryIndexes(func(secondary *index.SecondaryIndexSet) {
+		for _, op := range ops {
+			if op.Delete {
+				secondary.Remove(op.Key)
+			} else {
+				secondary.Update(op.Key, op.Value)
+			}
+		}
+	})
+	kv.maintainFullTextIndex(func(fulltext *index.FullTextIndex) {
+		for _, op := range ops {
+			if op.Delete {
+				fulltext.Remove(op.Key)
+			} else {
+				fulltext.Update(op.Key, op.Value)
+			}
+		}
+	})
+
+	// Increment operation count and check for auto-compaction
+	kv.operationCount += len(ops)
+	kv.triggerAutoCompactionIfNeeded()
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package kvstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKVStore_ApplyBatch(t *testing.T) {
+	dir := t.TempDir()
+	store := newSecondaryTestStore(dir)
+	if err := store.CreateSecondaryIndex("by_value", ""); err != nil {
+		t.Fatalf("CreateSecondaryIndex failed: %v", err)
+	}
+	_ = store.Put("a", "old")
+	_ = store.Put("b", "old")
+
+	err := store.ApplyBatch([]WriteOp{
+		{Key: "a", Value: "new"},
+		{Key: "b", Delete: true},
+		{Key: "c", Value: "new"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch failed: %v", err)
+	}
+
+	// A batch that fails validation writes nothing
+	before := fileSize(store.LogFilePath())
+	err = store.ApplyBatch([]WriteOp{
+		{Key: "d", Value: "new"},
+		{Key: "b", Delete: true},
+	})
+	if err == nil {
+		t.Fatal("Expected an error deleting a missing key")
+	}
+	if fileSize(store.LogFilePath()) != before {
+		t.Error("A failed batch must not write to the log")
+	}
+	if _, err := store.Get("d"); err == nil {
+		t.Error("A failed batch must not apply earlier writes")
+	}
+
+	// Secondary indexes are maintained and the log replays to the same state
+	if keys, ok := store.LookupSecondary("", "new"); !ok || !reflect.DeepEqual(keys, []string{"a", "c"}) {
+		t.Errorf("Expected [a c] from the secondary index, got %v (ok=%v)", keys, ok)
+	}
+	reopened := newSecondaryTestStore(dir)
+	keys, _ := reopened.List()
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys after reopen, got %v", keys)
+	}
+	if value, _ := reopened.Get("a"); value != "new" {
+		t.Errorf("Expected a=new after reopen, got %q", value)
+	}
+}
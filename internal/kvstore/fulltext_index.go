@@ -0,0 +1,178 @@
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyasuto/moz/internal/index"
+)
+
+// fullTextIndexPath returns the file holding the full-text index, next to the primary index file
+func fullTextIndexPath(dataDir string, storageConfig StorageConfig) string {
+	name := storageConfig.IndexFile
+	if name == "" {
+		name = "moz.idx"
+	}
+	return filepath.Join(dataDir, strings.TrimSuffix(name, filepath.Ext(name))+".ftidx")
+}
+
+// refreshFullTextIndex rebuilds a full-text index that was saved for a different log.
+// Callers hold kv.mu.
+func (kv *KVStore) refreshFullTextIndex() error {
+	if kv.fulltext == nil || !kv.fulltext.Stale() {
+		return nil
+	}
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild full-text index: %w", err)
+	}
+	kv.fulltext.Rebuild(data)
+	return nil
+}
+
+// maintainFullTextIndex applies a write to the in-memory full-text index. Like the
+// secondary indexes, it is persisted by Close and Compact.
+// Callers hold kv.mu and have already written the log.
+func (kv *KVStore) maintainFullTextIndex(apply func(*index.FullTextIndex)) {
+	if kv.fulltext == nil {
+		return
+	}
+	if err := kv.refreshFullTextIndex(); err != nil {
+		// The saved file still describes an older log and is rebuilt on next use
+		fmt.Printf("Warning: failed to update full-text index: %v\n", err)
+		return
+	}
+	apply(kv.fulltext)
+	kv.fulltextDirty = true
+}
+
+// flushFullTextIndex saves the full-text index if it changed since it was last saved.
+// Callers hold kv.mu.
+func (kv *KVStore) flushFullTextIndex() error {
+	if kv.fulltext == nil || !kv.fulltextDirty {
+		return nil
+	}
+	if err := kv.refreshFullTextIndex(); err != nil {
+		return err
+	}
+	if err := kv.fulltext.Save(kv.fulltextFile, fileSize(kv.logFile)); err != nil {
+		return err
+	}
+	kv.fulltextDirty = false
+	return nil
+}
+
+// CreateFullTextIndex builds a word index over values, or over a JSON field of values
+// when field is a path such as "description". A store has at most one full-text index.
+func (kv *KVStore) CreateFullTextIndex(field string) error {
+	if err := index.ValidateSecondaryIndexDef(index.SecondaryIndexDef{Name: "fulltext", Field: field}); err != nil {
+		return err
+	}
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if kv.fulltext != nil {
+		return fmt.Errorf("full-text index already exists")
+	}
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return fmt.Errorf("failed to build current state: %w", err)
+	}
+	fulltext := index.NewFullTextIndex(field)
+	fulltext.Rebuild(data)
+	if err := fulltext.Save(kv.fulltextFile, fileSize(kv.logFile)); err != nil {
+		return err
+	}
+	kv.fulltext = fulltext
+	return nil
+}
+
+// DropFullTextIndex removes the full-text index
+func (kv *KVStore) DropFullTextIndex() error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if kv.fulltext == nil {
+		return fmt.Errorf("full-text index does not exist")
+	}
+	if err := os.Remove(kv.fulltextFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove full-text index file: %w", err)
+	}
+	kv.fulltext = nil
+	kv.fulltextDirty = false
+	return nil
+}
+
+// FullTextField returns the JSON field path covered by the full-text index (empty for
+// the whole value). It returns false if there is no full-text index.
+func (kv *KVStore) FullTextField() (string, bool) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	if kv.fulltext == nil {
+		return "", false
+	}
+	return kv.fulltext.Field(), true
+}
+
+// LookupFullText returns the keys whose field contains every word of query, ranked
+// by term frequency. It returns false if the full-text index does not cover the field.
+func (kv *KVStore) LookupFullText(field, query string) ([]index.SearchHit, bool) {
+	// Rebuilding a stale index modifies it, so it needs the write lock
+	kv.mu.RLock()
+	stale := kv.fulltext != nil && kv.fulltext.Stale()
+	kv.mu.RUnlock()
+	if stale {
+		kv.mu.Lock()
+		err := kv.refreshFullTextIndex()
+		kv.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			return nil, false
+		}
+	}
+
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	if kv.fulltext == nil || kv.fulltext.Field() != field {
+		return nil, false
+	}
+	return kv.fulltext.Search(query), true
+}
+
+// Search returns the keys whose values contain every word of query, ranked by term
+// frequency. The full-text index is used when it exists; otherwise every value is scanned.
+func (kv *KVStore) Search(query string) ([]index.SearchHit, error) {
+	field, indexed := kv.FullTextField()
+	if indexed {
+		if hits, ok := kv.LookupFullText(field, query); ok {
+			return hits, nil
+		}
+	}
+
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build current state: %w", err)
+	}
+
+	terms := index.Tokenize(query)
+	hits := []index.SearchHit{}
+	for key, value := range data {
+		text, ok := index.ExtractField(value, field)
+		if !ok {
+			continue
+		}
+		if score, ok := index.MatchScore(terms, text); ok {
+			hits = append(hits, index.SearchHit{Key: key, Score: score})
+		}
+	}
+	index.RankHits(hits)
+	return hits, nil
+}
@@ -0,0 +1,110 @@
+package kvstore
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nyasuto/moz/internal/index"
+)
+
+func TestKVStore_FullTextIndex(t *testing.T) {
+	dir := t.TempDir()
+	store := newSecondaryTestStore(dir)
+
+	_ = store.Put("p1", "red shoes")
+	_ = store.Put("p2", "blue shoes")
+
+	// Search works without an index by scanning the values
+	hits, err := store.Search("shoes")
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("Expected 2 hits without an index, got %v (err=%v)", hits, err)
+	}
+
+	if err := store.CreateFullTextIndex(""); err != nil {
+		t.Fatalf("CreateFullTextIndex failed: %v", err)
+	}
+	if err := store.CreateFullTextIndex(""); err == nil {
+		t.Error("Expected error for a second full-text index")
+	}
+
+	saved, err := os.ReadFile(store.fulltextFile)
+	if err != nil {
+		t.Fatalf("Expected index file after create: %v", err)
+	}
+
+	// Maintained on Put, Delete and range deletes
+	_ = store.Put("p3", "red red shoes")
+	_ = store.Put("p2", "red boots")
+	_ = store.Delete("p1")
+	_ = store.Put("x:1", "red shoes")
+	_ = store.DeletePrefix("x:")
+
+	want := []index.SearchHit{{Key: "p3", Score: 3}}
+	if hits, ok := store.LookupFullText("", "red shoes"); !ok || !reflect.DeepEqual(hits, want) {
+		t.Errorf("Expected %v, got %v (ok=%v)", want, hits, ok)
+	}
+
+	// Writes only update the index in memory
+	if current, _ := os.ReadFile(store.fulltextFile); string(current) != string(saved) {
+		t.Error("Expected writes to leave the index file unchanged")
+	}
+	unclosed := newSecondaryTestStore(dir)
+	if hits, _ := unclosed.LookupFullText("", "red shoes"); !reflect.DeepEqual(hits, want) {
+		t.Errorf("Expected %v from a rebuild without close, got %v", want, hits)
+	}
+
+	// Persisted alongside the primary index on close
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	reopened := newSecondaryTestStore(dir)
+	if reopened.fulltext.Stale() {
+		t.Error("Expected the closed store's index file to be current")
+	}
+	if hits, err := reopened.Search("red shoes"); err != nil || !reflect.DeepEqual(hits, want) {
+		t.Errorf("Expected %v after reopen, got %v (err=%v)", want, hits, err)
+	}
+
+	// Writes the index file did not see are picked up by a rebuild
+	file, err := os.OpenFile(store.LogFilePath(), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	_, _ = file.WriteString("p4\tred shoes\n")
+	_ = file.Close()
+
+	stale := newSecondaryTestStore(dir)
+	want = []index.SearchHit{{Key: "p3", Score: 3}, {Key: "p4", Score: 2}}
+	if hits, _ := stale.LookupFullText("", "red shoes"); !reflect.DeepEqual(hits, want) {
+		t.Errorf("Expected %v after rebuild, got %v", want, hits)
+	}
+
+	// Concurrent lookups on a stale store rebuild it once under the write lock
+	racing := newSecondaryTestStore(dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if hits, _ := racing.LookupFullText("", "red shoes"); !reflect.DeepEqual(hits, want) {
+				t.Errorf("Expected %v from a concurrent lookup, got %v", want, hits)
+			}
+		}()
+	}
+	wg.Wait()
+	if racing.fulltext.Stale() {
+		t.Error("Expected concurrent lookups to leave the index rebuilt")
+	}
+
+	if err := stale.DropFullTextIndex(); err != nil {
+		t.Fatalf("DropFullTextIndex failed: %v", err)
+	}
+	if _, ok := stale.LookupFullText("", "red"); ok {
+		t.Error("Expected no index after drop")
+	}
+	if _, err := os.Stat(stale.fulltextFile); !os.IsNotExist(err) {
+		t.Errorf("Expected index file to be removed, got %v", err)
+	}
+}
@@ -62,6 +62,34 @@ func TestKVStore_IndexIntegration_BTree(t *testing.T) {
 	testIndexedOperations(t, store, "btree")
 }
 
+func TestKVStore_IndexIntegration_Radix(t *testing.T) {
+	// Create KVStore with radix index
+	compactionConfig := CompactionConfig{
+		Enabled:         false,
+		MaxFileSize:     1024 * 1024,
+		MaxOperations:   1000,
+		CompactionRatio: 0.5,
+	}
+
+	storageConfig := StorageConfig{
+		Format:     "text",
+		TextFile:   "test_radix_index.log",
+		BinaryFile: "test_radix_index.bin",
+		IndexType:  "radix",
+		IndexFile:  "test_radix_index.idx",
+	}
+
+	store := NewWithConfig(compactionConfig, storageConfig)
+	defer func() {
+		store.indexManager.Close()
+		// Clean up test files
+		removeTestFiles("test_radix_index.log", "test_radix_index.bin", "test_radix_index.idx")
+	}()
+
+	// Test basic operations with indexing
+	testIndexedOperations(t, store, "radix")
+}
+
 func TestKVStore_IndexIntegration_None(t *testing.T) {
 	// Create KVStore with no index (default behavior)
 	compactionConfig := CompactionConfig{
@@ -236,6 +264,7 @@ func TestKVStore_RangeQueries_Performance(t *testing.T) {
 		{"NoIndex", "none"},
 		{"HashIndex", "hash"},
 		{"BTreeIndex", "btree"},
+		{"RadixIndex", "radix"},
 	}
 
 	for _, config := range testConfigs {
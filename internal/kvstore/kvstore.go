@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,8 +35,9 @@ type StorageConfig struct {
 	Format     string // "text" or "binary"
 	TextFile   string // Text format log file
 	BinaryFile string // Binary format log file
-	IndexType  string // "hash", "btree", or "none"
+	IndexType  string // "hash", "btree", "radix", or "none"
 	IndexFile  string // Index persistence file
+	DataDir    string // Directory holding the files (default: MOZ_DATA_DIR or the current directory)
 }
 
 type KVStore struct {
@@ -57,7 +59,13 @@ type KVStore struct {
 	storageConfig StorageConfig
 
 	// Index fields
-	indexManager *index.IndexManager
+	indexManager   *index.IndexManager
+	secondary      *index.SecondaryIndexSet // User-declared indexes over values
+	secondaryFile  string
+	secondaryDirty bool                 // In-memory secondary indexes have changes not yet saved
+	fulltext       *index.FullTextIndex // Word index over values; nil until created
+	fulltextFile   string
+	fulltextDirty  bool // In-memory full-text index has changes not yet saved
 
 	// Memory optimization fields
 	memoryOptimizer *MemoryOptimizer
@@ -83,6 +91,9 @@ func NewWithConfig(compactionConfig CompactionConfig, storageConfig StorageConfi
 	if envDir := os.Getenv("MOZ_DATA_DIR"); envDir != "" {
 		dataDir = envDir
 	}
+	if storageConfig.DataDir != "" {
+		dataDir = storageConfig.DataDir
+	}
 
 	// Only create directory if it's not the current directory
 	if dataDir != "." {
@@ -105,6 +116,8 @@ func NewWithConfig(compactionConfig CompactionConfig, storageConfig StorageConfi
 		indexType = index.IndexTypeHash
 	case "btree":
 		indexType = index.IndexTypeBTree
+	case "radix":
+		indexType = index.IndexTypeRadix
 	default:
 		indexType = index.IndexTypeNone
 	}
@@ -117,6 +130,20 @@ func NewWithConfig(compactionConfig CompactionConfig, storageConfig StorageConfi
 		panic(fmt.Sprintf("Failed to create index manager: %v", err))
 	}
 
+	secondaryFile := secondaryIndexPath(dataDir, storageConfig)
+	secondary, err := index.LoadSecondaryIndexSet(secondaryFile, fileSize(logFile))
+	if err != nil {
+		fmt.Printf("Warning: ignoring secondary indexes: %v\n", err)
+		secondary = index.NewSecondaryIndexSet()
+	}
+
+	fulltextFile := fullTextIndexPath(dataDir, storageConfig)
+	fulltext, err := index.LoadFullTextIndex(fulltextFile, fileSize(logFile))
+	if err != nil {
+		fmt.Printf("Warning: ignoring full-text index: %v\n", err)
+		fulltext = nil
+	}
+
 	return &KVStore{
 		dataDir:          dataDir,
 		logFile:          logFile,
@@ -128,6 +155,10 @@ func NewWithConfig(compactionConfig CompactionConfig, storageConfig StorageConfi
 		lastCompaction:   0,
 		isCompacting:     false,
 		indexManager:     indexManager,
+		secondary:        secondary,
+		secondaryFile:    secondaryFile,
+		fulltext:         fulltext,
+		fulltextFile:     fulltextFile,
 		memoryOptimizer:  memoryOptimizer,
 	}
 }
@@ -182,6 +213,12 @@ func (kv *KVStore) Put(key, value string) error {
 			fmt.Printf("Warning: failed to update index: %v\n", err)
 		}
 	}
+	kv.maintainSecondaryIndexes(func(secondary *index.SecondaryIndexSet) {
+		secondary.Update(key, value)
+	})
+	kv.maintainFullTextIndex(func(fulltext *index.FullTextIndex) {
+		fulltext.Update(key, value)
+	})
 
 	// Increment operation count and check for auto-compaction
 	kv.operationCount++
@@ -262,6 +299,111 @@ func (kv *KVStore) Delete(key string) error {
 			fmt.Printf("Warning: failed to update index for deletion: %v\n", err)
 		}
 	}
+	kv.maintainSecondaryIndexes(func(secondary *index.SecondaryIndexSet) {
+		secondary.Remove(key)
+	})
+	kv.maintainFullTextIndex(func(fulltext *index.FullTextIndex) {
+		fulltext.Remove(key)
+	})
+
+	// Increment operation count and check for auto-compaction
+	kv.operationCount++
+	kv.triggerAutoCompactionIfNeeded()
+
+	return nil
+}
+
+// DeleteRange deletes every key in the inclusive range [start, end] with a single log record
+func (kv *KVStore) DeleteRange(start, end string) error {
+	if start == "" || end == "" {
+		return fmt.Errorf("range bounds cannot be empty")
+	}
+	if start > end {
+		return fmt.Errorf("invalid range: start %q is greater than end %q", start, end)
+	}
+	if err := ValidateKey(start); err != nil {
+		return err
+	}
+	if err := ValidateKey(end); err != nil {
+		return err
+	}
+
+	logEntry := fmt.Sprintf("%s\t%s\t%s\n", start, RangeDeletedMarker, end)
+	return kv.appendRangeDeletion(logEntry, func(key string) bool {
+		return key >= start && key <= end
+	})
+}
+
+// DeletePrefix deletes every key with the given prefix with a single log record
+func (kv *KVStore) DeletePrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("prefix cannot be empty")
+	}
+	if err := ValidateKey(prefix); err != nil {
+		return err
+	}
+
+	logEntry := fmt.Sprintf("%s\t%s\n", prefix, PrefixDeletedMarker)
+	return kv.appendRangeDeletion(logEntry, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// appendRangeDeletion writes a range deletion record and removes the covered keys
+// from the memory map and index
+func (kv *KVStore) appendRangeDeletion(logEntry string, covers func(string) bool) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if err := kv.loadMemoryMap(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(kv.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			// Log close error but don't override main error
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+
+	if _, err := file.WriteString(logEntry); err != nil {
+		return fmt.Errorf("failed to write to log: %w", err)
+	}
+
+	// Update memory map after successful write
+	var deleted []string
+	kv.mapMu.Lock()
+	for key := range kv.memoryMap {
+		if covers(key) {
+			deleted = append(deleted, key)
+			delete(kv.memoryMap, key)
+		}
+	}
+	kv.mapMu.Unlock()
+
+	// Update index if enabled
+	if kv.indexManager.IsEnabled() {
+		for _, key := range deleted {
+			if err := kv.indexManager.Delete(key); err != nil {
+				// Log error but don't fail the operation
+				fmt.Printf("Warning: failed to update index for deletion: %v\n", err)
+			}
+		}
+	}
+	kv.maintainSecondaryIndexes(func(secondary *index.SecondaryIndexSet) {
+		for _, key := range deleted {
+			secondary.Remove(key)
+		}
+	})
+	kv.maintainFullTextIndex(func(fulltext *index.FullTextIndex) {
+		for _, key := range deleted {
+			fulltext.Remove(key)
+		}
+	})
 
 	// Increment operation count and check for auto-compaction
 	kv.operationCount++
@@ -270,6 +412,11 @@ func (kv *KVStore) Delete(key string) error {
 	return nil
 }
 
+// LogFilePath returns the path of the log file backing the store
+func (kv *KVStore) LogFilePath() string {
+	return kv.logFile
+}
+
 func (kv *KVStore) List() ([]string, error) {
 	kv.mu.RLock()
 	defer kv.mu.RUnlock()
@@ -334,9 +481,31 @@ func (kv *KVStore) Compact() error {
 	kv.isLoaded = false
 	kv.mapMu.Unlock()
 
+	// The entries are unchanged; record the new log size
+	kv.secondaryDirty = true
+	if err := kv.flushSecondaryIndexes(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	kv.fulltextDirty = true
+	if err := kv.flushFullTextIndex(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
 	return nil
 }
 
+// Close saves index changes made since they were last persisted. Writes only update
+// the indexes in memory, so a store that is not closed rebuilds them on next open.
+func (kv *KVStore) Close() error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if err := kv.flushSecondaryIndexes(); err != nil {
+		return err
+	}
+	return kv.flushFullTextIndex()
+}
+
 // loadMemoryMap loads the current state from disk into memory
 func (kv *KVStore) loadMemoryMap() error {
 	kv.mapMu.Lock()
@@ -361,9 +530,31 @@ func (kv *KVStore) loadMemoryMap() error {
 
 	kv.memoryMap = data
 	kv.isLoaded = true
+
+	// The index is not persisted; rebuild it from the loaded data
+	if kv.indexManager.IsEnabled() {
+		if err := kv.indexManager.Rebuild(indexEntriesFor(data)); err != nil {
+			fmt.Printf("Warning: failed to rebuild index: %v\n", err)
+		}
+	}
 	return nil
 }
 
+// indexEntriesFor converts data to index entries. The log offsets are not known,
+// so entry sizes are approximated.
+func indexEntriesFor(data map[string]string) map[string]index.IndexEntry {
+	now := time.Now().UnixNano()
+	entries := make(map[string]index.IndexEntry, len(data))
+	for key, value := range data {
+		entries[key] = index.IndexEntry{
+			Key:       key,
+			Size:      int32(len(key) + len(value) + 2), // #nosec G115 - entries are far smaller than 2GB
+			Timestamp: now,
+		}
+	}
+	return entries
+}
+
 // getMemoryMap returns a copy of the current memory map
 func (kv *KVStore) getMemoryMap() (map[string]string, error) {
 	if err := kv.loadMemoryMap(); err != nil {
@@ -711,6 +902,11 @@ func (kv *KVStore) GetRange(start, end string) (map[string]string, error) {
 	kv.mu.RLock()
 	defer kv.mu.RUnlock()
 
+	// Loading the data also populates the index
+	if err := kv.loadMemoryMap(); err != nil {
+		return nil, err
+	}
+
 	result := make(map[string]string)
 
 	// If index is enabled, use it for efficient range queries
@@ -756,6 +952,11 @@ func (kv *KVStore) ListSorted() ([]string, error) {
 	kv.mu.RLock()
 	defer kv.mu.RUnlock()
 
+	// Loading the data also populates the index
+	if err := kv.loadMemoryMap(); err != nil {
+		return nil, err
+	}
+
 	// If index is enabled, use it for efficient sorted access
 	if kv.indexManager.IsEnabled() {
 		keys := kv.indexManager.Keys()
@@ -784,6 +985,11 @@ func (kv *KVStore) PrefixSearch(prefix string) (map[string]string, error) {
 	kv.mu.RLock()
 	defer kv.mu.RUnlock()
 
+	// Loading the data also populates the index
+	if err := kv.loadMemoryMap(); err != nil {
+		return nil, err
+	}
+
 	result := make(map[string]string)
 
 	// If index is enabled, use it for efficient prefix search
@@ -837,13 +1043,17 @@ func (kv *KVStore) GetIndexStats() (map[string]interface{}, error) {
 		stats["size"] = 0
 		stats["memory_usage"] = 0
 	}
+	stats["secondary"] = kv.secondary.Stats()
+	if kv.fulltext != nil {
+		stats["fulltext"] = kv.fulltext.Stats()
+	}
 
 	return stats, nil
 }
 
-// RebuildIndex rebuilds the index from the current data
+// RebuildIndex rebuilds the primary index, the secondary indexes and the full-text index from the current data
 func (kv *KVStore) RebuildIndex() error {
-	if !kv.indexManager.IsEnabled() {
+	if !kv.indexManager.IsEnabled() && kv.secondary.Len() == 0 && kv.fulltext == nil {
 		return fmt.Errorf("index is not enabled")
 	}
 
@@ -856,22 +1066,26 @@ func (kv *KVStore) RebuildIndex() error {
 		return fmt.Errorf("failed to build current state: %w", err)
 	}
 
-	// Convert to index entries
-	indexEntries := make(map[string]index.IndexEntry)
-	for key, value := range data {
-		// For rebuilt index, we don't have exact offset/size info
-		// These will be approximated
-		indexEntries[key] = index.IndexEntry{
-			Key:       key,
-			Offset:    0,                                // Will be recalculated if needed
-			Size:      int32(len(key) + len(value) + 2), // Approximate
-			Timestamp: time.Now().UnixNano(),
-			Deleted:   false,
+	if kv.secondary.Len() > 0 {
+		kv.secondary.Rebuild(data)
+		kv.secondaryDirty = true
+		if err := kv.flushSecondaryIndexes(); err != nil {
+			return err
+		}
+	}
+	if kv.fulltext != nil {
+		kv.fulltext.Rebuild(data)
+		kv.fulltextDirty = true
+		if err := kv.flushFullTextIndex(); err != nil {
+			return err
 		}
 	}
+	if !kv.indexManager.IsEnabled() {
+		return nil
+	}
 
 	// Rebuild the index
-	return kv.indexManager.Rebuild(indexEntries)
+	return kv.indexManager.Rebuild(indexEntriesFor(data))
 }
 
 // ValidateIndex validates the integrity of the index
@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -101,6 +102,45 @@ func TestMemoryMapDeletion(t *testing.T) {
 	}
 }
 
+func TestMemoryMapRangeDeletion(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Setenv("MOZ_DATA_DIR", tempDir)
+
+	kv1 := New()
+	for _, key := range []string{"tenant1:a", "tenant1:b", "tenant2:a", "user:1", "user:5", "user:9"} {
+		kv1.Put(key, "value-"+key)
+	}
+
+	if err := kv1.DeletePrefix("tenant1:"); err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if err := kv1.DeleteRange("user:1", "user:5"); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+	if err := kv1.DeleteRange("b", "a"); err == nil {
+		t.Error("Expected error for inverted range")
+	}
+	if err := kv1.DeletePrefix(""); err == nil {
+		t.Error("Expected error for empty prefix")
+	}
+
+	// Re-adding a key after the range deletion must survive a reload
+	kv1.Put("tenant1:c", "value-tenant1:c")
+
+	// The deletions must be replayed from the log by a new instance
+	kv2 := New()
+	expected := []string{"tenant1:c", "tenant2:a", "user:9"}
+	for _, kv := range []*KVStore{kv1, kv2} {
+		keys, err := kv.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Errorf("Expected keys %v, got %v", expected, keys)
+		}
+	}
+}
+
 func TestMemoryMapPersistence(t *testing.T) {
 	tempDir := t.TempDir()
 	os.Setenv("MOZ_DATA_DIR", tempDir)
@@ -9,6 +9,7 @@ import (
 
 // TestMemoryPoolIntegration tests the memory pool integration
 func TestMemoryPoolIntegration(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 
 	// Test that memory optimizer is enabled
@@ -45,6 +46,7 @@ func TestMemoryLeakDetection(t *testing.T) {
 		t.Skip("Skipping memory leak test in short mode")
 	}
 
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 
 	// Baseline memory usage
@@ -130,6 +132,7 @@ func TestLongRunningOperations(t *testing.T) {
 		t.Skip("Skipping long-running test in short mode")
 	}
 
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 
 	// Test duration and operation parameters
@@ -199,6 +202,7 @@ func TestLongRunningOperations(t *testing.T) {
 
 // TestDetailedMemoryStats tests the detailed memory statistics functionality
 func TestDetailedMemoryStats(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 
 	// Perform some operations
@@ -260,6 +264,7 @@ func TestDetailedMemoryStats(t *testing.T) {
 
 // TestGCOptimization tests garbage collection optimization features
 func TestGCOptimization(t *testing.T) {
+	t.Setenv("MOZ_DATA_DIR", t.TempDir())
 	store := New()
 
 	// Get initial GC stats
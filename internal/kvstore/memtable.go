@@ -113,6 +113,20 @@ func (mt *MemTable) Get(key string) (string, bool) {
 	return entry.Value, true
 }
 
+// Lookup returns a copy of the entry stored for a key, including deletion markers
+func (mt *MemTable) Lookup(key string) (*MemTableEntry, bool) {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+
+	entry, exists := mt.data[key]
+	if !exists {
+		return nil, false
+	}
+
+	entryCopy := *entry
+	return &entryCopy, true
+}
+
 // Delete marks a key as deleted in the MemTable
 func (mt *MemTable) Delete(key string, lsn uint64) {
 	mt.mu.Lock()
@@ -0,0 +1,279 @@
+package kvstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MigrationCheckpointKey is the reserved key in which a migration target records
+// the last key it has copied, so an interrupted migration can resume
+const MigrationCheckpointKey = "__MIGRATION_CHECKPOINT__"
+
+// MigrationStore is the set of operations a migration needs from its source and target
+type MigrationStore interface {
+	Put(key, value string) error
+	Get(key string) (string, error)
+	Delete(key string) error
+	List() ([]string, error)
+}
+
+// MigrationOptions configures a migration
+type MigrationOptions struct {
+	BatchSize int                     // Keys copied between checkpoints
+	Progress  func(MigrationProgress) // Called after each batch and verification pass (optional)
+}
+
+// DefaultMigrationOptions returns default migration options
+func DefaultMigrationOptions() MigrationOptions {
+	return MigrationOptions{
+		BatchSize: 1000,
+	}
+}
+
+// MigrationProgress reports how far a migration has got
+type MigrationProgress struct {
+	Phase string // "copy" or "verify"
+	Done  int
+	Total int
+}
+
+// MigrationResult summarizes a completed migration
+type MigrationResult struct {
+	TotalKeys   int           // Keys in the source store
+	Copied      int           // Keys copied during this run
+	Repaired    int           // Keys fixed up by verification
+	ResumedFrom string        // Checkpoint the copy resumed after ("" for a fresh start)
+	Duration    time.Duration // Total migration time
+}
+
+// Migrate copies every key from src to dst in key order, checkpointing in dst after
+// each batch. A migration interrupted part way resumes after its checkpoint; a fresh
+// migration requires an empty target. Once copied, every key/value pair is verified
+// (keys written to the source since they were copied are repaired), and the checkpoint
+// is removed only after the target matches the source exactly.
+func Migrate(src, dst MigrationStore, opts MigrationOptions) (MigrationResult, error) {
+	start := time.Now()
+	var result MigrationResult
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultMigrationOptions().BatchSize
+	}
+
+	keys, err := migrationKeys(src)
+	if err != nil {
+		return result, fmt.Errorf("failed to list source keys: %w", err)
+	}
+	result.TotalKeys = len(keys)
+
+	checkpoint, err := dst.Get(MigrationCheckpointKey)
+	if err == nil {
+		result.ResumedFrom = checkpoint
+	} else {
+		existing, err := migrationKeys(dst)
+		if err != nil {
+			return result, fmt.Errorf("failed to list target keys: %w", err)
+		}
+		if len(existing) > 0 {
+			// A run that finished copying but stopped before retiring the source
+			if VerifyMigration(src, dst) == nil {
+				result.Duration = time.Since(start)
+				return result, nil
+			}
+			return result, fmt.Errorf("target store already contains %d keys and has no migration checkpoint", len(existing))
+		}
+	}
+
+	// Copy phase: keys up to the checkpoint were copied by an earlier run
+	first := 0
+	if result.ResumedFrom != "" {
+		first = sort.SearchStrings(keys, result.ResumedFrom)
+		if first < len(keys) && keys[first] == result.ResumedFrom {
+			first++
+		}
+	}
+	for i := first; i < len(keys); i += opts.BatchSize {
+		end := i + opts.BatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		values, err := migrationValues(src, keys[i:end])
+		if err != nil {
+			return result, fmt.Errorf("failed to read source keys: %w", err)
+		}
+		for _, key := range keys[i:end] {
+			value, ok := values[key]
+			if !ok {
+				// Deleted since it was listed
+				continue
+			}
+			if err := dst.Put(key, value); err != nil {
+				return result, fmt.Errorf("failed to copy key %s: %w", key, err)
+			}
+			result.Copied++
+		}
+
+		if err := dst.Put(MigrationCheckpointKey, keys[end-1]); err != nil {
+			return result, fmt.Errorf("failed to write migration checkpoint: %w", err)
+		}
+		reportMigrationProgress(opts, "copy", end, len(keys))
+	}
+	if first >= len(keys) {
+		reportMigrationProgress(opts, "copy", len(keys), len(keys))
+	}
+
+	// Verify phase: repair anything that changed, then require an exact match
+	repaired, err := verifyMigration(src, dst, true, opts)
+	if err != nil {
+		return result, err
+	}
+	result.Repaired = repaired
+	if repaired > 0 {
+		if _, err := verifyMigration(src, dst, false, opts); err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := dst.Get(MigrationCheckpointKey); err == nil {
+		if err := dst.Delete(MigrationCheckpointKey); err != nil {
+			return result, fmt.Errorf("failed to remove migration checkpoint: %w", err)
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// VerifyMigration checks that dst holds exactly the key/value pairs of src
+func VerifyMigration(src, dst MigrationStore) error {
+	_, err := verifyMigration(src, dst, false, MigrationOptions{})
+	return err
+}
+
+// verifyMigration compares every key in both stores. With repair set, mismatches are
+// fixed and counted; otherwise the first mismatches are reported as an error.
+func verifyMigration(src, dst MigrationStore, repair bool, opts MigrationOptions) (int, error) {
+	srcKeys, err := migrationKeys(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list source keys: %w", err)
+	}
+	dstKeys, err := migrationKeys(dst)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list target keys: %w", err)
+	}
+	srcValues, err := migrationValues(src, srcKeys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source keys: %w", err)
+	}
+	dstValues, err := migrationValues(dst, dstKeys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read target keys: %w", err)
+	}
+
+	var mismatches []string
+	fixed := 0
+	mismatch := func(key, reason string, fix func() error) error {
+		if !repair {
+			mismatches = append(mismatches, fmt.Sprintf("%s (%s)", key, reason))
+			return nil
+		}
+		if err := fix(); err != nil {
+			return fmt.Errorf("failed to repair key %s: %w", key, err)
+		}
+		fixed++
+		return nil
+	}
+
+	for i, key := range srcKeys {
+		value, ok := srcValues[key]
+		if !ok {
+			continue
+		}
+		copied, ok := dstValues[key]
+		var err error
+		switch {
+		case !ok:
+			err = mismatch(key, "missing", func() error { return dst.Put(key, value) })
+		case copied != value:
+			err = mismatch(key, "value differs", func() error { return dst.Put(key, value) })
+		}
+		if err != nil {
+			return fixed, err
+		}
+		if (i+1)%opts.batchSize() == 0 {
+			reportMigrationProgress(opts, "verify", i+1, len(srcKeys))
+		}
+	}
+
+	// Keys deleted from the source after they were copied
+	for _, key := range dstKeys {
+		if _, ok := srcValues[key]; ok {
+			continue
+		}
+		if _, ok := dstValues[key]; !ok {
+			continue
+		}
+		if err := mismatch(key, "not in source", func() error { return dst.Delete(key) }); err != nil {
+			return fixed, err
+		}
+	}
+	reportMigrationProgress(opts, "verify", len(srcKeys), len(srcKeys))
+
+	if len(mismatches) > 0 {
+		shown := mismatches
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		return fixed, fmt.Errorf("migration verification failed: %d mismatched keys, e.g. %v", len(mismatches), shown)
+	}
+	return fixed, nil
+}
+
+// migrationKeys lists a store's keys in order, without the checkpoint key
+func migrationKeys(store MigrationStore) ([]string, error) {
+	keys, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != MigrationCheckpointKey {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// migrationValues reads the current values of keys. Log stores are read from a single
+// snapshot of their memory map, since each Get copies the whole map.
+func migrationValues(store MigrationStore, keys []string) (map[string]string, error) {
+	if kv, ok := store.(*KVStore); ok {
+		kv.mu.RLock()
+		defer kv.mu.RUnlock()
+		return kv.buildCurrentState()
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, err := store.Get(key); err == nil {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (opts MigrationOptions) batchSize() int {
+	if opts.BatchSize <= 0 {
+		return DefaultMigrationOptions().BatchSize
+	}
+	return opts.BatchSize
+}
+
+func reportMigrationProgress(opts MigrationOptions, phase string, done, total int) {
+	if opts.Progress != nil {
+		opts.Progress(MigrationProgress{Phase: phase, Done: done, Total: total})
+	}
+}
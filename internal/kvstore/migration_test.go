@@ -0,0 +1,108 @@
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingStore fails every Put after the first limit calls, simulating a crash
+type failingStore struct {
+	*KVStore
+	limit int
+	puts  int
+}
+
+func (f *failingStore) Put(key, value string) error {
+	if f.puts >= f.limit {
+		return fmt.Errorf("simulated crash")
+	}
+	f.puts++
+	return f.KVStore.Put(key, value)
+}
+
+func newMigrationTestStore(t *testing.T, file string) *KVStore {
+	t.Helper()
+	return NewWithConfig(CompactionConfig{Enabled: false}, StorageConfig{
+		Format:     "text",
+		TextFile:   file,
+		BinaryFile: "moz.bin",
+		IndexType:  "none",
+		IndexFile:  "moz.idx",
+	})
+}
+
+func TestMigrateResumesFromCheckpoint(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Setenv("MOZ_DATA_DIR", tempDir)
+
+	src := newMigrationTestStore(t, "src.log")
+	for i := 0; i < 25; i++ {
+		src.Put(fmt.Sprintf("key%02d", i), fmt.Sprintf("value%d", i))
+	}
+
+	// The first run is interrupted part way through the copy
+	opts := MigrationOptions{BatchSize: 10}
+	dst := &failingStore{KVStore: newMigrationTestStore(t, "dst.log"), limit: 15}
+	if _, err := Migrate(src, dst, opts); err == nil {
+		t.Fatal("Expected interrupted migration to fail")
+	}
+
+	// Changes to the source while the migration is stopped
+	src.Put("key03", "updated")
+	src.Delete("key05")
+
+	// A new instance resumes after the checkpoint written by the first batch
+	dst2 := newMigrationTestStore(t, "dst.log")
+	var phases []string
+	opts.Progress = func(p MigrationProgress) { phases = append(phases, p.Phase) }
+	result, err := Migrate(src, dst2, opts)
+	if err != nil {
+		t.Fatalf("Resumed migration failed: %v", err)
+	}
+	if result.ResumedFrom != "key09" {
+		t.Errorf("Expected resume after key09, got %q", result.ResumedFrom)
+	}
+	if result.Copied != 15 {
+		t.Errorf("Expected 15 keys copied on resume, got %d", result.Copied)
+	}
+	if result.Repaired != 2 {
+		t.Errorf("Expected 2 repaired keys, got %d", result.Repaired)
+	}
+	if len(phases) == 0 || phases[len(phases)-1] != "verify" {
+		t.Errorf("Expected progress to end with verification, got %v", phases)
+	}
+
+	if err := VerifyMigration(src, dst2); err != nil {
+		t.Errorf("Target does not match source: %v", err)
+	}
+	if _, err := dst2.Get(MigrationCheckpointKey); err == nil {
+		t.Error("Expected checkpoint to be removed after migration")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "dst.log")); err != nil {
+		t.Errorf("Expected target log file: %v", err)
+	}
+
+	// A finished migration is recognised rather than refused
+	if _, err := Migrate(src, dst2, opts); err != nil {
+		t.Errorf("Re-running a finished migration failed: %v", err)
+	}
+}
+
+func TestMigrateRejectsNonEmptyTarget(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Setenv("MOZ_DATA_DIR", tempDir)
+
+	src := newMigrationTestStore(t, "src.log")
+	src.Put("a", "1")
+	dst := newMigrationTestStore(t, "dst.log")
+	dst.Put("b", "2")
+
+	if _, err := Migrate(src, dst, DefaultMigrationOptions()); err == nil {
+		t.Error("Expected error when migrating into a non-empty target")
+	}
+	if err := VerifyMigration(src, dst); err == nil {
+		t.Error("Expected verification to fail for differing stores")
+	}
+}
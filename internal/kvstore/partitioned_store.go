@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -36,11 +37,17 @@ func DefaultPartitionConfig() PartitionConfig {
 
 // Partition represents a single partition
 type Partition struct {
-	id          int
-	store       *KVStore
-	batchBuffer []*BatchEntry
-	bufferMutex sync.Mutex
-	lastFlush   time.Time
+	id           int
+	store        *KVStore
+	batchBuffer  []*BatchEntry
+	bufferMutex  sync.Mutex
+	lastFlush    time.Time
+	nextSequence uint64 // Sequence of the last buffered write; guarded by bufferMutex
+
+	// Flushes hold flushMutex while writing to the store, so the store always holds
+	// exactly the writes up to flushedSequence
+	flushMutex      sync.Mutex
+	flushedSequence uint64
 }
 
 // BatchEntry represents an entry in the batch buffer
@@ -49,6 +56,7 @@ type BatchEntry struct {
 	Value     string
 	Operation string // "PUT" or "DELETE"
 	Timestamp time.Time
+	Sequence  uint64 // Order of the write within its partition
 }
 
 // PartitionedKVStore implements high-performance partitioned key-value storage
@@ -170,6 +178,8 @@ func (pks *PartitionedKVStore) Put(key, value string) error {
 
 	// Add to partition's batch buffer
 	partition.bufferMutex.Lock()
+	partition.nextSequence++
+	entry.Sequence = partition.nextSequence
 	partition.batchBuffer = append(partition.batchBuffer, entry)
 	shouldFlush := len(partition.batchBuffer) >= pks.config.BatchSize
 	partition.bufferMutex.Unlock()
@@ -226,6 +236,8 @@ func (pks *PartitionedKVStore) Delete(key string) error {
 
 	// Add to partition's batch buffer
 	partition.bufferMutex.Lock()
+	partition.nextSequence++
+	entry.Sequence = partition.nextSequence
 	partition.batchBuffer = append(partition.batchBuffer, entry)
 	shouldFlush := len(partition.batchBuffer) >= pks.config.BatchSize
 	partition.bufferMutex.Unlock()
@@ -269,6 +281,10 @@ func (pks *PartitionedKVStore) List() ([]string, error) {
 
 // flushPartition flushes a single partition's batch buffer
 func (pks *PartitionedKVStore) flushPartition(partition *Partition) error {
+	// Serialize flushes so entries reach the store in sequence order
+	partition.flushMutex.Lock()
+	defer partition.flushMutex.Unlock()
+
 	partition.bufferMutex.Lock()
 	if len(partition.batchBuffer) == 0 {
 		partition.bufferMutex.Unlock()
@@ -299,6 +315,7 @@ func (pks *PartitionedKVStore) flushPartition(partition *Partition) error {
 			pks.entryPool.Put(entry)
 			return fmt.Errorf("batch operation failed on partition %d: %w", partition.id, err)
 		}
+		partition.flushedSequence = entry.Sequence
 
 		// Return entry to pool
 		pks.entryPool.Put(entry)
@@ -357,7 +374,15 @@ func (pks *PartitionedKVStore) Close() error {
 	pks.flushWg.Wait()
 
 	// Final flush
-	return pks.FlushAll()
+	if err := pks.FlushAll(); err != nil {
+		return err
+	}
+	for _, partition := range pks.partitions {
+		if err := partition.store.Close(); err != nil {
+			return fmt.Errorf("failed to close partition %d: %w", partition.id, err)
+		}
+	}
+	return nil
 }
 
 // Compact performs compaction on all partitions
@@ -476,6 +501,182 @@ func (pks *PartitionedKVStore) GetPartitionedStats() (map[string]interface{}, er
 	return stats, nil
 }
 
+// bufferedEntries returns a copy of a partition's unflushed writes, oldest first
+func (p *Partition) bufferedEntries() []BatchEntry {
+	p.bufferMutex.Lock()
+	defer p.bufferMutex.Unlock()
+
+	entries := make([]BatchEntry, len(p.batchBuffer))
+	for i, entry := range p.batchBuffer {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// readFlushed reads the partition store along with the sequence of the last write it holds
+func (p *Partition) readFlushed(read func(*KVStore) (map[string]string, error)) (map[string]string, uint64, error) {
+	p.flushMutex.Lock()
+	defer p.flushMutex.Unlock()
+
+	data, err := read(p.store)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read partition %d: %w", p.id, err)
+	}
+	return data, p.flushedSequence, nil
+}
+
+// replayBuffered applies buffered writes newer than flushed to data. Writes at or
+// below flushed are already in data, possibly overwritten by a newer flushed write.
+func replayBuffered(data map[string]string, buffered []BatchEntry, flushed uint64, match func(string) bool) {
+	for _, entry := range buffered {
+		if entry.Sequence <= flushed || !match(entry.Key) {
+			continue
+		}
+		switch entry.Operation {
+		case "PUT":
+			data[entry.Key] = entry.Value
+		case "DELETE":
+			delete(data, entry.Key)
+		}
+	}
+}
+
+// scan reads the pairs of one partition whose keys satisfy match. read loads the
+// flushed pairs; unflushed writes in the batch buffer are applied on top.
+func (p *Partition) scan(read func(*KVStore) (map[string]string, error), match func(string) bool) (map[string]string, error) {
+	// Snapshot the buffer before reading the store, so a write flushed in between is
+	// seen by the store read; its sequence keeps the snapshot from replaying it again
+	buffered := p.bufferedEntries()
+
+	data, flushed, err := p.readFlushed(read)
+	if err != nil {
+		return nil, err
+	}
+	replayBuffered(data, buffered, flushed, match)
+	return data, nil
+}
+
+// scatterGather scans every partition in parallel and returns the results by partition
+func (pks *PartitionedKVStore) scatterGather(read func(*KVStore) (map[string]string, error), match func(string) bool) ([]map[string]string, error) {
+	results := make([]map[string]string, len(pks.partitions))
+	errs := make([]error, len(pks.partitions))
+
+	var wg sync.WaitGroup
+	for i, partition := range pks.partitions {
+		wg.Add(1)
+		go func(i int, p *Partition) {
+			defer wg.Done()
+			results[i], errs[i] = p.scan(read, match)
+		}(i, partition)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// mergePartitionMaps combines the results of scatterGather. A key lives in exactly
+// one partition, so the maps never share keys.
+func mergePartitionMaps(results []map[string]string) map[string]string {
+	total := 0
+	for _, data := range results {
+		total += len(data)
+	}
+
+	merged := make(map[string]string, total)
+	for _, data := range results {
+		for key, value := range data {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// mergeSortedKeys k-way merges sorted key lists. A key lives in exactly one
+// partition, so the lists never share keys.
+func mergeSortedKeys(lists [][]string) []string {
+	total := 0
+	for _, list := range lists {
+		total += len(list)
+	}
+
+	merged := make([]string, 0, total)
+	positions := make([]int, len(lists))
+	for len(merged) < total {
+		// At most 16 partitions: a linear scan for the smallest head is enough
+		smallest := -1
+		for i, list := range lists {
+			if positions[i] < len(list) && (smallest < 0 || list[positions[i]] < lists[smallest][positions[smallest]]) {
+				smallest = i
+			}
+		}
+		merged = append(merged, lists[smallest][positions[smallest]])
+		positions[smallest]++
+	}
+	return merged
+}
+
+// GetRange returns all key-value pairs with keys in [start, end], including unflushed writes
+func (pks *PartitionedKVStore) GetRange(start, end string) (map[string]string, error) {
+	results, err := pks.scatterGather(func(store *KVStore) (map[string]string, error) {
+		return store.GetRange(start, end)
+	}, func(key string) bool {
+		return key >= start && key <= end
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergePartitionMaps(results), nil
+}
+
+// PrefixSearch returns all key-value pairs whose keys start with prefix, including unflushed writes
+func (pks *PartitionedKVStore) PrefixSearch(prefix string) (map[string]string, error) {
+	results, err := pks.scatterGather(func(store *KVStore) (map[string]string, error) {
+		return store.PrefixSearch(prefix)
+	}, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergePartitionMaps(results), nil
+}
+
+// ListSorted returns all keys in sorted order, including unflushed writes
+func (pks *PartitionedKVStore) ListSorted() ([]string, error) {
+	results, err := pks.scatterGather(func(store *KVStore) (map[string]string, error) {
+		keys, err := store.List()
+		if err != nil {
+			return nil, err
+		}
+		data := make(map[string]string, len(keys))
+		for _, key := range keys {
+			data[key] = ""
+		}
+		return data, nil
+	}, func(string) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([][]string, len(results))
+	for i, data := range results {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		lists[i] = keys
+	}
+	return mergeSortedKeys(lists), nil
+}
+
 // hashString computes FNV-1a hash for a string
 func hashString(s string) uint32 {
 	h := fnv.New32a()
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -524,3 +526,116 @@ func TestPartitionedKVStore_Configuration(t *testing.T) {
 		t.Errorf("Expected BatchSize 50, got %d", store.config.BatchSize)
 	}
 }
+
+func TestPartitionedKVStore_ScatterGather(t *testing.T) {
+	config := PartitionConfig{
+		NumPartitions: 4,
+		DataDir:       t.TempDir(),
+		BatchSize:     1000,      // Keep writes in the batch buffers
+		FlushInterval: time.Hour, // No background flush during the test
+	}
+	store, err := NewPartitionedKVStore(config)
+	if err != nil {
+		t.Fatalf("Failed to create partitioned store: %v", err)
+	}
+	defer store.Close()
+
+	// Flushed writes
+	for i := 0; i < 20; i++ {
+		_ = store.Put(fmt.Sprintf("user:%02d", i), fmt.Sprintf("v%d", i))
+	}
+	_ = store.Put("admin:1", "root")
+	if err := store.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+
+	// Buffered writes override and delete flushed ones
+	_ = store.Put("user:03", "updated")
+	_ = store.Delete("user:04")
+	_ = store.Put("user:20", "new")
+	_ = store.Put("user:21", "gone")
+	_ = store.Delete("user:21")
+
+	results, err := store.GetRange("user:02", "user:05")
+	if err != nil {
+		t.Fatalf("GetRange failed: %v", err)
+	}
+	want := map[string]string{"user:02": "v2", "user:03": "updated", "user:05": "v5"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("GetRange = %v, want %v", results, want)
+	}
+
+	results, err = store.PrefixSearch("user:2")
+	if err != nil {
+		t.Fatalf("PrefixSearch failed: %v", err)
+	}
+	if !reflect.DeepEqual(results, map[string]string{"user:20": "new"}) {
+		t.Errorf("PrefixSearch = %v", results)
+	}
+
+	keys, err := store.ListSorted()
+	if err != nil {
+		t.Fatalf("ListSorted failed: %v", err)
+	}
+	if len(keys) != 21 || keys[0] != "admin:1" || keys[len(keys)-1] != "user:20" || !sort.StringsAreSorted(keys) {
+		t.Errorf("Unexpected sorted keys %v", keys)
+	}
+	for _, key := range keys {
+		if key == "user:04" || key == "user:21" {
+			t.Errorf("Deleted key %s listed", key)
+		}
+	}
+}
+
+func TestPartitionedKVStore_ScatterGatherFlushInterleaving(t *testing.T) {
+	config := PartitionConfig{
+		NumPartitions: 1, // Both keys share the partition
+		DataDir:       t.TempDir(),
+		BatchSize:     1000,
+		FlushInterval: time.Hour,
+	}
+	store, err := NewPartitionedKVStore(config)
+	if err != nil {
+		t.Fatalf("Failed to create partitioned store: %v", err)
+	}
+	defer store.Close()
+
+	// A scan snapshots the buffer, then the old write is flushed and a newer
+	// write to the same key is flushed before the scan reads the store
+	partition := store.getPartition("k")
+	_ = store.Put("k", "old")
+	_ = store.Put("gone", "buffered")
+	snapshot := partition.bufferedEntries()
+	if err := store.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+	_ = store.Put("k", "new")
+	_ = store.Delete("gone")
+	if err := store.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+
+	data, flushed, err := partition.readFlushed(func(s *KVStore) (map[string]string, error) {
+		return s.GetRange("", "\xff")
+	})
+	if err != nil {
+		t.Fatalf("readFlushed failed: %v", err)
+	}
+	replayBuffered(data, snapshot, flushed, func(string) bool { return true })
+	if data["k"] != "new" {
+		t.Errorf("Expected the newer flushed value to win, got %q", data["k"])
+	}
+	if _, ok := data["gone"]; ok {
+		t.Error("Expected the flushed delete to win over the snapshot")
+	}
+
+	// Buffered writes newer than the store are still applied
+	_ = store.Put("k", "buffered")
+	results, err := store.PrefixSearch("k")
+	if err != nil {
+		t.Fatalf("PrefixSearch failed: %v", err)
+	}
+	if !reflect.DeepEqual(results, map[string]string{"k": "buffered"}) {
+		t.Errorf("PrefixSearch = %v", results)
+	}
+}
@@ -160,9 +160,15 @@ func (lr *LogReader) parseLogFile(reader io.Reader) (map[string]string, error) {
 			continue
 		}
 
-		// Handle deletion marker
+		// Handle deletion markers
 		if entry.Value == "__DELETED__" {
 			delete(data, entry.Key)
+		} else if covers, ok := RangeDeletionMatcher(entry.Key, entry.Value); ok {
+			for key := range data {
+				if covers(key) {
+					delete(data, key)
+				}
+			}
 		} else {
 			data[entry.Key] = entry.Value
 		}
@@ -271,3 +277,22 @@ func ValidateKey(key string) error {
 func IsDeleted(value string) bool {
 	return value == "__DELETED__"
 }
+
+// Range deletion markers. A range deletion is logged as start\t__DELETED_RANGE__\tend
+// and a prefix deletion as prefix\t__DELETED_PREFIX__.
+const (
+	RangeDeletedMarker  = "__DELETED_RANGE__"
+	PrefixDeletedMarker = "__DELETED_PREFIX__"
+)
+
+// RangeDeletionMatcher reports whether a log entry is a range or prefix deletion
+// and returns a predicate matching the keys it deletes
+func RangeDeletionMatcher(key, value string) (func(string) bool, bool) {
+	if value == PrefixDeletedMarker {
+		return func(k string) bool { return strings.HasPrefix(k, key) }, true
+	}
+	if end, ok := strings.CutPrefix(value, RangeDeletedMarker+"\t"); ok {
+		return func(k string) bool { return k >= key && k <= end }, true
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyasuto/moz/internal/index"
+)
+
+// secondaryIndexPath returns the file holding the secondary indexes, next to the primary index file
+func secondaryIndexPath(dataDir string, storageConfig StorageConfig) string {
+	name := storageConfig.IndexFile
+	if name == "" {
+		name = "moz.idx"
+	}
+	return filepath.Join(dataDir, strings.TrimSuffix(name, filepath.Ext(name))+".sidx")
+}
+
+// fileSize returns the size of a file, or 0 if it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// refreshSecondaryIndexes rebuilds secondary indexes that were saved for a different log.
+// Callers hold kv.mu.
+func (kv *KVStore) refreshSecondaryIndexes() error {
+	if kv.secondary.Len() == 0 || !kv.secondary.Stale() {
+		return nil
+	}
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild secondary indexes: %w", err)
+	}
+	kv.secondary.Rebuild(data)
+	return nil
+}
+
+// maintainSecondaryIndexes applies a write to the in-memory secondary indexes. They are
+// persisted by Close and Compact; until then the saved file records an older log size
+// and is rebuilt if the store is reopened without closing.
+// Callers hold kv.mu and have already written the log.
+func (kv *KVStore) maintainSecondaryIndexes(apply func(*index.SecondaryIndexSet)) {
+	if kv.secondary.Len() == 0 {
+		return
+	}
+	if err := kv.refreshSecondaryIndexes(); err != nil {
+		// The saved file still describes an older log and is rebuilt on next use
+		fmt.Printf("Warning: failed to update secondary indexes: %v\n", err)
+		return
+	}
+	apply(kv.secondary)
+	kv.secondaryDirty = true
+}
+
+// flushSecondaryIndexes saves secondary indexes changed since they were last saved.
+// Callers hold kv.mu.
+func (kv *KVStore) flushSecondaryIndexes() error {
+	if kv.secondary.Len() == 0 || !kv.secondaryDirty {
+		return nil
+	}
+	if err := kv.refreshSecondaryIndexes(); err != nil {
+		return err
+	}
+	if err := kv.secondary.Save(kv.secondaryFile, fileSize(kv.logFile)); err != nil {
+		return err
+	}
+	kv.secondaryDirty = false
+	return nil
+}
+
+// CreateSecondaryIndex declares an index over values, or over a JSON field of
+// values when field is a path such as "status" or "user.name"
+func (kv *KVStore) CreateSecondaryIndex(name, field string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if err := kv.refreshSecondaryIndexes(); err != nil {
+		return err
+	}
+	data, err := kv.buildCurrentState()
+	if err != nil {
+		return fmt.Errorf("failed to build current state: %w", err)
+	}
+	if err := kv.secondary.Create(index.SecondaryIndexDef{Name: name, Field: field}, data); err != nil {
+		return err
+	}
+	kv.secondaryDirty = true
+	return kv.flushSecondaryIndexes()
+}
+
+// DropSecondaryIndex removes a secondary index
+func (kv *KVStore) DropSecondaryIndex(name string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if err := kv.secondary.Drop(name); err != nil {
+		return err
+	}
+	if kv.secondary.Len() == 0 {
+		if err := os.Remove(kv.secondaryFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove secondary index file: %w", err)
+		}
+		return nil
+	}
+	kv.secondaryDirty = true
+	return kv.flushSecondaryIndexes()
+}
+
+// SecondaryIndexes returns the declared secondary indexes sorted by name
+func (kv *KVStore) SecondaryIndexes() []index.SecondaryIndexDef {
+	return kv.secondary.Definitions()
+}
+
+// LookupSecondary returns the sorted keys whose field equals value (the whole value
+// when field is empty). It returns false if no secondary index covers the field.
+func (kv *KVStore) LookupSecondary(field, value string) ([]string, bool) {
+	// Rebuilding stale indexes modifies them, so it needs the write lock
+	kv.mu.RLock()
+	stale := kv.secondary.Stale()
+	kv.mu.RUnlock()
+	if stale {
+		kv.mu.Lock()
+		err := kv.refreshSecondaryIndexes()
+		kv.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			return nil, false
+		}
+	}
+
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	return kv.secondary.Lookup(field, value)
+}
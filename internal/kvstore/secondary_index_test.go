@@ -0,0 +1,113 @@
+package kvstore
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newSecondaryTestStore(dir string) *KVStore {
+	return NewWithConfig(CompactionConfig{Enabled: false}, StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexType: "none",
+		IndexFile: "moz.idx",
+		DataDir:   dir,
+	})
+}
+
+func TestKVStore_SecondaryIndexes(t *testing.T) {
+	dir := t.TempDir()
+	store := newSecondaryTestStore(dir)
+
+	_ = store.Put("user:1", `{"status":"active"}`)
+	_ = store.Put("user:2", `{"status":"inactive"}`)
+	if err := store.CreateSecondaryIndex("by_status", "status"); err != nil {
+		t.Fatalf("CreateSecondaryIndex failed: %v", err)
+	}
+
+	saved, err := os.ReadFile(store.secondaryFile)
+	if err != nil {
+		t.Fatalf("Expected index file after create: %v", err)
+	}
+
+	// Maintained on Put, Delete and range deletes
+	_ = store.Put("user:3", `{"status":"active"}`)
+	_ = store.Put("user:2", `{"status":"active"}`)
+	_ = store.Delete("user:1")
+	_ = store.Put("admin:1", `{"status":"active"}`)
+	_ = store.DeletePrefix("admin:")
+
+	keys, ok := store.LookupSecondary("status", "active")
+	if !ok || !reflect.DeepEqual(keys, []string{"user:2", "user:3"}) {
+		t.Errorf("Expected [user:2 user:3], got %v (ok=%v)", keys, ok)
+	}
+
+	// Writes only update the indexes in memory
+	if current, _ := os.ReadFile(store.secondaryFile); string(current) != string(saved) {
+		t.Error("Expected writes to leave the index file unchanged")
+	}
+	unclosed := newSecondaryTestStore(dir)
+	if keys, _ := unclosed.LookupSecondary("status", "active"); !reflect.DeepEqual(keys, []string{"user:2", "user:3"}) {
+		t.Errorf("Expected [user:2 user:3] from a rebuild without close, got %v", keys)
+	}
+
+	// Persisted alongside the primary index on close
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	reopened := newSecondaryTestStore(dir)
+	if reopened.secondary.Stale() {
+		t.Error("Expected the closed store's index file to be current")
+	}
+	if defs := reopened.SecondaryIndexes(); len(defs) != 1 || defs[0].Field != "status" {
+		t.Fatalf("Expected by_status after reopen, got %v", defs)
+	}
+	if keys, _ := reopened.LookupSecondary("status", "active"); !reflect.DeepEqual(keys, []string{"user:2", "user:3"}) {
+		t.Errorf("Expected [user:2 user:3] after reopen, got %v", keys)
+	}
+
+	// Writes the index file did not see are picked up by a rebuild
+	file, err := os.OpenFile(store.LogFilePath(), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	_, _ = file.WriteString("user:4\t{\"status\":\"active\"}\n")
+	_ = file.Close()
+
+	stale := newSecondaryTestStore(dir)
+	if keys, _ := stale.LookupSecondary("status", "active"); !reflect.DeepEqual(keys, []string{"user:2", "user:3", "user:4"}) {
+		t.Errorf("Expected [user:2 user:3 user:4] after rebuild, got %v", keys)
+	}
+
+	// Concurrent lookups on a stale store rebuild it once under the write lock
+	racing := newSecondaryTestStore(dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if keys, _ := racing.LookupSecondary("status", "active"); len(keys) != 3 {
+				t.Errorf("Expected 3 keys from a concurrent lookup, got %v", keys)
+			}
+		}()
+	}
+	wg.Wait()
+	if racing.secondary.Stale() {
+		t.Error("Expected concurrent lookups to leave the indexes rebuilt")
+	}
+
+	if err := stale.RebuildIndex(); err != nil {
+		t.Errorf("RebuildIndex with only secondary indexes failed: %v", err)
+	}
+	if err := stale.DropSecondaryIndex("by_status"); err != nil {
+		t.Fatalf("DropSecondaryIndex failed: %v", err)
+	}
+	if _, ok := stale.LookupSecondary("status", "active"); ok {
+		t.Error("Expected no index after drop")
+	}
+	if _, err := os.Stat(stale.secondaryFile); !os.IsNotExist(err) {
+		t.Errorf("Expected index file to be removed with the last index, got %v", err)
+	}
+}
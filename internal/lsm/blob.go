@@ -0,0 +1,453 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// BlobConfig controls key-value separation: large values are written to
+// append-only blob files and SSTables keep only a pointer to them, so
+// compactions move the pointer instead of rewriting the value
+type BlobConfig struct {
+	MinBlobSize  int     // Values at least this large are stored in blob files (0 disables separation)
+	BlobFileSize int64   // Size at which the active blob file is sealed and a new one started
+	GCLiveRatio  float64 // Sealed blob files with a smaller fraction of live bytes are garbage-collected
+}
+
+// DefaultBlobConfig returns default blob file configuration (separation disabled)
+func DefaultBlobConfig() BlobConfig {
+	return BlobConfig{
+		MinBlobSize:  0,
+		BlobFileSize: 64 * 1024 * 1024, // 64MB
+		GCLiveRatio:  0.5,
+	}
+}
+
+const (
+	blobFileMagic   = 0x4d4f5a42 // "MOZB"
+	blobFileVersion = 1
+	// blobHeaderSize covers the magic and version at the start of a blob file
+	blobHeaderSize = 4 + 4
+	// blobRecordOverhead covers key length, value length and checksum of a record
+	blobRecordOverhead = 4 + 4 + 4
+	// blobIndexSize is the encoded size of a blob pointer stored in an SSTable
+	blobIndexSize = 8 + 8 + 4
+)
+
+// blobIndex points at a record in a blob file
+type blobIndex struct {
+	File   uint64
+	Offset int64
+	Length uint32
+}
+
+// encode returns the pointer as stored in an SSTable entry value
+func (bi blobIndex) encode() string {
+	data := make([]byte, blobIndexSize)
+	binary.LittleEndian.PutUint64(data[0:], bi.File)
+	binary.LittleEndian.PutUint64(data[8:], uint64(bi.Offset))
+	binary.LittleEndian.PutUint32(data[16:], bi.Length)
+	return string(data)
+}
+
+// decodeBlobIndex parses a pointer stored in an SSTable entry value
+func decodeBlobIndex(value string) (blobIndex, error) {
+	if len(value) != blobIndexSize {
+		return blobIndex{}, fmt.Errorf("invalid blob index length: %d", len(value))
+	}
+	data := []byte(value)
+	return blobIndex{
+		File:   binary.LittleEndian.Uint64(data[0:]),
+		Offset: int64(binary.LittleEndian.Uint64(data[8:])),
+		Length: binary.LittleEndian.Uint32(data[16:]),
+	}, nil
+}
+
+// blobFile is a single append-only file of key/value records
+type blobFile struct {
+	number uint64
+	path   string
+	file   *os.File
+	size   int64
+}
+
+// blobStore manages the blob files of an LSM-Tree.
+// New values are appended to the active file; all other files are sealed.
+type blobStore struct {
+	mu       sync.RWMutex
+	dir      string
+	config   BlobConfig
+	files    map[uint64]*blobFile
+	active   *blobFile
+	nextFile uint64
+
+	bytesWritten uint64 // Updated atomically
+}
+
+// blobFileName returns the file name of a blob file
+func blobFileName(number uint64) string {
+	return fmt.Sprintf("blob_%d.blob", number)
+}
+
+// openBlobStore opens the blob files found in the data directory as sealed files
+func openBlobStore(dir string, config BlobConfig) (*blobStore, error) {
+	defaults := DefaultBlobConfig()
+	if config.BlobFileSize <= 0 {
+		config.BlobFileSize = defaults.BlobFileSize
+	}
+	if config.GCLiveRatio <= 0 {
+		config.GCLiveRatio = defaults.GCLiveRatio
+	}
+
+	bs := &blobStore{
+		dir:    dir,
+		config: config,
+		files:  make(map[uint64]*blobFile),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return bs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "blob_") || filepath.Ext(name) != ".blob" {
+			continue
+		}
+		number, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "blob_"), ".blob"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		file, err := os.Open(path) // #nosec G304 - path is safely constructed with filepath.Join
+		if err != nil {
+			bs.close()
+			return nil, fmt.Errorf("failed to open blob file %s: %w", name, err)
+		}
+		stat, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			bs.close()
+			return nil, fmt.Errorf("failed to stat blob file %s: %w", name, err)
+		}
+
+		bs.files[number] = &blobFile{number: number, path: path, file: file, size: stat.Size()}
+		if number > bs.nextFile {
+			bs.nextFile = number
+		}
+	}
+
+	return bs, nil
+}
+
+// separates reports whether a value is large enough to be stored in a blob file
+func (bs *blobStore) separates(value string) bool {
+	return bs.config.MinBlobSize > 0 && len(value) >= bs.config.MinBlobSize
+}
+
+// add appends a record to the active blob file, starting a new file once it is full
+func (bs *blobStore) add(key, value string) (blobIndex, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.active == nil || bs.active.size >= bs.config.BlobFileSize {
+		if err := bs.rotate(); err != nil {
+			return blobIndex{}, err
+		}
+	}
+
+	record := make([]byte, blobRecordOverhead+len(key)+len(value))
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(key)))
+	binary.LittleEndian.PutUint32(record[4:], uint32(len(value)))
+	copy(record[8:], key)
+	copy(record[8+len(key):], value)
+	binary.LittleEndian.PutUint32(record[len(record)-4:], crc32.ChecksumIEEE(record[8:len(record)-4]))
+
+	ref := blobIndex{File: bs.active.number, Offset: bs.active.size, Length: uint32(len(record))}
+	if _, err := bs.active.file.WriteAt(record, ref.Offset); err != nil {
+		return blobIndex{}, fmt.Errorf("failed to write blob record: %w", err)
+	}
+	bs.active.size += int64(len(record))
+	atomic.AddUint64(&bs.bytesWritten, uint64(len(record)))
+
+	return ref, nil
+}
+
+// rotate seals the active blob file and creates a new one. Callers hold mu.
+func (bs *blobStore) rotate() error {
+	if bs.active != nil {
+		if err := bs.active.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync blob file: %w", err)
+		}
+		bs.active = nil
+	}
+
+	if err := os.MkdirAll(bs.dir, 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	bs.nextFile++
+	number := bs.nextFile
+	path := filepath.Join(bs.dir, blobFileName(number))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+
+	header := make([]byte, blobHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], blobFileMagic)
+	binary.LittleEndian.PutUint32(header[4:], blobFileVersion)
+	if _, err := file.WriteAt(header, 0); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to write blob file header: %w", err)
+	}
+
+	bs.active = &blobFile{number: number, path: path, file: file, size: blobHeaderSize}
+	bs.files[number] = bs.active
+	return nil
+}
+
+// get reads the value a pointer refers to and checks it belongs to key
+func (bs *blobStore) get(key string, ref blobIndex) (string, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	file, ok := bs.files[ref.File]
+	if !ok {
+		return "", fmt.Errorf("blob file %d not found", ref.File)
+	}
+	if ref.Length < blobRecordOverhead || ref.Offset+int64(ref.Length) > file.size {
+		return "", fmt.Errorf("blob record out of range in file %d", ref.File)
+	}
+
+	record := make([]byte, ref.Length)
+	if _, err := file.file.ReadAt(record, ref.Offset); err != nil {
+		return "", fmt.Errorf("failed to read blob record: %w", err)
+	}
+
+	keyLen := int(binary.LittleEndian.Uint32(record[0:]))
+	valueLen := int(binary.LittleEndian.Uint32(record[4:]))
+	if blobRecordOverhead+keyLen+valueLen != len(record) {
+		return "", fmt.Errorf("invalid blob record length in file %d", ref.File)
+	}
+	if crc32.ChecksumIEEE(record[8:len(record)-4]) != binary.LittleEndian.Uint32(record[len(record)-4:]) {
+		return "", fmt.Errorf("blob record checksum mismatch in file %d", ref.File)
+	}
+	if string(record[8:8+keyLen]) != key {
+		return "", fmt.Errorf("blob record in file %d belongs to another key", ref.File)
+	}
+
+	return string(record[8+keyLen : 8+keyLen+valueLen]), nil
+}
+
+// sync flushes the active blob file, so SSTables written afterwards never point at lost records
+func (bs *blobStore) sync() error {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	if bs.active == nil {
+		return nil
+	}
+	if err := bs.active.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync blob file: %w", err)
+	}
+	return nil
+}
+
+// sealedFiles returns the numbers of the blob files no longer written to, oldest first
+func (bs *blobStore) sealedFiles() []uint64 {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	var numbers []uint64
+	for number := range bs.files {
+		if bs.active == nil || number != bs.active.number {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// dataSize returns the record bytes held by a blob file
+func (bs *blobStore) dataSize(number uint64) int64 {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	if file, ok := bs.files[number]; ok {
+		return file.size - blobHeaderSize
+	}
+	return 0
+}
+
+// usage returns the number of blob files and their total size
+func (bs *blobStore) usage() (int, int64) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	var size int64
+	for _, file := range bs.files {
+		size += file.size
+	}
+	return len(bs.files), size
+}
+
+// remove deletes a sealed blob file
+func (bs *blobStore) remove(number uint64) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	file, ok := bs.files[number]
+	if !ok {
+		return nil
+	}
+	if bs.active == file {
+		return fmt.Errorf("cannot remove active blob file %d", number)
+	}
+
+	delete(bs.files, number)
+	if err := file.file.Close(); err != nil {
+		fmt.Printf("Warning: failed to close blob file %s: %v\n", file.path, err)
+	}
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove blob file %s: %w", file.path, err)
+	}
+	return nil
+}
+
+// close syncs the active blob file and closes every file
+func (bs *blobStore) close() error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var errors []string
+	if bs.active != nil {
+		if err := bs.active.file.Sync(); err != nil {
+			errors = append(errors, fmt.Sprintf("sync %s: %v", bs.active.path, err))
+		}
+		bs.active = nil
+	}
+	for _, file := range bs.files {
+		if err := file.file.Close(); err != nil {
+			errors = append(errors, fmt.Sprintf("close %s: %v", file.path, err))
+		}
+	}
+	bs.files = make(map[uint64]*blobFile)
+
+	if len(errors) > 0 {
+		return fmt.Errorf("errors closing blob files: %s", strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// blobValue returns the value of an SSTable entry, reading it from its blob file if separated
+func (lsm *LSMTree) blobValue(entry *SSTableEntry) (string, error) {
+	if !entry.BlobIndex || entry.Deleted {
+		return entry.Value, nil
+	}
+	ref, err := decodeBlobIndex(entry.Value)
+	if err != nil {
+		return "", fmt.Errorf("key %s: %w", entry.Key, err)
+	}
+	value, err := lsm.blobs.get(entry.Key, ref)
+	if err != nil {
+		return "", fmt.Errorf("key %s: %w", entry.Key, err)
+	}
+	return value, nil
+}
+
+// separateValue moves a large value into a blob file and replaces it with a pointer
+func (lsm *LSMTree) separateValue(entry *SSTableEntry) error {
+	if entry.Deleted || entry.BlobIndex || !lsm.blobs.separates(entry.Value) {
+		return nil
+	}
+	ref, err := lsm.blobs.add(entry.Key, entry.Value)
+	if err != nil {
+		return err
+	}
+	entry.Value = ref.encode()
+	entry.BlobIndex = true
+	return nil
+}
+
+// blobLiveBytes sums, per blob file, the record bytes referenced by SSTables.
+// Callers hold mu.
+func (lsm *LSMTree) blobLiveBytes() (map[uint64]int64, error) {
+	live := make(map[uint64]int64)
+	for _, level := range lsm.levels {
+		for _, sstable := range level.SSTables {
+			refs, err := sstable.blobReferences()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read blob references of %s: %w", sstable.ID, err)
+			}
+			for number, bytes := range refs {
+				live[number] += bytes
+			}
+		}
+	}
+	return live, nil
+}
+
+// collectBlobGarbage deletes sealed blob files no SSTable points into, and rewrites
+// the SSTables pointing into files whose live ratio fell below GCLiveRatio so that
+// their remaining values move to the active blob file. Callers hold mu.
+func (lsm *LSMTree) collectBlobGarbage() error {
+	sealed := lsm.blobs.sealedFiles()
+	if len(sealed) == 0 {
+		return nil
+	}
+
+	live, err := lsm.blobLiveBytes()
+	if err != nil {
+		return err
+	}
+
+	relocate := make(map[uint64]bool)
+	for _, number := range sealed {
+		size := lsm.blobs.dataSize(number)
+		if live[number] > 0 && size > 0 && float64(live[number])/float64(size) < lsm.blobs.config.GCLiveRatio {
+			relocate[number] = true
+		}
+	}
+
+	if len(relocate) > 0 {
+		fmt.Printf("Relocating values out of %d sparse blob files\n", len(relocate))
+		if err := lsm.compactionManager.RelocateBlobs(relocate); err != nil {
+			return fmt.Errorf("failed to relocate blob values: %w", err)
+		}
+		if live, err = lsm.blobLiveBytes(); err != nil {
+			return err
+		}
+	}
+
+	deleted := 0
+	for _, number := range sealed {
+		if live[number] > 0 {
+			continue
+		}
+		if err := lsm.blobs.remove(number); err != nil {
+			return err
+		}
+		deleted++
+	}
+
+	if deleted > 0 || len(relocate) > 0 {
+		lsm.stats.BlobGCRuns++
+		lsm.stats.BlobFilesDeleted += uint64(deleted)
+	}
+	return nil
+}
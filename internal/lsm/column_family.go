@@ -0,0 +1,624 @@
+package lsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DefaultColumnFamily is the name of the column family the LSMTree methods operate on
+const DefaultColumnFamily = "default"
+
+const familyManifestFile = "families.json"
+
+// ColumnFamily is a named keyspace of an LSM-Tree with its own MemTable, SSTables
+// and compaction settings. All column families share the tree's write-ahead log.
+type ColumnFamily struct {
+	mu      sync.RWMutex // Held exclusively while the family is dropped
+	name    string
+	id      uint32
+	tree    *LSMTree
+	dropped bool
+}
+
+// familyManifest persists the column families and how much of the WAL each has flushed
+type familyManifest struct {
+	NextID   uint32                   `json:"next_id"`
+	Families map[string]*familyRecord `json:"families"`
+}
+
+// familyRecord describes one column family in the manifest
+type familyRecord struct {
+	ID         uint32     `json:"id"`
+	Config     *LSMConfig `json:"config,omitempty"` // nil for the default family, which is configured by the caller
+	FlushedLSN uint64     `json:"flushed_lsn"`      // WAL records up to this LSN are in SSTables
+}
+
+// columnFamilySet tracks the column families of a tree and their shared WAL
+type columnFamilySet struct {
+	mu       sync.Mutex
+	dir      string
+	manifest familyManifest
+	handles  map[string]*ColumnFamily
+	wal      *writeAheadLog
+}
+
+// familyDir returns the data directory of a non-default column family
+func (s *columnFamilySet) familyDir(name string) string {
+	return filepath.Join(s.dir, "families", name)
+}
+
+// open loads the manifest, reopens the column families and recovers writes from the WAL.
+// On failure the caller closes the root tree, which closes whatever was opened.
+func (s *columnFamilySet) open(root *LSMTree, config WALConfig) error {
+	s.manifest = familyManifest{NextID: 1, Families: map[string]*familyRecord{DefaultColumnFamily: {ID: 0}}}
+	s.handles = map[string]*ColumnFamily{DefaultColumnFamily: {name: DefaultColumnFamily, id: 0, tree: root}}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, familyManifestFile)) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read column family manifest: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.manifest); err != nil {
+			return fmt.Errorf("failed to parse column family manifest: %w", err)
+		}
+	}
+
+	byID := make(map[uint32]*ColumnFamily)
+	for name, record := range s.manifest.Families {
+		if name == DefaultColumnFamily {
+			byID[record.ID] = s.handles[name]
+			continue
+		}
+		if record.Config == nil {
+			return fmt.Errorf("column family %q has no configuration", name)
+		}
+		familyConfig := *record.Config
+		familyConfig.DataDir = s.familyDir(name)
+		tree, err := openLSMTree(familyConfig, s, record.ID)
+		if err != nil {
+			return fmt.Errorf("failed to open column family %q: %w", name, err)
+		}
+		s.handles[name] = &ColumnFamily{name: name, id: record.ID, tree: tree}
+		byID[record.ID] = s.handles[name]
+	}
+
+	wal, records, err := openWAL(s.dir, config)
+	if err != nil {
+		return err
+	}
+	s.wal = wal
+
+	// Replay writes the families had not flushed, skipping families dropped since
+	flushed := make(map[uint32]uint64)
+	for _, record := range s.manifest.Families {
+		flushed[record.ID] = record.FlushedLSN
+		if record.FlushedLSN >= wal.nextLSN {
+			wal.nextLSN = record.FlushedLSN + 1
+		}
+	}
+	for _, record := range records {
+		for _, op := range record.ops {
+			cf, ok := byID[op.family]
+			if !ok || record.lsn <= flushed[op.family] {
+				continue
+			}
+			cf.tree.mu.Lock()
+			cf.tree.replay(op, record.lsn)
+			cf.tree.mu.Unlock()
+		}
+	}
+
+	// Flush the recovered writes so the old segments can be deleted
+	for _, cf := range s.handles {
+		cf.tree.mu.Lock()
+		err := cf.tree.flushMemTable()
+		if err == nil {
+			err = cf.tree.flushImmutableMemTables()
+		}
+		cf.tree.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to flush recovered writes of column family %q: %w", cf.name, err)
+		}
+	}
+	wal.mu.Lock()
+	wal.removeObsoleteSegments()
+	wal.mu.Unlock()
+
+	return nil
+}
+
+// saveManifest atomically replaces the manifest file. Callers hold mu.
+func (s *columnFamilySet) saveManifest() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode column family manifest: %w", err)
+	}
+
+	path := filepath.Join(s.dir, familyManifestFile)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to write column family manifest: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write column family manifest: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync column family manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write column family manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace column family manifest: %w", err)
+	}
+	return nil
+}
+
+// flushed records that a column family flushed the WAL records up to lsn,
+// and which of its records is now the oldest one still only in the WAL
+func (s *columnFamilySet) flushed(family uint32, lsn, oldestUnflushed uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	var record *familyRecord
+	for _, r := range s.manifest.Families {
+		if r.ID == family {
+			record = r
+		}
+	}
+	if record == nil {
+		// Dropped while flushing; its data is about to be deleted
+		return nil
+	}
+
+	if lsn > record.FlushedLSN {
+		record.FlushedLSN = lsn
+		if err := s.saveManifest(); err != nil {
+			return err
+		}
+	}
+	s.wal.markFlushed(family, oldestUnflushed)
+	return nil
+}
+
+// create adds a column family
+func (s *columnFamilySet) create(name string, config LSMConfig) (*ColumnFamily, error) {
+	if err := validateColumnFamilyName(name); err != nil {
+		return nil, err
+	}
+	if config.NumLevels <= 0 {
+		return nil, fmt.Errorf("column family %q needs at least one level", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.manifest.Families[name]; exists {
+		return nil, fmt.Errorf("column family %q already exists", name)
+	}
+
+	// Remove files left behind by a drop that was interrupted
+	dir := s.familyDir(name)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear column family directory: %w", err)
+	}
+
+	id := s.manifest.NextID
+	config.DataDir = dir
+	tree, err := openLSMTree(config, s, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create column family %q: %w", name, err)
+	}
+
+	// The data directory is derived from the name when the family is reopened
+	persisted := config
+	persisted.DataDir = ""
+	s.manifest.NextID++
+	s.manifest.Families[name] = &familyRecord{ID: id, Config: &persisted}
+	if err := s.saveManifest(); err != nil {
+		delete(s.manifest.Families, name)
+		_ = tree.Close()
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cf := &ColumnFamily{name: name, id: id, tree: tree}
+	s.handles[name] = cf
+	return cf, nil
+}
+
+// drop removes a column family and deletes its data
+func (s *columnFamilySet) drop(name string) error {
+	if name == DefaultColumnFamily {
+		return fmt.Errorf("cannot drop the default column family")
+	}
+
+	s.mu.Lock()
+	cf, exists := s.handles[name]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("column family %q does not exist", name)
+	}
+	record := s.manifest.Families[name]
+	delete(s.manifest.Families, name)
+	if err := s.saveManifest(); err != nil {
+		s.manifest.Families[name] = record
+		s.mu.Unlock()
+		return err
+	}
+	delete(s.handles, name)
+	s.mu.Unlock()
+
+	// Wait for operations in progress, then refuse new ones
+	cf.mu.Lock()
+	cf.dropped = true
+	cf.mu.Unlock()
+
+	if err := cf.tree.Close(); err != nil {
+		fmt.Printf("Warning: failed to close dropped column family %q: %v\n", name, err)
+	}
+	s.wal.forget(cf.id)
+	if err := os.RemoveAll(s.familyDir(name)); err != nil {
+		return fmt.Errorf("failed to delete column family %q: %w", name, err)
+	}
+	return nil
+}
+
+// get returns the handle of a column family
+func (s *columnFamilySet) get(name string) (*ColumnFamily, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cf, exists := s.handles[name]
+	if !exists {
+		return nil, fmt.Errorf("column family %q does not exist", name)
+	}
+	return cf, nil
+}
+
+// names returns the column family names in sorted order
+func (s *columnFamilySet) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.handles))
+	for name := range s.handles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// closeFamilies closes every column family except the default one
+func (s *columnFamilySet) closeFamilies() error {
+	s.mu.Lock()
+	families := make([]*ColumnFamily, 0, len(s.handles))
+	for name, cf := range s.handles {
+		if name != DefaultColumnFamily {
+			families = append(families, cf)
+		}
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, cf := range families {
+		if err := cf.tree.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close column family %q: %w", cf.name, err)
+		}
+	}
+	return firstErr
+}
+
+// close closes the WAL once every column family has been closed. After a clean
+// shutdown everything is in SSTables, so the log is deleted and the LSNs restart.
+func (s *columnFamilySet) close(clean bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	if err := s.wal.close(clean); err != nil {
+		return err
+	}
+	if !clean {
+		return nil
+	}
+
+	for _, record := range s.manifest.Families {
+		record.FlushedLSN = 0
+	}
+	if len(s.manifest.Families) == 1 {
+		// Only the default family; there is nothing to remember
+		if err := os.Remove(filepath.Join(s.dir, familyManifestFile)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove column family manifest: %w", err)
+		}
+		return nil
+	}
+	return s.saveManifest()
+}
+
+// validateColumnFamilyName accepts names usable as directory names
+func validateColumnFamilyName(name string) error {
+	if name == "" || name == DefaultColumnFamily {
+		return fmt.Errorf("invalid column family name %q", name)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("invalid column family name %q: only letters, digits, '_' and '-' are allowed", name)
+		}
+	}
+	return nil
+}
+
+// CreateColumnFamily creates a column family with its own MemTable, SSTables and
+// compaction settings, typically DefaultLSMConfig() with some fields changed.
+// config.DataDir and config.WAL are ignored.
+func (lsm *LSMTree) CreateColumnFamily(name string, config LSMConfig) (*ColumnFamily, error) {
+	return lsm.families.create(name, config)
+}
+
+// DropColumnFamily removes a column family and deletes all of its data
+func (lsm *LSMTree) DropColumnFamily(name string) error {
+	return lsm.families.drop(name)
+}
+
+// ColumnFamily returns the handle of an existing column family
+func (lsm *LSMTree) ColumnFamily(name string) (*ColumnFamily, error) {
+	return lsm.families.get(name)
+}
+
+// ColumnFamilies returns the names of all column families, including the default one
+func (lsm *LSMTree) ColumnFamilies() []string {
+	return lsm.families.names()
+}
+
+// Name returns the column family name
+func (cf *ColumnFamily) Name() string {
+	return cf.name
+}
+
+// use runs fn on the family's tree unless the family has been dropped
+func (cf *ColumnFamily) use(fn func(tree *LSMTree) error) error {
+	cf.mu.RLock()
+	defer cf.mu.RUnlock()
+
+	if cf.dropped {
+		return fmt.Errorf("column family %q has been dropped", cf.name)
+	}
+	return fn(cf.tree)
+}
+
+// Put writes a key-value pair to the column family
+func (cf *ColumnFamily) Put(key, value string) error {
+	return cf.use(func(tree *LSMTree) error {
+		return tree.Put(key, value)
+	})
+}
+
+// Get retrieves the value of a key in the column family
+func (cf *ColumnFamily) Get(key string) (string, error) {
+	var value string
+	err := cf.use(func(tree *LSMTree) error {
+		var err error
+		value, err = tree.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// Delete removes a key from the column family
+func (cf *ColumnFamily) Delete(key string) error {
+	return cf.use(func(tree *LSMTree) error {
+		return tree.Delete(key)
+	})
+}
+
+// DeleteRange removes every key in the inclusive range [start, end] from the column family
+func (cf *ColumnFamily) DeleteRange(start, end string) error {
+	return cf.use(func(tree *LSMTree) error {
+		return tree.DeleteRange(start, end)
+	})
+}
+
+// DeletePrefix removes every key with the given prefix from the column family
+func (cf *ColumnFamily) DeletePrefix(prefix string) error {
+	return cf.use(func(tree *LSMTree) error {
+		return tree.DeletePrefix(prefix)
+	})
+}
+
+// PrefixSearch returns all live keys and values with the given prefix in the column family
+func (cf *ColumnFamily) PrefixSearch(prefix string) (map[string]string, error) {
+	var result map[string]string
+	err := cf.use(func(tree *LSMTree) error {
+		var err error
+		result, err = tree.PrefixSearch(prefix)
+		return err
+	})
+	return result, err
+}
+
+// GetRange returns all live keys and values with keys in [start, end] in the column family
+func (cf *ColumnFamily) GetRange(start, end string) (map[string]string, error) {
+	var result map[string]string
+	err := cf.use(func(tree *LSMTree) error {
+		var err error
+		result, err = tree.GetRange(start, end)
+		return err
+	})
+	return result, err
+}
+
+// NewIterator returns an iterator over the live keys with the given prefix in the column family
+func (cf *ColumnFamily) NewIterator(prefix string) (*Iterator, error) {
+	var iter *Iterator
+	err := cf.use(func(tree *LSMTree) error {
+		var err error
+		iter, err = tree.NewIterator(prefix)
+		return err
+	})
+	return iter, err
+}
+
+// Compact flushes the column family's MemTable and compacts its SSTables
+func (cf *ColumnFamily) Compact() error {
+	return cf.use(func(tree *LSMTree) error {
+		return tree.Compact()
+	})
+}
+
+// GetStats returns the statistics of the column family
+func (cf *ColumnFamily) GetStats() LSMStats {
+	var stats LSMStats
+	_ = cf.use(func(tree *LSMTree) error {
+		stats = tree.GetStats()
+		return nil
+	})
+	return stats
+}
+
+// Iterator walks a snapshot of live keys in key order
+type Iterator struct {
+	keys   []string
+	values map[string]string
+	pos    int
+}
+
+// NewIterator returns an iterator over a snapshot of the live keys with the given prefix
+func (lsm *LSMTree) NewIterator(prefix string) (*Iterator, error) {
+	values, err := lsm.PrefixSearch(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return &Iterator{keys: keys, values: values}, nil
+}
+
+// HasNext returns true if there are more keys
+func (it *Iterator) HasNext() bool {
+	return it.pos < len(it.keys)
+}
+
+// Next returns the next key and its value
+func (it *Iterator) Next() (string, string) {
+	key := it.keys[it.pos]
+	it.pos++
+	return key, it.values[key]
+}
+
+// WriteBatch groups writes to one or more column families that are logged and applied atomically
+type WriteBatch struct {
+	ops []batchOp
+}
+
+// batchOp is a write to a column family named by the caller
+type batchOp struct {
+	family string
+	op     walOp
+}
+
+// NewWriteBatch creates an empty write batch
+func NewWriteBatch() *WriteBatch {
+	return &WriteBatch{}
+}
+
+// Put adds a key-value pair for the column family to the batch
+func (b *WriteBatch) Put(family, key, value string) {
+	b.ops = append(b.ops, batchOp{family: family, op: walOp{kind: walPut, key: key, value: value}})
+}
+
+// Delete adds a deletion of the key in the column family to the batch
+func (b *WriteBatch) Delete(family, key string) {
+	b.ops = append(b.ops, batchOp{family: family, op: walOp{kind: walDelete, key: key}})
+}
+
+// Len returns the number of writes in the batch
+func (b *WriteBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write logs the batch as a single WAL record and applies it to every column family
+// it touches, so after a crash either all of its writes are recovered or none are.
+func (lsm *LSMTree) Write(batch *WriteBatch) error {
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	// Resolve the families, ordered by ID so concurrent batches lock them in the same order
+	ops := make([]walOp, len(batch.ops))
+	targets := make([]*ColumnFamily, len(batch.ops))
+	var families []*ColumnFamily
+	for i, bop := range batch.ops {
+		cf, err := lsm.families.get(bop.family)
+		if err != nil {
+			return err
+		}
+		ops[i] = bop.op
+		ops[i].family = cf.id
+		targets[i] = cf
+		if !containsFamily(families, cf) {
+			families = append(families, cf)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].id < families[j].id
+	})
+
+	for _, cf := range families {
+		cf.mu.RLock()
+		defer cf.mu.RUnlock()
+		if cf.dropped {
+			return fmt.Errorf("column family %q has been dropped", cf.name)
+		}
+	}
+
+	// Apply backpressure and make room in each MemTable before taking all the locks
+	for _, cf := range families {
+		cf.tree.mu.Lock()
+		err := cf.tree.prepareWrite()
+		cf.tree.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cf := range families {
+		cf.tree.mu.Lock()
+		defer cf.tree.mu.Unlock()
+	}
+
+	lsn, err := lsm.families.wal.append(ops)
+	if err != nil {
+		return fmt.Errorf("failed to write WAL: %w", err)
+	}
+	for i, op := range ops {
+		targets[i].tree.applyOp(op, lsn)
+	}
+	return nil
+}
+
+// containsFamily reports whether the list holds the column family
+func containsFamily(families []*ColumnFamily, cf *ColumnFamily) bool {
+	for _, f := range families {
+		if f == cf {
+			return true
+		}
+	}
+	return false
+}
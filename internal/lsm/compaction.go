@@ -2,14 +2,18 @@ package lsm
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // CompactionManager handles different compaction strategies for the LSM-Tree
 type CompactionManager struct {
-	lsm    *LSMTree
-	config CompactionConfig
+	lsm         *LSMTree
+	config      CompactionConfig
+	rateLimiter *RateLimiter
 }
 
 // CompactionConfig holds configuration for compaction operations
@@ -18,6 +22,9 @@ type CompactionConfig struct {
 	CompactionTimeout      time.Duration // Timeout for compaction operations
 	MinCompactionSize      int64         // Minimum size to trigger compaction
 	MaxCompactionSize      int64         // Maximum size for a single compaction
+	RateLimitBytesPerSec   int64         // Compaction write budget in bytes per second (0 = unlimited)
+	MaxSubcompactions      int           // Maximum number of concurrent key-range subcompactions
+	MinSubcompactionSize   int64         // Minimum input size handled by each subcompaction
 }
 
 // DefaultCompactionConfig returns default compaction configuration
@@ -27,14 +34,18 @@ func DefaultCompactionConfig() CompactionConfig {
 		CompactionTimeout:      30 * time.Minute,
 		MinCompactionSize:      4 * 1024 * 1024,   // 4MB
 		MaxCompactionSize:      100 * 1024 * 1024, // 100MB
+		RateLimitBytesPerSec:   0,                 // Unlimited
+		MaxSubcompactions:      4,
+		MinSubcompactionSize:   8 * 1024 * 1024, // 8MB
 	}
 }
 
 // NewCompactionManager creates a new compaction manager
 func NewCompactionManager(lsm *LSMTree, config CompactionConfig) *CompactionManager {
 	return &CompactionManager{
-		lsm:    lsm,
-		config: config,
+		lsm:         lsm,
+		config:      config,
+		rateLimiter: NewRateLimiter(config.RateLimitBytesPerSec),
 	}
 }
 
@@ -60,8 +71,9 @@ func (cm *CompactionManager) PerformLeveledCompaction(sourceLevel int) error {
 	fmt.Printf("Compacting %d SSTables from L%d with %d SSTables from L%d\n",
 		len(sourceSSTables), sourceLevel, len(targetSSTables), sourceLevel+1)
 
-	// Perform the actual compaction
-	newSSTables, err := cm.mergeSSTables(sourceSSTables, targetSSTables, sourceLevel+1)
+	// Perform the actual compaction; deletions can be dropped once nothing older remains below
+	dropDeletions := cm.isBottommostLevel(sourceLevel + 1)
+	newSSTables, err := cm.mergeSSTables(cm.newestFirst(sourceLevelData, sourceSSTables), targetSSTables, sourceLevel+1, dropDeletions)
 	if err != nil {
 		return fmt.Errorf("failed to merge SSTables: %w", err)
 	}
@@ -100,6 +112,167 @@ func (cm *CompactionManager) PerformSizeTieredCompaction(level int) error {
 	return nil
 }
 
+// DropObsoleteSSTables removes whole SSTables under the FIFO and TTL styles:
+// tables whose newest entry is older than the TTL, and the oldest tables once
+// the total size exceeds the FIFO cap. Dropped data is not merged anywhere.
+func (cm *CompactionManager) DropObsoleteSSTables() error {
+	obsolete := cm.selectObsoleteSSTables()
+	if len(obsolete) == 0 {
+		return nil
+	}
+
+	drop := make(map[*SSTable]bool, len(obsolete))
+	for _, sstable := range obsolete {
+		drop[sstable] = true
+	}
+
+	for i := range cm.lsm.levels {
+		level := &cm.lsm.levels[i]
+		kept := make([]*SSTable, 0, len(level.SSTables))
+		for _, sstable := range level.SSTables {
+			if !drop[sstable] {
+				kept = append(kept, sstable)
+			}
+		}
+		level.SSTables = kept
+	}
+
+	fmt.Printf("Dropping %d obsolete SSTables\n", len(obsolete))
+	cm.cleanupOldSSTables(obsolete)
+	cm.lsm.stats.DroppedSSTables += uint64(len(obsolete))
+
+	return nil
+}
+
+// RelocateBlobs rewrites every SSTable that points into the given blob files, moving
+// the values still referenced there into the active blob file. Each SSTable is
+// rewritten into a single SSTable at its own level. In L0, every table from the
+// first affected one onward is rewritten, oldest first, so flush order survives a reopen.
+func (cm *CompactionManager) RelocateBlobs(files map[uint64]bool) error {
+	for i := range cm.lsm.levels {
+		level := &cm.lsm.levels[i]
+
+		var selected []*SSTable
+		for _, sstable := range level.SSTables {
+			refs, err := sstable.blobReferences()
+			if err != nil {
+				return fmt.Errorf("failed to read blob references of %s: %w", sstable.ID, err)
+			}
+			references := i == 0 && len(selected) > 0
+			for number := range refs {
+				references = references || files[number]
+			}
+			if references {
+				selected = append(selected, sstable)
+			}
+		}
+
+		for _, sstable := range selected {
+			rewritten, err := cm.rewriteSSTable(sstable, i, files)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite SSTable %s: %w", sstable.ID, err)
+			}
+
+			// Replace the table in place to keep the level ordered
+			for idx, current := range level.SSTables {
+				if current == sstable {
+					tables := append(append([]*SSTable{}, level.SSTables[:idx]...), rewritten...)
+					level.SSTables = append(tables, level.SSTables[idx+1:]...)
+					break
+				}
+			}
+			cm.cleanupOldSSTables([]*SSTable{sstable})
+		}
+	}
+
+	return nil
+}
+
+// rewriteSSTable copies an SSTable into a new one at the same level, moving
+// values that point into the relocated blob files. Callers hold lsm.mu.
+func (cm *CompactionManager) rewriteSSTable(sstable *SSTable, level int, relocate map[uint64]bool) ([]*SSTable, error) {
+	tombstones := sstable.RangeTombstones()
+	job := &compactionJob{
+		inputs:           []*SSTable{sstable},
+		ages:             []int{0},
+		rangeTombstones:  [][]RangeTombstone{tombstones},
+		outputTombstones: tombstones,
+		targetLevel:      level,
+		targetFileSize:   math.MaxInt64, // A single output keeps the table's key range and position
+		relocate:         relocate,
+	}
+
+	var tables []*SSTable
+	err := cm.withTreeUnlocked(func() error {
+		var err error
+		tables, err = cm.runSubcompaction(job, keyRange{}, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cm.lsm.stats.BlobBytesRelocated += uint64(job.relocated)
+	return tables, nil
+}
+
+// selectObsoleteSSTables selects the SSTables to drop for the configured compaction style
+func (cm *CompactionManager) selectObsoleteSSTables() []*SSTable {
+	config := cm.lsm.config
+
+	switch config.CompactionStyle {
+	case TTLCompaction:
+		if config.TTL <= 0 {
+			return nil
+		}
+		cutoff := time.Now().Add(-config.TTL)
+
+		var expired []*SSTable
+		for _, level := range cm.lsm.levels {
+			for _, sstable := range level.SSTables {
+				if sstable.NewestEntryTime().Before(cutoff) {
+					expired = append(expired, sstable)
+				}
+			}
+		}
+		return expired
+
+	case FIFOCompaction:
+		if config.FIFOMaxTableFilesSize <= 0 {
+			return nil
+		}
+
+		var all []*SSTable
+		var totalSize int64
+		for _, level := range cm.lsm.levels {
+			for _, sstable := range level.SSTables {
+				all = append(all, sstable)
+				totalSize += sstable.FileSize
+			}
+		}
+		if totalSize <= config.FIFOMaxTableFilesSize {
+			return nil
+		}
+
+		// Drop oldest first; L0 is already in flush order, so keep it for ties
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].NewestEntryTime().Before(all[j].NewestEntryTime())
+		})
+
+		var dropped []*SSTable
+		for _, sstable := range all {
+			if totalSize <= config.FIFOMaxTableFilesSize {
+				break
+			}
+			dropped = append(dropped, sstable)
+			totalSize -= sstable.FileSize
+		}
+		return dropped
+	}
+
+	return nil
+}
+
 // selectSSTablesForLeveledCompaction selects SSTables for leveled compaction
 func (cm *CompactionManager) selectSSTablesForLeveledCompaction(sourceLevel, targetLevel *Level) ([]*SSTable, []*SSTable, error) {
 	if len(sourceLevel.SSTables) == 0 {
@@ -165,23 +338,37 @@ func (cm *CompactionManager) findOverlappingSSTables(sourceSSTables []*SSTable,
 		return nil
 	}
 
-	// Find min and max keys from source SSTables
-	minKey := sourceSSTables[0].metadata.MinKey
-	maxKey := sourceSSTables[0].metadata.MaxKey
+	// Find min and max keys from source SSTables, skipping tables that only hold range tombstones
+	var minKey, maxKey string
+	hasKeys := false
+	var tombstones []RangeTombstone
 
 	for _, sstable := range sourceSSTables {
-		if sstable.metadata.MinKey < minKey {
+		tombstones = append(tombstones, sstable.RangeTombstones()...)
+		if sstable.NumEntries == 0 {
+			continue
+		}
+		if !hasKeys || sstable.metadata.MinKey < minKey {
 			minKey = sstable.metadata.MinKey
 		}
-		if sstable.metadata.MaxKey > maxKey {
+		if !hasKeys || sstable.metadata.MaxKey > maxKey {
 			maxKey = sstable.metadata.MaxKey
 		}
+		hasKeys = true
 	}
 
-	// Find overlapping SSTables in target level
+	// Find overlapping SSTables in target level, including those covered by range tombstones
 	var overlapping []*SSTable
 	for _, sstable := range targetSSTables {
-		if cm.keyRangesOverlap(minKey, maxKey, sstable.metadata.MinKey, sstable.metadata.MaxKey) {
+		targetMin, targetMax := sstable.metadata.MinKey, sstable.metadata.MaxKey
+		overlaps := hasKeys && cm.keyRangesOverlap(minKey, maxKey, targetMin, targetMax)
+		for _, rt := range tombstones {
+			if overlaps {
+				break
+			}
+			overlaps = sstable.NumEntries > 0 && rt.Overlaps(targetMin, targetMax)
+		}
+		if overlaps {
 			overlapping = append(overlapping, sstable)
 		}
 	}
@@ -194,51 +381,267 @@ func (cm *CompactionManager) keyRangesOverlap(min1, max1, min2, max2 string) boo
 	return max1 >= min2 && max2 >= min1
 }
 
-// mergeSSTables merges multiple SSTables into new SSTables for the target level
-func (cm *CompactionManager) mergeSSTables(sourceSSTables, targetSSTables []*SSTable, targetLevel int) ([]*SSTable, error) {
-	// Collect all SSTables to merge
-	allSSTables := append(sourceSSTables, targetSSTables...)
+// mergeSSTables merges multiple SSTables into new SSTables for the target level.
+// Source SSTables come in age groups ordered newest first and are newer than the target SSTables.
+// Callers hold lsm.mu; it is released while the merge itself runs.
+func (cm *CompactionManager) mergeSSTables(sourceGroups [][]*SSTable, targetSSTables []*SSTable, targetLevel int, dropDeletions bool) ([]*SSTable, error) {
+	// Collect all SSTables to merge, newest first; the target level forms the oldest group
+	groups := append(append([][]*SSTable{}, sourceGroups...), targetSSTables)
+
+	var allSSTables []*SSTable
+	var ages []int
+	for age, group := range groups {
+		for _, sstable := range group {
+			allSSTables = append(allSSTables, sstable)
+			ages = append(ages, age)
+		}
+	}
 
 	if len(allSSTables) == 0 {
 		return nil, nil
 	}
 
-	// Create iterators for all SSTables
-	iterators := make([]*SSTableIterator, len(allSSTables))
+	job := &compactionJob{
+		inputs:          allSSTables,
+		ages:            ages,
+		rangeTombstones: make([][]RangeTombstone, len(allSSTables)),
+		targetLevel:     targetLevel,
+		targetFileSize:  cm.lsm.levels[targetLevel].Config.TargetFileSize,
+		dropDeletions:   dropDeletions,
+	}
 	for i, sstable := range allSSTables {
-		iterators[i] = sstable.Iterator()
+		job.rangeTombstones[i] = sstable.RangeTombstones()
+	}
+
+	// Range tombstones are still needed while older data may exist below the target level
+	if !dropDeletions {
+		job.outputTombstones = mergeRangeTombstones(job.rangeTombstones)
 	}
 
-	// Merge using k-way merge algorithm
-	mergedEntries, err := cm.kWayMerge(iterators)
+	ranges := cm.splitKeyRanges(allSSTables)
+
+	// Merge without holding the tree lock so foreground reads and writes are not blocked.
+	// Levels are only modified by compaction passes, which are serialized by compactionMu,
+	// so the inputs stay in place meanwhile.
+	var newSSTables []*SSTable
+	var duration time.Duration
+	err := cm.withTreeUnlocked(func() error {
+		start := time.Now()
+		defer func() { duration = time.Since(start) }()
+
+		var err error
+		newSSTables, err = cm.runSubcompactions(job, ranges)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to perform k-way merge: %w", err)
 	}
 
-	// Split merged entries into appropriately sized SSTables
-	newSSTables, err := cm.createSSTablesFromEntries(mergedEntries, targetLevel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create new SSTables: %w", err)
+	var bytesWritten int64
+	for _, sstable := range newSSTables {
+		bytesWritten += sstable.FileSize
 	}
 
+	stats := &cm.lsm.stats
+	stats.CompactionBytesRead += uint64(job.bytesRead)
+	stats.CompactionBytesWritten += uint64(bytesWritten)
+	stats.CompactionTime += duration
+	stats.CompactionThrottleTime += time.Duration(job.throttled)
+	stats.Subcompactions += uint64(len(ranges))
+
 	return newSSTables, nil
 }
 
-// kWayMerge performs k-way merge of multiple SSTable iterators
-func (cm *CompactionManager) kWayMerge(iterators []*SSTableIterator) ([]*SSTableEntry, error) {
+// withTreeUnlocked runs fn with lsm.mu released and re-acquires it afterwards
+func (cm *CompactionManager) withTreeUnlocked(fn func() error) error {
+	cm.lsm.mu.Unlock()
+	defer cm.lsm.mu.Lock()
+	return fn()
+}
+
+// compactionJob describes a single merge of input SSTables into a target level
+type compactionJob struct {
+	inputs           []*SSTable         // Ordered newest first
+	ages             []int              // Age group of each input; inputs of one group do not shadow each other
+	rangeTombstones  [][]RangeTombstone // Range tombstones of each input
+	outputTombstones []RangeTombstone   // Range tombstones carried into the output
+	targetLevel      int
+	targetFileSize   int64
+	dropDeletions    bool
+	relocate         map[uint64]bool // Blob files whose values are moved to the active blob file
+
+	// Updated atomically by concurrent subcompactions
+	bytesRead int64
+	throttled int64 // Nanoseconds spent waiting on the rate limiter
+	relocated int64 // Blob record bytes moved out of relocated files
+}
+
+// keyRange is the half-open key range [start, end) of a subcompaction; empty bounds are open
+type keyRange struct {
+	start, end string
+}
+
+// splitKeyRanges divides the input key space into ranges of roughly equal entry
+// counts, one per subcompaction. Small compactions are not split.
+func (cm *CompactionManager) splitKeyRanges(sstables []*SSTable) []keyRange {
+	var totalSize int64
+	var totalEntries int
+	for _, sstable := range sstables {
+		totalSize += sstable.FileSize
+		totalEntries += len(sstable.index)
+	}
+
+	n := cm.config.MaxSubcompactions
+	if cm.config.MinSubcompactionSize > 0 {
+		if bySize := int(totalSize / cm.config.MinSubcompactionSize); bySize < n {
+			n = bySize
+		}
+	}
+	if n <= 1 || totalEntries < n {
+		return []keyRange{{}}
+	}
+
+	// Sample keys at a uniform stride across all inputs so each sample stands for the same number of entries
+	stride := totalEntries / (n * 16)
+	if stride == 0 {
+		stride = 1
+	}
+	var samples []string
+	for _, sstable := range sstables {
+		for i := 0; i < len(sstable.index); i += stride {
+			samples = append(samples, sstable.index[i].Key)
+		}
+	}
+	sort.Strings(samples)
+
+	ranges := make([]keyRange, 0, n)
+	start := ""
+	for i := 1; i < n; i++ {
+		boundary := samples[len(samples)*i/n]
+		if boundary <= start {
+			continue
+		}
+		ranges = append(ranges, keyRange{start: start, end: boundary})
+		start = boundary
+	}
+	return append(ranges, keyRange{start: start})
+}
+
+// runSubcompactions merges each key range concurrently and returns the output
+// SSTables in key order. Outputs are removed again if any subcompaction fails.
+func (cm *CompactionManager) runSubcompactions(job *compactionJob, ranges []keyRange) ([]*SSTable, error) {
+	outputs := make([][]*SSTable, len(ranges))
+	errs := make([]error, len(ranges))
+
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r keyRange) {
+			defer wg.Done()
+			// Range tombstones are written once, with the last key range
+			outputs[i], errs[i] = cm.runSubcompaction(job, r, i == len(ranges)-1)
+		}(i, r)
+	}
+	wg.Wait()
+
+	var result []*SSTable
+	for _, output := range outputs {
+		result = append(result, output...)
+	}
+	for _, err := range errs {
+		if err != nil {
+			cm.cleanupOldSSTables(result)
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// runSubcompaction merges the entries of one key range into new SSTables
+func (cm *CompactionManager) runSubcompaction(job *compactionJob, r keyRange, writeTombstones bool) ([]*SSTable, error) {
+	iterators := make([]*SSTableIterator, len(job.inputs))
+	for i, sstable := range job.inputs {
+		iterators[i] = sstable.Iterator()
+		if r.start != "" {
+			iterators[i].Seek(r.start)
+		}
+	}
+
+	output := &compactionOutput{cm: cm, job: job}
+	err := cm.kWayMerge(iterators, job.ages, job.rangeTombstones, job.dropDeletions, r.end, output.add)
+
+	for _, iter := range iterators {
+		atomic.AddInt64(&job.bytesRead, iter.BytesRead())
+	}
+
+	var tombstones []RangeTombstone
+	if writeTombstones {
+		tombstones = job.outputTombstones
+	}
+	if err == nil {
+		err = output.finish(tombstones)
+	}
+	if err != nil {
+		output.abort()
+		return nil, err
+	}
+
+	return output.tables, nil
+}
+
+// mergeRangeTombstones collects the distinct range tombstones of all merged SSTables
+func mergeRangeTombstones(rangeTombstones [][]RangeTombstone) []RangeTombstone {
+	seen := make(map[RangeTombstone]bool)
+	var result []RangeTombstone
+	for _, tombstones := range rangeTombstones {
+		for _, rt := range tombstones {
+			if !seen[rt] {
+				seen[rt] = true
+				result = append(result, rt)
+			}
+		}
+	}
+	return result
+}
+
+// kWayMerge performs k-way merge of multiple SSTable iterators ordered newest first,
+// passing merged entries to emit in key order until a key reaches end (empty for no limit).
+// Only the newest version of each key is kept; entries covered by a range tombstone
+// of an iterator in a newer age group are dropped, as are deletion markers when
+// dropDeletions is set.
+func (cm *CompactionManager) kWayMerge(iterators []*SSTableIterator, ages []int, rangeTombstones [][]RangeTombstone, dropDeletions bool, end string, emit func(*SSTableEntry) error) error {
 	type iteratorItem struct {
 		iterator *SSTableIterator
 		entry    *SSTableEntry
 		index    int
 	}
 
+	// Range tombstones of newer age groups that apply to each iterator
+	newerTombstones := make([][]RangeTombstone, len(iterators))
+	for i := range iterators {
+		for j := 0; j < i; j++ {
+			if ages[j] < ages[i] {
+				newerTombstones[i] = append(newerTombstones[i], rangeTombstones[j]...)
+			}
+		}
+	}
+
+	// Order by key, then newest iterator first
+	less := func(a, b *iteratorItem) bool {
+		if a.entry.Key != b.entry.Key {
+			return a.entry.Key < b.entry.Key
+		}
+		return a.index < b.index
+	}
+
 	// Initialize priority queue with first entry from each iterator
 	heap := make([]*iteratorItem, 0, len(iterators))
 	for i, iter := range iterators {
 		if iter.HasNext() {
 			entry, err := iter.Next()
 			if err != nil {
-				return nil, err
+				return err
 			}
 			heap = append(heap, &iteratorItem{
 				iterator: iter,
@@ -250,31 +653,39 @@ func (cm *CompactionManager) kWayMerge(iterators []*SSTableIterator) ([]*SSTable
 
 	// Sort initial heap
 	sort.Slice(heap, func(i, j int) bool {
-		return heap[i].entry.Key < heap[j].entry.Key
+		return less(heap[i], heap[j])
 	})
 
-	var result []*SSTableEntry
 	var lastKey string
+	first := true
 
 	for len(heap) > 0 {
 		// Take the smallest entry
 		item := heap[0]
 		heap = heap[1:]
 
-		// Only add if this is a new key or the newest version of the key
-		if item.entry.Key != lastKey {
-			// For duplicates, take the one with highest timestamp (newest)
-			if !item.entry.Deleted {
-				result = append(result, item.entry)
+		// All remaining entries are past the end of the key range
+		if end != "" && item.entry.Key >= end {
+			break
+		}
+
+		// The first occurrence of a key comes from the newest iterator
+		if first || item.entry.Key != lastKey {
+			covered := coveredByAny(newerTombstones[item.index], item.entry.Key)
+			if !covered && !(item.entry.Deleted && dropDeletions) {
+				if err := emit(item.entry); err != nil {
+					return err
+				}
 			}
 			lastKey = item.entry.Key
+			first = false
 		}
 
 		// Get next entry from the same iterator
 		if item.iterator.HasNext() {
 			entry, err := item.iterator.Next()
 			if err != nil {
-				return nil, err
+				return err
 			}
 
 			// Insert back into heap maintaining order
@@ -286,7 +697,7 @@ func (cm *CompactionManager) kWayMerge(iterators []*SSTableIterator) ([]*SSTable
 
 			// Find insertion point to maintain sorted order
 			insertIndex := sort.Search(len(heap), func(i int) bool {
-				return heap[i].entry.Key > entry.Key
+				return less(newItem, heap[i])
 			})
 
 			// Insert at the correct position
@@ -296,62 +707,140 @@ func (cm *CompactionManager) kWayMerge(iterators []*SSTableIterator) ([]*SSTable
 		}
 	}
 
-	return result, nil
+	return nil
 }
 
-// createSSTablesFromEntries creates new SSTables from merged entries
-func (cm *CompactionManager) createSSTablesFromEntries(entries []*SSTableEntry, level int) ([]*SSTable, error) {
-	if len(entries) == 0 {
-		return nil, nil
+// compactionOutput streams merged entries into SSTables of the target file size
+type compactionOutput struct {
+	cm      *CompactionManager
+	job     *compactionJob
+	current *SSTable
+	size    int64
+	tables  []*SSTable
+}
+
+// add writes an entry, starting a new SSTable once the current one is full
+func (out *compactionOutput) add(entry *SSTableEntry) error {
+	if out.current == nil || out.size >= out.job.targetFileSize {
+		if err := out.rotate(); err != nil {
+			return err
+		}
 	}
 
-	var newSSTables []*SSTable
-	var currentSSTable *SSTable
-	var currentSize int64
-
-	targetFileSize := cm.lsm.levels[level].Config.TargetFileSize
-
-	for _, entry := range entries {
-		// Create new SSTable if needed
-		if currentSSTable == nil || currentSize >= targetFileSize {
-			if currentSSTable != nil {
-				// Finalize current SSTable
-				if err := currentSSTable.Finalize(); err != nil {
-					return nil, fmt.Errorf("failed to finalize SSTable: %w", err)
-				}
-				newSSTables = append(newSSTables, currentSSTable)
-			}
+	// Blob values are carried over as pointers unless their file is being garbage-collected
+	if entry.BlobIndex && len(out.job.relocate) > 0 {
+		if err := out.relocateBlob(entry); err != nil {
+			return err
+		}
+	}
 
-			// Create new SSTable
-			cm.lsm.nextSSTableID++
-			sstableID := fmt.Sprintf("sstable_L%d_%d", level, cm.lsm.nextSSTableID)
+	if err := out.current.putEntry(*entry); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
 
-			var err error
-			currentSSTable, err = NewSSTable(sstableID, cm.lsm.dataDir, level)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create SSTable: %w", err)
-			}
-			currentSize = 0
+	// Estimate size (simplified)
+	size := int64(len(entry.Key) + len(entry.Value) + 32) // Overhead estimate
+	out.size += size
+
+	throttled := out.cm.rateLimiter.Wait(size)
+	atomic.AddInt64(&out.job.throttled, int64(throttled))
+
+	return nil
+}
+
+// relocateBlob copies a blob value out of a file being garbage-collected and repoints the entry
+func (out *compactionOutput) relocateBlob(entry *SSTableEntry) error {
+	ref, err := decodeBlobIndex(entry.Value)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", entry.Key, err)
+	}
+	if !out.job.relocate[ref.File] {
+		return nil
+	}
+
+	blobs := out.cm.lsm.blobs
+	value, err := blobs.get(entry.Key, ref)
+	if err != nil {
+		return fmt.Errorf("failed to read blob of key %s: %w", entry.Key, err)
+	}
+	newRef, err := blobs.add(entry.Key, value)
+	if err != nil {
+		return fmt.Errorf("failed to relocate blob of key %s: %w", entry.Key, err)
+	}
+
+	entry.Value = newRef.encode()
+	atomic.AddInt64(&out.job.relocated, int64(ref.Length))
+	return nil
+}
+
+// finalize makes relocated blob values durable, then finalizes the current SSTable
+func (out *compactionOutput) finalize() error {
+	if len(out.job.relocate) > 0 {
+		if err := out.cm.lsm.blobs.sync(); err != nil {
+			return err
 		}
+	}
+	return out.current.Finalize()
+}
 
-		// Add entry to current SSTable
-		if err := currentSSTable.Put(entry.Key, entry.Value, entry.Deleted); err != nil {
-			return nil, fmt.Errorf("failed to write entry: %w", err)
+// rotate finalizes the current SSTable and opens a new one
+func (out *compactionOutput) rotate() error {
+	if out.current != nil {
+		if err := out.finalize(); err != nil {
+			return fmt.Errorf("failed to finalize SSTable: %w", err)
 		}
+		out.tables = append(out.tables, out.current)
+		out.current = nil
+	}
+
+	level := out.job.targetLevel
+	sstableID := fmt.Sprintf("sstable_L%d_%d", level, atomic.AddUint64(&out.cm.lsm.nextSSTableID, 1))
 
-		// Estimate size (simplified)
-		currentSize += int64(len(entry.Key) + len(entry.Value) + 32) // Overhead estimate
+	sstable, err := NewSSTableWithOptions(sstableID, out.cm.lsm.dataDir, level, out.cm.lsm.sstableOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create SSTable: %w", err)
 	}
+	out.current = sstable
+	out.size = 0
 
-	// Finalize last SSTable
-	if currentSSTable != nil {
-		if err := currentSSTable.Finalize(); err != nil {
-			return nil, fmt.Errorf("failed to finalize final SSTable: %w", err)
+	return nil
+}
+
+// finish writes range tombstones that must be preserved to the last SSTable and finalizes it
+func (out *compactionOutput) finish(tombstones []RangeTombstone) error {
+	if out.current == nil {
+		if len(tombstones) == 0 {
+			return nil
+		}
+		if err := out.rotate(); err != nil {
+			return err
 		}
-		newSSTables = append(newSSTables, currentSSTable)
 	}
 
-	return newSSTables, nil
+	for _, rt := range tombstones {
+		if err := out.current.AddRangeTombstone(rt); err != nil {
+			return fmt.Errorf("failed to write range tombstone: %w", err)
+		}
+	}
+
+	// Finalize last SSTable
+	if err := out.finalize(); err != nil {
+		return fmt.Errorf("failed to finalize final SSTable: %w", err)
+	}
+	out.tables = append(out.tables, out.current)
+	out.current = nil
+
+	return nil
+}
+
+// abort removes every SSTable written so far
+func (out *compactionOutput) abort() {
+	if out.current != nil {
+		out.tables = append(out.tables, out.current)
+		out.current = nil
+	}
+	out.cm.cleanupOldSSTables(out.tables)
+	out.tables = nil
 }
 
 // updateLevelsAfterCompaction updates the level structure after compaction
@@ -397,12 +886,47 @@ func (cm *CompactionManager) cleanupOldSSTables(sstables []*SSTable) {
 			fmt.Printf("Warning: failed to close SSTable %s: %v\n", sstable.ID, err)
 		}
 
-		// Remove bloom filter
-		delete(cm.lsm.bloomFilters, sstable.ID)
+		if err := sstable.removeFiles(); err != nil {
+			fmt.Printf("Warning: failed to remove SSTable %s: %v\n", sstable.ID, err)
+		}
+	}
+}
+
+// isBottommostLevel reports whether no level below the given one holds SSTables
+func (cm *CompactionManager) isBottommostLevel(level int) bool {
+	for i := level + 1; i < len(cm.lsm.levels); i++ {
+		if len(cm.lsm.levels[i].SSTables) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newestFirst groups SSTables of a level by age, newest group first. L0 SSTables are
+// appended in flush order, so each forms its own group by position in the level. Deeper
+// levels are sorted by key and every table was resolved against the range tombstones of
+// the others when it was written, so they form a single group, matching the read path.
+func (cm *CompactionManager) newestFirst(level *Level, sstables []*SSTable) [][]*SSTable {
+	if level.Level > 0 {
+		return [][]*SSTable{sstables}
+	}
 
-		// TODO: Remove SSTable files from disk
-		// This would involve deleting the .sst and .idx files
+	position := make(map[string]int, len(level.SSTables))
+	for i, sstable := range level.SSTables {
+		position[sstable.ID] = i
 	}
+
+	sorted := make([]*SSTable, len(sstables))
+	copy(sorted, sstables)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return position[sorted[i].ID] > position[sorted[j].ID]
+	})
+
+	groups := make([][]*SSTable, len(sorted))
+	for i, sstable := range sorted {
+		groups[i] = []*SSTable{sstable}
+	}
+	return groups
 }
 
 // groupSSTablesBySize groups SSTables by similar size for size-tiered compaction
@@ -470,14 +994,15 @@ func (cm *CompactionManager) compactSSTableGroup(group []*SSTable, level int) er
 
 	fmt.Printf("Size-tiered compaction: merging %d SSTables in level %d\n", len(group), level)
 
-	// Merge the group into new SSTables
-	newSSTables, err := cm.mergeSSTables(group, nil, level)
+	// Merge the group into new SSTables; deletions can only be dropped if nothing older survives
+	levelData := &cm.lsm.levels[level]
+	dropDeletions := cm.isBottommostLevel(level) && len(group) == len(levelData.SSTables)
+	newSSTables, err := cm.mergeSSTables(cm.newestFirst(levelData, group), nil, level, dropDeletions)
 	if err != nil {
 		return fmt.Errorf("failed to merge SSTable group: %w", err)
 	}
 
 	// Update level
-	levelData := &cm.lsm.levels[level]
 	levelData.SSTables = cm.removeSSTables(levelData.SSTables, group)
 	levelData.SSTables = append(levelData.SSTables, newSSTables...)
 
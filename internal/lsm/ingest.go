@@ -0,0 +1,449 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+)
+
+// ingestPendingFile lists the SSTables of an ingestion that is being installed.
+// Until it is removed the ingestion has not committed, and opening the tree
+// deletes the listed SSTables.
+const ingestPendingFile = "ingest.pending"
+
+// ingestRename moves staged files to their SSTable names; tests replace it to simulate failures
+var ingestRename = os.Rename
+
+// IngestedFile reports where an external SSTable was placed
+type IngestedFile struct {
+	Path    string // Source file
+	ID      string // SSTable ID inside the tree
+	Level   int
+	Entries uint64
+}
+
+// ingestFile is an external SSTable being ingested
+type ingestFile struct {
+	source  string
+	dir     string // Data directory holding the copy
+	staged  string // Name of the copy in the data directory before it is installed
+	span    sstableSpan
+	entries uint64
+	level   int
+	id      string
+	sstable *SSTable
+}
+
+// sstableSpan is the key range an SSTable's entries and range tombstones affect
+type sstableSpan struct {
+	minKey, maxKey string
+	hasKeys        bool
+	tombstones     []RangeTombstone
+}
+
+// spanOf returns the span of an SSTable
+func spanOf(sstable *SSTable) sstableSpan {
+	return sstableSpan{
+		minKey:     sstable.metadata.MinKey,
+		maxKey:     sstable.metadata.MaxKey,
+		hasKeys:    sstable.NumEntries > 0,
+		tombstones: sstable.RangeTombstones(),
+	}
+}
+
+// overlaps reports whether either span holds or deletes keys of the other
+func (s sstableSpan) overlaps(other sstableSpan) bool {
+	if s.hasKeys && other.hasKeys && s.minKey <= other.maxKey && other.minKey <= s.maxKey {
+		return true
+	}
+	for _, rt := range s.tombstones {
+		if other.hasKeys && rt.Overlaps(other.minKey, other.maxKey) {
+			return true
+		}
+	}
+	for _, rt := range other.tombstones {
+		if s.hasKeys && rt.Overlaps(s.minKey, s.maxKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// IngestExternalFiles adds SSTables built with SSTableWriter to the tree without
+// going through the MemTable. Ingested data is newer than everything already in
+// the tree, and files later in the list are newer than earlier ones. Each file is
+// placed at the lowest level where nothing in it or any level above overlaps it;
+// the MemTable is flushed first if it overlaps. The source files are copied, and
+// either all files become visible at once or none do, even if the process crashes.
+func (lsm *LSMTree) IngestExternalFiles(paths []string) ([]IngestedFile, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	// Validate and copy the files into the data directory without blocking the tree
+	files := make([]*ingestFile, 0, len(paths))
+	abort := func() {
+		for _, file := range files {
+			file.remove()
+		}
+	}
+	for _, path := range paths {
+		file, err := lsm.stageIngestFile(path)
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to ingest %s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+
+	lsm.compactionMu.Lock()
+	defer lsm.compactionMu.Unlock()
+	lsm.mu.Lock()
+	defer lsm.mu.Unlock()
+
+	// Buffered writes overlapping the files are older than them, so they must reach L0 first
+	for _, file := range files {
+		if lsm.memTablesOverlap(file.span) {
+			if err := lsm.flushMemTable(); err != nil {
+				abort()
+				return nil, fmt.Errorf("failed to flush MemTable: %w", err)
+			}
+			if err := lsm.flushImmutableMemTables(); err != nil {
+				abort()
+				return nil, fmt.Errorf("failed to flush MemTable: %w", err)
+			}
+			break
+		}
+	}
+
+	// Choose levels, treating files placed earlier in the batch as part of the tree
+	placed := make([][]sstableSpan, len(lsm.levels))
+	for _, file := range files {
+		file.level = lsm.ingestLevel(file.span, placed)
+		placed[file.level] = append(placed[file.level], file.span)
+	}
+
+	// Record the SSTables before any of them appears in the data directory, so
+	// that a crash while they are renamed removes all of them on the next open
+	for _, file := range files {
+		file.id = fmt.Sprintf("sstable_L%d_%d", file.level, atomic.AddUint64(&lsm.nextSSTableID, 1))
+		if err := setSSTableLevel(filepath.Join(lsm.dataDir, file.staged+".sst"), file.level); err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to ingest %s: %w", file.source, err)
+		}
+	}
+	if err := lsm.writeIngestPending(files); err != nil {
+		abort()
+		return nil, err
+	}
+	abort = func() {
+		for _, file := range files {
+			file.remove()
+		}
+		_ = os.Remove(filepath.Join(lsm.dataDir, ingestPendingFile))
+	}
+
+	for _, file := range files {
+		if err := lsm.installIngestFile(file); err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to ingest %s: %w", file.source, err)
+		}
+	}
+
+	// Removing the pending list commits every file at once
+	if err := os.Remove(filepath.Join(lsm.dataDir, ingestPendingFile)); err != nil {
+		abort()
+		return nil, fmt.Errorf("failed to commit ingestion: %w", err)
+	}
+	if err := syncDir(lsm.dataDir); err != nil {
+		// The files are installed; only their durability across a power loss is uncertain
+		fmt.Printf("Warning: failed to sync data directory after ingestion: %v\n", err)
+	}
+
+	// Every file is committed; add them to the levels in one step
+	result := make([]IngestedFile, 0, len(files))
+	for _, file := range files {
+		level := &lsm.levels[file.level]
+		level.SSTables = append(level.SSTables, file.sstable)
+		if file.level > 0 {
+			sort.Slice(level.SSTables, func(i, j int) bool {
+				return level.SSTables[i].metadata.MinKey < level.SSTables[j].metadata.MinKey
+			})
+		}
+
+		lsm.stats.IngestedFiles++
+		lsm.stats.IngestedEntries += file.entries
+		result = append(result, IngestedFile{
+			Path:    file.source,
+			ID:      file.id,
+			Level:   file.level,
+			Entries: file.entries,
+		})
+	}
+
+	// Ingesting into L0 may call for a compaction
+	lsm.scheduleCompaction()
+
+	return result, nil
+}
+
+// stageIngestFile validates an external SSTable and copies it into the data directory
+func (lsm *LSMTree) stageIngestFile(path string) (*ingestFile, error) {
+	source, err := OpenSSTableFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	id := source.ID
+
+	for i := 1; i < len(source.index); i++ {
+		if source.index[i].Key <= source.index[i-1].Key {
+			return nil, fmt.Errorf("keys are not strictly increasing at %q", source.index[i].Key)
+		}
+	}
+	refs, err := source.blobReferences()
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		return nil, fmt.Errorf("SSTable references blob files of another tree")
+	}
+
+	file := &ingestFile{
+		source:  path,
+		dir:     lsm.dataDir,
+		staged:  fmt.Sprintf("ingest_%d", atomic.AddUint64(&lsm.nextSSTableID, 1)),
+		span:    spanOf(source),
+		entries: source.NumEntries,
+	}
+	if !file.span.hasKeys && len(file.span.tombstones) == 0 {
+		return nil, fmt.Errorf("SSTable is empty")
+	}
+
+	if err := os.MkdirAll(lsm.dataDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	for _, ext := range []string{".sst", ".idx"} {
+		src := filepath.Join(filepath.Dir(path), id+ext)
+		dst := filepath.Join(lsm.dataDir, file.staged+ext)
+		if err := copyFile(src, dst); err != nil {
+			file.remove()
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// memTablesOverlap reports whether buffered writes or range deletions touch the span. Callers hold mu.
+func (lsm *LSMTree) memTablesOverlap(span sstableSpan) bool {
+	memTables := append([]*kvstore.MemTable{lsm.memTable}, lsm.immutableTables...)
+	tombstones := append([][]RangeTombstone{lsm.rangeTombstones}, lsm.immutableRangeTombstones...)
+
+	for i, memTable := range memTables {
+		buffered := sstableSpan{tombstones: tombstones[i]}
+		for _, entry := range memTable.GetAll() {
+			if !buffered.hasKeys || entry.Key < buffered.minKey {
+				buffered.minKey = entry.Key
+			}
+			if !buffered.hasKeys || entry.Key > buffered.maxKey {
+				buffered.maxKey = entry.Key
+			}
+			buffered.hasKeys = true
+		}
+		if buffered.overlaps(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// ingestLevel returns the lowest level at which nothing in that level or above
+// overlaps the span. FIFO compaction keeps every SSTable in L0. Callers hold mu.
+func (lsm *LSMTree) ingestLevel(span sstableSpan, placed [][]sstableSpan) int {
+	if lsm.config.CompactionStyle == FIFOCompaction {
+		return 0
+	}
+
+	target := 0
+	for level := range lsm.levels {
+		overlaps := false
+		for _, sstable := range lsm.levels[level].SSTables {
+			overlaps = overlaps || spanOf(sstable).overlaps(span)
+		}
+		for _, other := range placed[level] {
+			overlaps = overlaps || other.overlaps(span)
+		}
+		if overlaps {
+			break
+		}
+		target = level
+	}
+	return target
+}
+
+// installIngestFile renames a staged file to its SSTable ID and opens it. Callers hold mu.
+func (lsm *LSMTree) installIngestFile(file *ingestFile) error {
+	for _, ext := range []string{".sst", ".idx"} {
+		if err := ingestRename(filepath.Join(lsm.dataDir, file.staged+ext), filepath.Join(lsm.dataDir, file.id+ext)); err != nil {
+			return fmt.Errorf("failed to rename ingested file: %w", err)
+		}
+	}
+
+	sstable, err := OpenSSTable(file.id, lsm.dataDir)
+	if err != nil {
+		return err
+	}
+	file.sstable = sstable
+	return nil
+}
+
+// remove deletes the staged and installed copies of the file
+func (file *ingestFile) remove() {
+	if file.sstable != nil {
+		_ = file.sstable.Close()
+	}
+	for _, name := range []string{file.staged, file.id} {
+		if name == "" {
+			continue
+		}
+		for _, ext := range []string{".sst", ".idx"} {
+			_ = os.Remove(filepath.Join(file.dir, name+ext))
+		}
+	}
+}
+
+// writeIngestPending atomically writes the list of SSTables an ingestion installs
+func (lsm *LSMTree) writeIngestPending(files []*ingestFile) error {
+	ids := make([]string, len(files))
+	for i, file := range files {
+		ids[i] = file.id
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending ingestion: %w", err)
+	}
+
+	path := filepath.Join(lsm.dataDir, ingestPendingFile)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to write pending ingestion: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write pending ingestion: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to sync pending ingestion: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write pending ingestion: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write pending ingestion: %w", err)
+	}
+	return syncDir(lsm.dataDir)
+}
+
+// recoverIngest undoes an ingestion interrupted before it committed: it deletes the
+// SSTables listed as pending and any staged copies. It runs before SSTables are loaded.
+func (lsm *LSMTree) recoverIngest() error {
+	path := filepath.Join(lsm.dataDir, ingestPendingFile)
+	data, err := os.ReadFile(path) // #nosec G304 - path is safely constructed with filepath.Join
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read pending ingestion: %w", err)
+	default:
+		var ids []string
+		if err := json.Unmarshal(data, &ids); err != nil {
+			return fmt.Errorf("failed to decode pending ingestion: %w", err)
+		}
+		for _, id := range ids {
+			for _, ext := range []string{".sst", ".idx"} {
+				if err := os.Remove(filepath.Join(lsm.dataDir, id+ext)); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("failed to remove uncommitted SSTable %s: %w", id, err)
+				}
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove pending ingestion: %w", err)
+		}
+	}
+
+	// Staged copies and unfinished pending lists never belong to a committed ingestion
+	for _, pattern := range []string{"ingest_*", ingestPendingFile + ".tmp"} {
+		stale, err := filepath.Glob(filepath.Join(lsm.dataDir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, name := range stale {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// syncDir makes renames and removals in a directory durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir) // #nosec G304 - dir is the tree's data directory
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// setSSTableLevel rewrites the level stored in an SSTable header
+func setSSTableLevel(path string, level int) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to open SSTable: %w", err)
+	}
+	defer file.Close()
+
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(int32(level)))
+	if _, err := file.WriteAt(data, 4); err != nil {
+		return fmt.Errorf("failed to write SSTable level: %w", err)
+	}
+	return file.Sync()
+}
+
+// copyFile copies src to a new file at dst and syncs it
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) // #nosec G304 - src is an SSTable path given by the caller
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 - dst is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to sync %s: %w", dst, err)
+	}
+	return out.Close()
+}
@@ -12,6 +12,7 @@ import (
 // TestLSMPerformanceImprovement validates the expected performance improvements
 func TestLSMPerformanceImprovement(t *testing.T) {
 	numOperations := 1000
+	t.Setenv("MOZ_DATA_DIR", t.TempDir()) // Keep the legacy log out of the package directory
 
 	// Benchmark traditional KVStore
 	legacyDuration := benchmarkLegacyKVStore(t, numOperations)
@@ -115,6 +116,7 @@ func benchmarkLSMKVStore(t *testing.T, numOps int) time.Duration {
 // BenchmarkWritePerformance compares write performance
 func BenchmarkWritePerformance(b *testing.B) {
 	b.Run("Legacy", func(b *testing.B) {
+		b.Setenv("MOZ_DATA_DIR", b.TempDir())
 		store := kvstore.New()
 
 		b.ResetTimer()
@@ -155,6 +157,7 @@ func BenchmarkReadPerformance(b *testing.B) {
 	numKeys := 10000
 
 	b.Run("Legacy", func(b *testing.B) {
+		b.Setenv("MOZ_DATA_DIR", b.TempDir())
 		store := kvstore.New()
 
 		// Populate data
@@ -216,6 +219,7 @@ func BenchmarkReadPerformance(b *testing.B) {
 // BenchmarkConcurrentOperations tests concurrent performance
 func BenchmarkConcurrentOperations(b *testing.B) {
 	b.Run("Legacy", func(b *testing.B) {
+		b.Setenv("MOZ_DATA_DIR", b.TempDir())
 		store := kvstore.New()
 
 		b.ResetTimer()
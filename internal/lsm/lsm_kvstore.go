@@ -38,6 +38,10 @@ func DefaultLSMKVStoreConfig() LSMKVStoreConfig {
 
 // NewLSMKVStore creates a new LSM-Tree based KVStore
 func NewLSMKVStore(config LSMKVStoreConfig) (*LSMKVStore, error) {
+	if config.DataDir != "" {
+		config.LSMConfig.DataDir = config.DataDir
+	}
+
 	// Create LSM-Tree
 	lsm, err := NewLSMTree(config.LSMConfig)
 	if err != nil {
@@ -130,12 +134,140 @@ func (lkv *LSMKVStore) Delete(key string) error {
 	return nil
 }
 
+// DeleteRange removes every key in the inclusive range [start, end]
+func (lkv *LSMKVStore) DeleteRange(start, end string) error {
+	if start == "" || end == "" {
+		return fmt.Errorf("range bounds cannot be empty")
+	}
+
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	if err := lkv.lsm.DeleteRange(start, end); err != nil {
+		return fmt.Errorf("LSM delete range failed: %w", err)
+	}
+
+	// Also delete from legacy store during migration
+	if lkv.migrationMode && lkv.legacyStore != nil {
+		if err := lkv.legacyStore.DeleteRange(start, end); err != nil {
+			fmt.Printf("Warning: legacy store delete range failed: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// DeletePrefix removes every key with the given prefix
+func (lkv *LSMKVStore) DeletePrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("prefix cannot be empty")
+	}
+
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	if err := lkv.lsm.DeletePrefix(prefix); err != nil {
+		return fmt.Errorf("LSM delete prefix failed: %w", err)
+	}
+
+	// Also delete from legacy store during migration
+	if lkv.migrationMode && lkv.legacyStore != nil {
+		if err := lkv.legacyStore.DeletePrefix(prefix); err != nil {
+			fmt.Printf("Warning: legacy store delete prefix failed: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// IngestExternalFiles bulk loads SSTables built with SSTableWriter.
+// It is refused during a migration, whose verification would undo it.
+func (lkv *LSMKVStore) IngestExternalFiles(paths []string) ([]IngestedFile, error) {
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	if lkv.migrationMode {
+		return nil, fmt.Errorf("cannot ingest files while a migration is in progress")
+	}
+	return lkv.lsm.IngestExternalFiles(paths)
+}
+
+// CreateColumnFamily creates a column family with its own MemTable, SSTables and compaction settings
+func (lkv *LSMKVStore) CreateColumnFamily(name string, config LSMConfig) (*ColumnFamily, error) {
+	return lkv.lsm.CreateColumnFamily(name, config)
+}
+
+// DropColumnFamily removes a column family and deletes all of its data
+func (lkv *LSMKVStore) DropColumnFamily(name string) error {
+	return lkv.lsm.DropColumnFamily(name)
+}
+
+// ColumnFamily returns the handle of an existing column family
+func (lkv *LSMKVStore) ColumnFamily(name string) (*ColumnFamily, error) {
+	return lkv.lsm.ColumnFamily(name)
+}
+
+// ColumnFamilies returns the names of all column families
+func (lkv *LSMKVStore) ColumnFamilies() []string {
+	return lkv.lsm.ColumnFamilies()
+}
+
+// ColumnFamilyStats returns the statistics of a column family in the same form as Stats
+func (lkv *LSMKVStore) ColumnFamilyStats(name string) (map[string]interface{}, error) {
+	cf, err := lkv.lsm.ColumnFamily(name)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"lsm":           statsMap(cf.GetStats()),
+		"column_family": name,
+	}, nil
+}
+
+// Write atomically applies a batch of writes spanning column families.
+// The legacy store only holds the default family, so batches are refused during a migration.
+func (lkv *LSMKVStore) Write(batch *WriteBatch) error {
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	if lkv.migrationMode {
+		return fmt.Errorf("cannot write batches while a migration is in progress")
+	}
+	return lkv.lsm.Write(batch)
+}
+
 // List returns all keys (this is an expensive operation in LSM-Tree)
 func (lkv *LSMKVStore) List() ([]string, error) {
 	lkv.mu.RLock()
 	defer lkv.mu.RUnlock()
 
-	// Collect keys from all levels
+	keySet := lkv.lsmKeySet()
+
+	// During migration, also get keys from legacy store
+	if lkv.migrationMode && lkv.legacyStore != nil {
+		if legacyKeys, err := lkv.legacyStore.List(); err == nil {
+			for _, key := range legacyKeys {
+				keySet[key] = true
+			}
+		}
+	}
+
+	// Convert to slice and verify existence (to filter out deleted keys)
+	var result []string
+	for key := range keySet {
+		if key == kvstore.MigrationCheckpointKey {
+			continue
+		}
+		if _, err := lkv.Get(key); err == nil {
+			result = append(result, key)
+		}
+	}
+
+	return result, nil
+}
+
+// lsmKeySet collects every key present in the LSM-Tree, including deleted ones
+func (lkv *LSMKVStore) lsmKeySet() map[string]bool {
 	keySet := make(map[string]bool)
 
 	// Get keys from MemTable
@@ -165,33 +297,67 @@ func (lkv *LSMKVStore) List() ([]string, error) {
 		}
 	}
 
-	// During migration, also get keys from legacy store
+	return keySet
+}
+
+// PrefixSearch returns all keys and values with the specified prefix
+func (lkv *LSMKVStore) PrefixSearch(prefix string) (map[string]string, error) {
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	result, err := lkv.lsm.PrefixSearch(prefix)
+	if err != nil {
+		return nil, err
+	}
+	delete(result, kvstore.MigrationCheckpointKey)
+
+	// During migration, fill in keys that only exist in the legacy store
 	if lkv.migrationMode && lkv.legacyStore != nil {
-		if legacyKeys, err := lkv.legacyStore.List(); err == nil {
-			for _, key := range legacyKeys {
-				keySet[key] = true
+		if legacy, err := lkv.legacyStore.PrefixSearch(prefix); err == nil {
+			for key, value := range legacy {
+				if _, exists := result[key]; !exists {
+					result[key] = value
+				}
 			}
 		}
 	}
 
-	// Convert to slice and verify existence (to filter out deleted keys)
-	var result []string
-	for key := range keySet {
-		if _, err := lkv.Get(key); err == nil {
-			result = append(result, key)
+	return result, nil
+}
+
+// GetRange returns all keys and values with keys in [start, end]
+func (lkv *LSMKVStore) GetRange(start, end string) (map[string]string, error) {
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
+
+	result, err := lkv.lsm.GetRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	delete(result, kvstore.MigrationCheckpointKey)
+
+	// During migration, fill in keys that only exist in the legacy store
+	if lkv.migrationMode && lkv.legacyStore != nil {
+		if legacy, err := lkv.legacyStore.GetRange(start, end); err == nil {
+			for key, value := range legacy {
+				if _, exists := result[key]; !exists {
+					result[key] = value
+				}
+			}
 		}
 	}
 
 	return result, nil
 }
 
-// Compact performs compaction on the LSM-Tree
+// Compact flushes the MemTable and compacts the LSM-Tree synchronously
 func (lkv *LSMKVStore) Compact() error {
-	lkv.mu.Lock()
-	defer lkv.mu.Unlock()
+	if err := lkv.lsm.Compact(); err != nil {
+		return fmt.Errorf("failed to compact LSM-Tree: %w", err)
+	}
 
-	// Trigger LSM-Tree compaction
-	lkv.lsm.compactionCh <- struct{}{}
+	lkv.mu.RLock()
+	defer lkv.mu.RUnlock()
 
 	// Also compact legacy store if present
 	if lkv.migrationMode && lkv.legacyStore != nil {
@@ -212,25 +378,17 @@ func (lkv *LSMKVStore) Stats() map[string]interface{} {
 
 	// LSM-Tree stats
 	lsmStats := lkv.lsm.GetStats()
-	stats["lsm"] = map[string]interface{}{
-		"total_levels":         lsmStats.TotalLevels,
-		"active_sstables":      lsmStats.ActiveSSTables,
-		"memtable_flushes":     lsmStats.MemTableFlushes,
-		"compaction_count":     lsmStats.CompactionCount,
-		"bytes_read":           lsmStats.BytesRead,
-		"bytes_written":        lsmStats.BytesWritten,
-		"bloom_filter_hits":    lsmStats.BloomFilterHits,
-		"bloom_filter_misses":  lsmStats.BloomFilterMisses,
-		"avg_read_latency":     lsmStats.AvgReadLatency,
-		"avg_write_latency":    lsmStats.AvgWriteLatency,
-		"last_compaction_time": lsmStats.LastCompactionTime,
-	}
+	stats["lsm"] = statsMap(lsmStats)
 
 	// Migration status
-	stats["migration"] = map[string]interface{}{
+	migration := map[string]interface{}{
 		"migration_mode":   lkv.migrationMode,
 		"has_legacy_store": lkv.legacyStore != nil,
 	}
+	if checkpoint, err := lkv.lsm.Get(kvstore.MigrationCheckpointKey); err == nil {
+		migration["checkpoint"] = checkpoint
+	}
+	stats["migration"] = migration
 
 	// Legacy store stats if available
 	if lkv.migrationMode && lkv.legacyStore != nil {
@@ -244,6 +402,47 @@ func (lkv *LSMKVStore) Stats() map[string]interface{} {
 	return stats
 }
 
+// statsMap converts LSM-Tree statistics to the map reported by Stats
+func statsMap(lsmStats LSMStats) map[string]interface{} {
+	return map[string]interface{}{
+		"total_levels":             lsmStats.TotalLevels,
+		"active_sstables":          lsmStats.ActiveSSTables,
+		"memtable_flushes":         lsmStats.MemTableFlushes,
+		"compaction_count":         lsmStats.CompactionCount,
+		"bytes_read":               lsmStats.BytesRead,
+		"bytes_written":            lsmStats.BytesWritten,
+		"bloom_filter_hits":        lsmStats.BloomFilterHits,
+		"bloom_filter_misses":      lsmStats.BloomFilterMisses,
+		"prefix_bloom_skips":       lsmStats.PrefixBloomSkips,
+		"range_deletes":            lsmStats.RangeDeletes,
+		"dropped_sstables":         lsmStats.DroppedSSTables,
+		"avg_read_latency":         lsmStats.AvgReadLatency,
+		"avg_write_latency":        lsmStats.AvgWriteLatency,
+		"last_compaction_time":     lsmStats.LastCompactionTime,
+		"compaction_bytes_read":    lsmStats.CompactionBytesRead,
+		"compaction_bytes_written": lsmStats.CompactionBytesWritten,
+		"compaction_throughput":    lsmStats.CompactionThroughput,
+		"compaction_throttle_time": lsmStats.CompactionThrottleTime,
+		"compaction_stall_time":    lsmStats.CompactionStallTime,
+		"subcompactions":           lsmStats.Subcompactions,
+		"blob_files":               lsmStats.BlobFiles,
+		"blob_file_bytes":          lsmStats.BlobFileBytes,
+		"blob_live_bytes":          lsmStats.BlobLiveBytes,
+		"blob_bytes_written":       lsmStats.BlobBytesWritten,
+		"blob_bytes_relocated":     lsmStats.BlobBytesRelocated,
+		"blob_gc_runs":             lsmStats.BlobGCRuns,
+		"blob_files_deleted":       lsmStats.BlobFilesDeleted,
+		"ingested_files":           lsmStats.IngestedFiles,
+		"ingested_entries":         lsmStats.IngestedEntries,
+		"write_stall_time":         lsmStats.WriteStallTime,
+		"write_slowdowns":          lsmStats.WriteSlowdowns,
+		"write_stops":              lsmStats.WriteStops,
+		"column_families":          lsmStats.ColumnFamilies,
+		"wal_segments":             lsmStats.WALSegments,
+		"wal_bytes":                lsmStats.WALBytes,
+	}
+}
+
 // Close gracefully shuts down the LSM-KVStore
 func (lkv *LSMKVStore) Close() error {
 	lkv.mu.Lock()
@@ -283,6 +482,9 @@ func (lkv *LSMKVStore) CompleteMigration() error {
 	if err := lkv.migrateRemainingData(); err != nil {
 		return fmt.Errorf("failed to complete data migration: %w", err)
 	}
+	if err := kvstore.VerifyMigration(lkv.legacyStore, &lsmMigrationTarget{lkv: lkv}); err != nil {
+		return err
+	}
 
 	// Disable migration mode
 	lkv.migrationMode = false
@@ -292,6 +494,66 @@ func (lkv *LSMKVStore) CompleteMigration() error {
 	return nil
 }
 
+// AttachLegacyStore enters migration mode with the given legacy store without copying
+// any data: reads fall back to it and writes go to both stores until Migrate completes
+func (lkv *LSMKVStore) AttachLegacyStore(legacy *kvstore.KVStore) {
+	lkv.mu.Lock()
+	defer lkv.mu.Unlock()
+
+	lkv.legacyStore = legacy
+	lkv.migrationMode = true
+}
+
+// MigrationCheckpoint returns the last key copied by an unfinished migration
+func (lkv *LSMKVStore) MigrationCheckpoint() (string, bool) {
+	checkpoint, err := lkv.lsm.Get(kvstore.MigrationCheckpointKey)
+	return checkpoint, err == nil
+}
+
+// Migrate copies the legacy store into the LSM-Tree, resuming from the persisted
+// checkpoint, and verifies every key/value pair. On success the store leaves
+// migration mode and the legacy store can be retired.
+func (lkv *LSMKVStore) Migrate(opts kvstore.MigrationOptions) (kvstore.MigrationResult, error) {
+	lkv.mu.RLock()
+	legacy := lkv.legacyStore
+	lkv.mu.RUnlock()
+
+	if legacy == nil {
+		return kvstore.MigrationResult{}, fmt.Errorf("no legacy store attached")
+	}
+
+	result, err := kvstore.Migrate(legacy, &lsmMigrationTarget{lkv: lkv}, opts)
+	if err != nil {
+		return result, err
+	}
+
+	lkv.mu.Lock()
+	lkv.migrationMode = false
+	lkv.legacyStore = nil
+	lkv.mu.Unlock()
+
+	return result, nil
+}
+
+// lsmMigrationTarget exposes the LSM-Tree alone, without the legacy fallback
+type lsmMigrationTarget struct {
+	lkv *LSMKVStore
+}
+
+func (t *lsmMigrationTarget) Put(key, value string) error    { return t.lkv.lsm.Put(key, value) }
+func (t *lsmMigrationTarget) Get(key string) (string, error) { return t.lkv.lsm.Get(key) }
+func (t *lsmMigrationTarget) Delete(key string) error        { return t.lkv.lsm.Delete(key) }
+
+func (t *lsmMigrationTarget) List() ([]string, error) {
+	var keys []string
+	for key := range t.lkv.lsmKeySet() {
+		if _, err := t.lkv.lsm.Get(key); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
 // migrateExistingData migrates data from legacy store to LSM-Tree
 func (lkv *LSMKVStore) migrateExistingData() error {
 	if lkv.legacyStore == nil {
@@ -341,7 +603,10 @@ func (lkv *LSMKVStore) ForceFlush() error {
 	defer lkv.mu.Unlock()
 
 	// Flush MemTable to SSTable
-	if err := lkv.lsm.flushMemTable(); err != nil {
+	lkv.lsm.mu.Lock()
+	err := lkv.lsm.flushMemTable()
+	lkv.lsm.mu.Unlock()
+	if err != nil {
 		return fmt.Errorf("failed to flush MemTable: %w", err)
 	}
 
@@ -404,10 +669,12 @@ func (lkv *LSMKVStore) EstimateMemoryUsage() map[string]int64 {
 	}
 	usage["immutable_memtables"] = immutableSize
 
-	// Bloom filters
+	// Bloom filters (only those currently loaded from their SSTables)
 	var bloomFilterSize int64
-	for _, bf := range lkv.lsm.bloomFilters {
-		bloomFilterSize += int64(bf.MemoryUsage())
+	for _, level := range lkv.lsm.levels {
+		for _, sstable := range level.SSTables {
+			bloomFilterSize += sstable.filterMemoryUsage()
+		}
 	}
 	usage["bloom_filters"] = bloomFilterSize
 
@@ -427,6 +694,7 @@ func (lkv *LSMKVStore) GetLevelInfo() []map[string]interface{} {
 	for i, level := range lkv.lsm.levels {
 		var totalSize int64
 		var keyRange []string
+		sstables := make([]map[string]interface{}, 0, len(level.SSTables))
 
 		if len(level.SSTables) > 0 {
 			minKey := level.SSTables[0].metadata.MinKey
@@ -440,6 +708,7 @@ func (lkv *LSMKVStore) GetLevelInfo() []map[string]interface{} {
 				if sstable.metadata.MaxKey > maxKey {
 					maxKey = sstable.metadata.MaxKey
 				}
+				sstables = append(sstables, sstableInfo(sstable))
 			}
 
 			keyRange = []string{minKey, maxKey}
@@ -451,6 +720,7 @@ func (lkv *LSMKVStore) GetLevelInfo() []map[string]interface{} {
 			"total_size":    totalSize,
 			"max_size":      level.Config.MaxSize,
 			"key_range":     keyRange,
+			"sstables":      sstables,
 		}
 
 		levelInfo = append(levelInfo, info)
@@ -458,3 +728,19 @@ func (lkv *LSMKVStore) GetLevelInfo() []map[string]interface{} {
 
 	return levelInfo
 }
+
+// sstableInfo describes a single SSTable for GetLevelInfo, including its bloom filter statistics
+func sstableInfo(sstable *SSTable) map[string]interface{} {
+	info := map[string]interface{}{
+		"id":               sstable.ID,
+		"size":             sstable.FileSize,
+		"entries":          sstable.NumEntries,
+		"min_key":          sstable.metadata.MinKey,
+		"max_key":          sstable.metadata.MaxKey,
+		"range_tombstones": len(sstable.RangeTombstones()),
+	}
+	if bf := sstable.BloomFilter(); bf != nil {
+		info["bloom"] = bf.GetStats()
+	}
+	return info
+}
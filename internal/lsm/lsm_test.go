@@ -3,6 +3,11 @@ package lsm
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -112,6 +117,62 @@ func TestLSMTree_MemTableFlush(t *testing.T) {
 	}
 }
 
+func TestLSMTree_Reopen(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	lsm.Put("user:1", "alice")
+	lsm.Put("user:2", "bob")
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	lsm.Put("user:1", "alice2")
+	lsm.Delete("user:2")
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A flush holding only a range tombstone must reopen cleanly
+	lsm, err = NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM-Tree: %v", err)
+	}
+	lsm.DeletePrefix("tmp:")
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A new instance picks up the SSTables in order
+	lsm, err = NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	if value, err := lsm.Get("user:1"); err != nil || value != "alice2" {
+		t.Errorf("Expected user:1=alice2 after reopen, got %q (err=%v)", value, err)
+	}
+	if _, err := lsm.Get("user:2"); err == nil {
+		t.Error("Expected user:2 to stay deleted after reopen")
+	}
+
+	// New SSTables must not reuse the IDs of reopened ones
+	lsm.Put("user:3", "carol")
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	for _, key := range []string{"user:1", "user:3"} {
+		if _, err := lsm.Get(key); err != nil {
+			t.Errorf("Expected %s after second flush: %v", key, err)
+		}
+	}
+}
+
 func TestSSTable_BasicOperations(t *testing.T) {
 	tempDir := t.TempDir()
 	sstableID := "test_sstable"
@@ -339,6 +400,460 @@ func TestBloomFilter_Serialization(t *testing.T) {
 	}
 }
 
+func TestSSTable_BloomFilterPersistence(t *testing.T) {
+	tempDir := t.TempDir()
+	sstableID := "bloom_persistence_test"
+
+	options := DefaultSSTableOptions()
+	options.PrefixBloomLength = 5
+
+	// Create SSTable with key and prefix filters
+	{
+		sstable, err := NewSSTableWithOptions(sstableID, tempDir, 1, options)
+		if err != nil {
+			t.Fatalf("Failed to create SSTable: %v", err)
+		}
+
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("user:%03d", i)
+			if err := sstable.Put(key, fmt.Sprintf("value_%d", i), false); err != nil {
+				t.Errorf("Put failed for %s: %v", key, err)
+			}
+		}
+
+		if err := sstable.Finalize(); err != nil {
+			t.Fatalf("Failed to finalize SSTable: %v", err)
+		}
+		sstable.Close()
+	}
+
+	// Reopen and verify filters are read from the file lazily
+	sstable, err := OpenSSTable(sstableID, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to open SSTable: %v", err)
+	}
+	defer sstable.Close()
+
+	if sstable.FiltersLoaded() {
+		t.Error("Filters should not be loaded before first use")
+	}
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("user:%03d", i)
+		if !sstable.MightContain(key) {
+			t.Errorf("Persisted bloom filter should contain %s", key)
+		}
+	}
+
+	if !sstable.FiltersLoaded() {
+		t.Error("Filters should be loaded after first use")
+	}
+
+	bf := sstable.BloomFilter()
+	if bf == nil || bf.Count() != 50 {
+		t.Fatalf("Expected bloom filter with 50 items, got %v", bf)
+	}
+
+	pf, prefixLen := sstable.PrefixBloomFilter()
+	if pf == nil || prefixLen != 5 {
+		t.Fatalf("Expected prefix bloom filter of length 5, got %v (%d)", pf, prefixLen)
+	}
+
+	// Prefix checks
+	if !sstable.MightContainPrefix("user:0") {
+		t.Error("SSTable should report possible keys for prefix user:0")
+	}
+	if sstable.MightContainPrefix("admin:") {
+		t.Error("SSTable should rule out prefix admin: by key range")
+	}
+	if sstable.MightContainPrefix("user;") {
+		t.Error("SSTable should rule out prefix user; by key range")
+	}
+
+	entries, err := sstable.PrefixScan("user:01")
+	if err != nil {
+		t.Fatalf("PrefixScan failed: %v", err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("Expected 10 entries for prefix user:01, got %d", len(entries))
+	}
+}
+
+func TestLSMTree_PrefixSearch(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.MemTableConfig.MaxEntries = 5
+	config.PrefixBloomLength = 4
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := lsm.Put(fmt.Sprintf("usr:%02d", i), fmt.Sprintf("user_%d", i)); err != nil {
+			t.Errorf("Put failed: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if err := lsm.Put(fmt.Sprintf("grp:%02d", i), fmt.Sprintf("group_%d", i)); err != nil {
+			t.Errorf("Put failed: %v", err)
+		}
+	}
+
+	// Overwrite and delete a key after it has been flushed
+	if err := lsm.Put("usr:01", "updated"); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+	if err := lsm.Delete("usr:02"); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+
+	// Wait for background flush
+	time.Sleep(500 * time.Millisecond)
+
+	results, err := lsm.PrefixSearch("usr:")
+	if err != nil {
+		t.Fatalf("PrefixSearch failed: %v", err)
+	}
+
+	if len(results) != 9 {
+		t.Errorf("Expected 9 results, got %d: %v", len(results), results)
+	}
+	if results["usr:01"] != "updated" {
+		t.Errorf("Expected newest value for usr:01, got %q", results["usr:01"])
+	}
+	if _, exists := results["usr:02"]; exists {
+		t.Error("Deleted key usr:02 should not be returned")
+	}
+	for key := range results {
+		if key[:4] != "usr:" {
+			t.Errorf("Unexpected key in results: %s", key)
+		}
+	}
+
+	skips := lsm.GetStats().PrefixBloomSkips
+	if skips == 0 {
+		t.Error("Expected PrefixSearch to skip SSTables without matching keys")
+	}
+
+	// Concurrent searches only share the read lock while counting skipped SSTables
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := lsm.PrefixSearch("usr:"); err != nil {
+				t.Errorf("PrefixSearch failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := lsm.GetStats().PrefixBloomSkips; got <= skips {
+		t.Errorf("Expected concurrent searches to add to %d prefix bloom skips, got %d", skips, got)
+	}
+}
+
+func TestLSMTree_GetRange(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.MemTableConfig.MaxEntries = 5
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := lsm.Put(fmt.Sprintf("key:%02d", i), fmt.Sprintf("value_%d", i)); err != nil {
+			t.Errorf("Put failed: %v", err)
+		}
+	}
+
+	// Overwrite, delete and range-delete keys after they have been flushed
+	if err := lsm.Put("key:05", "updated"); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+	if err := lsm.Delete("key:06"); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if err := lsm.DeleteRange("key:08", "key:09"); err != nil {
+		t.Errorf("DeleteRange failed: %v", err)
+	}
+
+	// Wait for background flush
+	time.Sleep(500 * time.Millisecond)
+
+	results, err := lsm.GetRange("key:04", "key:10")
+	if err != nil {
+		t.Fatalf("GetRange failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"key:04": "value_4",
+		"key:05": "updated",
+		"key:07": "value_7",
+		"key:10": "value_10",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v, got %v", expected, results)
+	}
+}
+
+func TestLSMTree_DeleteRange(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	flush := func() {
+		lsm.mu.Lock()
+		defer lsm.mu.Unlock()
+		if err := lsm.flushMemTable(); err != nil {
+			t.Fatalf("flushMemTable failed: %v", err)
+		}
+		if err := lsm.flushImmutableMemTables(); err != nil {
+			t.Fatalf("flushImmutableMemTables failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		lsm.Put(fmt.Sprintf("tenant1:%02d", i), fmt.Sprintf("t1_%d", i))
+	}
+	for i := 0; i < 5; i++ {
+		lsm.Put(fmt.Sprintf("tenant2:%02d", i), fmt.Sprintf("t2_%d", i))
+	}
+	lsm.Put("other", "value")
+	flush()
+
+	// A prefix delete covers flushed data and keys written before it in the same MemTable
+	lsm.Put("tenant1:99", "unflushed")
+	if err := lsm.DeletePrefix("tenant1:"); err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	lsm.Put("tenant1:05", "rewritten")
+
+	if err := lsm.DeleteRange("tenant2:03", "tenant2:01"); err == nil {
+		t.Error("Expected error for inverted range")
+	}
+	if err := lsm.DeleteRange("tenant2:01", "tenant2:03"); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"tenant1:05": "rewritten",
+		"tenant2:00": "t2_0",
+		"tenant2:04": "t2_4",
+		"other":      "value",
+	}
+	verify := func(stage string) {
+		for _, key := range []string{"tenant1:00", "tenant1:09", "tenant1:99", "tenant2:01", "tenant2:03"} {
+			if _, err := lsm.Get(key); err == nil {
+				t.Errorf("%s: expected %s to be deleted", stage, key)
+			}
+		}
+		for key, value := range expected {
+			if got, err := lsm.Get(key); err != nil || got != value {
+				t.Errorf("%s: expected %s=%s, got %q (err=%v)", stage, key, value, got, err)
+			}
+		}
+
+		results, err := lsm.PrefixSearch("tenant")
+		if err != nil {
+			t.Fatalf("%s: PrefixSearch failed: %v", stage, err)
+		}
+		if len(results) != 3 {
+			t.Errorf("%s: expected 3 prefix results, got %v", stage, results)
+		}
+	}
+
+	verify("memtable")
+	flush()
+	verify("sstable")
+
+	// Range tombstones are persisted with the SSTable
+	lsm.mu.RLock()
+	newest := lsm.levels[0].SSTables[len(lsm.levels[0].SSTables)-1]
+	lsm.mu.RUnlock()
+
+	reopened, err := OpenSSTable(newest.ID, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen SSTable: %v", err)
+	}
+	if got := reopened.RangeTombstones(); len(got) != 2 {
+		t.Errorf("Expected 2 persisted range tombstones, got %v", got)
+	}
+	if !reopened.CoveredByRangeTombstone("tenant1:42") || reopened.CoveredByRangeTombstone("tenant2:04") {
+		t.Error("Reopened range tombstones cover the wrong keys")
+	}
+	reopened.Close()
+
+	// Compacting into the bottommost level drops covered data and the tombstones themselves
+	lsm.mu.Lock()
+	err = lsm.compactionManager.PerformLeveledCompaction(0)
+	lsm.mu.Unlock()
+	if err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+
+	verify("compacted")
+
+	lsm.mu.RLock()
+	var entries uint64
+	for _, sstable := range lsm.levels[1].SSTables {
+		entries += sstable.NumEntries
+		if len(sstable.RangeTombstones()) != 0 {
+			t.Errorf("Expected range tombstones to be dropped from %s", sstable.ID)
+		}
+	}
+	l0Count := len(lsm.levels[0].SSTables)
+	lsm.mu.RUnlock()
+
+	if l0Count != 0 {
+		t.Errorf("Expected L0 to be empty after compaction, got %d SSTables", l0Count)
+	}
+	if entries != uint64(len(expected)) {
+		t.Errorf("Expected %d live entries after compaction, got %d", len(expected), entries)
+	}
+	if fileExists(newest.FilePath) {
+		t.Error("Expected compacted SSTable files to be removed")
+	}
+
+	if stats := lsm.GetStats(); stats.RangeDeletes != 2 {
+		t.Errorf("Expected 2 range deletes, got %d", stats.RangeDeletes)
+	}
+}
+
+func TestLSMTree_DeleteRangeAcrossLevels(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.Compaction.MaxSubcompactions = 4
+	config.Compaction.MinSubcompactionSize = 1 // Split the output so tombstones and keys land in different SSTables
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	flush := func() {
+		lsm.mu.Lock()
+		defer lsm.mu.Unlock()
+		if err := lsm.flushMemTable(); err != nil {
+			t.Fatalf("flushMemTable failed: %v", err)
+		}
+		if err := lsm.flushImmutableMemTables(); err != nil {
+			t.Fatalf("flushImmutableMemTables failed: %v", err)
+		}
+	}
+	compact := func(level int) {
+		lsm.mu.Lock()
+		defer lsm.mu.Unlock()
+		lsm.levels[level].Config.CompactionSize = 0 // Compact regardless of the level size
+		if err := lsm.compactionManager.PerformLeveledCompaction(level); err != nil {
+			t.Fatalf("Compaction of L%d failed: %v", level, err)
+		}
+	}
+
+	// Older data in L2 keeps L1 from being the bottommost level
+	lsm.Put("b", "old")
+	lsm.Put("zzz", "bottom")
+	flush()
+	compact(0)
+	compact(1)
+
+	// The key is rewritten after the range delete, in a later L0 SSTable
+	if err := lsm.DeleteRange("a", "c"); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		lsm.Put(fmt.Sprintf("d%04d", i), "filler")
+	}
+	flush()
+	lsm.Put("b", "new")
+	flush()
+
+	verify := func(stage string) {
+		if got, err := lsm.Get("b"); err != nil || got != "new" {
+			t.Errorf("%s: expected b=new, got %q (err=%v)", stage, got, err)
+		}
+		if got, err := lsm.Get("zzz"); err != nil || got != "bottom" {
+			t.Errorf("%s: expected zzz=bottom, got %q (err=%v)", stage, got, err)
+		}
+	}
+
+	verify("L0")
+	compact(0)
+
+	lsm.mu.RLock()
+	l1 := len(lsm.levels[1].SSTables)
+	lsm.mu.RUnlock()
+	if l1 < 2 {
+		t.Fatalf("Expected several L1 SSTables, got %d", l1)
+	}
+	verify("L1")
+
+	// Tables of one level must not shadow each other with their range tombstones
+	compact(1)
+	verify("L2")
+}
+
+func TestLSMKVStore_DeleteEmptyBounds(t *testing.T) {
+	config := DefaultLSMKVStoreConfig()
+	config.DataDir = t.TempDir()
+	config.EnableMigration = false
+
+	store, err := NewLSMKVStore(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM KVStore: %v", err)
+	}
+	defer store.Close()
+
+	keys := []string{"a", "tenant:1", "zzz"}
+	for _, key := range keys {
+		if err := store.Put(key, "value"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	// Empty bounds would create tombstones covering every key, like the log engine they are rejected
+	if err := store.DeletePrefix(""); err == nil || err.Error() != "prefix cannot be empty" {
+		t.Errorf("Expected empty prefix error, got %v", err)
+	}
+	if err := store.DeleteRange("", "tenant:9"); err == nil {
+		t.Error("Expected error for empty range start")
+	}
+	if err := store.lsm.DeletePrefix(""); err == nil {
+		t.Error("Expected LSM-Tree to reject an empty prefix")
+	}
+	if err := store.lsm.DeleteRange("", "tenant:9"); err == nil {
+		t.Error("Expected LSM-Tree to reject an empty range start")
+	}
+
+	got, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, keys) {
+		t.Errorf("Expected all keys to remain, got %v", got)
+	}
+	if stats := store.lsm.GetStats(); stats.RangeDeletes != 0 {
+		t.Errorf("Expected no range deletes, got %d", stats.RangeDeletes)
+	}
+}
+
 func TestLSMKVStore_Integration(t *testing.T) {
 	tempDir := t.TempDir()
 	config := DefaultLSMKVStoreConfig()
@@ -412,6 +927,7 @@ func TestLSMKVStore_Integration(t *testing.T) {
 
 func TestLSMKVStore_Migration(t *testing.T) {
 	tempDir := t.TempDir()
+	t.Setenv("MOZ_DATA_DIR", t.TempDir()) // Legacy log location
 
 	// Create legacy data first
 	legacyStore := kvstore.New()
@@ -542,6 +1058,936 @@ func TestLSMTree_Compaction(t *testing.T) {
 	}
 }
 
+func TestLSMTree_FIFOCompaction(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.CompactionStyle = FIFOCompaction
+	config.L0MaxSSTables = 2
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	lsm.mu.Lock()
+	defer lsm.mu.Unlock()
+
+	// Write five batches, each flushed to its own L0 SSTable
+	for batch := 0; batch < 5; batch++ {
+		for i := 0; i < 10; i++ {
+			lsm.memTable.Put(fmt.Sprintf("event:%d:%02d", batch, i), "payload", 0)
+		}
+		if err := lsm.flushMemTable(); err != nil {
+			t.Fatalf("flushMemTable failed: %v", err)
+		}
+		if err := lsm.flushImmutableMemTables(); err != nil {
+			t.Fatalf("flushImmutableMemTables failed: %v", err)
+		}
+	}
+
+	// FIFO never merges SSTables, even past the L0 file limit
+	if lsm.shouldCompactLevel(0) {
+		t.Error("Expected FIFO style to skip level compaction")
+	}
+
+	// Cap the total size so only the two newest SSTables fit
+	tables := lsm.levels[0].SSTables
+	lsm.config.FIFOMaxTableFilesSize = tables[3].FileSize + tables[4].FileSize
+	oldest := tables[0]
+
+	if err := lsm.compactionManager.DropObsoleteSSTables(); err != nil {
+		t.Fatalf("DropObsoleteSSTables failed: %v", err)
+	}
+
+	if got := len(lsm.levels[0].SSTables); got != 2 {
+		t.Fatalf("Expected 2 SSTables to remain, got %d", got)
+	}
+	if lsm.levels[0].SSTables[0] != tables[3] || lsm.levels[0].SSTables[1] != tables[4] {
+		t.Error("Expected the newest SSTables to be kept")
+	}
+	if fileExists(oldest.FilePath) {
+		t.Error("Expected dropped SSTable files to be removed")
+	}
+	if lsm.stats.DroppedSSTables != 3 {
+		t.Errorf("Expected 3 dropped SSTables, got %d", lsm.stats.DroppedSSTables)
+	}
+}
+
+func TestLSMTree_TTLCompaction(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.CompactionStyle = TTLCompaction
+	config.TTL = 200 * time.Millisecond
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	flush := func() {
+		lsm.mu.Lock()
+		defer lsm.mu.Unlock()
+		if err := lsm.flushMemTable(); err != nil {
+			t.Fatalf("flushMemTable failed: %v", err)
+		}
+		if err := lsm.flushImmutableMemTables(); err != nil {
+			t.Fatalf("flushImmutableMemTables failed: %v", err)
+		}
+	}
+
+	lsm.Put("metric:old", "1")
+	flush()
+
+	// The newest entry time is persisted with the SSTable
+	lsm.mu.RLock()
+	oldTable := lsm.levels[0].SSTables[0]
+	lsm.mu.RUnlock()
+
+	reopened, err := OpenSSTable(oldTable.ID, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen SSTable: %v", err)
+	}
+	if !reopened.NewestEntryTime().Equal(oldTable.NewestEntryTime()) {
+		t.Errorf("Expected newest entry time %v, got %v", oldTable.NewestEntryTime(), reopened.NewestEntryTime())
+	}
+	reopened.Close()
+
+	time.Sleep(300 * time.Millisecond)
+	lsm.Put("metric:new", "2")
+	flush()
+
+	if !lsm.needsCompaction() {
+		t.Error("Expected expired SSTable to require compaction")
+	}
+
+	lsm.mu.Lock()
+	err = lsm.compactionManager.DropObsoleteSSTables()
+	lsm.mu.Unlock()
+	if err != nil {
+		t.Fatalf("DropObsoleteSSTables failed: %v", err)
+	}
+
+	if _, err := lsm.Get("metric:old"); err == nil {
+		t.Error("Expected expired key to be dropped")
+	}
+	if value, err := lsm.Get("metric:new"); err != nil || value != "2" {
+		t.Errorf("Expected metric:new=2, got %q (err=%v)", value, err)
+	}
+	if stats := lsm.GetStats(); stats.DroppedSSTables != 1 {
+		t.Errorf("Expected 1 dropped SSTable, got %d", stats.DroppedSSTables)
+	}
+}
+
+func TestLSMTree_Subcompactions(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.Compaction.MaxSubcompactions = 4
+	config.Compaction.MinSubcompactionSize = 1 // Split even small compactions
+	config.Compaction.RateLimitBytesPerSec = 10 * 1024 * 1024
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	lsm.mu.Lock()
+	defer lsm.mu.Unlock()
+
+	// Three overlapping L0 SSTables; later batches overwrite and delete earlier keys
+	expected := make(map[string]string)
+	for batch := 0; batch < 3; batch++ {
+		for i := batch; i < 1000; i += 2 {
+			key := fmt.Sprintf("key_%04d", i)
+			value := fmt.Sprintf("value_%d_%d", batch, i)
+			lsm.memTable.Put(key, value, 0)
+			expected[key] = value
+		}
+		if batch == 2 {
+			for i := 0; i < 1000; i += 10 {
+				key := fmt.Sprintf("key_%04d", i)
+				lsm.memTable.Delete(key, 0)
+				delete(expected, key)
+			}
+		}
+		if err := lsm.flushMemTable(); err != nil {
+			t.Fatalf("flushMemTable failed: %v", err)
+		}
+		if err := lsm.flushImmutableMemTables(); err != nil {
+			t.Fatalf("flushImmutableMemTables failed: %v", err)
+		}
+	}
+
+	if err := lsm.compactionManager.PerformLeveledCompaction(0); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+
+	// Subcompaction outputs must not overlap
+	l1 := lsm.levels[1].SSTables
+	if len(l1) < 2 {
+		t.Fatalf("Expected several L1 SSTables from subcompactions, got %d", len(l1))
+	}
+	var entries uint64
+	for i, sstable := range l1 {
+		entries += sstable.NumEntries
+		if i > 0 && sstable.metadata.MinKey <= l1[i-1].metadata.MaxKey {
+			t.Errorf("SSTables %s and %s overlap", l1[i-1].ID, sstable.ID)
+		}
+	}
+	if entries != uint64(len(expected)) {
+		t.Errorf("Expected %d entries after compaction, got %d", len(expected), entries)
+	}
+
+	lsm.mu.Unlock()
+	for key, value := range expected {
+		if got, err := lsm.Get(key); err != nil || got != value {
+			t.Errorf("Expected %s=%s, got %q (err=%v)", key, value, got, err)
+		}
+	}
+	if _, err := lsm.Get("key_0010"); err == nil {
+		t.Error("Expected deleted key to stay deleted")
+	}
+	stats := lsm.GetStats()
+	lsm.mu.Lock()
+
+	if stats.Subcompactions != 4 {
+		t.Errorf("Expected 4 subcompactions, got %d", stats.Subcompactions)
+	}
+	if stats.CompactionBytesRead == 0 || stats.CompactionBytesWritten == 0 {
+		t.Errorf("Expected compaction I/O to be recorded, got read=%d written=%d",
+			stats.CompactionBytesRead, stats.CompactionBytesWritten)
+	}
+	if stats.CompactionThroughput <= 0 {
+		t.Errorf("Expected positive compaction throughput, got %f", stats.CompactionThroughput)
+	}
+}
+
+func TestLSMTree_BlobFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.L0MaxSSTables = 1 // Compact L0 into L1 once a second SSTable is flushed
+	config.Blob.MinBlobSize = 1024
+	config.Blob.BlobFileSize = 16 * 1024 // About four values per blob file
+	config.Blob.GCLiveRatio = 0.5
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+
+	expected := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("doc_%02d", i)
+		expected[key] = fmt.Sprintf("%s:%s", key, strings.Repeat("x", 4096))
+		if err := lsm.Put(key, expected[key]); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	expected["small"] = "inline"
+	if err := lsm.Put("small", "inline"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	// Only pointers to the large values are stored in the SSTable
+	stats := lsm.GetStats()
+	if stats.BlobFiles < 5 {
+		t.Errorf("Expected at least 5 blob files, got %d", stats.BlobFiles)
+	}
+	if sstable := lsm.levels[0].SSTables[0]; sstable.FileSize > 16*1024 {
+		t.Errorf("Expected large values outside the SSTable, got %d bytes", sstable.FileSize)
+	}
+	if stats.BlobLiveBytes == 0 || stats.BlobLiveBytes != int64(stats.BlobBytesWritten) {
+		t.Errorf("Expected all %d written blob bytes to be live, got %d", stats.BlobBytesWritten, stats.BlobLiveBytes)
+	}
+
+	// Overwrite most documents with small values; compaction into L1 drops the old pointers
+	for i := 0; i < 15; i++ {
+		key := fmt.Sprintf("doc_%02d", i)
+		expected[key] = "replaced"
+		if err := lsm.Put(key, "replaced"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	stats = lsm.GetStats()
+	if stats.BlobGCRuns == 0 || stats.BlobFilesDeleted < 3 {
+		t.Errorf("Expected blob garbage collection to delete at least 3 files, got runs=%d deleted=%d",
+			stats.BlobGCRuns, stats.BlobFilesDeleted)
+	}
+	if stats.BlobBytesRelocated == 0 {
+		t.Error("Expected live values to be relocated out of a sparse blob file")
+	}
+
+	lsm.mu.RLock()
+	live, err := lsm.blobLiveBytes()
+	if err != nil {
+		t.Fatalf("blobLiveBytes failed: %v", err)
+	}
+	for _, number := range lsm.blobs.sealedFiles() {
+		if ratio := float64(live[number]) / float64(lsm.blobs.dataSize(number)); ratio < config.Blob.GCLiveRatio {
+			t.Errorf("Blob file %d left with live ratio %.2f", number, ratio)
+		}
+	}
+	lsm.mu.RUnlock()
+
+	for key, value := range expected {
+		if got, err := lsm.Get(key); err != nil || got != value {
+			t.Errorf("Expected %s to have its latest value, got %d bytes (err=%v)", key, len(got), err)
+		}
+	}
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Blob files are reopened with the SSTables
+	reopened, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM-Tree: %v", err)
+	}
+	defer reopened.Close()
+
+	for key, value := range expected {
+		if got, err := reopened.Get(key); err != nil || got != value {
+			t.Errorf("Expected %s to survive a reopen, got %d bytes (err=%v)", key, len(got), err)
+		}
+	}
+	results, err := reopened.PrefixSearch("doc_1")
+	if err != nil {
+		t.Fatalf("PrefixSearch failed: %v", err)
+	}
+	if results["doc_19"] != expected["doc_19"] {
+		t.Errorf("Expected PrefixSearch to resolve blob values, got %d bytes", len(results["doc_19"]))
+	}
+	if files := reopened.GetStats().BlobFiles; files != stats.BlobFiles {
+		t.Errorf("Expected %d blob files after reopen, got %d", stats.BlobFiles, files)
+	}
+}
+
+func TestSSTableWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewSSTableWriter(filepath.Join(dir, "bulk.sst"), DefaultSSTableOptions())
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Put("b", "2"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := writer.Put("a", "1"); err == nil {
+		t.Error("Expected out-of-order key to be rejected")
+	}
+	if err := writer.Delete("c"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := writer.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	sstable, err := OpenSSTable("bulk", dir)
+	if err != nil {
+		t.Fatalf("Failed to open written SSTable: %v", err)
+	}
+	defer sstable.Close()
+	if value, found, err := sstable.Get("b"); err != nil || !found || value != "2" {
+		t.Errorf("Expected b=2, got %q (found=%v, err=%v)", value, found, err)
+	}
+	if entry, found, _ := sstable.Lookup("c"); !found || !entry.Deleted {
+		t.Error("Expected c to be written as a deletion marker")
+	}
+
+	if _, err := NewSSTableWriter(filepath.Join(dir, "bulk.data"), DefaultSSTableOptions()); err == nil {
+		t.Error("Expected a path without .sst to be rejected")
+	}
+}
+
+func TestSSTable_Verify(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "verify.sst")
+	writer, err := NewSSTableWriter(path, DefaultSSTableOptions())
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := writer.Put(fmt.Sprintf("key_%02d", i), fmt.Sprintf("value_%02d", i)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := writer.Delete("key_99"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := writer.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+
+	sstable, err := OpenSSTableFile(path)
+	if err != nil {
+		t.Fatalf("Failed to open SSTable: %v", err)
+	}
+	report := sstable.Verify()
+	if !report.OK() || report.Entries != 21 || report.Deletions != 1 {
+		t.Errorf("Expected a clean report with 21 entries and 1 deletion, got %+v", report)
+	}
+	target := sstable.Index()[5]
+	_ = sstable.Close()
+
+	// Flip the last byte of an entry's value
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	offset := target.Offset + int64(target.Length) - 6
+	buf := make([]byte, 1)
+	if _, err := file.ReadAt(buf, offset); err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	buf[0] ^= 0xff
+	if _, err := file.WriteAt(buf, offset); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	_ = file.Close()
+
+	sstable, err = OpenSSTableFile(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen SSTable: %v", err)
+	}
+	defer sstable.Close()
+	if report := sstable.Verify(); report.OK() {
+		t.Error("Expected verification to detect the corrupted entry")
+	}
+
+	if _, err := OpenSSTableFile(filepath.Join(dir, "verify.idx")); err == nil {
+		t.Error("Expected a path without .sst to be rejected")
+	}
+}
+
+func TestLSMTree_IngestExternalFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+
+	writeFile := func(name string, pairs ...string) string {
+		return writeTestSSTable(t, sourceDir, name, pairs...)
+	}
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+
+	// m1 and m2 are flushed to L0; z1 stays in the MemTable
+	for _, key := range []string{"m1", "m2"} {
+		if err := lsm.Put(key, "old"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if err := lsm.Put("z1", "buffered"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	disjoint := writeFile("disjoint", "a1", "bulk", "a2", "bulk")
+	overlapping := writeFile("overlapping", "m1", "new", "m5", "new")
+	buffered := writeFile("buffered", "z0", "new", "z1", "new")
+
+	ingested, err := lsm.IngestExternalFiles([]string{disjoint, overlapping, buffered})
+	if err != nil {
+		t.Fatalf("IngestExternalFiles failed: %v", err)
+	}
+	levels := []int{config.NumLevels - 1, 0, 0}
+	for i, file := range ingested {
+		if file.Level != levels[i] {
+			t.Errorf("Expected %s at L%d, got L%d", file.Path, levels[i], file.Level)
+		}
+	}
+
+	expected := map[string]string{
+		"a1": "bulk", "a2": "bulk",
+		"m1": "new", "m2": "old", "m5": "new",
+		"z0": "new", "z1": "new",
+	}
+	check := func(tree *LSMTree) {
+		t.Helper()
+		for key, value := range expected {
+			if got, err := tree.Get(key); err != nil || got != value {
+				t.Errorf("Expected %s=%s, got %q (err=%v)", key, value, got, err)
+			}
+		}
+	}
+	check(lsm)
+
+	if stats := lsm.GetStats(); stats.IngestedFiles != 3 || stats.IngestedEntries != 6 {
+		t.Errorf("Expected 3 ingested files with 6 entries, got %d files with %d entries",
+			stats.IngestedFiles, stats.IngestedEntries)
+	}
+	if _, err := os.Stat(disjoint); err != nil {
+		t.Errorf("Expected source files to be kept: %v", err)
+	}
+
+	// A failing file leaves the tree and data directory untouched
+	extra := writeFile("extra", "b1", "bulk")
+	if _, err := lsm.IngestExternalFiles([]string{extra, filepath.Join(sourceDir, "missing.sst")}); err == nil {
+		t.Error("Expected ingestion of a missing file to fail")
+	}
+	if _, err := lsm.Get("b1"); err == nil {
+		t.Error("Expected no file of a failed ingestion to be visible")
+	}
+	if staged, _ := filepath.Glob(filepath.Join(tempDir, "ingest_*")); len(staged) > 0 {
+		t.Errorf("Expected staged copies to be removed, found %v", staged)
+	}
+
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Levels and L0 order survive a reopen
+	reopened, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM-Tree: %v", err)
+	}
+	defer reopened.Close()
+	check(reopened)
+}
+
+func TestLSMTree_IngestInterrupted(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+	if err := lsm.Put("existing", "value"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	paths := []string{
+		writeTestSSTable(t, sourceDir, "first", "a1", "bulk"),
+		writeTestSSTable(t, sourceDir, "second", "b1", "bulk"),
+		writeTestSSTable(t, sourceDir, "third", "c1", "bulk"),
+	}
+
+	// Fail once the first file is installed, keeping a copy of the directory as a crash would leave it
+	crashDir := filepath.Join(t.TempDir(), "crash")
+	renames := 0
+	ingestRename = func(from, to string) error {
+		if renames == 2 {
+			copyTestDir(t, tempDir, crashDir)
+			return fmt.Errorf("simulated failure")
+		}
+		renames++
+		return os.Rename(from, to)
+	}
+	defer func() { ingestRename = os.Rename }()
+
+	if _, err := lsm.IngestExternalFiles(paths); err == nil {
+		t.Fatal("Expected the interrupted ingestion to fail")
+	}
+	ingestRename = os.Rename
+
+	check := func(stage string, tree *LSMTree, dir string) {
+		t.Helper()
+		for _, key := range []string{"a1", "b1", "c1"} {
+			if _, err := tree.Get(key); err == nil {
+				t.Errorf("%s: expected %s of the interrupted ingestion to be invisible", stage, key)
+			}
+		}
+		if value, err := tree.Get("existing"); err != nil || value != "value" {
+			t.Errorf("%s: expected existing=value, got %q (err=%v)", stage, value, err)
+		}
+		for _, pattern := range []string{"ingest*", "sstable_L*"} {
+			if leftover, _ := filepath.Glob(filepath.Join(dir, pattern)); len(leftover) > 0 {
+				t.Errorf("%s: expected no files of the ingestion to remain, found %v", stage, leftover)
+			}
+		}
+	}
+	check("failed", lsm, tempDir)
+
+	// The crashed directory had the first file installed and the pending list in place
+	if installed, _ := filepath.Glob(filepath.Join(crashDir, "sstable_L*.sst")); len(installed) != 1 {
+		t.Fatalf("Expected the crash copy to hold 1 installed file, got %v", installed)
+	}
+	crashConfig := config
+	crashConfig.DataDir = crashDir
+	recovered, err := NewLSMTree(crashConfig)
+	if err != nil {
+		t.Fatalf("Failed to recover LSM-Tree: %v", err)
+	}
+	defer recovered.Close()
+	check("recovered", recovered, crashDir)
+
+	// The tree accepts the same files again
+	if _, err := recovered.IngestExternalFiles(paths); err != nil {
+		t.Fatalf("IngestExternalFiles failed: %v", err)
+	}
+	for _, key := range []string{"a1", "b1", "c1"} {
+		if value, err := recovered.Get(key); err != nil || value != "bulk" {
+			t.Errorf("Expected %s=bulk, got %q (err=%v)", key, value, err)
+		}
+	}
+}
+
+func TestLSMTree_WriteStalls(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.L0MaxSSTables = 10 // Keep flushed SSTables in L0
+	config.WriteStall = WriteStallConfig{
+		ImmutableStopTrigger: 1,
+		L0SlowdownTrigger:    1,
+		SlowdownDelay:        20 * time.Millisecond,
+	}
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	defer lsm.Close()
+
+	// Hold back the compaction worker while a MemTable waits for its flush
+	lsm.compactionMu.Lock()
+	lsm.mu.Lock()
+	lsm.memTable.Put("queued", "value", 0)
+	if err := lsm.flushMemTable(); err != nil {
+		t.Fatalf("flushMemTable failed: %v", err)
+	}
+	lsm.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() { done <- lsm.Put("blocked", "value") }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the write to block until the flush, got err=%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lsm.compactionMu.Unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the write to resume after the flush")
+	}
+
+	stats := lsm.GetStats()
+	if stats.WriteStops != 1 || stats.WriteStallTime < 50*time.Millisecond {
+		t.Errorf("Expected one write stop of at least 50ms, got stops=%d stall=%v", stats.WriteStops, stats.WriteStallTime)
+	}
+
+	// The flushed SSTable in L0 now slows down each write
+	start := time.Now()
+	if err := lsm.Put("slowed", "value"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < config.WriteStall.SlowdownDelay {
+		t.Errorf("Expected the write to be delayed by %v, took %v", config.WriteStall.SlowdownDelay, elapsed)
+	}
+	if stats := lsm.GetStats(); stats.WriteSlowdowns == 0 {
+		t.Error("Expected a delayed write to be counted")
+	}
+
+	for _, key := range []string{"queued", "blocked", "slowed"} {
+		if _, err := lsm.Get(key); err != nil {
+			t.Errorf("Expected %s to be readable: %v", key, err)
+		}
+	}
+}
+
+func TestLSMTree_ColumnFamilies(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+
+	cacheConfig := DefaultLSMConfig()
+	cacheConfig.CompactionStyle = FIFOCompaction
+	cache, err := lsm.CreateColumnFamily("cache", cacheConfig)
+	if err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	if _, err := lsm.CreateColumnFamily("cache", cacheConfig); err == nil {
+		t.Error("Expected a duplicate column family to be rejected")
+	}
+	if _, err := lsm.CreateColumnFamily("../escape", cacheConfig); err == nil {
+		t.Error("Expected an invalid column family name to be rejected")
+	}
+
+	// Families are separate keyspaces
+	if err := lsm.Put("user", "durable"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := cache.Put("user", "cached"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if value, err := lsm.Get("user"); err != nil || value != "durable" {
+		t.Errorf("Expected default user=durable, got %q (err=%v)", value, err)
+	}
+	if value, err := cache.Get("user"); err != nil || value != "cached" {
+		t.Errorf("Expected cache user=cached, got %q (err=%v)", value, err)
+	}
+
+	// A batch spans families
+	batch := NewWriteBatch()
+	batch.Put(DefaultColumnFamily, "order:1", "placed")
+	batch.Put("cache", "order:1", "hot")
+	batch.Put("cache", "order:2", "hot")
+	batch.Delete("cache", "user")
+	if err := lsm.Write(batch); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	missing := NewWriteBatch()
+	missing.Put("nope", "k", "v")
+	if err := lsm.Write(missing); err == nil {
+		t.Error("Expected a batch for an unknown column family to fail")
+	}
+
+	iter, err := cache.NewIterator("order:")
+	if err != nil {
+		t.Fatalf("NewIterator failed: %v", err)
+	}
+	var keys []string
+	for iter.HasNext() {
+		key, value := iter.Next()
+		if value != "hot" {
+			t.Errorf("Expected %s=hot, got %q", key, value)
+		}
+		keys = append(keys, key)
+	}
+	if strings.Join(keys, ",") != "order:1,order:2" {
+		t.Errorf("Expected order:1,order:2, got %v", keys)
+	}
+	if _, err := cache.Get("user"); err == nil {
+		t.Error("Expected cache user to be deleted by the batch")
+	}
+
+	// Unflushed writes survive a crash: open a copy of the directory taken while the tree is running
+	crashDir := filepath.Join(t.TempDir(), "crash")
+	copyTestDir(t, tempDir, crashDir)
+	crashConfig := config
+	crashConfig.DataDir = crashDir
+	recovered, err := NewLSMTree(crashConfig)
+	if err != nil {
+		t.Fatalf("Failed to recover LSM-Tree: %v", err)
+	}
+	recoveredCache, err := recovered.ColumnFamily("cache")
+	if err != nil {
+		t.Fatalf("Expected the column family to be recovered: %v", err)
+	}
+	if value, err := recovered.Get("order:1"); err != nil || value != "placed" {
+		t.Errorf("Expected recovered order:1=placed, got %q (err=%v)", value, err)
+	}
+	if value, err := recoveredCache.Get("order:2"); err != nil || value != "hot" {
+		t.Errorf("Expected recovered cache order:2=hot, got %q (err=%v)", value, err)
+	}
+	if _, err := recoveredCache.Get("user"); err == nil {
+		t.Error("Expected the recovered batch to delete cache user")
+	}
+	if err := recovered.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Dropping removes the data and invalidates handles
+	if err := lsm.DropColumnFamily(DefaultColumnFamily); err == nil {
+		t.Error("Expected dropping the default column family to fail")
+	}
+	if _, err := lsm.CreateColumnFamily("scratch", DefaultLSMConfig()); err != nil {
+		t.Fatalf("CreateColumnFamily failed: %v", err)
+	}
+	if err := lsm.DropColumnFamily("scratch"); err != nil {
+		t.Fatalf("DropColumnFamily failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "families", "scratch")); !os.IsNotExist(err) {
+		t.Errorf("Expected the dropped column family's directory to be removed: %v", err)
+	}
+	if names := lsm.ColumnFamilies(); strings.Join(names, ",") != "cache,default" {
+		t.Errorf("Expected column families cache,default, got %v", names)
+	}
+
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(tempDir, "wal_*.log")); len(segments) > 0 {
+		t.Errorf("Expected a clean close to remove the WAL, found %v", segments)
+	}
+
+	// Column families and their data survive a reopen
+	reopened, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM-Tree: %v", err)
+	}
+	defer reopened.Close()
+	cache, err = reopened.ColumnFamily("cache")
+	if err != nil {
+		t.Fatalf("Expected the column family to be reopened: %v", err)
+	}
+	if value, err := cache.Get("order:1"); err != nil || value != "hot" {
+		t.Errorf("Expected cache order:1=hot, got %q (err=%v)", value, err)
+	}
+	if stats := cache.GetStats(); stats.ActiveSSTables == 0 {
+		t.Error("Expected the column family to have its own SSTables")
+	}
+	if _, err := reopened.ColumnFamily("scratch"); err == nil {
+		t.Error("Expected the dropped column family to stay dropped")
+	}
+}
+
+func TestLSMTree_WALRecovery(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultLSMConfig()
+	config.DataDir = tempDir
+	config.WAL.SegmentSize = 256 // Rotate often
+
+	lsm, err := NewLSMTree(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM-Tree: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if err := lsm.Put(fmt.Sprintf("key_%02d", i), fmt.Sprintf("value_%02d", i)); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := lsm.DeletePrefix("key_4"); err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+
+	crashDir := filepath.Join(t.TempDir(), "crash")
+	copyTestDir(t, tempDir, crashDir)
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Tear the last record, as a crash in the middle of a write would
+	segments, _ := filepath.Glob(filepath.Join(crashDir, "wal_*.log"))
+	if len(segments) < 2 {
+		t.Fatalf("Expected the WAL to rotate, found %v", segments)
+	}
+	file, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Failed to open WAL segment: %v", err)
+	}
+	if _, err := file.Write([]byte{0x10, 0, 0, 0, 1, 2}); err != nil {
+		t.Fatalf("Failed to write WAL segment: %v", err)
+	}
+	_ = file.Close()
+
+	crashConfig := config
+	crashConfig.DataDir = crashDir
+	recovered, err := NewLSMTree(crashConfig)
+	if err != nil {
+		t.Fatalf("Failed to recover LSM-Tree: %v", err)
+	}
+	defer recovered.Close()
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key_%02d", i)
+		value, err := recovered.Get(key)
+		if i >= 40 {
+			if err == nil {
+				t.Errorf("Expected %s to stay deleted, got %q", key, value)
+			}
+		} else if err != nil || value != fmt.Sprintf("value_%02d", i) {
+			t.Errorf("Expected %s to be recovered, got %q (err=%v)", key, value, err)
+		}
+	}
+
+	// The recovered writes were flushed, so only the new active segment remains
+	if segments, _ := filepath.Glob(filepath.Join(crashDir, "wal_*.log")); len(segments) != 1 {
+		t.Errorf("Expected one WAL segment after recovery, found %v", segments)
+	}
+}
+
+// copyTestDir copies a directory tree, as a snapshot of the files a crashed process leaves behind
+// writeTestSSTable writes an external SSTable with the given keys and values
+func writeTestSSTable(t *testing.T, dir, name string, pairs ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".sst")
+	writer, err := NewSSTableWriter(path, DefaultSSTableOptions())
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if err := writer.Put(pairs[i], pairs[i+1]); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	if err := writer.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	return path
+}
+
+func copyTestDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0750)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0600)
+	})
+	if err != nil {
+		t.Fatalf("Failed to copy %s: %v", src, err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	// A disabled limiter never blocks
+	if wait := NewRateLimiter(0).Wait(1 << 30); wait != 0 {
+		t.Errorf("Expected unlimited rate limiter not to wait, got %v", wait)
+	}
+
+	rl := NewRateLimiter(1000)
+
+	// The first second of budget is available immediately
+	if wait := rl.Wait(1000); wait > 10*time.Millisecond {
+		t.Errorf("Expected burst to pass without waiting, got %v", wait)
+	}
+
+	// Overdrawing the budget throttles the caller
+	start := time.Now()
+	rl.Wait(100)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected throttling of about 100ms, got %v", elapsed)
+	}
+}
+
 // Helper function to verify file exists
 func fileExists(filename string) bool {
 	_, err := os.Stat(filename)
@@ -2,7 +2,13 @@ package lsm
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nyasuto/moz/internal/kvstore"
@@ -18,11 +24,25 @@ type LSMTree struct {
 	// L0: Immutable in-memory tables (ready for flush)
 	immutableTables []*kvstore.MemTable
 
+	// Range tombstones written alongside the active and immutable MemTables.
+	// They cover keys in older tables only and are flushed with their MemTable.
+	rangeTombstones          []RangeTombstone
+	immutableRangeTombstones [][]RangeTombstone
+
+	// WAL records held by the active and immutable MemTables
+	memTableLSNs  lsnRange
+	immutableLSNs []lsnRange
+
+	// Column families share one write-ahead log; familyID identifies this tree's family in it
+	families *columnFamilySet
+	familyID uint32
+
 	// L1-LN: Hierarchical SSTables on disk
+	// Each SSTable carries its own bloom filters for fast negative lookups
 	levels []Level
 
-	// Bloom filters for fast negative lookups
-	bloomFilters map[string]*BloomFilter
+	// Blob files holding values separated from the SSTables
+	blobs *blobStore
 
 	// Configuration and state
 	config        LSMConfig
@@ -30,12 +50,21 @@ type LSMTree struct {
 	nextSSTableID uint64
 
 	// Background processes
-	compactionCh chan struct{}
-	stopCh       chan struct{}
-	wg           sync.WaitGroup
+	compactionManager *CompactionManager
+	compactionMu      sync.Mutex // Serializes compaction passes, which merge without holding mu
+	compactionCh      chan struct{}
+	compactionPasses  uint64     // Completed compaction passes, guarded by mu
+	stallCond         *sync.Cond // Signalled on mu when a compaction pass completes
+	stopCh            chan struct{}
+	wg                sync.WaitGroup
 
 	// Statistics
 	stats LSMStats
+
+	// Read-path counters, updated atomically since readers only hold mu.RLock
+	bloomFilterHits   uint64
+	bloomFilterMisses uint64
+	prefixBloomSkips  uint64
 }
 
 // Level represents a single level in the LSM-Tree hierarchy
@@ -63,6 +92,31 @@ type LSMConfig struct {
 	LevelSizeRatio  int     // Size ratio between levels (default: 10)
 	BloomFilterFPR  float64 // False positive rate (default: 0.01)
 	CompactionStyle CompactionStyle
+
+	// PrefixBloomLength enables per-SSTable prefix bloom filters over the first
+	// N bytes of each key so PrefixSearch can skip tables (0 disables them)
+	PrefixBloomLength int
+
+	// FIFOMaxTableFilesSize caps the total SSTable size under FIFOCompaction;
+	// the oldest SSTables are dropped once it is exceeded (0 disables the cap)
+	FIFOMaxTableFilesSize int64
+
+	// TTL is the retention period under TTLCompaction; SSTables whose newest
+	// entry is older than it are dropped (0 disables expiry)
+	TTL time.Duration
+
+	// Compaction controls compaction I/O rate limiting and subcompactions
+	Compaction CompactionConfig
+
+	// Blob controls key-value separation of large values into blob files
+	Blob BlobConfig
+
+	// WriteStall controls how writers are slowed down or stopped when flushes
+	// and L0 compaction fall behind
+	WriteStall WriteStallConfig
+
+	// WAL controls the write-ahead log shared by all column families
+	WAL WALConfig
 }
 
 // CompactionStyle defines the compaction strategy
@@ -72,6 +126,12 @@ const (
 	SizeTieredCompaction CompactionStyle = iota
 	LeveledCompaction
 	HybridCompaction
+	// FIFOCompaction keeps all SSTables in L0 without merging them and drops
+	// the oldest ones when FIFOMaxTableFilesSize is exceeded
+	FIFOCompaction
+	// TTLCompaction drops SSTables whose data has outlived TTL and compacts
+	// the remaining ones like LeveledCompaction
+	TTLCompaction
 )
 
 // LSMStats holds statistics about LSM-Tree operations
@@ -82,11 +142,46 @@ type LSMStats struct {
 	BytesWritten       uint64
 	BloomFilterHits    uint64
 	BloomFilterMisses  uint64
+	PrefixBloomSkips   uint64
+	RangeDeletes       uint64
+	DroppedSSTables    uint64
 	AvgReadLatency     time.Duration
 	AvgWriteLatency    time.Duration
 	LastCompactionTime time.Time
 	TotalLevels        int
 	ActiveSSTables     int
+
+	// Compaction I/O
+	CompactionBytesRead    uint64
+	CompactionBytesWritten uint64
+	CompactionTime         time.Duration // Time spent merging
+	CompactionThroughput   float64       // Bytes written per second of merge time
+	CompactionThrottleTime time.Duration // Time merges waited on the rate limiter
+	CompactionStallTime    time.Duration // Time compaction blocked foreground operations
+	Subcompactions         uint64
+
+	// Blob files
+	BlobFiles          int
+	BlobFileBytes      int64  // Total size of all blob files
+	BlobLiveBytes      int64  // Blob record bytes still referenced by SSTables
+	BlobBytesWritten   uint64 // Blob record bytes written, including relocations
+	BlobBytesRelocated uint64 // Blob record bytes moved out of sparse files by garbage collection
+	BlobGCRuns         uint64
+	BlobFilesDeleted   uint64
+
+	// Bulk ingestion
+	IngestedFiles   uint64
+	IngestedEntries uint64
+
+	// Write stalls
+	WriteStallTime time.Duration // Time writers were delayed or blocked
+	WriteSlowdowns uint64        // Writes delayed by the slowdown triggers
+	WriteStops     uint64        // Writes blocked by the stop triggers
+
+	// Column families and their shared write-ahead log
+	ColumnFamilies int
+	WALSegments    int
+	WALBytes       int64
 }
 
 // DefaultLSMConfig returns a default LSM-Tree configuration
@@ -99,27 +194,61 @@ func DefaultLSMConfig() LSMConfig {
 		LevelSizeRatio:  10,
 		BloomFilterFPR:  0.01, // 1% false positive rate
 		CompactionStyle: LeveledCompaction,
+		Compaction:      DefaultCompactionConfig(),
+		Blob:            DefaultBlobConfig(),
+		WriteStall:      DefaultWriteStallConfig(),
+		WAL:             DefaultWALConfig(),
 	}
 }
 
-// NewLSMTree creates a new LSM-Tree instance
+// NewLSMTree creates a new LSM-Tree instance. Column families created earlier are
+// reopened and writes not yet flushed to SSTables are recovered from the WAL.
 func NewLSMTree(config LSMConfig) (*LSMTree, error) {
 	if config.DataDir == "" {
 		config.DataDir = "data/lsm"
 	}
+	if config.WAL == (WALConfig{}) {
+		config.WAL = DefaultWALConfig()
+	}
+
+	families := &columnFamilySet{dir: config.DataDir}
+	lsm, err := openLSMTree(config, families, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := families.open(lsm, config.WAL); err != nil {
+		_ = lsm.Close()
+		return nil, fmt.Errorf("failed to open column families: %w", err)
+	}
+
+	return lsm, nil
+}
+
+// openLSMTree opens the tree of one column family
+func openLSMTree(config LSMConfig, families *columnFamilySet, familyID uint32) (*LSMTree, error) {
+	if config.WriteStall == (WriteStallConfig{}) {
+		config.WriteStall = DefaultWriteStallConfig()
+	}
 
 	lsm := &LSMTree{
 		config:       config,
 		dataDir:      config.DataDir,
 		levels:       make([]Level, config.NumLevels),
-		bloomFilters: make(map[string]*BloomFilter),
 		compactionCh: make(chan struct{}, 1),
 		stopCh:       make(chan struct{}),
+		families:     families,
+		familyID:     familyID,
 	}
+	lsm.stallCond = sync.NewCond(&lsm.mu)
 
 	// Initialize MemTable
 	memTable := kvstore.NewMemTable(config.MemTableConfig)
 	lsm.memTable = memTable
+	compactionConfig := config.Compaction
+	if compactionConfig == (CompactionConfig{}) {
+		compactionConfig = DefaultCompactionConfig()
+	}
+	lsm.compactionManager = NewCompactionManager(lsm, compactionConfig)
 
 	// Initialize levels with appropriate configurations
 	for i := range lsm.levels {
@@ -130,6 +259,18 @@ func NewLSMTree(config LSMConfig) (*LSMTree, error) {
 		}
 	}
 
+	// Reopen blob files and SSTables written by a previous instance
+	blobs, err := openBlobStore(lsm.dataDir, config.Blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob files: %w", err)
+	}
+	lsm.blobs = blobs
+
+	if err := lsm.loadSSTables(); err != nil {
+		_ = blobs.close()
+		return nil, fmt.Errorf("failed to load SSTables: %w", err)
+	}
+
 	// Start background compaction process
 	lsm.wg.Add(1)
 	go lsm.compactionWorker()
@@ -137,6 +278,80 @@ func NewLSMTree(config LSMConfig) (*LSMTree, error) {
 	return lsm, nil
 }
 
+// loadSSTables reopens the SSTables found in the data directory, after undoing an
+// interrupted ingestion.
+// L0 tables are ordered oldest first by sequence number, other levels by key range.
+func (lsm *LSMTree) loadSSTables() error {
+	// SSTables of an ingestion that did not commit must not become visible
+	if err := lsm.recoverIngest(); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(lsm.dataDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	sequences := make(map[*SSTable]uint64)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".sst" {
+			continue
+		}
+
+		id := strings.TrimSuffix(name, ".sst")
+		seq, ok := sstableSequence(id)
+		if !ok {
+			continue
+		}
+
+		sstable, err := OpenSSTable(id, lsm.dataDir)
+		if err != nil {
+			// Tables left incomplete by an interrupted flush or compaction are skipped
+			fmt.Printf("Warning: skipping unreadable SSTable %s: %v\n", id, err)
+			continue
+		}
+
+		level := sstable.Level
+		if level < 0 || level >= len(lsm.levels) {
+			level = len(lsm.levels) - 1
+		}
+		lsm.levels[level].SSTables = append(lsm.levels[level].SSTables, sstable)
+		sequences[sstable] = seq
+
+		if seq > lsm.nextSSTableID {
+			lsm.nextSSTableID = seq
+		}
+	}
+
+	for i := range lsm.levels {
+		tables := lsm.levels[i].SSTables
+		if i == 0 {
+			sort.Slice(tables, func(a, b int) bool {
+				return sequences[tables[a]] < sequences[tables[b]]
+			})
+		} else {
+			sort.Slice(tables, func(a, b int) bool {
+				return tables[a].metadata.MinKey < tables[b].metadata.MinKey
+			})
+		}
+	}
+
+	return nil
+}
+
+// sstableSequence extracts the sequence number from SSTable IDs such as sstable_12 or sstable_L1_12
+func sstableSequence(id string) (uint64, bool) {
+	if !strings.HasPrefix(id, "sstable_") {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(id[strings.LastIndex(id, "_")+1:], 10, 64)
+	return seq, err == nil
+}
+
 // calculateLevelConfig calculates configuration for a specific level
 func (lsm *LSMTree) calculateLevelConfig(level int) LevelConfig {
 	if level == 0 {
@@ -177,17 +392,7 @@ func (lsm *LSMTree) Put(key, value string) error {
 	lsm.mu.Lock()
 	defer lsm.mu.Unlock()
 
-	// Check if MemTable needs to be flushed
-	if lsm.memTable.ShouldFlush(lsm.config.MemTableConfig) {
-		if err := lsm.flushMemTable(); err != nil {
-			return fmt.Errorf("failed to flush MemTable: %w", err)
-		}
-	}
-
-	// Write to active MemTable
-	lsm.memTable.Put(key, value, 0) // LSN will be handled by WAL integration
-
-	return nil
+	return lsm.write(walOp{kind: walPut, key: key, value: value})
 }
 
 // Get retrieves a value for a key from the LSM-Tree
@@ -201,14 +406,26 @@ func (lsm *LSMTree) Get(key string) (string, error) {
 	defer lsm.mu.RUnlock()
 
 	// 1. Check active MemTable first
-	if value, found := lsm.memTable.Get(key); found {
-		return value, nil
+	if entry, found := lsm.memTable.Lookup(key); found {
+		if entry.Deleted {
+			return "", keyNotFound(key)
+		}
+		return entry.Value, nil
+	}
+	if coveredByAny(lsm.rangeTombstones, key) {
+		return "", keyNotFound(key)
 	}
 
 	// 2. Check immutable MemTables
 	for i := len(lsm.immutableTables) - 1; i >= 0; i-- {
-		if value, found := lsm.immutableTables[i].Get(key); found {
-			return value, nil
+		if entry, found := lsm.immutableTables[i].Lookup(key); found {
+			if entry.Deleted {
+				return "", keyNotFound(key)
+			}
+			return entry.Value, nil
+		}
+		if coveredByAny(lsm.immutableRangeTombstones[i], key) {
+			return "", keyNotFound(key)
 		}
 	}
 
@@ -221,29 +438,50 @@ func (lsm *LSMTree) Get(key string) (string, error) {
 			for i := len(level.SSTables) - 1; i >= 0; i-- {
 				sstable := level.SSTables[i]
 				if lsm.bloomFilterMightContain(sstable, key) {
-					if value, found, err := sstable.Get(key); err != nil {
+					if entry, found, err := sstable.Lookup(key); err != nil {
 						return "", err
 					} else if found {
-						return value, nil
+						if entry.Deleted {
+							return "", keyNotFound(key)
+						}
+						return lsm.blobValue(entry)
 					}
 				}
+				if sstable.CoveredByRangeTombstone(key) {
+					return "", keyNotFound(key)
+				}
 			}
 		} else {
 			// For L1+, SSTables don't overlap, so binary search by key range
 			for _, sstable := range level.SSTables {
 				if sstable.ContainsKey(key) && lsm.bloomFilterMightContain(sstable, key) {
-					if value, found, err := sstable.Get(key); err != nil {
+					if entry, found, err := sstable.Lookup(key); err != nil {
 						return "", err
 					} else if found {
-						return value, nil
+						if entry.Deleted {
+							return "", keyNotFound(key)
+						}
+						return lsm.blobValue(entry)
 					}
 					break // Only one SSTable can contain the key in L1+
 				}
 			}
+
+			// Range tombstones in this level cover every older level
+			for _, sstable := range level.SSTables {
+				if sstable.CoveredByRangeTombstone(key) {
+					return "", keyNotFound(key)
+				}
+			}
 		}
 	}
 
-	return "", fmt.Errorf("key not found: %s", key)
+	return "", keyNotFound(key)
+}
+
+// keyNotFound returns the error reported for missing or deleted keys
+func keyNotFound(key string) error {
+	return fmt.Errorf("key not found: %s", key)
 }
 
 // Delete marks a key as deleted in the LSM-Tree
@@ -251,54 +489,257 @@ func (lsm *LSMTree) Delete(key string) error {
 	lsm.mu.Lock()
 	defer lsm.mu.Unlock()
 
+	return lsm.write(walOp{kind: walDelete, key: key})
+}
+
+// DeleteRange deletes every key in the inclusive range [start, end] with a single range tombstone
+func (lsm *LSMTree) DeleteRange(start, end string) error {
+	if start == "" || end == "" {
+		return fmt.Errorf("range bounds cannot be empty")
+	}
+	if start > end {
+		return fmt.Errorf("invalid range: start %q is greater than end %q", start, end)
+	}
+	return lsm.addRangeTombstone(newRangeTombstone(start, end))
+}
+
+// DeletePrefix deletes every key with the given prefix with a single range tombstone
+func (lsm *LSMTree) DeletePrefix(prefix string) error {
+	// An empty prefix would produce a tombstone without bounds that deletes every key
+	if prefix == "" {
+		return fmt.Errorf("prefix cannot be empty")
+	}
+	return lsm.addRangeTombstone(newPrefixTombstone(prefix))
+}
+
+// addRangeTombstone records a range tombstone in the active MemTable
+func (lsm *LSMTree) addRangeTombstone(rt RangeTombstone) error {
+	lsm.mu.Lock()
+	defer lsm.mu.Unlock()
+
+	return lsm.write(walOp{kind: walRangeDelete, key: rt.Start, value: rt.End})
+}
+
+// prepareWrite applies write backpressure and makes room in the active MemTable. Callers hold mu.
+func (lsm *LSMTree) prepareWrite() error {
+	if err := lsm.throttleWrite(); err != nil {
+		return err
+	}
+
 	// Check if MemTable needs to be flushed
 	if lsm.memTable.ShouldFlush(lsm.config.MemTableConfig) {
 		if err := lsm.flushMemTable(); err != nil {
 			return fmt.Errorf("failed to flush MemTable: %w", err)
 		}
 	}
+	return nil
+}
 
-	// Add deletion marker to MemTable
-	lsm.memTable.Delete(key, 0) // LSN will be handled by WAL integration
+// write logs a write to the WAL and applies it to the active MemTable. Callers hold mu.
+func (lsm *LSMTree) write(op walOp) error {
+	if err := lsm.prepareWrite(); err != nil {
+		return err
+	}
 
+	op.family = lsm.familyID
+	lsn, err := lsm.families.wal.append([]walOp{op})
+	if err != nil {
+		return fmt.Errorf("failed to write WAL: %w", err)
+	}
+	lsm.applyOp(op, lsn)
 	return nil
 }
 
+// replay applies a write recovered from the WAL. Callers hold mu.
+func (lsm *LSMTree) replay(op walOp, lsn uint64) {
+	if lsm.memTable.ShouldFlush(lsm.config.MemTableConfig) {
+		// Only moves the MemTable aside; recovery flushes it afterwards
+		_ = lsm.flushMemTable()
+	}
+	lsm.applyOp(op, lsn)
+}
+
+// applyOp applies a logged write to the active MemTable. Callers hold mu.
+func (lsm *LSMTree) applyOp(op walOp, lsn uint64) {
+	switch op.kind {
+	case walPut:
+		lsm.memTable.Put(op.key, op.value, lsn)
+	case walDelete:
+		lsm.memTable.Delete(op.key, lsn)
+	case walRangeDelete:
+		rt := RangeTombstone{Start: op.key, End: op.value}
+
+		// The tombstone only covers older tables, so keys already in the
+		// active MemTable are deleted individually
+		for _, entry := range lsm.memTable.GetAll() {
+			if !entry.Deleted && rt.Covers(entry.Key) {
+				lsm.memTable.Delete(entry.Key, lsn)
+			}
+		}
+
+		lsm.rangeTombstones = append(lsm.rangeTombstones, rt)
+		lsm.stats.RangeDeletes++
+	}
+	lsm.memTableLSNs.add(lsn)
+}
+
 // flushMemTable flushes the current MemTable to disk as an SSTable
 func (lsm *LSMTree) flushMemTable() error {
-	if lsm.memTable.IsEmpty() {
+	if lsm.memTable.IsEmpty() && len(lsm.rangeTombstones) == 0 {
 		return nil
 	}
 
-	// Move current MemTable to immutable list
+	// Move current MemTable and its range tombstones to the immutable list
 	lsm.immutableTables = append(lsm.immutableTables, lsm.memTable)
+	lsm.immutableRangeTombstones = append(lsm.immutableRangeTombstones, lsm.rangeTombstones)
+	lsm.immutableLSNs = append(lsm.immutableLSNs, lsm.memTableLSNs)
 
 	// Create new active MemTable
 	lsm.memTable = kvstore.NewMemTable(lsm.config.MemTableConfig)
+	lsm.rangeTombstones = nil
+	lsm.memTableLSNs = lsnRange{}
 
 	// Trigger background flush
-	select {
-	case lsm.compactionCh <- struct{}{}:
-	default:
-		// Compaction already queued
-	}
+	lsm.scheduleCompaction()
 
 	lsm.stats.MemTableFlushes++
 	return nil
 }
 
-// bloomFilterMightContain checks if a bloom filter might contain a key
+// bloomFilterMightContain checks if an SSTable's bloom filter might contain a key
 func (lsm *LSMTree) bloomFilterMightContain(sstable *SSTable, key string) bool {
-	if bf, exists := lsm.bloomFilters[sstable.ID]; exists {
-		might := bf.MightContain([]byte(key))
-		if might {
-			lsm.stats.BloomFilterHits++
+	might := sstable.MightContain(key)
+	if might {
+		atomic.AddUint64(&lsm.bloomFilterHits, 1)
+	} else {
+		atomic.AddUint64(&lsm.bloomFilterMisses, 1)
+	}
+	return might
+}
+
+// sstableOptions returns the options used for newly written SSTables
+func (lsm *LSMTree) sstableOptions() SSTableOptions {
+	return SSTableOptions{
+		BloomFilterFPR:    lsm.config.BloomFilterFPR,
+		PrefixBloomLength: lsm.config.PrefixBloomLength,
+	}
+}
+
+// PrefixSearch returns all live keys and values with the specified prefix.
+// SSTables whose key range or prefix bloom filter rules out the prefix are skipped.
+func (lsm *LSMTree) PrefixSearch(prefix string) (map[string]string, error) {
+	return lsm.scanLive(func(sstable *SSTable) ([]*SSTableEntry, error) {
+		if !sstable.MightContainPrefix(prefix) {
+			atomic.AddUint64(&lsm.prefixBloomSkips, 1)
+			return nil, nil
+		}
+		entries, err := sstable.PrefixScan(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("prefix scan of %s failed: %w", sstable.ID, err)
+		}
+		return entries, nil
+	}, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// GetRange returns all live keys and values with keys in [start, end].
+// SSTables whose key range does not overlap the range are skipped.
+func (lsm *LSMTree) GetRange(start, end string) (map[string]string, error) {
+	return lsm.scanLive(func(sstable *SSTable) ([]*SSTableEntry, error) {
+		if !sstable.MightContainRange(start, end) {
+			return nil, nil
+		}
+		entries, err := sstable.RangeScan(start, end)
+		if err != nil {
+			return nil, fmt.Errorf("range scan of %s failed: %w", sstable.ID, err)
+		}
+		return entries, nil
+	}, func(key string) bool {
+		return key >= start && key <= end
+	})
+}
+
+// scanLive merges the entries a scan finds in every SSTable with the MemTable entries
+// matching match, and returns the keys that are still live
+func (lsm *LSMTree) scanLive(scanTable func(*SSTable) ([]*SSTableEntry, error), match func(key string) bool) (map[string]string, error) {
+	lsm.mu.RLock()
+	defer lsm.mu.RUnlock()
+
+	result := make(map[string]string)
+	apply := func(key, value string, deleted bool) {
+		if deleted {
+			delete(result, key)
 		} else {
-			lsm.stats.BloomFilterMisses++
+			result[key] = value
+		}
+	}
+
+	applyTombstones := func(tombstones []RangeTombstone) {
+		for _, rt := range tombstones {
+			for key := range result {
+				if rt.Covers(key) {
+					delete(result, key)
+				}
+			}
 		}
-		return might
 	}
-	return true // No bloom filter, assume it might contain
+	scan := func(sstable *SSTable) error {
+		entries, err := scanTable(sstable)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			value, err := lsm.blobValue(entry)
+			if err != nil {
+				return err
+			}
+			apply(entry.Key, value, entry.Deleted)
+		}
+		return nil
+	}
+
+	// Walk from oldest to newest so newer versions overwrite older ones.
+	// Range tombstones are applied before the entries of the table holding them.
+	for levelIdx := len(lsm.levels) - 1; levelIdx >= 0; levelIdx-- {
+		sstables := lsm.levels[levelIdx].SSTables
+		if levelIdx == 0 {
+			for _, sstable := range sstables {
+				applyTombstones(sstable.RangeTombstones())
+				if err := scan(sstable); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		for _, sstable := range sstables {
+			applyTombstones(sstable.RangeTombstones())
+		}
+		for _, sstable := range sstables {
+			if err := scan(sstable); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, memTable := range lsm.immutableTables {
+		applyTombstones(lsm.immutableRangeTombstones[i])
+		for _, entry := range memTable.GetAll() {
+			if match(entry.Key) {
+				apply(entry.Key, entry.Value, entry.Deleted)
+			}
+		}
+	}
+
+	applyTombstones(lsm.rangeTombstones)
+	for _, entry := range lsm.memTable.GetAll() {
+		if match(entry.Key) {
+			apply(entry.Key, entry.Value, entry.Deleted)
+		}
+	}
+
+	return result, nil
 }
 
 // compactionWorker runs background compaction operations
@@ -335,6 +776,14 @@ func (lsm *LSMTree) needsCompaction() bool {
 		return true
 	}
 
+	// Check for SSTables dropped by the FIFO and TTL styles
+	if len(lsm.compactionManager.selectObsoleteSSTables()) > 0 {
+		return true
+	}
+	if lsm.config.CompactionStyle == FIFOCompaction {
+		return false
+	}
+
 	// Check if any level exceeds its size/count limits
 	for i := range lsm.levels {
 		level := &lsm.levels[i]
@@ -354,15 +803,38 @@ func (lsm *LSMTree) needsCompaction() bool {
 	return false
 }
 
+// Compact flushes the active MemTable and runs a compaction pass synchronously
+func (lsm *LSMTree) Compact() error {
+	lsm.mu.Lock()
+	err := lsm.flushMemTable()
+	lsm.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to flush MemTable: %w", err)
+	}
+
+	lsm.performCompaction()
+	return nil
+}
+
 // performCompaction performs the actual compaction work
 func (lsm *LSMTree) performCompaction() {
+	lsm.compactionMu.Lock()
+	defer lsm.compactionMu.Unlock()
+
 	lsm.mu.Lock()
 	defer lsm.mu.Unlock()
 
+	// Merges release the lock; the rest of the time foreground operations are stalled
 	start := time.Now()
+	mergeTime := lsm.stats.CompactionTime
 	defer func() {
 		lsm.stats.LastCompactionTime = time.Now()
 		lsm.stats.CompactionCount++
+		lsm.stats.CompactionStallTime += time.Since(start) - (lsm.stats.CompactionTime - mergeTime)
+
+		// Release writers stalled on the backlog this pass worked through
+		lsm.compactionPasses++
+		lsm.stallCond.Broadcast()
 	}()
 
 	// 1. Flush any immutable MemTables to L0
@@ -371,7 +843,13 @@ func (lsm *LSMTree) performCompaction() {
 		return
 	}
 
-	// 2. Perform level compaction if needed
+	// 2. Drop SSTables that are expired or exceed the FIFO size cap
+	if err := lsm.compactionManager.DropObsoleteSSTables(); err != nil {
+		fmt.Printf("Error dropping obsolete SSTables: %v\n", err)
+		return
+	}
+
+	// 3. Perform level compaction if needed
 	for level := 0; level < len(lsm.levels)-1; level++ {
 		if lsm.shouldCompactLevel(level) {
 			if err := lsm.compactLevel(level); err != nil {
@@ -381,6 +859,12 @@ func (lsm *LSMTree) performCompaction() {
 		}
 	}
 
+	// 4. Reclaim blob files whose values were overwritten or deleted
+	if err := lsm.collectBlobGarbage(); err != nil {
+		fmt.Printf("Error collecting blob garbage: %v\n", err)
+		return
+	}
+
 	fmt.Printf("Compaction completed in %v\n", time.Since(start))
 }
 
@@ -390,6 +874,11 @@ func (lsm *LSMTree) shouldCompactLevel(level int) bool {
 		return false
 	}
 
+	// FIFO keeps every SSTable in L0 and never merges them
+	if lsm.config.CompactionStyle == FIFOCompaction {
+		return false
+	}
+
 	levelData := &lsm.levels[level]
 
 	// Check SSTable count
@@ -397,6 +886,11 @@ func (lsm *LSMTree) shouldCompactLevel(level int) bool {
 		return true
 	}
 
+	// L0 SSTables overlap, so L0 is compacted by file count only
+	if level == 0 {
+		return false
+	}
+
 	// Check total size
 	totalSize := int64(0)
 	for _, sstable := range levelData.SSTables {
@@ -411,10 +905,14 @@ func (lsm *LSMTree) flushImmutableMemTables() error {
 	for len(lsm.immutableTables) > 0 {
 		// Take the oldest immutable MemTable
 		memTable := lsm.immutableTables[0]
+		tombstones := lsm.immutableRangeTombstones[0]
+		lsns := lsm.immutableLSNs[0]
 		lsm.immutableTables = lsm.immutableTables[1:]
+		lsm.immutableRangeTombstones = lsm.immutableRangeTombstones[1:]
+		lsm.immutableLSNs = lsm.immutableLSNs[1:]
 
 		// Create SSTable from MemTable
-		sstable, err := lsm.createSSTableFromMemTable(memTable)
+		sstable, err := lsm.createSSTableFromMemTable(memTable, tombstones)
 		if err != nil {
 			return fmt.Errorf("failed to create SSTable: %w", err)
 		}
@@ -422,36 +920,69 @@ func (lsm *LSMTree) flushImmutableMemTables() error {
 		// Add to L0
 		lsm.levels[0].SSTables = append(lsm.levels[0].SSTables, sstable)
 
-		// Create bloom filter for the new SSTable
-		bf, err := lsm.createBloomFilter(sstable)
-		if err != nil {
-			return fmt.Errorf("failed to create bloom filter: %w", err)
+		if err := lsm.recordFlush(lsns); err != nil {
+			return err
 		}
-		lsm.bloomFilters[sstable.ID] = bf
 	}
 
 	return nil
 }
 
-// createSSTableFromMemTable creates an SSTable from a MemTable
-func (lsm *LSMTree) createSSTableFromMemTable(memTable *kvstore.MemTable) (*SSTable, error) {
-	lsm.nextSSTableID++
-	sstableID := fmt.Sprintf("sstable_%d", lsm.nextSSTableID)
+// recordFlush tells the WAL that the records of a flushed MemTable are in an SSTable. Callers hold mu.
+func (lsm *LSMTree) recordFlush(flushed lsnRange) error {
+	if flushed.last == 0 {
+		return nil
+	}
 
-	sstable, err := NewSSTable(sstableID, lsm.dataDir, 0)
+	oldestUnflushed := lsm.memTableLSNs.first
+	if len(lsm.immutableLSNs) > 0 {
+		oldestUnflushed = lsm.immutableLSNs[0].first
+	}
+	if err := lsm.families.flushed(lsm.familyID, flushed.last, oldestUnflushed); err != nil {
+		return fmt.Errorf("failed to record flush: %w", err)
+	}
+	return nil
+}
+
+// createSSTableFromMemTable creates an SSTable from a MemTable and its range tombstones
+func (lsm *LSMTree) createSSTableFromMemTable(memTable *kvstore.MemTable, tombstones []RangeTombstone) (*SSTable, error) {
+	sstableID := fmt.Sprintf("sstable_%d", atomic.AddUint64(&lsm.nextSSTableID, 1))
+
+	sstable, err := NewSSTableWithOptions(sstableID, lsm.dataDir, 0, lsm.sstableOptions())
 	if err != nil {
 		return nil, err
 	}
 
-	// Get all entries from MemTable in sorted order
+	// Get all entries from MemTable in key order, so blob files keep key locality
 	entries := memTable.GetAll()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
 
-	// Write entries to SSTable
+	// Write entries to SSTable, moving large values into blob files
 	for _, entry := range entries {
-		if err := sstable.Put(entry.Key, entry.Value, entry.Deleted); err != nil {
+		sstEntry := SSTableEntry{
+			Key:       entry.Key,
+			Value:     entry.Value,
+			Deleted:   entry.Deleted,
+			Timestamp: entry.Timestamp,
+		}
+		if err := lsm.separateValue(&sstEntry); err != nil {
+			return nil, fmt.Errorf("failed to write blob: %w", err)
+		}
+		if err := sstable.putEntry(sstEntry); err != nil {
 			return nil, fmt.Errorf("failed to write to SSTable: %w", err)
 		}
 	}
+	if err := lsm.blobs.sync(); err != nil {
+		return nil, err
+	}
+
+	for _, rt := range tombstones {
+		if err := sstable.AddRangeTombstone(rt); err != nil {
+			return nil, fmt.Errorf("failed to write range tombstone: %w", err)
+		}
+	}
 
 	// Finalize SSTable
 	if err := sstable.Finalize(); err != nil {
@@ -461,26 +992,6 @@ func (lsm *LSMTree) createSSTableFromMemTable(memTable *kvstore.MemTable) (*SSTa
 	return sstable, nil
 }
 
-// createBloomFilter creates a bloom filter for an SSTable
-func (lsm *LSMTree) createBloomFilter(sstable *SSTable) (*BloomFilter, error) {
-	// Estimate number of keys in SSTable
-	numKeys := sstable.NumEntries
-
-	bf := NewBloomFilter(numKeys, lsm.config.BloomFilterFPR)
-
-	// Add all keys to bloom filter
-	keys, err := sstable.GetAllKeys()
-	if err != nil {
-		return nil, err
-	}
-
-	for _, key := range keys {
-		bf.Add([]byte(key))
-	}
-
-	return bf, nil
-}
-
 // compactLevel compacts a specific level with the next level
 func (lsm *LSMTree) compactLevel(level int) error {
 	if level >= len(lsm.levels)-1 {
@@ -500,6 +1011,8 @@ func (lsm *LSMTree) compactLevel(level int) error {
 			return lsm.performSizeTieredCompaction(level)
 		}
 		return lsm.performLeveledCompaction(level)
+	case FIFOCompaction:
+		return nil
 	default:
 		return lsm.performLeveledCompaction(level)
 	}
@@ -507,28 +1020,12 @@ func (lsm *LSMTree) compactLevel(level int) error {
 
 // performLeveledCompaction performs leveled compaction between two levels
 func (lsm *LSMTree) performLeveledCompaction(level int) error {
-	// This is a simplified implementation
-	// In a full implementation, this would involve:
-	// 1. Select SSTables to compact from current level
-	// 2. Find overlapping SSTables in next level
-	// 3. Merge all selected SSTables
-	// 4. Write new SSTables to next level
-	// 5. Delete old SSTables
-
-	fmt.Printf("Performing leveled compaction on level %d (placeholder)\n", level)
-	return nil
+	return lsm.compactionManager.PerformLeveledCompaction(level)
 }
 
 // performSizeTieredCompaction performs size-tiered compaction
 func (lsm *LSMTree) performSizeTieredCompaction(level int) error {
-	// This is a simplified implementation
-	// In a full implementation, this would:
-	// 1. Group SSTables by similar size
-	// 2. Merge groups that exceed size threshold
-	// 3. Write merged results to next level
-
-	fmt.Printf("Performing size-tiered compaction on level %d (placeholder)\n", level)
-	return nil
+	return lsm.compactionManager.PerformSizeTieredCompaction(level)
 }
 
 // GetStats returns current LSM-Tree statistics
@@ -538,21 +1035,66 @@ func (lsm *LSMTree) GetStats() LSMStats {
 
 	stats := lsm.stats
 	stats.TotalLevels = len(lsm.levels)
+	stats.BloomFilterHits = atomic.LoadUint64(&lsm.bloomFilterHits)
+	stats.BloomFilterMisses = atomic.LoadUint64(&lsm.bloomFilterMisses)
+	stats.PrefixBloomSkips = atomic.LoadUint64(&lsm.prefixBloomSkips)
 
 	// Count active SSTables
 	for _, level := range lsm.levels {
 		stats.ActiveSSTables += len(level.SSTables)
 	}
 
+	if stats.CompactionTime > 0 {
+		stats.CompactionThroughput = float64(stats.CompactionBytesWritten) / stats.CompactionTime.Seconds()
+	}
+
+	if lsm.familyID == 0 {
+		stats.ColumnFamilies = len(lsm.families.names())
+	}
+	if lsm.families.wal != nil {
+		stats.WALSegments, stats.WALBytes = lsm.families.wal.size()
+	}
+
+	stats.BlobFiles, stats.BlobFileBytes = lsm.blobs.usage()
+	stats.BlobBytesWritten = atomic.LoadUint64(&lsm.blobs.bytesWritten)
+	if live, err := lsm.blobLiveBytes(); err == nil {
+		for _, bytes := range live {
+			stats.BlobLiveBytes += bytes
+		}
+	}
+
 	return stats
 }
 
-// Close shuts down the LSM-Tree gracefully
+// Close shuts down the LSM-Tree gracefully, including its column families
 func (lsm *LSMTree) Close() error {
+	if lsm.familyID == 0 {
+		familiesErr := lsm.families.closeFamilies()
+		err := lsm.close()
+		if err == nil {
+			err = familiesErr
+		}
+		if closeErr := lsm.families.close(err == nil); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	return lsm.close()
+}
+
+// close shuts down the tree of one column family
+func (lsm *LSMTree) close() error {
 	close(lsm.stopCh)
 	lsm.wg.Wait()
 
+	// Writers blocked by a write stop fail instead of waiting for a compaction that never comes
+	lsm.mu.Lock()
+	lsm.stallCond.Broadcast()
+	lsm.mu.Unlock()
+
 	// Perform final flush
+	lsm.compactionMu.Lock()
+	defer lsm.compactionMu.Unlock()
 	lsm.mu.Lock()
 	defer lsm.mu.Unlock()
 
@@ -573,5 +1115,9 @@ func (lsm *LSMTree) Close() error {
 		}
 	}
 
+	if err := lsm.blobs.close(); err != nil {
+		return fmt.Errorf("failed to close blob files: %w", err)
+	}
+
 	return nil
 }
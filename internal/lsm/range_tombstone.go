@@ -0,0 +1,121 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RangeTombstone marks every key in [Start, End) as deleted in older data.
+// An empty End means the range has no upper bound.
+type RangeTombstone struct {
+	Start string
+	End   string
+}
+
+// newRangeTombstone creates a tombstone for the inclusive key range [start, end]
+func newRangeTombstone(start, end string) RangeTombstone {
+	// Appending a zero byte yields the smallest key greater than end
+	return RangeTombstone{Start: start, End: end + "\x00"}
+}
+
+// newPrefixTombstone creates a tombstone covering every key with the given prefix
+func newPrefixTombstone(prefix string) RangeTombstone {
+	return RangeTombstone{Start: prefix, End: prefixSuccessor(prefix)}
+}
+
+// prefixSuccessor returns the smallest key greater than every key with the given prefix,
+// or an empty string if no such key exists
+func prefixSuccessor(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
+// Covers reports whether the tombstone deletes the key
+func (rt RangeTombstone) Covers(key string) bool {
+	return key >= rt.Start && (rt.End == "" || key < rt.End)
+}
+
+// Overlaps reports whether the tombstone covers any key in the inclusive range [minKey, maxKey]
+func (rt RangeTombstone) Overlaps(minKey, maxKey string) bool {
+	return rt.Start <= maxKey && (rt.End == "" || rt.End > minKey)
+}
+
+// String returns a human readable form of the tombstone
+func (rt RangeTombstone) String() string {
+	if rt.End == "" {
+		return fmt.Sprintf("[%q, +inf)", rt.Start)
+	}
+	return fmt.Sprintf("[%q, %q)", rt.Start, rt.End)
+}
+
+// coveredByAny reports whether any of the tombstones deletes the key
+func coveredByAny(tombstones []RangeTombstone, key string) bool {
+	for _, rt := range tombstones {
+		if rt.Covers(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// serializeRangeTombstones encodes tombstones as a count followed by length-prefixed bounds
+func serializeRangeTombstones(tombstones []RangeTombstone) []byte {
+	size := 4
+	for _, rt := range tombstones {
+		size += 8 + len(rt.Start) + len(rt.End)
+	}
+
+	data := make([]byte, 0, size)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(tombstones)))
+	for _, rt := range tombstones {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(rt.Start)))
+		data = append(data, rt.Start...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(rt.End)))
+		data = append(data, rt.End...)
+	}
+	return data
+}
+
+// deserializeRangeTombstones decodes tombstones written by serializeRangeTombstones
+func deserializeRangeTombstones(data []byte) ([]RangeTombstone, error) {
+	readString := func() (string, error) {
+		if len(data) < 4 {
+			return "", fmt.Errorf("truncated range tombstone section")
+		}
+		n := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		if uint32(len(data)) < n {
+			return "", fmt.Errorf("truncated range tombstone section")
+		}
+		s := string(data[:n])
+		data = data[n:]
+		return s, nil
+	}
+
+	if len(data) < 4 {
+		return nil, fmt.Errorf("truncated range tombstone section")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	tombstones := make([]RangeTombstone, 0, count)
+	for i := uint32(0); i < count; i++ {
+		start, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		end, err := readString()
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, RangeTombstone{Start: start, End: end})
+	}
+
+	return tombstones, nil
+}
@@ -0,0 +1,63 @@
+package lsm
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter throttles background I/O to a configured number of bytes per second.
+// It is a token bucket holding at most one second of budget; callers that overdraw
+// it sleep until the debt is repaid. A nil or zero-rate limiter never blocks.
+type RateLimiter struct {
+	mu          sync.Mutex
+	bytesPerSec int64
+	tokens      float64
+	last        time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing bytesPerSec bytes per second
+// (0 or less disables limiting)
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{
+		bytesPerSec: bytesPerSec,
+		tokens:      float64(bytesPerSec),
+		last:        time.Now(),
+	}
+}
+
+// Wait charges n bytes against the budget, sleeping if it is exhausted,
+// and returns how long the caller was throttled
+func (rl *RateLimiter) Wait(n int64) time.Duration {
+	if rl == nil || rl.bytesPerSec <= 0 || n <= 0 {
+		return 0
+	}
+
+	rl.mu.Lock()
+	now := time.Now()
+	rate := float64(rl.bytesPerSec)
+	rl.tokens += now.Sub(rl.last).Seconds() * rate
+	if rl.tokens > rate {
+		rl.tokens = rate
+	}
+	rl.last = now
+	rl.tokens -= float64(n)
+
+	var wait time.Duration
+	if rl.tokens < 0 {
+		wait = time.Duration(-rl.tokens / rate * float64(time.Second))
+	}
+	rl.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return wait
+}
+
+// BytesPerSecond returns the configured rate (0 if unlimited)
+func (rl *RateLimiter) BytesPerSecond() int64 {
+	if rl == nil {
+		return 0
+	}
+	return rl.bytesPerSec
+}
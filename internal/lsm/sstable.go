@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // SSTable represents a Sorted String Table on disk
@@ -29,6 +31,25 @@ type SSTable struct {
 	// Metadata
 	metadata SSTableMetadata
 	index    []IndexEntry
+	options  SSTableOptions
+
+	// Bloom filters stored in the filter section of the data file.
+	// They are loaded lazily on first use.
+	filterMu      sync.Mutex
+	footer        sstableFooter
+	bloomFilter   *BloomFilter
+	prefixFilter  *BloomFilter
+	filtersLoaded bool
+	filterLoadErr error
+
+	// Range tombstones written with this table; they cover keys in older tables only
+	rangeTombstones []RangeTombstone
+
+	// Write time of the newest entry in UnixNano (0 if unknown)
+	newestTimestamp int64
+
+	// Record bytes referenced in each blob file; loaded on first use for opened tables
+	blobRefs map[uint64]int64
 
 	// State
 	finalized bool
@@ -63,17 +84,77 @@ type SSTableEntry struct {
 	Key       string
 	Value     string
 	Deleted   bool
+	BlobIndex bool // Value holds a pointer into a blob file rather than the value itself
 	Timestamp int64
 	Checksum  uint32
 }
 
+// Entry flag bits stored in the byte following the value
 const (
-	SSTableVersion = 1
+	entryFlagDeleted   = 1 << 0
+	entryFlagBlobIndex = 1 << 1 // Version 5+
+)
+
+// flags returns the entry flag byte
+func (e *SSTableEntry) flags() byte {
+	var flags byte
+	if e.Deleted {
+		flags |= entryFlagDeleted
+	}
+	if e.BlobIndex {
+		flags |= entryFlagBlobIndex
+	}
+	return flags
+}
+
+// SSTableOptions controls optional sections written into an SSTable
+type SSTableOptions struct {
+	BloomFilterFPR    float64 // False positive rate of the key bloom filter
+	PrefixBloomLength int     // Length of key prefixes stored in the prefix bloom filter (0 disables it)
+}
+
+// DefaultSSTableOptions returns default SSTable options
+func DefaultSSTableOptions() SSTableOptions {
+	return SSTableOptions{
+		BloomFilterFPR:    0.01,
+		PrefixBloomLength: 0,
+	}
+}
+
+// sstableFooter locates the filter section at the end of the data file
+type sstableFooter struct {
+	BloomOffset       int64
+	BloomLength       uint32
+	PrefixOffset      int64
+	PrefixLength      uint32
+	PrefixBloomLength uint32
+	FalsePositiveRate float64
+	RangeDelOffset    int64
+	RangeDelLength    uint32
+	NewestTimestamp   int64
+}
+
+const (
+	SSTableVersion = 5     // Version 5 adds blob index entries
 	IndexEntrySize = 8 + 4 // offset (8 bytes) + length (4 bytes)
+
+	// sstableFooterSizeV2 is the size of the footer written by version 2 tables
+	sstableFooterSizeV2 = 8 + 4 + 8 + 4 + 4 + 8 + 4
+	// sstableFooterSizeV3 adds the range tombstone section location
+	sstableFooterSizeV3 = sstableFooterSizeV2 + 8 + 4
+	// sstableFooterSize adds the newest entry timestamp (version 4+)
+	sstableFooterSize = sstableFooterSizeV3 + 8
+	// sstableFooterMagic marks a valid footer
+	sstableFooterMagic = 0x4d4f5a46 // "MOZF"
 )
 
 // NewSSTable creates a new SSTable for writing
 func NewSSTable(id, dataDir string, level int) (*SSTable, error) {
+	return NewSSTableWithOptions(id, dataDir, level, DefaultSSTableOptions())
+}
+
+// NewSSTableWithOptions creates a new SSTable for writing with custom options
+func NewSSTableWithOptions(id, dataDir string, level int, options SSTableOptions) (*SSTable, error) {
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
@@ -88,7 +169,9 @@ func NewSSTable(id, dataDir string, level int) (*SSTable, error) {
 			Level:     level,
 			CreatedAt: int64(0), // Will be set when finalized
 		},
-		index: make([]IndexEntry, 0),
+		index:    make([]IndexEntry, 0),
+		options:  options,
+		blobRefs: make(map[uint64]int64),
 	}
 
 	// Create file paths
@@ -157,6 +240,17 @@ func OpenSSTable(id, dataDir string) (*SSTable, error) {
 		return nil, fmt.Errorf("failed to read index: %w", err)
 	}
 
+	// Locate the filter section; the filters themselves are loaded on first use
+	if err := sstable.readFooter(); err != nil {
+		sstable.cleanup()
+		return nil, fmt.Errorf("failed to read footer: %w", err)
+	}
+
+	if err := sstable.readRangeTombstones(); err != nil {
+		sstable.cleanup()
+		return nil, fmt.Errorf("failed to read range tombstones: %w", err)
+	}
+
 	// Get file size
 	if stat, err := sstable.dataFile.Stat(); err == nil {
 		sstable.FileSize = stat.Size()
@@ -186,8 +280,24 @@ func (sst *SSTable) writeHeader() error {
 	return nil
 }
 
-// Put adds a key-value pair to the SSTable
+// Put adds a key-value pair to the SSTable, stamped with the current time
 func (sst *SSTable) Put(key, value string, deleted bool) error {
+	return sst.PutWithTimestamp(key, value, deleted, time.Now().UnixNano())
+}
+
+// PutWithTimestamp adds a key-value pair written at the given time (UnixNano).
+// Flushes and compactions use it to carry the original write time forward.
+func (sst *SSTable) PutWithTimestamp(key, value string, deleted bool, timestamp int64) error {
+	return sst.putEntry(SSTableEntry{
+		Key:       key,
+		Value:     value,
+		Deleted:   deleted,
+		Timestamp: timestamp,
+	})
+}
+
+// putEntry appends an entry, keeping its blob index flag
+func (sst *SSTable) putEntry(entry SSTableEntry) error {
 	if sst.finalized {
 		return fmt.Errorf("cannot write to finalized SSTable")
 	}
@@ -201,13 +311,13 @@ func (sst *SSTable) Put(key, value string, deleted bool) error {
 		return fmt.Errorf("failed to get file position: %w", err)
 	}
 
-	// Create entry
-	entry := SSTableEntry{
-		Key:       key,
-		Value:     value,
-		Deleted:   deleted,
-		Timestamp: 0, // Will be set from MemTable
+	var ref blobIndex
+	if entry.BlobIndex {
+		if ref, err = decodeBlobIndex(entry.Value); err != nil {
+			return err
+		}
 	}
+	key, timestamp := entry.Key, entry.Timestamp
 
 	// Calculate checksum
 	entry.Checksum = sst.calculateChecksum(&entry)
@@ -233,6 +343,12 @@ func (sst *SSTable) Put(key, value string, deleted bool) error {
 
 	// Update metadata
 	sst.NumEntries++
+	if entry.BlobIndex {
+		sst.blobRefs[ref.File] += int64(ref.Length)
+	}
+	if timestamp > sst.newestTimestamp {
+		sst.newestTimestamp = timestamp
+	}
 	if sst.metadata.MinKey == "" || key < sst.metadata.MinKey {
 		sst.metadata.MinKey = key
 	}
@@ -253,7 +369,7 @@ func (sst *SSTable) serializeEntry(entry *SSTableEntry) ([]byte, error) {
 		keyLen + // key
 		4 + // value length
 		valueLen + // value
-		1 + // deleted flag
+		1 + // flags
 		8 + // timestamp
 		4 // checksum
 
@@ -272,12 +388,8 @@ func (sst *SSTable) serializeEntry(entry *SSTableEntry) ([]byte, error) {
 	copy(data[offset:], entry.Value)
 	offset += valueLen
 
-	// Write deleted flag
-	if entry.Deleted {
-		data[offset] = 1
-	} else {
-		data[offset] = 0
-	}
+	// Write flags
+	data[offset] = entry.flags()
 	offset++
 
 	// Write timestamp
@@ -295,19 +407,26 @@ func (sst *SSTable) calculateChecksum(entry *SSTableEntry) uint32 {
 	hasher := crc32.NewIEEE()
 	hasher.Write([]byte(entry.Key))
 	hasher.Write([]byte(entry.Value))
-	if entry.Deleted {
-		hasher.Write([]byte{1})
-	} else {
-		hasher.Write([]byte{0})
-	}
+	hasher.Write([]byte{entry.flags()})
 	_ = binary.Write(hasher, binary.LittleEndian, entry.Timestamp)
 	return hasher.Sum32()
 }
 
-// Get retrieves a value for a key from the SSTable
+// Get retrieves a value for a key from the SSTable.
+// Blob index entries return the encoded pointer; LSMTree resolves them.
 func (sst *SSTable) Get(key string) (string, bool, error) {
+	entry, found, err := sst.Lookup(key)
+	if err != nil || !found || entry.Deleted {
+		return "", false, err
+	}
+
+	return entry.Value, true, nil
+}
+
+// Lookup returns the entry stored for a key, including deletion markers
+func (sst *SSTable) Lookup(key string) (*SSTableEntry, bool, error) {
 	if !sst.finalized {
-		return "", false, fmt.Errorf("SSTable not finalized")
+		return nil, false, fmt.Errorf("SSTable not finalized")
 	}
 
 	sst.mu.RLock()
@@ -319,21 +438,85 @@ func (sst *SSTable) Get(key string) (string, bool, error) {
 	})
 
 	if idx >= len(sst.index) || sst.index[idx].Key != key {
-		return "", false, nil // Key not found
+		return nil, false, nil // Key not found
 	}
 
 	// Read entry from file
 	indexEntry := sst.index[idx]
 	entry, err := sst.readEntryAt(indexEntry.Offset, indexEntry.Length)
 	if err != nil {
-		return "", false, fmt.Errorf("failed to read entry: %w", err)
+		return nil, false, fmt.Errorf("failed to read entry: %w", err)
 	}
 
-	if entry.Deleted {
-		return "", false, nil // Deleted entry
+	return entry, true, nil
+}
+
+// AddRangeTombstone records a range deletion that covers keys in older tables
+func (sst *SSTable) AddRangeTombstone(rt RangeTombstone) error {
+	if sst.finalized {
+		return fmt.Errorf("cannot write to finalized SSTable")
 	}
 
-	return entry.Value, true, nil
+	sst.mu.Lock()
+	defer sst.mu.Unlock()
+
+	sst.rangeTombstones = append(sst.rangeTombstones, rt)
+	return nil
+}
+
+// RangeTombstones returns the range deletions stored in the SSTable
+func (sst *SSTable) RangeTombstones() []RangeTombstone {
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+	return append([]RangeTombstone(nil), sst.rangeTombstones...)
+}
+
+// CoveredByRangeTombstone reports whether a range deletion in this table deletes the key in older tables
+func (sst *SSTable) CoveredByRangeTombstone(key string) bool {
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+	return coveredByAny(sst.rangeTombstones, key)
+}
+
+// blobReferences returns the record bytes the table references in each blob file.
+// Opened tables are scanned once on first use.
+func (sst *SSTable) blobReferences() (map[uint64]int64, error) {
+	sst.mu.Lock()
+	defer sst.mu.Unlock()
+
+	if sst.blobRefs == nil {
+		refs := make(map[uint64]int64)
+		if sst.metadata.Version >= 5 {
+			for _, indexEntry := range sst.index {
+				entry, err := sst.readEntryAt(indexEntry.Offset, indexEntry.Length)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read entry: %w", err)
+				}
+				if !entry.BlobIndex {
+					continue
+				}
+				ref, err := decodeBlobIndex(entry.Value)
+				if err != nil {
+					return nil, err
+				}
+				refs[ref.File] += int64(ref.Length)
+			}
+		}
+		sst.blobRefs = refs
+	}
+	return sst.blobRefs, nil
+}
+
+// NewestEntryTime returns the write time of the newest entry in the SSTable.
+// Tables written without entry timestamps fall back to the file modification time.
+func (sst *SSTable) NewestEntryTime() time.Time {
+	if sst.newestTimestamp > 0 {
+		return time.Unix(0, sst.newestTimestamp)
+	}
+	if stat, err := os.Stat(sst.FilePath); err == nil {
+		return stat.ModTime()
+	}
+	return time.Time{}
 }
 
 // readEntryAt reads an entry at a specific offset
@@ -378,11 +561,12 @@ func (sst *SSTable) deserializeEntry(data []byte) (*SSTableEntry, error) {
 	entry.Value = string(data[offset : offset+int(valueLen)])
 	offset += int(valueLen)
 
-	// Read deleted flag
+	// Read flags
 	if offset >= len(data) {
-		return nil, fmt.Errorf("missing deleted flag")
+		return nil, fmt.Errorf("missing flags")
 	}
-	entry.Deleted = data[offset] == 1
+	entry.Deleted = data[offset]&entryFlagDeleted != 0
+	entry.BlobIndex = data[offset]&entryFlagBlobIndex != 0
 	offset++
 
 	// Read timestamp
@@ -446,6 +630,11 @@ func (sst *SSTable) Finalize() error {
 		return sst.index[i].Key < sst.index[j].Key
 	})
 
+	// Write bloom filters after the last entry
+	if err := sst.writeFilters(); err != nil {
+		return fmt.Errorf("failed to write filters: %w", err)
+	}
+
 	// Update metadata
 	sst.metadata.NumEntries = sst.NumEntries
 	if stat, err := sst.dataFile.Stat(); err == nil {
@@ -643,6 +832,384 @@ func (sst *SSTable) readIndex() error {
 	return nil
 }
 
+// writeFilters writes the bloom filter section, range tombstones and footer after the last entry
+func (sst *SSTable) writeFilters() error {
+	fpr := sst.options.BloomFilterFPR
+	if fpr <= 0 || fpr >= 1 {
+		fpr = DefaultSSTableOptions().BloomFilterFPR
+	}
+
+	// Build the key bloom filter from the index
+	bf := NewBloomFilter(uint64(len(sst.index)), fpr)
+	for _, entry := range sst.index {
+		bf.Add([]byte(entry.Key))
+	}
+
+	// Build the optional prefix bloom filter
+	var pf *BloomFilter
+	if sst.options.PrefixBloomLength > 0 {
+		pf = NewBloomFilter(uint64(len(sst.index)), fpr)
+		for _, entry := range sst.index {
+			if len(entry.Key) >= sst.options.PrefixBloomLength {
+				pf.Add([]byte(entry.Key[:sst.options.PrefixBloomLength]))
+			}
+		}
+	}
+
+	// Write at the current position rather than the end of the file: a table holding
+	// only range tombstones has no entries, so the reserved metadata space is still unwritten
+	offset, err := sst.dataFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	footer := sstableFooter{
+		BloomOffset:       offset,
+		FalsePositiveRate: fpr,
+		NewestTimestamp:   sst.newestTimestamp,
+	}
+
+	bloomData := bf.Serialize()
+	if _, err := sst.dataFile.Write(bloomData); err != nil {
+		return err
+	}
+	footer.BloomLength = uint32(len(bloomData))
+	footer.PrefixOffset = offset + int64(len(bloomData))
+
+	if pf != nil {
+		prefixData := pf.Serialize()
+		if _, err := sst.dataFile.Write(prefixData); err != nil {
+			return err
+		}
+		footer.PrefixLength = uint32(len(prefixData))
+		footer.PrefixBloomLength = uint32(sst.options.PrefixBloomLength)
+	}
+	footer.RangeDelOffset = footer.PrefixOffset + int64(footer.PrefixLength)
+
+	if len(sst.rangeTombstones) > 0 {
+		rangeDelData := serializeRangeTombstones(sst.rangeTombstones)
+		if _, err := sst.dataFile.Write(rangeDelData); err != nil {
+			return err
+		}
+		footer.RangeDelLength = uint32(len(rangeDelData))
+	}
+
+	// Write fixed-size footer
+	data := make([]byte, sstableFooterSize)
+	pos := 0
+	binary.LittleEndian.PutUint64(data[pos:], uint64(footer.BloomOffset))
+	pos += 8
+	binary.LittleEndian.PutUint32(data[pos:], footer.BloomLength)
+	pos += 4
+	binary.LittleEndian.PutUint64(data[pos:], uint64(footer.PrefixOffset))
+	pos += 8
+	binary.LittleEndian.PutUint32(data[pos:], footer.PrefixLength)
+	pos += 4
+	binary.LittleEndian.PutUint32(data[pos:], footer.PrefixBloomLength)
+	pos += 4
+	binary.LittleEndian.PutUint64(data[pos:], math.Float64bits(footer.FalsePositiveRate))
+	pos += 8
+	binary.LittleEndian.PutUint64(data[pos:], uint64(footer.RangeDelOffset))
+	pos += 8
+	binary.LittleEndian.PutUint32(data[pos:], footer.RangeDelLength)
+	pos += 4
+	binary.LittleEndian.PutUint64(data[pos:], uint64(footer.NewestTimestamp))
+	pos += 8
+	binary.LittleEndian.PutUint32(data[pos:], sstableFooterMagic)
+
+	if _, err := sst.dataFile.Write(data); err != nil {
+		return err
+	}
+
+	sst.filterMu.Lock()
+	sst.footer = footer
+	sst.bloomFilter = bf
+	sst.prefixFilter = pf
+	sst.filtersLoaded = true
+	sst.filterMu.Unlock()
+
+	return nil
+}
+
+// readFooter reads the footer that locates the filter section
+func (sst *SSTable) readFooter() error {
+	// Version 1 tables have no filter section; filters are rebuilt from keys on demand
+	if sst.metadata.Version < 2 {
+		return nil
+	}
+
+	footerSize := int64(sstableFooterSize)
+	switch sst.metadata.Version {
+	case 2:
+		footerSize = sstableFooterSizeV2
+	case 3:
+		footerSize = sstableFooterSizeV3
+	}
+
+	stat, err := sst.dataFile.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() < footerSize {
+		return fmt.Errorf("file too small for footer")
+	}
+
+	data := make([]byte, footerSize)
+	if _, err := sst.dataFile.ReadAt(data, stat.Size()-footerSize); err != nil {
+		return err
+	}
+
+	if magic := binary.LittleEndian.Uint32(data[footerSize-4:]); magic != sstableFooterMagic {
+		return fmt.Errorf("invalid footer magic: %x", magic)
+	}
+
+	pos := 0
+	sst.footer.BloomOffset = int64(binary.LittleEndian.Uint64(data[pos:]))
+	pos += 8
+	sst.footer.BloomLength = binary.LittleEndian.Uint32(data[pos:])
+	pos += 4
+	sst.footer.PrefixOffset = int64(binary.LittleEndian.Uint64(data[pos:]))
+	pos += 8
+	sst.footer.PrefixLength = binary.LittleEndian.Uint32(data[pos:])
+	pos += 4
+	sst.footer.PrefixBloomLength = binary.LittleEndian.Uint32(data[pos:])
+	pos += 4
+	sst.footer.FalsePositiveRate = math.Float64frombits(binary.LittleEndian.Uint64(data[pos:]))
+	pos += 8
+	if footerSize >= sstableFooterSizeV3 {
+		sst.footer.RangeDelOffset = int64(binary.LittleEndian.Uint64(data[pos:]))
+		pos += 8
+		sst.footer.RangeDelLength = binary.LittleEndian.Uint32(data[pos:])
+		pos += 4
+	}
+	if footerSize >= sstableFooterSize {
+		sst.footer.NewestTimestamp = int64(binary.LittleEndian.Uint64(data[pos:]))
+	}
+	sst.newestTimestamp = sst.footer.NewestTimestamp
+
+	sst.options = SSTableOptions{
+		BloomFilterFPR:    sst.footer.FalsePositiveRate,
+		PrefixBloomLength: int(sst.footer.PrefixBloomLength),
+	}
+
+	return nil
+}
+
+// readRangeTombstones reads the range tombstone section located by the footer
+func (sst *SSTable) readRangeTombstones() error {
+	if sst.footer.RangeDelLength == 0 {
+		return nil
+	}
+
+	data := make([]byte, sst.footer.RangeDelLength)
+	if _, err := sst.dataFile.ReadAt(data, sst.footer.RangeDelOffset); err != nil {
+		return err
+	}
+
+	tombstones, err := deserializeRangeTombstones(data)
+	if err != nil {
+		return err
+	}
+	sst.rangeTombstones = tombstones
+	return nil
+}
+
+// loadFilters loads the bloom filters from disk on first use
+func (sst *SSTable) loadFilters() error {
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+
+	if sst.filtersLoaded {
+		return sst.filterLoadErr
+	}
+	sst.filtersLoaded = true
+
+	// Older tables carry no filter section, so rebuild the key filter from the index
+	if sst.footer.BloomLength == 0 {
+		fpr := sst.options.BloomFilterFPR
+		if fpr <= 0 || fpr >= 1 {
+			fpr = DefaultSSTableOptions().BloomFilterFPR
+		}
+		bf := NewBloomFilter(uint64(len(sst.index)), fpr)
+		for _, entry := range sst.index {
+			bf.Add([]byte(entry.Key))
+		}
+		sst.bloomFilter = bf
+		return nil
+	}
+
+	bloomData := make([]byte, sst.footer.BloomLength)
+	if _, err := sst.dataFile.ReadAt(bloomData, sst.footer.BloomOffset); err != nil {
+		sst.filterLoadErr = fmt.Errorf("failed to read bloom filter: %w", err)
+		return sst.filterLoadErr
+	}
+	bf, err := DeserializeBloomFilter(bloomData, sst.footer.FalsePositiveRate)
+	if err != nil {
+		sst.filterLoadErr = fmt.Errorf("failed to decode bloom filter: %w", err)
+		return sst.filterLoadErr
+	}
+	sst.bloomFilter = bf
+
+	if sst.footer.PrefixLength > 0 {
+		prefixData := make([]byte, sst.footer.PrefixLength)
+		if _, err := sst.dataFile.ReadAt(prefixData, sst.footer.PrefixOffset); err != nil {
+			sst.filterLoadErr = fmt.Errorf("failed to read prefix bloom filter: %w", err)
+			return sst.filterLoadErr
+		}
+		pf, err := DeserializeBloomFilter(prefixData, sst.footer.FalsePositiveRate)
+		if err != nil {
+			sst.filterLoadErr = fmt.Errorf("failed to decode prefix bloom filter: %w", err)
+			return sst.filterLoadErr
+		}
+		sst.prefixFilter = pf
+	}
+
+	return nil
+}
+
+// BloomFilter returns the key bloom filter, loading it from disk if needed.
+// Returns nil if the filter could not be loaded.
+func (sst *SSTable) BloomFilter() *BloomFilter {
+	if err := sst.loadFilters(); err != nil {
+		return nil
+	}
+
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+	return sst.bloomFilter
+}
+
+// PrefixBloomFilter returns the prefix bloom filter and the prefix length it was built with.
+// Returns nil if the table has no prefix filter.
+func (sst *SSTable) PrefixBloomFilter() (*BloomFilter, int) {
+	if err := sst.loadFilters(); err != nil {
+		return nil, 0
+	}
+
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+	return sst.prefixFilter, sst.options.PrefixBloomLength
+}
+
+// MightContain checks the key bloom filter
+func (sst *SSTable) MightContain(key string) bool {
+	bf := sst.BloomFilter()
+	if bf == nil {
+		return true // No bloom filter, assume it might contain
+	}
+
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+	return bf.MightContain([]byte(key))
+}
+
+// MightContainPrefix checks whether the SSTable might hold keys with the given prefix,
+// using the key range and, when available, the prefix bloom filter
+func (sst *SSTable) MightContainPrefix(prefix string) bool {
+	if !sst.finalized {
+		return false
+	}
+
+	// Key range check: the prefix range [prefix, prefix+\xff...] must overlap [MinKey, MaxKey]
+	if prefix != "" {
+		if sst.metadata.MaxKey < prefix {
+			return false
+		}
+		if sst.metadata.MinKey > prefix && !strings.HasPrefix(sst.metadata.MinKey, prefix) {
+			return false
+		}
+	}
+
+	pf, prefixLen := sst.PrefixBloomFilter()
+	if pf == nil || prefixLen == 0 || len(prefix) < prefixLen {
+		return true
+	}
+
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+	return pf.MightContain([]byte(prefix[:prefixLen]))
+}
+
+// FiltersLoaded reports whether the bloom filters are held in memory
+func (sst *SSTable) FiltersLoaded() bool {
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+	return sst.filtersLoaded && sst.filterLoadErr == nil
+}
+
+// filterMemoryUsage returns the memory held by loaded filters without forcing a load
+func (sst *SSTable) filterMemoryUsage() int64 {
+	sst.filterMu.Lock()
+	defer sst.filterMu.Unlock()
+
+	var usage int64
+	if sst.bloomFilter != nil {
+		usage += int64(sst.bloomFilter.MemoryUsage())
+	}
+	if sst.prefixFilter != nil {
+		usage += int64(sst.prefixFilter.MemoryUsage())
+	}
+	return usage
+}
+
+// PrefixScan returns all entries (including tombstones) whose key has the given prefix
+func (sst *SSTable) PrefixScan(prefix string) ([]*SSTableEntry, error) {
+	if !sst.finalized {
+		return nil, fmt.Errorf("SSTable not finalized")
+	}
+
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+
+	start := sort.Search(len(sst.index), func(i int) bool {
+		return sst.index[i].Key >= prefix
+	})
+
+	var entries []*SSTableEntry
+	for i := start; i < len(sst.index) && strings.HasPrefix(sst.index[i].Key, prefix); i++ {
+		entry, err := sst.readEntryAt(sst.index[i].Offset, sst.index[i].Length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// MightContainRange checks whether the SSTable's key range overlaps [start, end]
+func (sst *SSTable) MightContainRange(start, end string) bool {
+	if !sst.finalized {
+		return false
+	}
+	return sst.metadata.MaxKey >= start && sst.metadata.MinKey <= end
+}
+
+// RangeScan returns all entries (including tombstones) with keys in [start, end]
+func (sst *SSTable) RangeScan(start, end string) ([]*SSTableEntry, error) {
+	if !sst.finalized {
+		return nil, fmt.Errorf("SSTable not finalized")
+	}
+
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+
+	first := sort.Search(len(sst.index), func(i int) bool {
+		return sst.index[i].Key >= start
+	})
+
+	var entries []*SSTableEntry
+	for i := first; i < len(sst.index) && sst.index[i].Key <= end; i++ {
+		entry, err := sst.readEntryAt(sst.index[i].Offset, sst.index[i].Length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
 // Close closes the SSTable files
 func (sst *SSTable) Close() error {
 	sst.mu.Lock()
@@ -688,6 +1255,18 @@ func (sst *SSTable) cleanup() {
 	}
 }
 
+// removeFiles deletes the data and index files of a closed SSTable
+func (sst *SSTable) removeFiles() error {
+	if err := os.Remove(sst.FilePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	indexPath := filepath.Join(sst.DataDir, fmt.Sprintf("%s.idx", sst.ID))
+	if err := os.Remove(indexPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // Iterator returns an iterator for the SSTable
 func (sst *SSTable) Iterator() *SSTableIterator {
 	return &SSTableIterator{
@@ -698,9 +1277,10 @@ func (sst *SSTable) Iterator() *SSTableIterator {
 
 // SSTableIterator provides iteration over SSTable entries
 type SSTableIterator struct {
-	sstable *SSTable
-	index   int
-	current *SSTableEntry
+	sstable   *SSTable
+	index     int
+	current   *SSTableEntry
+	bytesRead int64
 }
 
 // HasNext returns true if there are more entries
@@ -722,9 +1302,23 @@ func (it *SSTableIterator) Next() (*SSTableEntry, error) {
 
 	it.current = entry
 	it.index++
+	it.bytesRead += int64(indexEntry.Length)
 	return entry, nil
 }
 
+// Seek positions the iterator at the first entry with a key >= key
+func (it *SSTableIterator) Seek(key string) {
+	it.index = sort.Search(len(it.sstable.index), func(i int) bool {
+		return it.sstable.index[i].Key >= key
+	})
+	it.current = nil
+}
+
+// BytesRead returns the number of entry bytes read by the iterator
+func (it *SSTableIterator) BytesRead() int64 {
+	return it.bytesRead
+}
+
 // Current returns the current entry
 func (it *SSTableIterator) Current() *SSTableEntry {
 	return it.current
@@ -0,0 +1,132 @@
+package lsm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// OpenSSTableFile opens an SSTable by the path of its .sst file; the .idx file must be next to it
+func OpenSSTableFile(path string) (*SSTable, error) {
+	if filepath.Ext(path) != ".sst" {
+		return nil, fmt.Errorf("not an SSTable file (expected .sst): %s", path)
+	}
+	return OpenSSTable(strings.TrimSuffix(filepath.Base(path), ".sst"), filepath.Dir(path))
+}
+
+// Metadata returns the SSTable metadata
+func (sst *SSTable) Metadata() SSTableMetadata {
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+	return sst.metadata
+}
+
+// Index returns a copy of the SSTable index in key order
+func (sst *SSTable) Index() []IndexEntry {
+	sst.mu.RLock()
+	defer sst.mu.RUnlock()
+	return append([]IndexEntry(nil), sst.index...)
+}
+
+// BlobLocation describes where the value of a blob index entry is stored,
+// or returns an empty string for other entries
+func (e *SSTableEntry) BlobLocation() string {
+	if !e.BlobIndex {
+		return ""
+	}
+	ref, err := decodeBlobIndex(e.Value)
+	if err != nil {
+		return fmt.Sprintf("invalid blob index: %v", err)
+	}
+	return fmt.Sprintf("%s@%d+%d", blobFileName(ref.File), ref.Offset, ref.Length)
+}
+
+// SSTableVerifyReport summarizes an SSTable verification
+type SSTableVerifyReport struct {
+	Entries         int
+	Deletions       int
+	BlobIndexes     int
+	RangeTombstones int
+	Problems        []string
+}
+
+// OK reports whether verification found no problems
+func (r SSTableVerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify reads every entry of the SSTable and checks entry checksums, index order,
+// metadata, range tombstones and that the bloom filters hold every key
+func (sst *SSTable) Verify() SSTableVerifyReport {
+	var report SSTableVerifyReport
+	problem := func(format string, args ...interface{}) {
+		report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+	}
+
+	index := sst.Index()
+	metadata := sst.Metadata()
+
+	for i, indexEntry := range index {
+		if i > 0 && indexEntry.Key <= index[i-1].Key {
+			problem("index out of order at %q (after %q)", indexEntry.Key, index[i-1].Key)
+		}
+
+		entry, err := sst.readEntryAt(indexEntry.Offset, indexEntry.Length)
+		if err != nil {
+			problem("entry %q at offset %d: %v", indexEntry.Key, indexEntry.Offset, err)
+			continue
+		}
+		if entry.Key != indexEntry.Key {
+			problem("index key %q points at entry %q", indexEntry.Key, entry.Key)
+		}
+
+		report.Entries++
+		if entry.Deleted {
+			report.Deletions++
+		}
+		if entry.BlobIndex {
+			report.BlobIndexes++
+			if _, err := decodeBlobIndex(entry.Value); err != nil {
+				problem("entry %q: %v", entry.Key, err)
+			}
+		}
+	}
+
+	if metadata.NumEntries != uint64(len(index)) {
+		problem("metadata records %d entries, index has %d", metadata.NumEntries, len(index))
+	}
+	if len(index) > 0 {
+		if metadata.MinKey != index[0].Key {
+			problem("metadata min key %q differs from first key %q", metadata.MinKey, index[0].Key)
+		}
+		if metadata.MaxKey != index[len(index)-1].Key {
+			problem("metadata max key %q differs from last key %q", metadata.MaxKey, index[len(index)-1].Key)
+		}
+	}
+	if metadata.FileSize != 0 && metadata.FileSize != sst.FileSize {
+		problem("metadata records %d bytes, file has %d", metadata.FileSize, sst.FileSize)
+	}
+
+	for _, rt := range sst.RangeTombstones() {
+		report.RangeTombstones++
+		if rt.End != "" && rt.Start >= rt.End {
+			problem("empty range tombstone %s", rt)
+		}
+	}
+
+	if err := sst.loadFilters(); err != nil {
+		problem("failed to load bloom filters: %v", err)
+		return report
+	}
+	_, prefixLen := sst.PrefixBloomFilter()
+	for _, indexEntry := range index {
+		if !sst.MightContain(indexEntry.Key) {
+			problem("bloom filter misses key %q", indexEntry.Key)
+		}
+		if prefixLen > 0 && len(indexEntry.Key) >= prefixLen && !sst.MightContainPrefix(indexEntry.Key[:prefixLen]) {
+			problem("prefix bloom filter misses key %q", indexEntry.Key)
+		}
+	}
+
+	return report
+}
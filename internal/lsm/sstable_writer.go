@@ -0,0 +1,80 @@
+package lsm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SSTableWriter builds an SSTable outside of an LSM-Tree, for example to bulk
+// load a dataset with IngestExternalFiles. Keys must be added in strictly
+// increasing order.
+type SSTableWriter struct {
+	sstable *SSTable
+	lastKey string
+	hasKey  bool
+}
+
+// NewSSTableWriter creates an SSTable at path, which must end in .sst.
+// The index is written next to it with an .idx extension.
+func NewSSTableWriter(path string, options SSTableOptions) (*SSTableWriter, error) {
+	if filepath.Ext(path) != ".sst" {
+		return nil, fmt.Errorf("SSTable path must end in .sst: %s", path)
+	}
+
+	id := strings.TrimSuffix(filepath.Base(path), ".sst")
+	sstable, err := NewSSTableWithOptions(id, filepath.Dir(path), 0, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSTableWriter{sstable: sstable}, nil
+}
+
+// Put adds a key-value pair
+func (w *SSTableWriter) Put(key, value string) error {
+	return w.add(key, value, false)
+}
+
+// Delete adds a deletion marker that hides the key in data ingested earlier
+func (w *SSTableWriter) Delete(key string) error {
+	return w.add(key, "", true)
+}
+
+func (w *SSTableWriter) add(key, value string, deleted bool) error {
+	if w.hasKey && key <= w.lastKey {
+		return fmt.Errorf("keys must be added in increasing order: %q after %q", key, w.lastKey)
+	}
+
+	if err := w.sstable.PutWithTimestamp(key, value, deleted, time.Now().UnixNano()); err != nil {
+		return err
+	}
+	w.lastKey = key
+	w.hasKey = true
+	return nil
+}
+
+// Entries returns the number of entries added so far
+func (w *SSTableWriter) Entries() uint64 {
+	return w.sstable.NumEntries
+}
+
+// Finish writes the index, filters and metadata and closes the files
+func (w *SSTableWriter) Finish() error {
+	if !w.hasKey {
+		w.Abort()
+		return fmt.Errorf("cannot write an empty SSTable")
+	}
+
+	if err := w.sstable.Finalize(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to finalize SSTable: %w", err)
+	}
+	return w.sstable.Close()
+}
+
+// Abort closes and removes the files written so far
+func (w *SSTableWriter) Abort() {
+	w.sstable.cleanup()
+}
@@ -0,0 +1,384 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WALConfig controls the write-ahead log shared by all column families
+type WALConfig struct {
+	SegmentSize int64 // Size at which the active log segment is rotated
+	Sync        bool  // Sync the log after every write instead of leaving it to the OS
+}
+
+// DefaultWALConfig returns the default write-ahead log configuration
+func DefaultWALConfig() WALConfig {
+	return WALConfig{
+		SegmentSize: 64 * 1024 * 1024, // 64MB
+	}
+}
+
+// walOpKind identifies the operation in a WAL record
+type walOpKind uint8
+
+const (
+	walPut walOpKind = iota + 1
+	walDelete
+	walRangeDelete // key and value hold the tombstone's Start and End
+)
+
+// walOp is one write to one column family
+type walOp struct {
+	kind   walOpKind
+	family uint32
+	key    string
+	value  string
+}
+
+// walRecord is a group of writes logged and applied atomically
+type walRecord struct {
+	lsn uint64
+	ops []walOp
+}
+
+// lsnRange is the range of WAL records held by a MemTable
+type lsnRange struct {
+	first, last uint64
+}
+
+func (r *lsnRange) add(lsn uint64) {
+	if r.first == 0 {
+		r.first = lsn
+	}
+	r.last = lsn
+}
+
+// walSegment is one log file
+type walSegment struct {
+	seq     uint64
+	path    string
+	size    int64
+	lastLSN uint64
+}
+
+// writeAheadLog appends records to numbered segments. A segment is deleted once
+// every record in it has been flushed to SSTables by its column families.
+type writeAheadLog struct {
+	mu        sync.Mutex
+	dir       string
+	config    WALConfig
+	file      *os.File
+	segments  []*walSegment // Oldest first; the last one is active
+	nextLSN   uint64
+	unflushed map[uint32]uint64 // Oldest LSN not yet flushed, per column family
+}
+
+const walHeaderSize = 8 // Record length and CRC32
+
+// walSegmentName returns the file name of a log segment
+func walSegmentName(seq uint64) string {
+	return fmt.Sprintf("wal_%06d.log", seq)
+}
+
+// openWAL reads the records left by a previous instance and opens a new active segment.
+// The old segments are kept until the recovered writes are flushed.
+func openWAL(dir string, config WALConfig) (*writeAheadLog, []walRecord, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	wal := &writeAheadLog{dir: dir, config: config, nextLSN: 1, unflushed: make(map[uint32]uint64)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "wal_") || filepath.Ext(name) != ".log" {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "wal_"), ".log"), 10, 64)
+		if err != nil {
+			continue
+		}
+		segment := &walSegment{seq: seq, path: filepath.Join(dir, name)}
+		if info, err := entry.Info(); err == nil {
+			segment.size = info.Size()
+		}
+		wal.segments = append(wal.segments, segment)
+	}
+	sort.Slice(wal.segments, func(i, j int) bool {
+		return wal.segments[i].seq < wal.segments[j].seq
+	})
+
+	// A torn record can only be the last one written; nothing after it is replayed
+	var records []walRecord
+	for _, segment := range wal.segments {
+		segmentRecords, err := readWALSegment(segment.path)
+		records = append(records, segmentRecords...)
+		if len(segmentRecords) > 0 {
+			segment.lastLSN = segmentRecords[len(segmentRecords)-1].lsn
+		}
+		if err != nil {
+			fmt.Printf("Warning: WAL replay stopped at %s: %v\n", filepath.Base(segment.path), err)
+			break
+		}
+	}
+	for _, record := range records {
+		if record.lsn >= wal.nextLSN {
+			wal.nextLSN = record.lsn + 1
+		}
+	}
+
+	seq := uint64(1)
+	if len(wal.segments) > 0 {
+		seq = wal.segments[len(wal.segments)-1].seq + 1
+	}
+	if err := wal.openSegment(seq); err != nil {
+		return nil, nil, err
+	}
+	return wal, records, nil
+}
+
+// readWALSegment decodes the records of a segment up to the first damaged one
+func readWALSegment(path string) ([]walRecord, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL segment: %w", err)
+	}
+
+	var records []walRecord
+	for len(data) > 0 {
+		if len(data) < walHeaderSize {
+			return records, io.ErrUnexpectedEOF
+		}
+		length := binary.LittleEndian.Uint32(data[0:4])
+		checksum := binary.LittleEndian.Uint32(data[4:8])
+		if uint64(len(data)-walHeaderSize) < uint64(length) {
+			return records, io.ErrUnexpectedEOF
+		}
+		payload := data[walHeaderSize : walHeaderSize+int(length)]
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return records, fmt.Errorf("checksum mismatch")
+		}
+
+		record, err := decodeWALRecord(payload)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+		data = data[walHeaderSize+int(length):]
+	}
+	return records, nil
+}
+
+// encodeWALRecord encodes the LSN, the op count and each op
+func encodeWALRecord(lsn uint64, ops []walOp) []byte {
+	size := 12
+	for _, op := range ops {
+		size += 13 + len(op.key) + len(op.value)
+	}
+
+	buf := make([]byte, 0, walHeaderSize+size)
+	buf = append(buf, make([]byte, walHeaderSize)...)
+	buf = binary.LittleEndian.AppendUint64(buf, lsn)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(ops))) // #nosec G115 - batches are far smaller than 4G ops
+	for _, op := range ops {
+		buf = append(buf, byte(op.kind))
+		buf = binary.LittleEndian.AppendUint32(buf, op.family)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(op.key))) // #nosec G115 - keys are far smaller than 4GB
+		buf = append(buf, op.key...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(op.value))) // #nosec G115 - values are far smaller than 4GB
+		buf = append(buf, op.value...)
+	}
+
+	payload := buf[walHeaderSize:]
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload))) // #nosec G115 - records are far smaller than 4GB
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	return buf
+}
+
+// decodeWALRecord decodes a record payload
+func decodeWALRecord(payload []byte) (walRecord, error) {
+	errCorrupt := errors.New("corrupt WAL record")
+	if len(payload) < 12 {
+		return walRecord{}, errCorrupt
+	}
+
+	record := walRecord{lsn: binary.LittleEndian.Uint64(payload[0:8])}
+	count := binary.LittleEndian.Uint32(payload[8:12])
+	data := payload[12:]
+
+	readString := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := binary.LittleEndian.Uint32(data[0:4])
+		if uint64(len(data)-4) < uint64(n) {
+			return "", false
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, true
+	}
+
+	for i := uint32(0); i < count; i++ {
+		if len(data) < 5 {
+			return walRecord{}, errCorrupt
+		}
+		op := walOp{kind: walOpKind(data[0]), family: binary.LittleEndian.Uint32(data[1:5])}
+		data = data[5:]
+
+		var ok bool
+		if op.key, ok = readString(); !ok {
+			return walRecord{}, errCorrupt
+		}
+		if op.value, ok = readString(); !ok {
+			return walRecord{}, errCorrupt
+		}
+		record.ops = append(record.ops, op)
+	}
+	return record, nil
+}
+
+// openSegment creates a segment and makes it the active one. Callers hold mu or own the log exclusively.
+func (w *writeAheadLog) openSegment(seq uint64) error {
+	path := filepath.Join(w.dir, walSegmentName(seq))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 - path is safely constructed with filepath.Join
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment: %w", err)
+	}
+
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to close WAL segment: %w", err)
+		}
+	}
+	w.file = file
+	w.segments = append(w.segments, &walSegment{seq: seq, path: path})
+	return nil
+}
+
+// append logs ops as one record and returns its LSN
+func (w *writeAheadLog) append(ops []walOp) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, fmt.Errorf("WAL is closed")
+	}
+
+	lsn := w.nextLSN
+	data := encodeWALRecord(lsn, ops)
+	if _, err := w.file.Write(data); err != nil {
+		return 0, fmt.Errorf("failed to append to WAL: %w", err)
+	}
+	if w.config.Sync {
+		if err := w.file.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync WAL: %w", err)
+		}
+	}
+	w.nextLSN++
+
+	active := w.segments[len(w.segments)-1]
+	active.size += int64(len(data))
+	active.lastLSN = lsn
+	for _, op := range ops {
+		if _, ok := w.unflushed[op.family]; !ok {
+			w.unflushed[op.family] = lsn
+		}
+	}
+
+	if w.config.SegmentSize > 0 && active.size >= w.config.SegmentSize {
+		if err := w.openSegment(active.seq + 1); err != nil {
+			// The record is logged; keep writing to the full segment
+			fmt.Printf("Warning: failed to rotate WAL: %v\n", err)
+		}
+	}
+	return lsn, nil
+}
+
+// markFlushed records the oldest LSN a column family has not flushed yet
+// (0 if it has flushed everything) and deletes segments no family needs anymore
+func (w *writeAheadLog) markFlushed(family uint32, oldestUnflushed uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if oldestUnflushed == 0 {
+		delete(w.unflushed, family)
+	} else {
+		w.unflushed[family] = oldestUnflushed
+	}
+	w.removeObsoleteSegments()
+}
+
+// forget stops tracking a dropped column family
+func (w *writeAheadLog) forget(family uint32) {
+	w.markFlushed(family, 0)
+}
+
+// removeObsoleteSegments deletes inactive segments whose records are all flushed. Callers hold mu.
+func (w *writeAheadLog) removeObsoleteSegments() {
+	oldest := w.nextLSN
+	for _, lsn := range w.unflushed {
+		if lsn < oldest {
+			oldest = lsn
+		}
+	}
+
+	for len(w.segments) > 1 && w.segments[0].lastLSN < oldest {
+		if err := os.Remove(w.segments[0].path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to remove WAL segment: %v\n", err)
+			return
+		}
+		w.segments = w.segments[1:]
+	}
+}
+
+// size returns the number of segments and their total size
+func (w *writeAheadLog) size() (int, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var total int64
+	for _, segment := range w.segments {
+		total += segment.size
+	}
+	return len(w.segments), total
+}
+
+// close closes the active segment. When every write has been flushed the log is
+// no longer needed and all segments are deleted.
+func (w *writeAheadLog) close(flushed bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close WAL segment: %w", err)
+	}
+
+	if flushed {
+		for _, segment := range w.segments {
+			if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove WAL segment: %w", err)
+			}
+		}
+		w.segments = nil
+	}
+	return nil
+}
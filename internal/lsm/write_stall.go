@@ -0,0 +1,122 @@
+package lsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// WriteStallConfig controls backpressure on writers when flushes and L0
+// compaction fall behind. A trigger of 0 disables it.
+type WriteStallConfig struct {
+	ImmutableSlowdownTrigger int           // Immutable MemTables at which each write is delayed
+	ImmutableStopTrigger     int           // Immutable MemTables at which writers block until a flush
+	L0SlowdownTrigger        int           // L0 SSTables at which each write is delayed
+	L0StopTrigger            int           // L0 SSTables at which writers block until L0 is compacted
+	SlowdownDelay            time.Duration // Delay applied to each write while slowed down
+}
+
+// DefaultWriteStallConfig returns default write stall thresholds
+func DefaultWriteStallConfig() WriteStallConfig {
+	return WriteStallConfig{
+		ImmutableSlowdownTrigger: 2,
+		ImmutableStopTrigger:     4,
+		L0SlowdownTrigger:        8,
+		L0StopTrigger:            12,
+		SlowdownDelay:            time.Millisecond,
+	}
+}
+
+// writeStallState is the backpressure currently applied to writers
+type writeStallState int
+
+const (
+	writeNormal writeStallState = iota
+	writeSlowdown
+	writeStop
+)
+
+// writeStallState compares the flush and L0 backlog with the triggers. Callers hold mu.
+func (lsm *LSMTree) writeStallState() writeStallState {
+	config := lsm.config.WriteStall
+	immutables := len(lsm.immutableTables)
+
+	// FIFO never compacts L0, so its file count must not stall writers
+	l0 := len(lsm.levels[0].SSTables)
+	if lsm.config.CompactionStyle == FIFOCompaction {
+		l0 = 0
+	}
+
+	reached := func(count, trigger int) bool {
+		return trigger > 0 && count >= trigger
+	}
+	switch {
+	case reached(immutables, config.ImmutableStopTrigger) || reached(l0, config.L0StopTrigger):
+		return writeStop
+	case reached(immutables, config.ImmutableSlowdownTrigger) || reached(l0, config.L0SlowdownTrigger):
+		return writeSlowdown
+	default:
+		return writeNormal
+	}
+}
+
+// throttleWrite delays the calling writer while the tree is slowed down and blocks
+// it while writes are stopped. A blocked writer is released after the next compaction
+// pass even if the backlog remains, so a level the compaction style cannot shrink
+// paces writers to compaction instead of blocking them forever.
+// Callers hold mu, which is released while waiting.
+func (lsm *LSMTree) throttleWrite() error {
+	var start time.Time
+	delayed := false
+	passes := lsm.compactionPasses
+	defer func() {
+		if !start.IsZero() {
+			lsm.stats.WriteStallTime += time.Since(start)
+		}
+	}()
+
+	for {
+		switch lsm.writeStallState() {
+		case writeStop:
+			select {
+			case <-lsm.stopCh:
+				return fmt.Errorf("LSM-Tree is closed")
+			default:
+			}
+			if lsm.compactionPasses != passes {
+				return nil
+			}
+			if start.IsZero() {
+				start = time.Now()
+				lsm.stats.WriteStops++
+			}
+			lsm.scheduleCompaction()
+			lsm.stallCond.Wait()
+
+		case writeSlowdown:
+			if delayed {
+				return nil
+			}
+			if start.IsZero() {
+				start = time.Now()
+			}
+			lsm.stats.WriteSlowdowns++
+			delayed = true
+
+			lsm.mu.Unlock()
+			time.Sleep(lsm.config.WriteStall.SlowdownDelay)
+			lsm.mu.Lock()
+
+		default:
+			return nil
+		}
+	}
+}
+
+// scheduleCompaction wakes the compaction worker unless a pass is already queued
+func (lsm *LSMTree) scheduleCompaction() {
+	select {
+	case lsm.compactionCh <- struct{}{}:
+	default:
+		// Compaction already queued
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/engine"
 )
 
 // Job represents a work item for the process pool
@@ -31,7 +31,7 @@ type JobResult struct {
 // Worker represents a worker in the process pool
 type Worker struct {
 	ID     int
-	store  *kvstore.KVStore
+	store  engine.Store
 	jobCh  chan Job
 	quitCh chan struct{}
 	wg     *sync.WaitGroup
@@ -62,7 +62,7 @@ type PoolStats struct {
 }
 
 // NewProcessPool creates a new process pool
-func NewProcessPool(workerSize int, queueSize int, store *kvstore.KVStore) *ProcessPool {
+func NewProcessPool(workerSize int, queueSize int, store engine.Store) *ProcessPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pool := &ProcessPool{
@@ -351,7 +351,7 @@ func (w *Worker) processJob(job Job) JobResult {
 		}
 
 	case "stats":
-		stats, err := w.store.GetCompactionStats()
+		stats, err := w.store.Stats()
 		if err != nil {
 			result.Success = false
 			result.Error = err
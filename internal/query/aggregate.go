@@ -0,0 +1,466 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isAggregateFunction reports whether a function combines the rows of a group
+func isAggregateFunction(name string) bool {
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		return true
+	default:
+		return false
+	}
+}
+
+// validateFunctions checks the function calls of a SELECT statement: known names,
+// argument counts, and aggregates only where groups exist
+func (e *Executor) validateFunctions(stmt *SelectStatement) error {
+	aggregate := e.isAggregationQuery(stmt)
+	for _, field := range stmt.Fields {
+		if err := e.checkFunctions(unalias(field), true); err != nil {
+			return err
+		}
+	}
+	if stmt.Where != nil {
+		if err := e.checkFunctions(stmt.Where, false); err != nil {
+			return err
+		}
+	}
+	for _, group := range stmt.GroupBy {
+		if err := e.checkFunctions(group, false); err != nil {
+			return err
+		}
+	}
+	if stmt.Having != nil {
+		if err := e.checkFunctions(stmt.Having, true); err != nil {
+			return err
+		}
+	}
+	if stmt.OrderBy != nil {
+		if err := e.checkFunctions(orderExpression(stmt), aggregate); err != nil {
+			return err
+		}
+	}
+
+	if !aggregate {
+		if stmt.Having != nil {
+			return fmt.Errorf("HAVING requires GROUP BY or an aggregate function")
+		}
+		return nil
+	}
+
+	// Every selected field is computed from aggregates and the grouping expressions
+	fields := append([]Expression{}, stmt.Fields...)
+	if stmt.OrderBy != nil {
+		fields = append(fields, orderExpression(stmt))
+	}
+	for _, field := range fields {
+		if !isGrouped(unalias(field), stmt.GroupBy) {
+			return fmt.Errorf("%s must appear in GROUP BY or be used in an aggregate function", unalias(field).String())
+		}
+	}
+	return nil
+}
+
+// checkFunctions checks the function calls of an expression: known names, argument
+// counts, and aggregates only if aggregateAllowed
+func (e *Executor) checkFunctions(expr Expression, aggregateAllowed bool) error {
+	switch exp := expr.(type) {
+	case *FunctionExpression:
+		switch {
+		case isAggregateFunction(exp.Name):
+			if !aggregateAllowed {
+				return fmt.Errorf("aggregate function %s is not allowed here", exp.Name)
+			}
+			if len(exp.Arguments) != 1 {
+				return fmt.Errorf("%s takes exactly one argument", exp.Name)
+			}
+			if _, ok := exp.Arguments[0].(*WildcardExpression); ok && exp.Name != "COUNT" {
+				return fmt.Errorf("%s(*) is not supported", exp.Name)
+			}
+			if exp.Distinct && exp.Name != "COUNT" {
+				return fmt.Errorf("DISTINCT is only supported in COUNT")
+			}
+			return e.checkFunctions(exp.Arguments[0], false)
+		case isScalarFunction(exp.Name):
+			if err := checkArguments(exp); err != nil {
+				return err
+			}
+			if exp.Distinct {
+				return fmt.Errorf("DISTINCT is only supported in COUNT")
+			}
+			for _, arg := range exp.Arguments {
+				if err := e.checkFunctions(arg, aggregateAllowed); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown function %s", exp.Name)
+		}
+	case *BinaryExpression:
+		if err := e.checkFunctions(exp.Left, aggregateAllowed); err != nil {
+			return err
+		}
+		return e.checkFunctions(exp.Right, aggregateAllowed)
+	case *UnaryExpression:
+		return e.checkFunctions(exp.Right, aggregateAllowed)
+	case *CastExpression:
+		return e.checkFunctions(exp.Expression, aggregateAllowed)
+	case *BetweenExpression:
+		for _, operand := range []Expression{exp.Field, exp.Start, exp.End} {
+			if err := e.checkFunctions(operand, aggregateAllowed); err != nil {
+				return err
+			}
+		}
+	case *InExpression:
+		for _, operand := range append([]Expression{exp.Field}, exp.Values...) {
+			if err := e.checkFunctions(operand, aggregateAllowed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isGrouped reports whether an expression has one value per group: a grouping
+// expression, an aggregate, a literal, or computed from those
+func isGrouped(expr Expression, groupBy []Expression) bool {
+	for _, group := range groupBy {
+		if group.String() == expr.String() {
+			return true
+		}
+	}
+
+	switch exp := expr.(type) {
+	case *StringLiteral, *NumberLiteral, *BooleanLiteral, *NullLiteral:
+		return true
+	case *FunctionExpression:
+		if isAggregateFunction(exp.Name) {
+			return true
+		}
+		for _, arg := range exp.Arguments {
+			if !isGrouped(arg, groupBy) {
+				return false
+			}
+		}
+		return true
+	case *BinaryExpression:
+		return isComputed(exp) && isGrouped(exp.Left, groupBy) && isGrouped(exp.Right, groupBy)
+	case *UnaryExpression:
+		return isComputed(exp) && isGrouped(exp.Right, groupBy)
+	case *CastExpression:
+		return isGrouped(exp.Expression, groupBy)
+	default:
+		return false
+	}
+}
+
+// containsAggregate reports whether an expression calls an aggregate function
+func containsAggregate(expr Expression) bool {
+	switch exp := expr.(type) {
+	case *FunctionExpression:
+		if isAggregateFunction(exp.Name) {
+			return true
+		}
+		for _, arg := range exp.Arguments {
+			if containsAggregate(arg) {
+				return true
+			}
+		}
+	case *BinaryExpression:
+		return containsAggregate(exp.Left) || containsAggregate(exp.Right)
+	case *UnaryExpression:
+		return containsAggregate(exp.Right)
+	case *CastExpression:
+		return containsAggregate(exp.Expression)
+	case *AliasExpression:
+		return containsAggregate(exp.Expression)
+	}
+	return false
+}
+
+// columnValue returns the text value of an expression for a row: a field, a JSON path,
+// a literal, a scalar function, arithmetic, a CAST or, in group rows, an aggregate
+// column. It returns false if the value is missing, including JSON paths that do not
+// resolve or are null and arithmetic on values that are not numbers.
+func (e *Executor) columnValue(expr Expression, row map[string]string) (string, bool) {
+	// Group rows of aggregation queries hold grouped and aggregate expressions as columns
+	if isComputed(expr) {
+		if value, exists := row[expr.String()]; exists {
+			return value, true
+		}
+	}
+
+	switch exp := expr.(type) {
+	case *Identifier:
+		value, exists := row[exp.Value]
+		return value, exists
+	case *JSONPathExpression:
+		value, ok := typedValue(exp, row)
+		if !ok || value == nil {
+			return "", false
+		}
+		return jsonText(value), true
+	case *StringLiteral:
+		return exp.Value, true
+	case *NumberLiteral:
+		return exp.Value, true
+	case *BooleanLiteral:
+		return exp.String(), true
+	case *FunctionExpression:
+		if isAggregateFunction(exp.Name) {
+			return "", false // Aggregates only have values in group rows
+		}
+		return e.scalarFunction(exp, row)
+	case *BinaryExpression:
+		if !isArithmeticOperator(exp.Operator) {
+			return "", false
+		}
+		return e.arithmetic(exp, row)
+	case *UnaryExpression:
+		number, ok := e.numberValue(exp.Right, row)
+		if !ok || exp.Operator != NEG_OP {
+			return "", false
+		}
+		return formatNumber(-number), true
+	case *CastExpression:
+		return e.cast(exp, row)
+	case *AliasExpression:
+		return e.columnValue(exp.Expression, row)
+	default:
+		return "", false
+	}
+}
+
+// aggregateState accumulates one aggregate function over the rows of a group
+type aggregateState struct {
+	count    int
+	sum      float64
+	numbers  int // Values SUM and AVG could parse as numbers
+	min, max string
+	seen     bool // min and max hold a value
+	distinct map[string]struct{}
+}
+
+// group is the grouping values and aggregate states of one GROUP BY group
+type group struct {
+	row    map[string]string // Grouping expression columns
+	values []string          // Grouping values in GROUP BY order, for sorting groups
+	states []*aggregateState
+}
+
+// aggregation groups the rows of an aggregate query and accumulates its aggregates
+type aggregation struct {
+	executor   *Executor
+	groupBy    []Expression
+	aggregates []*FunctionExpression // Aggregates of SELECT and HAVING, without duplicates
+	groups     map[string]*group
+}
+
+// newAggregation collects the aggregates a statement computes for each group
+func (e *Executor) newAggregation(stmt *SelectStatement) *aggregation {
+	a := &aggregation{executor: e, groupBy: stmt.GroupBy, groups: make(map[string]*group)}
+
+	seen := make(map[string]bool)
+	var collect func(expr Expression)
+	collect = func(expr Expression) {
+		switch exp := expr.(type) {
+		case *FunctionExpression:
+			if !isAggregateFunction(exp.Name) {
+				for _, arg := range exp.Arguments {
+					collect(arg)
+				}
+			} else if !seen[exp.String()] {
+				seen[exp.String()] = true
+				a.aggregates = append(a.aggregates, exp)
+			}
+		case *CastExpression:
+			collect(exp.Expression)
+		case *AliasExpression:
+			collect(exp.Expression)
+		case *BinaryExpression:
+			collect(exp.Left)
+			collect(exp.Right)
+		case *UnaryExpression:
+			collect(exp.Right)
+		case *BetweenExpression:
+			collect(exp.Field)
+			collect(exp.Start)
+			collect(exp.End)
+		case *InExpression:
+			collect(exp.Field)
+			for _, value := range exp.Values {
+				collect(value)
+			}
+		}
+	}
+	for _, field := range stmt.Fields {
+		collect(field)
+	}
+	if stmt.Having != nil {
+		collect(stmt.Having)
+	}
+	if stmt.OrderBy != nil {
+		collect(orderExpression(stmt))
+	}
+
+	// Without GROUP BY every query has exactly one group, even over no rows
+	if len(a.groupBy) == 0 {
+		a.groups[""] = a.newGroup(map[string]string{}, nil)
+	}
+	return a
+}
+
+func (a *aggregation) newGroup(row map[string]string, values []string) *group {
+	g := &group{row: row, values: values, states: make([]*aggregateState, len(a.aggregates))}
+	for i := range g.states {
+		g.states[i] = &aggregateState{distinct: make(map[string]struct{})}
+	}
+	return g
+}
+
+// add accumulates a row into its group
+func (a *aggregation) add(row map[string]string) {
+	var key strings.Builder
+	values := make([]string, len(a.groupBy))
+	groupRow := make(map[string]string, len(a.groupBy))
+	for i, expr := range a.groupBy {
+		// Rows without a grouping value form their own group, like SQL NULLs
+		value, ok := a.executor.columnValue(expr, row)
+		if ok {
+			key.WriteString("+" + value)
+			groupRow[expr.String()] = value
+		} else {
+			key.WriteString("-")
+		}
+		key.WriteByte(0)
+		values[i] = value
+	}
+
+	g := a.groups[key.String()]
+	if g == nil {
+		g = a.newGroup(groupRow, values)
+		a.groups[key.String()] = g
+	}
+	for i, fn := range a.aggregates {
+		a.accumulate(g.states[i], fn, row)
+	}
+}
+
+// accumulate adds a row to the state of one aggregate function
+func (a *aggregation) accumulate(state *aggregateState, fn *FunctionExpression, row map[string]string) {
+	if _, ok := fn.Arguments[0].(*WildcardExpression); ok {
+		state.count++
+		return
+	}
+	value, ok := a.executor.columnValue(fn.Arguments[0], row)
+	if !ok {
+		return
+	}
+
+	switch fn.Name {
+	case "COUNT":
+		if fn.Distinct {
+			state.distinct[value] = struct{}{}
+		} else {
+			state.count++
+		}
+	case "SUM", "AVG":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			state.sum += number
+			state.numbers++
+		}
+	case "MIN", "MAX":
+		if !state.seen || a.executor.compareValues(value, state.min) < 0 {
+			state.min = value
+		}
+		if !state.seen || a.executor.compareValues(value, state.max) > 0 {
+			state.max = value
+		}
+		state.seen = true
+	}
+}
+
+// result returns the value of an aggregate, or false if it has none (SUM, AVG, MIN and
+// MAX over no values)
+func (state *aggregateState) result(fn *FunctionExpression) (string, bool) {
+	switch fn.Name {
+	case "COUNT":
+		if fn.Distinct {
+			return strconv.Itoa(len(state.distinct)), true
+		}
+		return strconv.Itoa(state.count), true
+	case "SUM":
+		return formatNumber(state.sum), state.numbers > 0
+	case "AVG":
+		if state.numbers == 0 {
+			return "", false
+		}
+		return formatNumber(state.sum / float64(state.numbers)), true
+	case "MIN":
+		return state.min, state.seen
+	case "MAX":
+		return state.max, state.seen
+	default:
+		return "", false
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// rows returns one row per group, holding its grouping columns and aggregate columns,
+// ordered by the grouping values
+func (a *aggregation) rows() []map[string]string {
+	groups := make([]*group, 0, len(a.groups))
+	for _, g := range a.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		for k := range groups[i].values {
+			if cmp := a.executor.compareValues(groups[i].values[k], groups[j].values[k]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	rows := make([]map[string]string, len(groups))
+	for i, g := range groups {
+		row := make(map[string]string, len(g.row)+len(a.aggregates))
+		for column, value := range g.row {
+			row[column] = value
+		}
+		for j, fn := range a.aggregates {
+			if value, ok := g.states[j].result(fn); ok {
+				row[fn.String()] = value
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// selectColumns projects group rows onto the selected fields
+func (e *Executor) selectColumns(fields []Expression, rows []map[string]string) []map[string]string {
+	result := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		selected := make(map[string]string, len(fields))
+		for _, field := range fields {
+			if value, exists := row[unalias(field).String()]; exists {
+				selected[columnName(field)] = value
+			} else if value, ok := e.columnValue(unalias(field), row); ok {
+				selected[columnName(field)] = value
+			}
+		}
+		result[i] = selected
+	}
+	return result
+}
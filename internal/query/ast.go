@@ -2,6 +2,7 @@ package query
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -35,12 +36,19 @@ const (
 	GTE                  // >=
 	LIKE_OP              // LIKE
 	CONTAINS_OP          // CONTAINS
+	MATCH_OP             // MATCH
 	BETWEEN_OP           // BETWEEN
 	IN_OP                // IN
 	REGEX_OP             // REGEX
 	AND_OP               // AND
 	OR_OP                // OR
 	NOT_OP               // NOT
+	ADD_OP               // +
+	SUB_OP               // -
+	MUL_OP               // *
+	DIV_OP               // /
+	MOD_OP               // %
+	NEG_OP               // unary -
 )
 
 // String returns string representation of operator
@@ -62,6 +70,8 @@ func (op Operator) String() string {
 		return "LIKE"
 	case CONTAINS_OP:
 		return "CONTAINS"
+	case MATCH_OP:
+		return "MATCH"
 	case BETWEEN_OP:
 		return "BETWEEN"
 	case IN_OP:
@@ -74,6 +84,16 @@ func (op Operator) String() string {
 		return "OR"
 	case NOT_OP:
 		return "NOT"
+	case ADD_OP:
+		return "+"
+	case SUB_OP, NEG_OP:
+		return "-"
+	case MUL_OP:
+		return "*"
+	case DIV_OP:
+		return "/"
+	case MOD_OP:
+		return "%"
 	default:
 		return "UNKNOWN"
 	}
@@ -84,6 +104,8 @@ type SelectStatement struct {
 	Fields  []Expression // SELECT fields (* or specific fields)
 	From    string       // FROM table (always "moz" for our case)
 	Where   Expression   // WHERE clause
+	GroupBy []Expression // GROUP BY expressions
+	Having  Expression   // HAVING clause, evaluated on each group
 	OrderBy *OrderClause // ORDER BY clause
 	Limit   *LimitClause // LIMIT clause
 }
@@ -111,6 +133,20 @@ func (ss *SelectStatement) String() string {
 		out.WriteString(ss.Where.String())
 	}
 
+	if len(ss.GroupBy) > 0 {
+		groups := make([]string, len(ss.GroupBy))
+		for i, group := range ss.GroupBy {
+			groups[i] = group.String()
+		}
+		out.WriteString(" GROUP BY ")
+		out.WriteString(strings.Join(groups, ", "))
+	}
+
+	if ss.Having != nil {
+		out.WriteString(" HAVING ")
+		out.WriteString(ss.Having.String())
+	}
+
 	if ss.OrderBy != nil {
 		out.WriteString(" ")
 		out.WriteString(ss.OrderBy.String())
@@ -124,14 +160,92 @@ func (ss *SelectStatement) String() string {
 	return out.String()
 }
 
+// InsertStatement represents an INSERT query
+type InsertStatement struct {
+	Table   string         // INTO table
+	Columns []string       // Column list; key and value in either order
+	Rows    [][]Expression // VALUES tuples, one expression per column
+}
+
+func (is *InsertStatement) statementNode() {}
+func (is *InsertStatement) String() string {
+	rows := make([]string, len(is.Rows))
+	for i, row := range is.Rows {
+		values := make([]string, len(row))
+		for j, value := range row {
+			values[j] = value.String()
+		}
+		rows[i] = "(" + strings.Join(values, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", is.Table, strings.Join(is.Columns, ", "), strings.Join(rows, ", "))
+}
+
+// Assignment is one "field = expression" of an UPDATE SET clause
+type Assignment struct {
+	Field string
+	Value Expression
+}
+
+// UpdateStatement represents an UPDATE query
+type UpdateStatement struct {
+	Table string       // UPDATE table
+	Set   []Assignment // SET clause
+	Where Expression   // WHERE clause
+}
+
+func (us *UpdateStatement) statementNode() {}
+func (us *UpdateStatement) String() string {
+	var out strings.Builder
+
+	assignments := make([]string, len(us.Set))
+	for i, assignment := range us.Set {
+		assignments[i] = assignment.Field + " = " + assignment.Value.String()
+	}
+	out.WriteString(fmt.Sprintf("UPDATE %s SET %s", us.Table, strings.Join(assignments, ", ")))
+
+	if us.Where != nil {
+		out.WriteString(" WHERE ")
+		out.WriteString(us.Where.String())
+	}
+	return out.String()
+}
+
+// DeleteStatement represents a DELETE query
+type DeleteStatement struct {
+	Table string     // FROM table
+	Where Expression // WHERE clause
+}
+
+func (ds *DeleteStatement) statementNode() {}
+func (ds *DeleteStatement) String() string {
+	if ds.Where != nil {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", ds.Table, ds.Where.String())
+	}
+	return fmt.Sprintf("DELETE FROM %s", ds.Table)
+}
+
+// ExplainStatement represents EXPLAIN [ANALYZE] SELECT ...
+type ExplainStatement struct {
+	Analyze   bool      // Run the query and measure each stage
+	Statement Statement // Statement to explain
+}
+
+func (es *ExplainStatement) statementNode() {}
+func (es *ExplainStatement) String() string {
+	if es.Analyze {
+		return "EXPLAIN ANALYZE " + es.Statement.String()
+	}
+	return "EXPLAIN " + es.Statement.String()
+}
+
 // OrderClause represents ORDER BY clause
 type OrderClause struct {
-	Field     string
-	Direction string // ASC or DESC
+	Expression Expression // Sort expression; an identifier may name a SELECT alias
+	Direction  string     // ASC or DESC
 }
 
 func (oc *OrderClause) String() string {
-	return fmt.Sprintf("ORDER BY %s %s", oc.Field, oc.Direction)
+	return fmt.Sprintf("ORDER BY %s %s", oc.Expression.String(), oc.Direction)
 }
 
 // LimitClause represents LIMIT clause
@@ -171,6 +285,49 @@ type NumberLiteral struct {
 func (nl *NumberLiteral) expressionNode() {}
 func (nl *NumberLiteral) String() string  { return nl.Value }
 
+// BooleanLiteral represents true and false
+type BooleanLiteral struct {
+	Value bool
+}
+
+func (bl *BooleanLiteral) expressionNode() {}
+func (bl *BooleanLiteral) String() string  { return strconv.FormatBool(bl.Value) }
+
+// NullLiteral represents null
+type NullLiteral struct{}
+
+func (nl *NullLiteral) expressionNode() {}
+func (nl *NullLiteral) String() string  { return "null" }
+
+// JSONPathExpression represents a path into a JSON value (value.user.name, value.tags[0])
+type JSONPathExpression struct {
+	Field string   // Field holding the JSON document; always "value"
+	Path  []string // Object keys and array indexes, outermost first
+}
+
+func (jp *JSONPathExpression) expressionNode() {}
+func (jp *JSONPathExpression) String() string {
+	var out strings.Builder
+	out.WriteString(jp.Field)
+	for _, part := range jp.Path {
+		if isArrayIndex(part) {
+			out.WriteString("[" + part + "]")
+		} else {
+			out.WriteString("." + part)
+		}
+	}
+	return out.String()
+}
+
+// Parameter represents a placeholder (? or $1) bound to an argument when a
+// prepared statement is executed
+type Parameter struct {
+	Index int // 1-based argument number; ? placeholders are numbered in order
+}
+
+func (pm *Parameter) expressionNode() {}
+func (pm *Parameter) String() string  { return "$" + strconv.Itoa(pm.Index) }
+
 // WildcardExpression represents * in SELECT
 type WildcardExpression struct{}
 
@@ -189,7 +346,7 @@ func (be *BinaryExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", be.Left.String(), be.Operator.String(), be.Right.String())
 }
 
-// UnaryExpression represents unary operations (NOT condition)
+// UnaryExpression represents unary operations (NOT condition, -value)
 type UnaryExpression struct {
 	Operator Operator
 	Right    Expression
@@ -197,6 +354,9 @@ type UnaryExpression struct {
 
 func (ue *UnaryExpression) expressionNode() {}
 func (ue *UnaryExpression) String() string {
+	if ue.Operator == NEG_OP {
+		return fmt.Sprintf("(-%s)", ue.Right.String())
+	}
 	return fmt.Sprintf("(%s %s)", ue.Operator.String(), ue.Right.String())
 }
 
@@ -227,10 +387,33 @@ func (ie *InExpression) String() string {
 	return fmt.Sprintf("%s IN (%s)", ie.Field.String(), strings.Join(values, ", "))
 }
 
-// FunctionExpression represents function calls (COUNT, etc.)
+// CastExpression represents CAST(expression AS INT) and CAST(expression AS FLOAT)
+type CastExpression struct {
+	Expression Expression
+	Type       string // INT or FLOAT
+}
+
+func (ce *CastExpression) expressionNode() {}
+func (ce *CastExpression) String() string {
+	return fmt.Sprintf("CAST(%s AS %s)", ce.Expression.String(), ce.Type)
+}
+
+// AliasExpression represents a SELECT field renamed with AS
+type AliasExpression struct {
+	Expression Expression
+	Alias      string // Column name of the field
+}
+
+func (ae *AliasExpression) expressionNode() {}
+func (ae *AliasExpression) String() string {
+	return fmt.Sprintf("%s AS %s", ae.Expression.String(), ae.Alias)
+}
+
+// FunctionExpression represents function calls (COUNT, SUM, SPLIT_PART, etc.)
 type FunctionExpression struct {
-	Name      string
+	Name      string // Upper-case function name
 	Arguments []Expression
+	Distinct  bool // COUNT(DISTINCT ...)
 }
 
 func (fe *FunctionExpression) expressionNode() {}
@@ -242,5 +425,8 @@ func (fe *FunctionExpression) String() string {
 	for i, arg := range fe.Arguments {
 		args[i] = arg.String()
 	}
+	if fe.Distinct {
+		return fmt.Sprintf("%s(DISTINCT %s)", fe.Name, strings.Join(args, ", "))
+	}
 	return fmt.Sprintf("%s(%s)", fe.Name, strings.Join(args, ", "))
 }
@@ -3,34 +3,53 @@ package query
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/index"
 )
 
 // Executor executes parsed queries against the KV store
 type Executor struct {
-	store *kvstore.KVStore
+	store  Store
+	dryRun bool // Data-modifying statements report their changes without writing
 }
 
 // NewExecutor creates a new query executor
-func NewExecutor(store *kvstore.KVStore) *Executor {
+func NewExecutor(store Store) *Executor {
 	return &Executor{store: store}
 }
 
 // ExecuteResult represents the result of query execution
 type ExecuteResult struct {
-	Rows  []map[string]string // Result rows
-	Count int                 // Count for aggregation queries
-	Error error               // Execution error
+	Rows       []map[string]string // Result rows; one per group for aggregation queries
+	Columns    []string            // Column order of the rows; a row lacks the columns it has no value for
+	Aggregated bool                // Rows are the groups of an aggregation query
+	Count      int                 // Rows matched by an aggregation query
+	Affected   int                 // Rows inserted, updated or deleted (or that would be, in a dry run)
+	Explain    *Explanation        // Plan of an EXPLAIN statement
+	Error      error               // Execution error
 }
 
 // Execute executes a parsed query statement
 func (e *Executor) Execute(stmt Statement) *ExecuteResult {
+	if n := parameterCount(stmt); n > 0 {
+		return &ExecuteResult{Error: fmt.Errorf("query has %d unbound parameters; execute it with Prepare", n)}
+	}
+
 	switch s := stmt.(type) {
 	case *SelectStatement:
 		return e.executeSelect(s)
+	case *InsertStatement:
+		return e.executeInsert(s)
+	case *UpdateStatement:
+		return e.executeUpdate(s)
+	case *DeleteStatement:
+		return e.executeDelete(s)
+	case *ExplainStatement:
+		return e.executeExplain(s)
 	default:
 		return &ExecuteResult{Error: fmt.Errorf("unsupported statement type")}
 	}
@@ -38,66 +57,322 @@ func (e *Executor) Execute(stmt Statement) *ExecuteResult {
 
 // executeSelect executes SELECT statements
 func (e *Executor) executeSelect(stmt *SelectStatement) *ExecuteResult {
-	result := &ExecuteResult{Rows: []map[string]string{}}
+	if err := e.validateFunctions(stmt); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+	return e.runSelect(stmt, e.Plan(stmt), nil)
+}
 
-	// Get all keys from store
-	keys, err := e.store.List()
-	if err != nil {
-		return &ExecuteResult{Error: fmt.Errorf("failed to list keys: %v", err)}
+// runSelect executes a SELECT statement with a plan. A non-nil trace records the
+// rows and time of each stage for EXPLAIN ANALYZE.
+func (e *Executor) runSelect(stmt *SelectStatement, plan *Plan, trace *Explanation) *ExecuteResult {
+	result := &ExecuteResult{Rows: []map[string]string{}}
+	aggregate := e.isAggregationQuery(stmt)
+	var groups *aggregation
+	if aggregate {
+		groups = e.newAggregation(stmt)
 	}
 
-	// Build result set
-	for _, key := range keys {
-		value, err := e.store.Get(key)
-		if err != nil {
-			continue // Skip keys that can't be retrieved
-		}
+	earlyStop := plan.EarlyStop
+	ranked := plan.Rank != nil && stmt.OrderBy == nil && !aggregate
+	var scores []int
+	var sortValues []string // ORDER BY values, computed before field selection
+	wanted := 0
+	if earlyStop {
+		wanted = stmt.Limit.Offset + stmt.Limit.Count
+	}
 
+	start := time.Now()
+	scanned, matched := 0, 0
+	err := e.scan(plan, func(key, value string) bool {
+		scanned++
 		row := map[string]string{
 			"key":   key,
 			"value": value,
 		}
 
-		// Apply WHERE clause filtering
-		if stmt.Where != nil {
-			if !e.evaluateExpression(stmt.Where, row) {
-				continue
-			}
+		// Only the predicates the access method does not cover are evaluated per row
+		if plan.Residual != nil && !e.evaluateExpression(plan.Residual, row) {
+			return true
 		}
+		matched++
 
 		// Check if this is an aggregation query
-		if e.isAggregationQuery(stmt) {
+		if aggregate {
+			groups.add(row)
 			result.Count++
-		} else {
-			// Apply field selection
-			filteredRow := e.applyFieldSelection(stmt.Fields, row)
-			result.Rows = append(result.Rows, filteredRow)
+			return true
+		}
+
+		// ORDER BY compares numeric keys as numbers, so key order no longer matches
+		if stmt.OrderBy != nil && isNumeric(key) {
+			earlyStop = false
+		}
+
+		// Score and compute sort values before field selection drops the fields they use
+		if ranked {
+			scores = append(scores, e.matchScore(plan.Rank, row))
+		}
+		if stmt.OrderBy != nil {
+			sortValues = append(sortValues, e.getValueFromExpression(orderExpression(stmt), row))
 		}
+
+		// Apply field selection
+		result.Rows = append(result.Rows, e.applyFieldSelection(stmt.Fields, row))
+		return !earlyStop || len(result.Rows) < wanted
+	})
+	if err != nil {
+		return &ExecuteResult{Error: fmt.Errorf("failed to scan keys: %v", err)}
+	}
+	trace.measure(stageScan, matched, start)
+	if trace != nil {
+		trace.RowsScanned, trace.RowsMatched = scanned, matched
+	}
+
+	// Without ORDER BY, MATCH results are ranked by term frequency
+	if ranked {
+		start = time.Now()
+		rankRows(result.Rows, scores)
+		trace.measure(stageRank, len(result.Rows), start)
+	}
+
+	// Aggregation queries return one row per group that satisfies HAVING
+	if aggregate {
+		start = time.Now()
+		for _, row := range groups.rows() {
+			if stmt.Having == nil || e.evaluateExpression(stmt.Having, row) {
+				result.Rows = append(result.Rows, row)
+				if stmt.OrderBy != nil {
+					sortValues = append(sortValues, e.getValueFromExpression(orderExpression(stmt), row))
+				}
+			}
+		}
+		trace.measure(stageAggregate, len(result.Rows), start)
 	}
 
 	// Apply ORDER BY
-	if stmt.OrderBy != nil && !e.isAggregationQuery(stmt) {
-		e.applyOrderBy(result.Rows, stmt.OrderBy)
+	if stmt.OrderBy != nil {
+		start = time.Now()
+		e.applyOrderBy(result.Rows, sortValues, stmt.OrderBy.Direction == "DESC")
+		trace.measure(stageSort, len(result.Rows), start)
 	}
 
 	// Apply LIMIT
-	if stmt.Limit != nil && !e.isAggregationQuery(stmt) {
+	if stmt.Limit != nil {
+		start = time.Now()
 		e.applyLimit(result, stmt.Limit)
+		trace.measure(stageLimit, len(result.Rows), start)
+	}
+
+	// Group rows keep every aggregate until ordering is done
+	if aggregate {
+		result.Rows = e.selectColumns(stmt.Fields, result.Rows)
+		result.Aggregated = true
+	}
+	result.Columns = columnNames(stmt.Fields)
+
+	if trace != nil {
+		trace.RowsReturned = len(result.Rows)
+	}
+	return result
+}
+
+// indexedKeys returns the keys that can satisfy a WHERE clause according to the
+// secondary indexes, or false if the clause cannot be answered from them
+func (e *Executor) indexedKeys(expr Expression) ([]string, bool) {
+	switch exp := expr.(type) {
+	case *BinaryExpression:
+		switch exp.Operator {
+		case EQ:
+			field, value, ok := indexedComparison(exp.Left, exp.Right)
+			if !ok {
+				field, value, ok = indexedComparison(exp.Right, exp.Left)
+			}
+			if !ok {
+				return nil, false
+			}
+			return e.lookupSecondary(field, value)
+
+		case AND_OP:
+			left, leftOK := e.indexedKeys(exp.Left)
+			right, rightOK := e.indexedKeys(exp.Right)
+			switch {
+			case leftOK && rightOK:
+				return intersectKeys(left, right), true
+			case leftOK:
+				return left, true
+			default:
+				return right, rightOK
+			}
+
+		case OR_OP:
+			left, leftOK := e.indexedKeys(exp.Left)
+			if !leftOK {
+				return nil, false
+			}
+			right, rightOK := e.indexedKeys(exp.Right)
+			if !rightOK {
+				return nil, false
+			}
+			return unionKeys(left, right), true
+		}
+
+	case *InExpression:
+		var keys []string
+		for _, valueExpr := range exp.Values {
+			field, value, ok := indexedComparison(exp.Field, valueExpr)
+			if !ok {
+				return nil, false
+			}
+			matches, ok := e.lookupSecondary(field, value)
+			if !ok {
+				return nil, false
+			}
+			keys = unionKeys(keys, matches)
+		}
+		return keys, len(exp.Values) > 0
 	}
+	return nil, false
+}
 
+// lookupSecondary looks up a value in the store's secondary indexes, if it has any
+func (e *Executor) lookupSecondary(field, value string) ([]string, bool) {
+	indexer, ok := e.store.(SecondaryIndexer)
+	if !ok {
+		return nil, false
+	}
+	return indexer.LookupSecondary(field, value)
+}
+
+// indexedComparison returns the indexed field and literal of a "value = literal" or
+// "value.path = 'string'" comparison
+func indexedComparison(fieldExpr, valueExpr Expression) (string, string, bool) {
+	if path, ok := fieldExpr.(*JSONPathExpression); ok {
+		// Only strings are indexed under exactly the text they compare equal to
+		literal, ok := valueExpr.(*StringLiteral)
+		if !ok {
+			return "", "", false
+		}
+		return strings.Join(path.Path, "."), literal.Value, true
+	}
+
+	ident, ok := fieldExpr.(*Identifier)
+	if !ok || ident.Value != "value" {
+		return "", "", false
+	}
+	switch literal := valueExpr.(type) {
+	case *StringLiteral:
+		return "", literal.Value, true
+	case *NumberLiteral:
+		return "", literal.Value, true
+	default:
+		return "", "", false
+	}
+}
+
+// intersectKeys returns the keys present in both sorted lists
+func intersectKeys(a, b []string) []string {
+	result := []string{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
 	return result
 }
 
-// isAggregationQuery checks if the query contains aggregation functions
+// unionKeys merges two sorted key lists without duplicates
+func unionKeys(a, b []string) []string {
+	result := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			result = append(result, a[i])
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// isAggregationQuery checks if the query groups rows or contains aggregation functions
 func (e *Executor) isAggregationQuery(stmt *SelectStatement) bool {
+	if len(stmt.GroupBy) > 0 {
+		return true
+	}
 	for _, field := range stmt.Fields {
-		if _, ok := field.(*FunctionExpression); ok {
+		if containsAggregate(field) {
 			return true
 		}
 	}
 	return false
 }
 
+// orderExpression returns the ORDER BY expression of a statement, with a SELECT
+// alias replaced by the expression it names
+func orderExpression(stmt *SelectStatement) Expression {
+	if ident, ok := stmt.OrderBy.Expression.(*Identifier); ok {
+		for _, field := range stmt.Fields {
+			if alias, ok := field.(*AliasExpression); ok && alias.Alias == ident.Value {
+				return alias.Expression
+			}
+		}
+	}
+	return stmt.OrderBy.Expression
+}
+
+// columnName returns the column a selected field produces: its alias, or its text
+func columnName(field Expression) string {
+	if alias, ok := field.(*AliasExpression); ok {
+		return alias.Alias
+	}
+	return field.String()
+}
+
+// unalias returns the expression of a selected field without its alias
+func unalias(field Expression) Expression {
+	if alias, ok := field.(*AliasExpression); ok {
+		return alias.Expression
+	}
+	return field
+}
+
+// columnNames returns the columns selected fields produce, in SELECT order
+func columnNames(fields []Expression) []string {
+	if len(fields) == 0 {
+		return []string{"key", "value"}
+	}
+	columns := []string{}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		names := []string{columnName(field)}
+		if _, ok := field.(*WildcardExpression); ok {
+			names = []string{"key", "value"}
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	return columns
+}
+
 // applyFieldSelection filters the row based on selected fields
 func (e *Executor) applyFieldSelection(fields []Expression, row map[string]string) map[string]string {
 	if len(fields) == 0 {
@@ -111,12 +386,19 @@ func (e *Executor) applyFieldSelection(fields []Expression, row map[string]strin
 		}
 	}
 
-	// Select specific fields
+	// Select specific fields; fields without a value leave their column out
 	result := make(map[string]string)
 	for _, field := range fields {
-		if ident, ok := field.(*Identifier); ok {
-			if value, exists := row[ident.Value]; exists {
-				result[ident.Value] = value
+		name := columnName(field)
+		switch exp := unalias(field).(type) {
+		case *JSONPathExpression:
+			// Rows whose value is not JSON or lacks the path leave the column out
+			if value, ok := typedValue(exp, row); ok {
+				result[name] = jsonText(value)
+			}
+		default:
+			if value, ok := e.columnValue(exp, row); ok {
+				result[name] = value
 			}
 		}
 	}
@@ -164,8 +446,24 @@ func (e *Executor) evaluateUnaryExpression(expr *UnaryExpression, row map[string
 
 // evaluateComparison evaluates comparison operations
 func (e *Executor) evaluateComparison(expr *BinaryExpression, row map[string]string) bool {
-	leftValue := e.getValueFromExpression(expr.Left, row)
-	rightValue := e.getValueFromExpression(expr.Right, row)
+	// JSON paths compare by JSON type and never match a value that is not JSON or lacks the path
+	if hasJSONPath(expr.Left, expr.Right) {
+		left, leftOK := e.typedOperand(expr.Left, row)
+		right, rightOK := e.typedOperand(expr.Right, row)
+		if !leftOK || !rightOK {
+			return false
+		}
+		if matched, handled := evaluateTyped(expr.Operator, left, right); handled {
+			return matched
+		}
+	}
+
+	// Computed operands without a value, such as arithmetic on text, never match
+	leftValue, leftOK := e.operandValue(expr.Left, row)
+	rightValue, rightOK := e.operandValue(expr.Right, row)
+	if !leftOK || !rightOK {
+		return false
+	}
 
 	switch expr.Operator {
 	case EQ:
@@ -184,6 +482,9 @@ func (e *Executor) evaluateComparison(expr *BinaryExpression, row map[string]str
 		return e.evaluateLike(leftValue, rightValue)
 	case CONTAINS_OP:
 		return strings.Contains(leftValue, rightValue)
+	case MATCH_OP:
+		_, matched := index.MatchScore(index.Tokenize(rightValue), leftValue)
+		return matched
 	case REGEX_OP:
 		return e.evaluateRegex(leftValue, rightValue)
 	default:
@@ -193,25 +494,67 @@ func (e *Executor) evaluateComparison(expr *BinaryExpression, row map[string]str
 
 // evaluateBetweenExpression evaluates BETWEEN expressions
 func (e *Executor) evaluateBetweenExpression(expr *BetweenExpression, row map[string]string) bool {
-	fieldValue := e.getValueFromExpression(expr.Field, row)
-	startValue := e.getValueFromExpression(expr.Start, row)
-	endValue := e.getValueFromExpression(expr.End, row)
+	if hasJSONPath(expr.Field, expr.Start, expr.End) {
+		field, fieldOK := e.typedOperand(expr.Field, row)
+		start, startOK := e.typedOperand(expr.Start, row)
+		end, endOK := e.typedOperand(expr.End, row)
+		if !fieldOK || !startOK || !endOK {
+			return false
+		}
+		afterStart, _ := evaluateTyped(GTE, field, start)
+		beforeEnd, _ := evaluateTyped(LTE, field, end)
+		return afterStart && beforeEnd
+	}
+
+	fieldValue, fieldOK := e.operandValue(expr.Field, row)
+	startValue, startOK := e.operandValue(expr.Start, row)
+	endValue, endOK := e.operandValue(expr.End, row)
+	if !fieldOK || !startOK || !endOK {
+		return false
+	}
 
 	return e.compareValues(fieldValue, startValue) >= 0 && e.compareValues(fieldValue, endValue) <= 0
 }
 
 // evaluateInExpression evaluates IN expressions
 func (e *Executor) evaluateInExpression(expr *InExpression, row map[string]string) bool {
-	fieldValue := e.getValueFromExpression(expr.Field, row)
+	if hasJSONPath(expr.Field) {
+		field, ok := e.typedOperand(expr.Field, row)
+		if !ok {
+			return false
+		}
+		for _, valueExpr := range expr.Values {
+			if value, ok := e.typedOperand(valueExpr, row); ok {
+				if equal, _ := evaluateTyped(EQ, field, value); equal {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	fieldValue, ok := e.operandValue(expr.Field, row)
+	if !ok {
+		return false
+	}
 
 	for _, valueExpr := range expr.Values {
-		if fieldValue == e.getValueFromExpression(valueExpr, row) {
+		if value, ok := e.operandValue(valueExpr, row); ok && fieldValue == value {
 			return true
 		}
 	}
 	return false
 }
 
+// operandValue returns the value of a comparison operand. Only computed operands can
+// lack a value; a missing field compares as an empty string.
+func (e *Executor) operandValue(expr Expression, row map[string]string) (string, bool) {
+	if isComputed(expr) {
+		return e.columnValue(expr, row)
+	}
+	return e.getValueFromExpression(expr, row), true
+}
+
 // getValueFromExpression extracts the actual value from an expression
 func (e *Executor) getValueFromExpression(expr Expression, row map[string]string) string {
 	switch exp := expr.(type) {
@@ -224,6 +567,16 @@ func (e *Executor) getValueFromExpression(expr Expression, row map[string]string
 		return exp.Value
 	case *NumberLiteral:
 		return exp.Value
+	case *BooleanLiteral, *NullLiteral:
+		return exp.String()
+	case *JSONPathExpression:
+		if value, ok := typedValue(exp, row); ok {
+			return jsonText(value)
+		}
+		return ""
+	case *FunctionExpression, *BinaryExpression, *UnaryExpression, *CastExpression:
+		value, _ := e.columnValue(exp, row)
+		return value
 	default:
 		return ""
 	}
@@ -248,6 +601,28 @@ func (e *Executor) compareValues(left, right string) int {
 	return strings.Compare(left, right)
 }
 
+// matchScore returns how often the words of a MATCH expression occur in its field
+func (e *Executor) matchScore(expr *BinaryExpression, row map[string]string) int {
+	text := e.getValueFromExpression(expr.Left, row)
+	score, _ := index.MatchScore(index.Tokenize(e.getValueFromExpression(expr.Right, row)), text)
+	return score
+}
+
+// rankRows orders rows by descending score, keeping scan order for equal scores
+func rankRows(rows []map[string]string, scores []int) {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	ranked := make([]map[string]string, len(rows))
+	for i, j := range order {
+		ranked[i] = rows[j]
+	}
+	copy(rows, ranked)
+}
+
 // evaluateLike evaluates LIKE pattern matching
 func (e *Executor) evaluateLike(value, pattern string) bool {
 	// Convert SQL LIKE pattern to regex
@@ -265,30 +640,26 @@ func (e *Executor) evaluateRegex(value, pattern string) bool {
 	return err == nil && matched
 }
 
-// applyOrderBy sorts the result rows
-func (e *Executor) applyOrderBy(rows []map[string]string, orderBy *OrderClause) {
-	if len(rows) <= 1 {
-		return
+// applyOrderBy sorts the result rows by their ORDER BY values, keeping the current
+// order of rows with equal values
+func (e *Executor) applyOrderBy(rows []map[string]string, values []string, descending bool) {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
 	}
-
-	// Simple bubble sort for demonstration (could be optimized)
-	for i := 0; i < len(rows)-1; i++ {
-		for j := 0; j < len(rows)-i-1; j++ {
-			val1 := rows[j][orderBy.Field]
-			val2 := rows[j+1][orderBy.Field]
-
-			var shouldSwap bool
-			if orderBy.Direction == "ASC" {
-				shouldSwap = e.compareValues(val1, val2) > 0
-			} else {
-				shouldSwap = e.compareValues(val1, val2) < 0
-			}
-
-			if shouldSwap {
-				rows[j], rows[j+1] = rows[j+1], rows[j]
-			}
+	sort.SliceStable(order, func(i, j int) bool {
+		cmp := e.compareValues(values[order[i]], values[order[j]])
+		if descending {
+			return cmp > 0
 		}
+		return cmp < 0
+	})
+
+	sorted := make([]map[string]string, len(rows))
+	for i, j := range order {
+		sorted[i] = rows[j]
 	}
+	copy(rows, sorted)
 }
 
 // applyLimit applies LIMIT and OFFSET to the result
@@ -2,9 +2,12 @@ package query
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/nyasuto/moz/internal/kvstore"
+	"github.com/nyasuto/moz/internal/lsm"
 )
 
 func TestExecutor_SelectAll(t *testing.T) {
@@ -145,6 +148,358 @@ func TestExecutor_CountFunction(t *testing.T) {
 	}
 }
 
+func TestExecutor_GroupBy(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("order:1", `{"user":"alice","amount":30,"status":"paid"}`)
+	store.Put("order:2", `{"user":"bob","amount":12.5,"status":"paid"}`)
+	store.Put("order:3", `{"user":"alice","amount":7,"status":"open"}`)
+	store.Put("user:alice", `{"name":"Alice"}`)
+	store.Put("user:bob", `{"name":"Bob"}`)
+	store.Put("note", "plain text")
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []map[string]string
+	}{
+		{
+			"SELECT COUNT(*), SUM(value.amount), AVG(value.amount), MIN(value.amount), MAX(value.amount) FROM moz",
+			[]map[string]string{{"COUNT(*)": "6", "SUM(value.amount)": "49.5", "AVG(value.amount)": "16.5", "MIN(value.amount)": "7", "MAX(value.amount)": "30"}},
+		},
+		{
+			"SELECT COUNT(value.user), COUNT(DISTINCT value.user) FROM moz",
+			[]map[string]string{{"COUNT(value.user)": "3", "COUNT(DISTINCT value.user)": "2"}},
+		},
+		{
+			"SELECT SPLIT_PART(key, ':', 1), COUNT(*) FROM moz GROUP BY SPLIT_PART(key, ':', 1)",
+			[]map[string]string{
+				{"SPLIT_PART(key, \":\", 1)": "note", "COUNT(*)": "1"},
+				{"SPLIT_PART(key, \":\", 1)": "order", "COUNT(*)": "3"},
+				{"SPLIT_PART(key, \":\", 1)": "user", "COUNT(*)": "2"},
+			},
+		},
+		{
+			"SELECT value.user, SUM(value.amount) FROM moz WHERE key LIKE 'order:%' GROUP BY value.user ORDER BY SUM(value.amount) DESC",
+			[]map[string]string{
+				{"value.user": "alice", "SUM(value.amount)": "37"},
+				{"value.user": "bob", "SUM(value.amount)": "12.5"},
+			},
+		},
+		{
+			"SELECT value.user, value.status, COUNT(*) FROM moz WHERE key LIKE 'order:%' GROUP BY value.user, value.status HAVING value.user = 'alice'",
+			[]map[string]string{
+				{"value.user": "alice", "value.status": "open", "COUNT(*)": "1"},
+				{"value.user": "alice", "value.status": "paid", "COUNT(*)": "1"},
+			},
+		},
+		{
+			"SELECT SPLIT_PART(key, ':', 1) FROM moz GROUP BY SPLIT_PART(key, ':', 1) HAVING COUNT(*) >= 2 LIMIT 1",
+			[]map[string]string{{"SPLIT_PART(key, \":\", 1)": "order"}},
+		},
+		{
+			// Values without the field group together and have no sum
+			"SELECT value.user, SUM(value.amount) FROM moz WHERE key LIKE 'user:%' GROUP BY value.user",
+			[]map[string]string{{}},
+		},
+		{
+			"SELECT COUNT(*) FROM moz WHERE key = 'missing'",
+			[]map[string]string{{"COUNT(*)": "0"}},
+		},
+		{
+			"SELECT value.user FROM moz WHERE key = 'missing' GROUP BY value.user",
+			[]map[string]string{},
+		},
+		{
+			"SELECT SPLIT_PART(key, ':', 2) FROM moz WHERE key LIKE 'user:%'",
+			[]map[string]string{{"SPLIT_PART(key, \":\", 2)": "alice"}, {"SPLIT_PART(key, \":\", 2)": "bob"}},
+		},
+	}
+
+	for _, tt := range tests {
+		result := executor.Execute(parseSelect(t, tt.query))
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		if !reflect.DeepEqual(result.Rows, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, result.Rows)
+		}
+	}
+
+	invalid := []string{
+		"SELECT key, COUNT(*) FROM moz",
+		"SELECT key FROM moz GROUP BY value",
+		"SELECT * FROM moz WHERE COUNT(*) > 1",
+		"SELECT SUM(*) FROM moz",
+		"SELECT SUM(DISTINCT value) FROM moz",
+		"SELECT MEDIAN(value) FROM moz",
+		"SELECT key FROM moz HAVING COUNT(*) > 1",
+	}
+	for _, query := range invalid {
+		if result := executor.Execute(parseSelect(t, query)); result.Error == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestExecutor_Expressions(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("item:1", `{"name":"Pen","price":"1.5","qty":4}`)
+	store.Put("item:2", `{"name":"notebook","price":"3","qty":10}`)
+	store.Put("item:3", `{"name":"Bag","price":"12.25","qty":1}`)
+	store.Put("count", "7")
+	store.Put("note", "héllo")
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []map[string]string
+	}{
+		{
+			"SELECT key, CAST(value.price AS FLOAT) * value.qty AS total FROM moz WHERE key LIKE 'item:%' ORDER BY total DESC",
+			[]map[string]string{{"key": "item:2", "total": "30"}, {"key": "item:3", "total": "12.25"}, {"key": "item:1", "total": "6"}},
+		},
+		{
+			"SELECT value + 3, value - 10, value * 2, value / 2, value % 4, -value FROM moz WHERE key = 'count'",
+			[]map[string]string{{"(value + 3)": "10", "(value - 10)": "-3", "(value * 2)": "14", "(value / 2)": "3.5", "(value % 4)": "3", "(-value)": "-7"}},
+		},
+		{
+			// Multiplication binds tighter than addition; parentheses group
+			"SELECT 1 + 2 * 3 AS a, (1 + 2) * 3 AS b, CAST('-2.9' AS INT) AS c, CAST(' 42 ' AS INT) AS d FROM moz WHERE key = 'count'",
+			[]map[string]string{{"a": "7", "b": "9", "c": "-2", "d": "42"}},
+		},
+		{
+			// Arithmetic on text, division by zero and failed casts have no value
+			"SELECT value + 1, value / 0, CAST(value AS INT), LENGTH(value) AS len FROM moz WHERE key = 'note'",
+			[]map[string]string{{"len": "5"}},
+		},
+		{
+			"SELECT UPPER(value.name) AS upper, LOWER(value.name) AS lower, SUBSTR(value.name, 2, 3) AS mid, SUBSTR(value.name, -3) AS tail, CONCAT(key, '=', value.missing, value.qty) AS pair FROM moz WHERE key = 'item:2'",
+			[]map[string]string{{"upper": "NOTEBOOK", "lower": "notebook", "mid": "ote", "tail": "ook", "pair": "item:2=10"}},
+		},
+		{
+			// SUBSTR follows SQLite: 1-based, 0 is before the first character, negative
+			// lengths count backwards and ranges are cut to the text
+			"SELECT SUBSTR(value.name, 0, 3) AS zero, SUBSTR(value.name, 0) AS all, SUBSTR(value.name, 5, 100) AS past, SUBSTR(value.name, -10, 4) AS early, SUBSTR(value.name, 3, -2) AS back, SUBSTR(value.name, -1, -3) AS backtail, SUBSTR(value.name, 9) AS after, SUBSTR(value.name, 2, 0) AS none FROM moz WHERE key = 'item:2'",
+			[]map[string]string{{"zero": "no", "all": "notebook", "past": "book", "early": "no", "back": "no", "backtail": "boo", "after": "", "none": ""}},
+		},
+		{
+			"SELECT key FROM moz WHERE UPPER(value.name) = 'PEN' OR value.qty * CAST(value.price AS FLOAT) > 20",
+			[]map[string]string{{"key": "item:1"}, {"key": "item:2"}},
+		},
+		{
+			// Text never compares as less than a computed number it has no value for
+			"SELECT key FROM moz WHERE value * 1 < 100",
+			[]map[string]string{{"key": "count"}},
+		},
+		{
+			"SELECT key, LENGTH(value.name) AS len FROM moz WHERE key LIKE 'item:%' ORDER BY len DESC",
+			[]map[string]string{{"key": "item:2", "len": "8"}, {"key": "item:1", "len": "3"}, {"key": "item:3", "len": "3"}},
+		},
+		{
+			"SELECT key FROM moz WHERE key LIKE 'item:%' AND SPLIT_PART(key, ':', 2) IN (1, 3) ORDER BY value.qty",
+			[]map[string]string{{"key": "item:3"}, {"key": "item:1"}},
+		},
+		{
+			"SELECT SPLIT_PART(key, ':', 1) AS kind, COUNT(*) AS n, SUM(value.qty) * 2 AS double FROM moz GROUP BY SPLIT_PART(key, ':', 1) HAVING COUNT(*) > 1 ORDER BY n DESC",
+			[]map[string]string{{"kind": "item", "n": "3", "double": "30"}},
+		},
+		{
+			"SELECT CAST(AVG(value.qty) AS INT) AS avg FROM moz WHERE key LIKE 'item:%'",
+			[]map[string]string{{"avg": "5"}},
+		},
+	}
+
+	for _, tt := range tests {
+		result := executor.Execute(parseSelect(t, tt.query))
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		if !reflect.DeepEqual(result.Rows, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, result.Rows)
+		}
+	}
+
+	// Aliases name the result columns
+	result := executor.Execute(parseSelect(t, "SELECT key AS k, value.qty * 2 AS q FROM moz WHERE key = 'item:3'"))
+	if !reflect.DeepEqual(result.Columns, []string{"k", "q"}) {
+		t.Errorf("Expected columns [k q], got %v", result.Columns)
+	}
+
+	// UPDATE and INSERT compute their values
+	if result := executor.Execute(parseStatement(t, "UPDATE moz SET value = value * 3 - 1 WHERE key = 'count'")); result.Error != nil {
+		t.Fatalf("UPDATE failed: %v", result.Error)
+	}
+	if value, _ := store.Get("count"); value != "20" {
+		t.Errorf("Expected count=20 after UPDATE, got %q", value)
+	}
+	if result := executor.Execute(parseStatement(t, "INSERT INTO moz VALUES (CONCAT('item:', 2 + 2), UPPER('new'))")); result.Error != nil {
+		t.Fatalf("INSERT failed: %v", result.Error)
+	}
+	if value, _ := store.Get("item:4"); value != "NEW" {
+		t.Errorf("Expected item:4=NEW after INSERT, got %q", value)
+	}
+
+	invalid := []string{
+		"SELECT UPPER(key, value) FROM moz",
+		"SELECT SUBSTR(key) FROM moz",
+		"SELECT CONCAT() FROM moz",
+		"SELECT REVERSE(key) FROM moz",
+		"SELECT key FROM moz ORDER BY COUNT(*)",
+		"SELECT key, SUM(value) + value FROM moz",
+		"SELECT SPLIT_PART(key, ':', 1), COUNT(*) FROM moz GROUP BY SPLIT_PART(key, ':', 1) ORDER BY value",
+		"UPDATE moz SET value = LENGTH() WHERE key = 'count'",
+	}
+	for _, query := range invalid {
+		if result := executor.Execute(parseStatement(t, query)); result.Error == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+// writeOnlyStore hides ApplyBatch, so statements fall back to one write per row
+type writeOnlyStore struct {
+	*kvstore.KVStore
+	puts int
+}
+
+func (s *writeOnlyStore) Put(key, value string) error {
+	s.puts++
+	return s.KVStore.Put(key, value)
+}
+
+func (s *writeOnlyStore) ApplyBatch() {}
+
+func TestExecutor_ModifyStatements(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("tmp:1", "a")
+	store.Put("tmp:2", "b")
+	store.Put("task:1", `{"status":"open"}`)
+	store.Put("task:2", `{"status":"done"}`)
+
+	executor := NewExecutor(store)
+	run := func(query string) *ExecuteResult {
+		p := NewParser(NewLexer(query))
+		stmt := p.ParseQuery()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors for %q: %v", query, p.Errors())
+		}
+		return executor.Execute(stmt)
+	}
+	keys := func() []string {
+		result := run("SELECT key FROM moz")
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		return keys
+	}
+
+	// A dry run reports the rows without changing them
+	executor.SetDryRun(true)
+	result := run("DELETE FROM moz WHERE key LIKE 'tmp:%'")
+	if result.Error != nil || result.Affected != 2 || len(result.Rows) != 2 {
+		t.Fatalf("Expected 2 rows from the dry run, got %+v", result)
+	}
+	if got := keys(); len(got) != 4 {
+		t.Fatalf("A dry run must not delete, got %v", got)
+	}
+	executor.SetDryRun(false)
+
+	result = run("DELETE FROM moz WHERE key LIKE 'tmp:%'")
+	if result.Error != nil || result.Affected != 2 {
+		t.Fatalf("Expected 2 deleted rows, got %+v", result)
+	}
+	if got, want := keys(), []string{"task:1", "task:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v after DELETE, got %v", want, got)
+	}
+
+	result = run(`UPDATE moz SET value = '{"status":"done"}' WHERE value.status = 'open'`)
+	if result.Error != nil || result.Affected != 1 || result.Rows[0]["key"] != "task:1" {
+		t.Fatalf("Expected task:1 to be updated, got %+v", result)
+	}
+	if value, _ := store.Get("task:1"); value != `{"status":"done"}` {
+		t.Errorf("Expected task:1 to be done, got %s", value)
+	}
+
+	// UPDATE can copy a part of the row into the value
+	result = run("UPDATE moz SET value = value.status WHERE key = 'task:2'")
+	if value, _ := store.Get("task:2"); result.Error != nil || value != "done" {
+		t.Errorf("Expected task:2 = done, got %s (err=%v)", value, result.Error)
+	}
+
+	result = run("INSERT INTO moz (key, value) VALUES ('k1', 'v1'), ('k2', 2)")
+	if result.Error != nil || result.Affected != 2 {
+		t.Fatalf("Expected 2 inserted rows, got %+v", result)
+	}
+	if value, _ := store.Get("k2"); value != "2" {
+		t.Errorf("Expected k2 = 2, got %s", value)
+	}
+
+	// A statement that fails part way changes nothing
+	for _, query := range []string{
+		"INSERT INTO moz (key, value) VALUES ('k3', 'v3'), ('k1', 'again')",
+		"INSERT INTO moz (key, value) VALUES ('k3', 'v3'), ('k3', 'again')",
+		"UPDATE moz SET value = value.status WHERE key LIKE 'k%' OR key = 'task:1'",
+	} {
+		if result := run(query); result.Error == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+	if got, want := keys(), []string{"k1", "k2", "task:1", "task:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Failed statements must not write, expected %v, got %v", want, got)
+	}
+	if value, _ := store.Get("task:1"); value != `{"status":"done"}` {
+		t.Errorf("A failed UPDATE must not change task:1, got %s", value)
+	}
+
+	for _, query := range []string{
+		"UPDATE moz SET key = 'x'",
+		"INSERT INTO moz (key) VALUES ('x')",
+		"INSERT INTO moz (key, value) VALUES ('x', null)",
+		"DELETE FROM moz WHERE COUNT(*) > 1",
+	} {
+		if result := run(query); result.Error == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+
+	// Stores without batches are written one row at a time
+	plain := &writeOnlyStore{KVStore: store}
+	executor = NewExecutor(plain)
+	result = run("UPDATE moz SET value = 'x' WHERE key LIKE 'k%'")
+	if result.Error != nil || result.Affected != 2 || plain.puts != 2 {
+		t.Errorf("Expected 2 single writes, got %+v after %d puts", result, plain.puts)
+	}
+
+	for query, want := range map[string]bool{
+		"INSERT INTO moz VALUES ('k', 'v')":       true,
+		"UPDATE moz SET value = 'x'":              true,
+		"DELETE FROM moz WHERE key = 'k'":         true,
+		"SELECT * FROM moz":                       false,
+		"EXPLAIN DELETE FROM moz WHERE key = 'k'": false,
+		"DELETE moz":                              false,
+	} {
+		if got := IsWrite(query); got != want {
+			t.Errorf("IsWrite(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
 func TestExecutor_ComplexConditions(t *testing.T) {
 	// Create test store with temporary file
 	compactionConfig := kvstore.CompactionConfig{Enabled: false}
@@ -191,3 +546,269 @@ func TestExecutor_ComplexConditions(t *testing.T) {
 		}
 	}
 }
+
+func TestExecutor_SecondaryIndex(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("user1", "active")
+	store.Put("user2", "inactive")
+	store.Put("user3", "active")
+	store.Put("admin1", "active")
+	if err := store.CreateSecondaryIndex("by_value", ""); err != nil {
+		t.Fatalf("CreateSecondaryIndex failed: %v", err)
+	}
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT key FROM moz WHERE value = 'active'", []string{"admin1", "user1", "user3"}},
+		{"SELECT key FROM moz WHERE value = 'active' AND key LIKE 'user%'", []string{"user1", "user3"}},
+		{"SELECT key FROM moz WHERE value IN ('inactive', 'missing')", []string{"user2"}},
+		{"SELECT key FROM moz WHERE value = 'inactive' OR value = 'active'", []string{"admin1", "user1", "user2", "user3"}},
+		{"SELECT key FROM moz WHERE value = 'active' OR key = 'user2'", []string{"admin1", "user1", "user2", "user3"}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(NewLexer(tt.query))
+		stmt := p.ParseQuery()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.query, p.Errors())
+		}
+
+		result := executor.Execute(stmt)
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, keys)
+		}
+	}
+}
+
+func TestExecutor_JSONPath(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("u1", `{"user":{"name":"Alice"},"age":34,"active":true,"tags":["go","db"],"manager":null}`)
+	store.Put("u2", `{"user":{"name":"Bob"},"age":28,"active":false,"tags":["rust"]}`)
+	store.Put("u3", `{"user":{"name":"Carol"},"age":"30","active":true,"tags":[]}`)
+	store.Put("u4", "plain text")
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT key FROM moz WHERE value.age >= 30", []string{"u1"}},
+		{"SELECT key FROM moz WHERE value.age = '30'", []string{"u3"}},
+		{"SELECT key FROM moz WHERE value.age BETWEEN 20 AND 30", []string{"u2"}},
+		{"SELECT key FROM moz WHERE value.user.name = 'Bob'", []string{"u2"}},
+		{"SELECT key FROM moz WHERE value.user.name > 'B'", []string{"u2", "u3"}},
+		{"SELECT key FROM moz WHERE value.active = true", []string{"u1", "u3"}},
+		{"SELECT key FROM moz WHERE value.active != true", []string{"u2"}},
+		{"SELECT key FROM moz WHERE value.manager = null", []string{"u1"}},
+		{"SELECT key FROM moz WHERE value.tags[0] = 'rust'", []string{"u2"}},
+		{"SELECT key FROM moz WHERE value.tags.1 IN ('db', 'go')", []string{"u1"}},
+		{"SELECT key FROM moz WHERE value.user.name LIKE '%o%'", []string{"u2", "u3"}},
+		{"SELECT key FROM moz WHERE value.user != 'x'", []string{"u1", "u2", "u3"}},
+		{"SELECT key FROM moz WHERE value.missing = null", nil},
+		{"SELECT key FROM moz WHERE value CONTAINS 'plain'", []string{"u4"}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(NewLexer(tt.query))
+		stmt := p.ParseQuery()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.query, p.Errors())
+		}
+
+		result := executor.Execute(stmt)
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, keys)
+		}
+	}
+
+	// Selected paths become columns; values that are not JSON or lack the path leave them out
+	p := NewParser(NewLexer("SELECT key, value.user.name, value.tags FROM moz ORDER BY value.user.name DESC"))
+	result := executor.Execute(p.ParseQuery())
+	if result.Error != nil {
+		t.Fatalf("Execution error: %v", result.Error)
+	}
+	want := []map[string]string{
+		{"key": "u3", "value.user.name": "Carol", "value.tags": "[]"},
+		{"key": "u2", "value.user.name": "Bob", "value.tags": `["rust"]`},
+		{"key": "u1", "value.user.name": "Alice", "value.tags": `["go","db"]`},
+		{"key": "u4"},
+	}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("expected %v, got %v", want, result.Rows)
+	}
+
+	// An equality on a string path can be answered by a secondary index on the field
+	if err := store.CreateSecondaryIndex("by_name", "user.name"); err != nil {
+		t.Fatalf("CreateSecondaryIndex failed: %v", err)
+	}
+	plan := executor.Plan(parseSelect(t, "SELECT key FROM moz WHERE value.user.name = 'Carol'"))
+	if plan.Access != SecondaryLookup || !reflect.DeepEqual(plan.Keys, []string{"u3"}) {
+		t.Errorf("expected a secondary lookup of u3, got %+v", plan)
+	}
+}
+
+func TestExecutor_Match(t *testing.T) {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	store.Put("p1", "red shoes")
+	store.Put("p2", "Red running shoes, red laces")
+	store.Put("p3", "blue shoes")
+	store.Put("p4", "red hat")
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT key FROM moz WHERE value MATCH 'red shoes'", []string{"p2", "p1"}},
+		{"SELECT key FROM moz WHERE value MATCH 'shoes' AND key != 'p1'", []string{"p2", "p3"}},
+		{"SELECT key FROM moz WHERE value MATCH 'red' LIMIT 1", []string{"p2"}},
+		{"SELECT key FROM moz WHERE value MATCH 'red' ORDER BY key DESC", []string{"p4", "p2", "p1"}},
+		{"SELECT key FROM moz WHERE value MATCH 'green'", nil},
+	}
+
+	run := func() {
+		for _, tt := range tests {
+			p := NewParser(NewLexer(tt.query))
+			stmt := p.ParseQuery()
+			if len(p.Errors()) > 0 {
+				t.Fatalf("Parser errors for %q: %v", tt.query, p.Errors())
+			}
+
+			result := executor.Execute(stmt)
+			if result.Error != nil {
+				t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+			}
+			var keys []string
+			for _, row := range result.Rows {
+				keys = append(keys, row["key"])
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.want, keys)
+			}
+		}
+	}
+
+	// Evaluated per row without an index, then answered by the full-text index
+	run()
+	if err := store.CreateFullTextIndex(""); err != nil {
+		t.Fatalf("CreateFullTextIndex failed: %v", err)
+	}
+	stmt := NewParser(NewLexer("SELECT key FROM moz WHERE value MATCH 'red shoes'")).ParseQuery()
+	if plan := executor.Plan(stmt.(*SelectStatement)); plan.Access != FullTextLookup || plan.Residual != nil {
+		t.Errorf("Expected a full-text lookup without residual, got %s", plan.Access)
+	}
+	run()
+}
+
+// Every storage engine can be queried
+var (
+	_ Store = (*kvstore.KVStore)(nil)
+	_ Store = (*kvstore.PartitionedKVStore)(nil)
+	_ Store = (*kvstore.AsyncKVStore)(nil)
+	_ Store = (*lsm.LSMKVStore)(nil)
+)
+
+func TestExecutor_PartitionedStore(t *testing.T) {
+	store, err := kvstore.NewPartitionedKVStore(kvstore.PartitionConfig{
+		NumPartitions: 4,
+		DataDir:       t.TempDir(),
+		BatchSize:     1000,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create partitioned store: %v", err)
+	}
+	defer store.Close()
+
+	store.Put("user:1", "Alice")
+	store.Put("user:2", "Bob")
+	store.Put("user:3", "Carol")
+	store.Put("admin:1", "Root")
+
+	executor := NewExecutor(store)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT key FROM moz WHERE key LIKE 'user:%' ORDER BY key DESC LIMIT 2", []string{"user:3", "user:2"}},
+		{"SELECT key FROM moz WHERE key BETWEEN 'admin:1' AND 'user:1'", []string{"admin:1", "user:1"}},
+		{"SELECT key FROM moz WHERE value = 'Bob'", []string{"user:2"}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(NewLexer(tt.query))
+		stmt := p.ParseQuery()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors for %q: %v", tt.query, p.Errors())
+		}
+
+		result := executor.Execute(stmt)
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, keys)
+		}
+	}
+}
+
+func TestExecutor_Columns(t *testing.T) {
+	executor := NewExecutor(newPlannerTestStore(t))
+
+	tests := []struct {
+		query   string
+		columns []string
+	}{
+		{"SELECT * FROM moz", []string{"key", "value"}},
+		{"SELECT value, key, value FROM moz", []string{"value", "key"}},
+		{"SELECT value.name, key FROM moz", []string{"value.name", "key"}},
+		{"SELECT COUNT(*) FROM moz", []string{"COUNT(*)"}},
+		{"DELETE FROM moz WHERE key = 'zeta'", []string{"key", "value"}},
+	}
+
+	for _, tt := range tests {
+		result := executor.Execute(parseStatement(t, tt.query))
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		if !reflect.DeepEqual(result.Columns, tt.columns) {
+			t.Errorf("%s: expected columns %v, got %v", tt.query, tt.columns, result.Columns)
+		}
+	}
+}
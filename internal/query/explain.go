@@ -0,0 +1,175 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stages of SELECT execution, in the order they run
+const (
+	stagePlan      = "plan"
+	stageScan      = "scan"
+	stageRank      = "rank"
+	stageAggregate = "aggregate"
+	stageSort      = "sort"
+	stageLimit     = "limit"
+)
+
+// ExplainStage is one step of query execution
+type ExplainStage struct {
+	Name       string  `json:"name"`
+	Detail     string  `json:"detail,omitempty"`
+	Rows       int     `json:"rows"`                  // Rows the stage produced (EXPLAIN ANALYZE)
+	DurationMs float64 `json:"duration_ms,omitempty"` // Time spent in the stage (EXPLAIN ANALYZE)
+}
+
+// Explanation describes how a SELECT statement runs. EXPLAIN ANALYZE also runs the
+// statement and fills in row counts and timings.
+type Explanation struct {
+	Access     string         `json:"access"`                    // Access path, such as KEY RANGE
+	Condition  string         `json:"index_condition,omitempty"` // Predicates the access path answers
+	Predicates []string       `json:"predicates,omitempty"`      // Predicates evaluated on each candidate row, in order
+	Stages     []ExplainStage `json:"stages"`
+
+	Analyzed     bool    `json:"analyzed"`
+	RowsScanned  int     `json:"rows_scanned"`  // Candidate rows read by the access path
+	RowsMatched  int     `json:"rows_matched"`  // Candidate rows that satisfied the predicates
+	RowsReturned int     `json:"rows_returned"` // Rows in the result
+	TotalMs      float64 `json:"total_ms,omitempty"`
+}
+
+// executeExplain executes EXPLAIN and EXPLAIN ANALYZE statements
+func (e *Executor) executeExplain(stmt *ExplainStatement) *ExecuteResult {
+	selectStmt, ok := stmt.Statement.(*SelectStatement)
+	if !ok {
+		return &ExecuteResult{Error: fmt.Errorf("EXPLAIN supports SELECT statements only")}
+	}
+	if err := e.validateFunctions(selectStmt); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+
+	start := time.Now()
+	plan := e.Plan(selectStmt)
+	explanation := e.explain(selectStmt, plan)
+	if !stmt.Analyze {
+		return &ExecuteResult{Rows: []map[string]string{}, Explain: explanation}
+	}
+
+	explanation.Analyzed = true
+	explanation.measure(stagePlan, 0, start)
+	if result := e.runSelect(selectStmt, plan, explanation); result.Error != nil {
+		return result
+	}
+	explanation.TotalMs = milliseconds(time.Since(start))
+	return &ExecuteResult{Rows: []map[string]string{}, Explain: explanation}
+}
+
+// explain describes the access path and stages of a planned SELECT statement
+func (e *Executor) explain(stmt *SelectStatement, plan *Plan) *Explanation {
+	explanation := &Explanation{Access: plan.Access.String(), Condition: plan.Condition()}
+	if plan.Residual != nil {
+		for _, conjunct := range splitConjuncts(plan.Residual) {
+			explanation.Predicates = append(explanation.Predicates, conjunct.String())
+		}
+	}
+
+	scan := "key order"
+	if plan.Descending {
+		scan = "descending key order"
+	}
+	if plan.EarlyStop {
+		scan += fmt.Sprintf(", stops after %d rows", stmt.Limit.Offset+stmt.Limit.Count)
+	}
+	switch len(explanation.Predicates) {
+	case 0:
+	case 1:
+		scan += ", 1 predicate per row"
+	default:
+		scan += fmt.Sprintf(", %d predicates per row", len(explanation.Predicates))
+	}
+
+	aggregate := e.isAggregationQuery(stmt)
+	stages := []ExplainStage{
+		{Name: stagePlan, Detail: "choose the access path"},
+		{Name: stageScan, Detail: scan},
+	}
+	if plan.Rank != nil && stmt.OrderBy == nil && !aggregate {
+		stages = append(stages, ExplainStage{Name: stageRank, Detail: "by " + plan.Rank.String() + " term frequency"})
+	}
+	if aggregate {
+		detail := "one group"
+		if len(stmt.GroupBy) > 0 {
+			groups := make([]string, len(stmt.GroupBy))
+			for i, group := range stmt.GroupBy {
+				groups[i] = group.String()
+			}
+			detail = "GROUP BY " + strings.Join(groups, ", ")
+		}
+		if stmt.Having != nil {
+			detail += " HAVING " + stmt.Having.String()
+		}
+		stages = append(stages, ExplainStage{Name: stageAggregate, Detail: detail})
+	}
+	if stmt.OrderBy != nil {
+		stages = append(stages, ExplainStage{Name: stageSort, Detail: stmt.OrderBy.String()})
+	}
+	if stmt.Limit != nil {
+		stages = append(stages, ExplainStage{Name: stageLimit, Detail: stmt.Limit.String()})
+	}
+	explanation.Stages = stages
+	return explanation
+}
+
+// measure records the rows and time of a stage since start; it does nothing on a nil explanation
+func (ex *Explanation) measure(name string, rows int, start time.Time) {
+	if ex == nil {
+		return
+	}
+	for i := range ex.Stages {
+		if ex.Stages[i].Name == name {
+			ex.Stages[i].Rows = rows
+			ex.Stages[i].DurationMs = milliseconds(time.Since(start))
+			return
+		}
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+// String formats the explanation as the text `moz query` prints
+func (ex *Explanation) String() string {
+	var out strings.Builder
+
+	out.WriteString("Access: " + ex.Access)
+	if ex.Condition != "" {
+		out.WriteString(" (" + ex.Condition + ")")
+	}
+	out.WriteString("\n")
+
+	if len(ex.Predicates) > 0 {
+		out.WriteString("Filter, in evaluation order:\n")
+		for i, predicate := range ex.Predicates {
+			out.WriteString(fmt.Sprintf("  %d. %s\n", i+1, predicate))
+		}
+	}
+
+	out.WriteString("Stages:\n")
+	for i, stage := range ex.Stages {
+		out.WriteString(fmt.Sprintf("  %d. %-9s %s", i+1, stage.Name, stage.Detail))
+		if ex.Analyzed && stage.Name != stagePlan {
+			out.WriteString(fmt.Sprintf(" [rows=%d, %.3fms]", stage.Rows, stage.DurationMs))
+		} else if ex.Analyzed {
+			out.WriteString(fmt.Sprintf(" [%.3fms]", stage.DurationMs))
+		}
+		out.WriteString("\n")
+	}
+
+	if ex.Analyzed {
+		out.WriteString(fmt.Sprintf("Rows: scanned %d, matched %d, returned %d\n", ex.RowsScanned, ex.RowsMatched, ex.RowsReturned))
+		out.WriteString(fmt.Sprintf("Total: %.3fms\n", ex.TotalMs))
+	}
+	return out.String()
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain_Plan(t *testing.T) {
+	executor := NewExecutor(newPlannerTestStore(t))
+
+	tests := []struct {
+		query      string
+		access     string
+		condition  string
+		predicates []string
+		stages     []string
+	}{
+		{
+			"EXPLAIN SELECT * FROM moz WHERE value = 'Bob'",
+			"FULL SCAN", "", []string{`(value = "Bob")`}, []string{"plan", "scan"},
+		},
+		{
+			"EXPLAIN SELECT key FROM moz WHERE key BETWEEN 'user:2' AND 'user:3' AND value != 'x' AND value LIKE 'C%' ORDER BY value LIMIT 1",
+			"KEY RANGE", `key >= "user:2" AND key <= "user:3"`,
+			[]string{`(value != "x")`, `(value LIKE "C%")`}, []string{"plan", "scan", "sort", "limit"},
+		},
+		{
+			"EXPLAIN SELECT key FROM moz WHERE key IN ('user:1', 'zeta')",
+			"KEY LOOKUP", `key IN ("user:1", "zeta")`, nil, []string{"plan", "scan"},
+		},
+		{
+			"EXPLAIN SELECT SPLIT_PART(key, ':', 1), COUNT(*) FROM moz WHERE key LIKE 'user:%' GROUP BY SPLIT_PART(key, ':', 1)",
+			"KEY PREFIX", `key LIKE "user:%"`, nil, []string{"plan", "scan", "aggregate"},
+		},
+		{
+			"EXPLAIN SELECT key FROM moz WHERE value MATCH 'alice'",
+			"FULL SCAN", "", []string{`(value MATCH "alice")`}, []string{"plan", "scan", "rank"},
+		},
+	}
+
+	for _, tt := range tests {
+		result := executor.Execute(parseStatement(t, tt.query))
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		ex := result.Explain
+		if ex == nil || ex.Analyzed {
+			t.Fatalf("%s: expected an explanation without measurements, got %+v", tt.query, ex)
+		}
+		if ex.Access != tt.access || ex.Condition != tt.condition {
+			t.Errorf("%s: expected %s (%s), got %s (%s)", tt.query, tt.access, tt.condition, ex.Access, ex.Condition)
+		}
+		if !reflect.DeepEqual(ex.Predicates, tt.predicates) {
+			t.Errorf("%s: expected predicates %v, got %v", tt.query, tt.predicates, ex.Predicates)
+		}
+		var stages []string
+		for _, stage := range ex.Stages {
+			stages = append(stages, stage.Name)
+		}
+		if !reflect.DeepEqual(stages, tt.stages) {
+			t.Errorf("%s: expected stages %v, got %v", tt.query, tt.stages, stages)
+		}
+	}
+}
+
+func TestExplain_Analyze(t *testing.T) {
+	store := newPlannerTestStore(t)
+	executor := NewExecutor(store)
+
+	result := executor.Execute(parseStatement(t, "EXPLAIN ANALYZE SELECT key FROM moz WHERE key LIKE 'user:%' AND value != 'Bob' ORDER BY value DESC LIMIT 2"))
+	if result.Error != nil {
+		t.Fatalf("Execution error: %v", result.Error)
+	}
+	ex := result.Explain
+	if !ex.Analyzed || ex.RowsScanned != 4 || ex.RowsMatched != 3 || ex.RowsReturned != 2 {
+		t.Errorf("Expected 4 scanned, 3 matched and 2 returned rows, got %+v", ex)
+	}
+	rows := map[string]int{}
+	for _, stage := range ex.Stages {
+		rows[stage.Name] = stage.Rows
+	}
+	if want := map[string]int{"plan": 0, "scan": 3, "sort": 3, "limit": 2}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("Expected stage rows %v, got %v", want, rows)
+	}
+
+	// An early stop shows up as fewer rows scanned than the access path offers
+	result = executor.Execute(parseStatement(t, "EXPLAIN ANALYZE SELECT key FROM moz LIMIT 2"))
+	if result.Explain.RowsScanned != 2 {
+		t.Errorf("Expected the scan to stop after 2 rows, got %+v", result.Explain)
+	}
+
+	text := result.Explain.String()
+	for _, want := range []string{"Access: FULL SCAN", "stops after 2 rows", "Rows: scanned 2, matched 2, returned 2", "Total:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+
+	// EXPLAIN ANALYZE runs the query but returns only the explanation
+	if len(result.Rows) != 0 {
+		t.Errorf("Expected no rows, got %v", result.Rows)
+	}
+}
+
+func parseStatement(t *testing.T, query string) Statement {
+	p := NewParser(NewLexer(query))
+	stmt := p.ParseQuery()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors for %q: %v", query, p.Errors())
+	}
+	return stmt
+}
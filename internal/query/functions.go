@@ -0,0 +1,281 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// scalarFunctions maps each function that computes a value from a single row to the
+// number of arguments it takes; a maximum of -1 means any number
+var scalarFunctions = map[string]struct{ min, max int }{
+	"UPPER":      {1, 1},
+	"LOWER":      {1, 1},
+	"LENGTH":     {1, 1},
+	"SUBSTR":     {2, 3},
+	"SPLIT_PART": {3, 3},
+	"CONCAT":     {1, -1},
+}
+
+// isScalarFunction reports whether a function computes a value from a single row
+func isScalarFunction(name string) bool {
+	_, ok := scalarFunctions[name]
+	return ok
+}
+
+// checkArguments checks the number of arguments of a scalar function call
+func checkArguments(fn *FunctionExpression) error {
+	arity := scalarFunctions[fn.Name]
+	n := len(fn.Arguments)
+	switch {
+	case arity.min == arity.max && n != arity.min:
+		return fmt.Errorf("%s takes exactly %s", fn.Name, arguments(arity.min))
+	case n < arity.min:
+		return fmt.Errorf("%s takes at least %s", fn.Name, arguments(arity.min))
+	case arity.max >= 0 && n > arity.max:
+		return fmt.Errorf("%s takes at most %s", fn.Name, arguments(arity.max))
+	}
+	return nil
+}
+
+func arguments(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
+
+// isArithmeticOperator reports whether a binary operator computes a number
+func isArithmeticOperator(op Operator) bool {
+	switch op {
+	case ADD_OP, SUB_OP, MUL_OP, DIV_OP, MOD_OP:
+		return true
+	default:
+		return false
+	}
+}
+
+// isComputed reports whether an expression computes its value from other expressions:
+// a function call, arithmetic, or a CAST
+func isComputed(expr Expression) bool {
+	switch exp := expr.(type) {
+	case *FunctionExpression, *CastExpression:
+		return true
+	case *BinaryExpression:
+		return isArithmeticOperator(exp.Operator)
+	case *UnaryExpression:
+		return exp.Operator == NEG_OP
+	default:
+		return false
+	}
+}
+
+// scalarFunction evaluates a scalar function call. It returns false if an argument
+// has no value, except for CONCAT, which skips arguments without a value.
+func (e *Executor) scalarFunction(fn *FunctionExpression, row map[string]string) (string, bool) {
+	if checkArguments(fn) != nil {
+		return "", false
+	}
+	if fn.Name == "CONCAT" {
+		var out strings.Builder
+		for _, arg := range fn.Arguments {
+			if value, ok := e.columnValue(arg, row); ok {
+				out.WriteString(value)
+			}
+		}
+		return out.String(), true
+	}
+
+	args := make([]string, len(fn.Arguments))
+	for i, arg := range fn.Arguments {
+		value, ok := e.columnValue(arg, row)
+		if !ok {
+			return "", false
+		}
+		args[i] = value
+	}
+
+	switch fn.Name {
+	case "UPPER":
+		return strings.ToUpper(args[0]), true
+	case "LOWER":
+		return strings.ToLower(args[0]), true
+	case "LENGTH":
+		return strconv.Itoa(utf8.RuneCountInString(args[0])), true
+	case "SUBSTR":
+		return substr(args)
+	case "SPLIT_PART":
+		return splitPart(args[0], args[1], args[2])
+	default:
+		return "", false
+	}
+}
+
+// substr evaluates SUBSTR(text, start[, length]) the way SQLite does. Positions count
+// characters from 1, or from the end if negative, and 0 is the position just before
+// the first character. A negative length takes the characters before start. The
+// range is cut to the text, so a start or length past either end shortens the result.
+func substr(args []string) (string, bool) {
+	runes := []rune(args[0])
+	from, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", false
+	}
+	length := math.MaxInt // To the end of the text
+	before := false
+	if len(args) == 3 {
+		if length, err = strconv.Atoi(args[2]); err != nil {
+			return "", false
+		}
+		if length < 0 {
+			length, before = -length, true
+		}
+	}
+
+	// Convert start to an offset, dropping the part of the range before the text
+	switch {
+	case from < 0:
+		from += len(runes)
+		if from < 0 {
+			length += from
+			from = 0
+		}
+	case from > 0:
+		from--
+	case length > 0:
+		length-- // Position 0 holds no character
+	}
+	if before {
+		from -= length
+		if from < 0 {
+			length += from
+			from = 0
+		}
+	}
+
+	if length <= 0 || from >= len(runes) {
+		return "", true
+	}
+	return string(runes[from : from+clamp(length, 0, len(runes)-from)]), true
+}
+
+// clamp limits n to [low, high]
+func clamp(n, low, high int) int {
+	if n < low {
+		return low
+	}
+	if n > high {
+		return high
+	}
+	return n
+}
+
+// splitPart evaluates SPLIT_PART(text, separator, n): the n-th field of text split on
+// separator, counting from 1, or an empty string past the last field
+func splitPart(text, separator, field string) (string, bool) {
+	n, err := strconv.Atoi(field)
+	if err != nil || n < 1 || separator == "" {
+		return "", false
+	}
+	parts := strings.Split(text, separator)
+	if n > len(parts) {
+		return "", true
+	}
+	return parts[n-1], true
+}
+
+// arithmetic evaluates +, -, *, / and % on two numbers. It returns false if an
+// operand is not a number or the result is undefined, such as division by zero.
+func (e *Executor) arithmetic(expr *BinaryExpression, row map[string]string) (string, bool) {
+	left, leftOK := e.numberValue(expr.Left, row)
+	right, rightOK := e.numberValue(expr.Right, row)
+	if !leftOK || !rightOK {
+		return "", false
+	}
+
+	var result float64
+	switch expr.Operator {
+	case ADD_OP:
+		result = left + right
+	case SUB_OP:
+		result = left - right
+	case MUL_OP:
+		result = left * right
+	case DIV_OP:
+		if right == 0 {
+			return "", false
+		}
+		result = left / right
+	case MOD_OP:
+		if right == 0 {
+			return "", false
+		}
+		result = math.Mod(left, right)
+	default:
+		return "", false
+	}
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return "", false
+	}
+	return formatNumber(result), true
+}
+
+// numberValue returns the value of an expression as a number
+func (e *Executor) numberValue(expr Expression, row map[string]string) (float64, bool) {
+	value, ok := e.columnValue(expr, row)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, false
+	}
+	return number, true
+}
+
+// cast evaluates CAST(expression AS INT) and CAST(expression AS FLOAT). INT truncates
+// toward zero. It returns false if the value is not a number.
+func (e *Executor) cast(expr *CastExpression, row map[string]string) (string, bool) {
+	value, ok := e.columnValue(expr.Expression, row)
+	if !ok {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+
+	if expr.Type == "INT" {
+		// Integers convert exactly, even beyond the precision of a float
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10), true
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return "", false
+	}
+	if expr.Type == "INT" {
+		if math.Abs(number) >= math.MaxInt64 {
+			return "", false
+		}
+		return strconv.FormatInt(int64(number), 10), true
+	}
+	return formatNumber(number), true
+}
+
+// typedOperand returns the JSON-typed value of a comparison operand. Computed values
+// that are numbers compare as JSON numbers and any other computed value as a string.
+func (e *Executor) typedOperand(expr Expression, row map[string]string) (interface{}, bool) {
+	if !isComputed(expr) {
+		return typedValue(expr, row)
+	}
+	value, ok := e.columnValue(expr, row)
+	if !ok {
+		return nil, false
+	}
+	if isNumeric(value) {
+		return json.Number(value), true
+	}
+	return value, true
+}
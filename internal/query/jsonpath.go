@@ -0,0 +1,177 @@
+package query
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// isArrayIndex reports whether a path part is written as an array index
+func isArrayIndex(part string) bool {
+	if part == "" {
+		return false
+	}
+	for i := 0; i < len(part); i++ {
+		if !isDigit(part[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveJSONPath decodes value as JSON and follows path into it. Numbers are returned
+// as json.Number. It returns false if value is not JSON or the path does not exist.
+func resolveJSONPath(value string, path []string) (interface{}, bool) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return nil, false
+	}
+
+	for _, part := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, exists := node[part]
+			if !exists {
+				return nil, false
+			}
+			doc = child
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// jsonText returns the text form of a JSON value: strings unquoted, anything else as JSON
+func jsonText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// typedValue returns the JSON-typed value of a comparison operand. Paths resolve against
+// the row's value; it returns false if the value is not JSON or lacks the path.
+func typedValue(expr Expression, row map[string]string) (interface{}, bool) {
+	switch exp := expr.(type) {
+	case *JSONPathExpression:
+		// Group rows of aggregation queries hold grouped paths as columns of JSON text
+		if text, exists := row[exp.String()]; exists {
+			if value, ok := resolveJSONPath(text, nil); ok {
+				return value, true
+			}
+			return text, true
+		}
+		value, exists := row[exp.Field]
+		if !exists {
+			return nil, false
+		}
+		return resolveJSONPath(value, exp.Path)
+	case *Identifier:
+		value, exists := row[exp.Value]
+		return value, exists
+	case *StringLiteral:
+		return exp.Value, true
+	case *NumberLiteral:
+		return json.Number(exp.Value), true
+	case *BooleanLiteral:
+		return exp.Value, true
+	case *NullLiteral:
+		return nil, true
+	default:
+		return nil, false
+	}
+}
+
+// compareTyped orders two JSON values of the same type: numbers numerically, strings
+// lexicographically, false before true, and null equal to null. It returns false for
+// values of different types and for objects and arrays, which are never comparable.
+func compareTyped(left, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case json.Number:
+		r, ok := right.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		lf, lerr := l.Float64()
+		rf, rerr := r.Float64()
+		if lerr != nil || rerr != nil {
+			return 0, false
+		}
+		switch {
+		case lf < rf:
+			return -1, true
+		case lf > rf:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(l, r), true
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case l == r:
+			return 0, true
+		case r:
+			return -1, true
+		default:
+			return 1, true
+		}
+	case nil:
+		return 0, right == nil
+	default:
+		return 0, false
+	}
+}
+
+// hasJSONPath reports whether any of the expressions is a JSON path
+func hasJSONPath(exprs ...Expression) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(*JSONPathExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTyped applies an equality or ordering operator to two JSON values. It
+// returns false for handled when the operator works on text instead.
+func evaluateTyped(operator Operator, left, right interface{}) (matched, handled bool) {
+	cmp, comparable := compareTyped(left, right)
+	switch operator {
+	case EQ:
+		return comparable && cmp == 0, true
+	case NEQ:
+		return !comparable || cmp != 0, true
+	case LT_OP:
+		return comparable && cmp < 0, true
+	case GT_OP:
+		return comparable && cmp > 0, true
+	case LTE:
+		return comparable && cmp <= 0, true
+	case GTE:
+		return comparable && cmp >= 0, true
+	default:
+		return false, false
+	}
+}
@@ -90,6 +90,17 @@ func (l *Lexer) NextToken() Token {
 		tok = newToken(LPAREN, l.ch, l.position)
 	case ')':
 		tok = newToken(RPAREN, l.ch, l.position)
+	case '?':
+		tok = newToken(PARAM, l.ch, l.position)
+	case '$':
+		if isDigit(l.peekChar()) {
+			tok.Position = l.position
+			l.readChar()
+			tok.Type = PARAM
+			tok.Literal = "$" + l.readNumber()
+			return tok // early return to avoid readChar()
+		}
+		tok = newToken(ILLEGAL, l.ch, l.position)
 	case '"':
 		tok.Type = STRING
 		tok.Literal = l.readString()
@@ -127,13 +138,37 @@ func newToken(tokenType TokenType, ch byte, position int) Token {
 	return Token{Type: tokenType, Literal: string(ch), Position: position}
 }
 
-// readIdentifier reads identifier (keywords and field names)
+// readIdentifier reads identifier (keywords and field names), including JSON paths
+// such as value.user.name, value.tags[0] and value.tags.0
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '_' {
-		l.readChar()
+	for {
+		switch {
+		case isLetter(l.ch) || isDigit(l.ch) || l.ch == '_':
+			l.readChar()
+		case l.ch == '.' && (isLetter(l.peekChar()) || isDigit(l.peekChar())):
+			l.readChar()
+		case l.ch == '[' && l.arrayIndexLength() > 0:
+			for n := l.arrayIndexLength(); n > 0; n-- {
+				l.readChar()
+			}
+		default:
+			return l.input[position:l.position]
+		}
 	}
-	return l.input[position:l.position]
+}
+
+// arrayIndexLength returns the length of an array index such as [12] starting at the
+// current char, or 0 if there is none
+func (l *Lexer) arrayIndexLength() int {
+	end := l.readPosition
+	for end < len(l.input) && isDigit(l.input[end]) {
+		end++
+	}
+	if end == l.readPosition || end >= len(l.input) || l.input[end] != ']' {
+		return 0
+	}
+	return end + 1 - l.position
 }
 
 // readNumber reads numeric literals
@@ -39,3 +39,79 @@ func TestLexer_BasicTokens(t *testing.T) {
 		}
 	}
 }
+
+func TestLexer_JSONPath(t *testing.T) {
+	input := `value.user.name >= value.tags[0] AND value.tags.1 = true. value[x]`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{IDENT, "value.user.name"},
+		{GT_EQ, ">="},
+		{IDENT, "value.tags[0]"},
+		{AND, "AND"},
+		{IDENT, "value.tags.1"},
+		{ASSIGN, "="},
+		{TRUE, "true"},
+		{ILLEGAL, "."},
+		{IDENT, "value"},
+		{ILLEGAL, "["},
+		{IDENT, "x"},
+		{ILLEGAL, "]"},
+		{EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLexer_Parameters(t *testing.T) {
+	input := `key = ? AND value IN ($1, $12) OR $x`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{IDENT, "key"},
+		{ASSIGN, "="},
+		{PARAM, "?"},
+		{AND, "AND"},
+		{IDENT, "value"},
+		{IN, "IN"},
+		{LPAREN, "("},
+		{PARAM, "$1"},
+		{COMMA, ","},
+		{PARAM, "$12"},
+		{RPAREN, ")"},
+		{OR, "OR"},
+		{ILLEGAL, "$"},
+		{IDENT, "x"},
+		{EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+)
+
+// Writer is implemented by stores that INSERT, UPDATE and DELETE can change
+type Writer interface {
+	Put(key, value string) error
+	Delete(key string) error
+}
+
+// BatchWriter is implemented by stores that apply a batch of writes atomically.
+// Data-modifying statements use it when available, so they change all of their
+// rows or none.
+type BatchWriter interface {
+	ApplyBatch(ops []kvstore.WriteOp) error
+}
+
+// IsWrite reports whether a query is an INSERT, UPDATE or DELETE statement.
+// Queries that do not parse are not writes.
+func IsWrite(query string) bool {
+	parser := NewParser(NewLexer(query))
+	stmt := parser.ParseQuery()
+	if len(parser.Errors()) > 0 {
+		return false
+	}
+	switch stmt.(type) {
+	case *InsertStatement, *UpdateStatement, *DeleteStatement:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetDryRun makes INSERT, UPDATE and DELETE report the rows they would change
+// without writing them
+func (e *Executor) SetDryRun(dryRun bool) {
+	e.dryRun = dryRun
+}
+
+// executeInsert executes INSERT statements. Inserting an existing key is an error.
+func (e *Executor) executeInsert(stmt *InsertStatement) *ExecuteResult {
+	keyColumn, valueColumn := -1, -1
+	for i, column := range stmt.Columns {
+		switch {
+		case column == "key" && keyColumn < 0:
+			keyColumn = i
+		case column == "value" && valueColumn < 0:
+			valueColumn = i
+		default:
+			return &ExecuteResult{Error: fmt.Errorf("invalid INSERT column %s: expected key and value", column)}
+		}
+	}
+	if keyColumn < 0 || valueColumn < 0 {
+		return &ExecuteResult{Error: fmt.Errorf("INSERT requires both key and value columns")}
+	}
+
+	result := &ExecuteResult{Rows: []map[string]string{}, Columns: []string{"key", "value"}}
+	var ops []kvstore.WriteOp
+	inserted := make(map[string]bool)
+	for _, values := range stmt.Rows {
+		if len(values) != len(stmt.Columns) {
+			return &ExecuteResult{Error: fmt.Errorf("INSERT has %d columns but %d values", len(stmt.Columns), len(values))}
+		}
+		for _, value := range values {
+			if err := e.checkFunctions(value, false); err != nil {
+				return &ExecuteResult{Error: err}
+			}
+		}
+		key, keyOK := e.columnValue(values[keyColumn], nil)
+		value, valueOK := e.columnValue(values[valueColumn], nil)
+		if !keyOK || !valueOK {
+			return &ExecuteResult{Error: fmt.Errorf("INSERT values must be non-null constants")}
+		}
+		if _, err := e.store.Get(key); err == nil || inserted[key] {
+			return &ExecuteResult{Error: fmt.Errorf("key already exists: %s", key)}
+		}
+		inserted[key] = true
+
+		ops = append(ops, kvstore.WriteOp{Key: key, Value: value})
+		result.Rows = append(result.Rows, map[string]string{"key": key, "value": value})
+	}
+
+	if err := e.applyWrites(ops); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+	result.Affected = len(ops)
+	return result
+}
+
+// executeUpdate executes UPDATE statements. Only the value can be set; the WHERE
+// clause is planned and evaluated like a SELECT.
+func (e *Executor) executeUpdate(stmt *UpdateStatement) *ExecuteResult {
+	if len(stmt.Set) != 1 || stmt.Set[0].Field != "value" {
+		return &ExecuteResult{Error: fmt.Errorf("UPDATE can only SET value")}
+	}
+	assignment := stmt.Set[0]
+	if err := e.checkFunctions(assignment.Value, false); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+
+	result := &ExecuteResult{Rows: []map[string]string{}, Columns: []string{"key", "value"}}
+	var ops []kvstore.WriteOp
+	var evalErr error
+	err := e.scanWhere(stmt.Where, func(row map[string]string) bool {
+		value, ok := e.columnValue(assignment.Value, row)
+		if !ok {
+			evalErr = fmt.Errorf("no value for %s in key %s", assignment.Value.String(), row["key"])
+			return false
+		}
+		ops = append(ops, kvstore.WriteOp{Key: row["key"], Value: value})
+		result.Rows = append(result.Rows, map[string]string{"key": row["key"], "value": value})
+		return true
+	})
+	if err == nil {
+		err = evalErr
+	}
+	if err != nil {
+		return &ExecuteResult{Error: err}
+	}
+
+	if err := e.applyWrites(ops); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+	result.Affected = len(ops)
+	return result
+}
+
+// executeDelete executes DELETE statements. The rows report the deleted values.
+func (e *Executor) executeDelete(stmt *DeleteStatement) *ExecuteResult {
+	result := &ExecuteResult{Rows: []map[string]string{}, Columns: []string{"key", "value"}}
+	var ops []kvstore.WriteOp
+	err := e.scanWhere(stmt.Where, func(row map[string]string) bool {
+		ops = append(ops, kvstore.WriteOp{Key: row["key"], Delete: true})
+		result.Rows = append(result.Rows, row)
+		return true
+	})
+	if err != nil {
+		return &ExecuteResult{Error: err}
+	}
+
+	if err := e.applyWrites(ops); err != nil {
+		return &ExecuteResult{Error: err}
+	}
+	result.Affected = len(ops)
+	return result
+}
+
+// scanWhere visits the rows matching a WHERE clause, using the same plan as a SELECT
+func (e *Executor) scanWhere(where Expression, visit func(row map[string]string) bool) error {
+	stmt := &SelectStatement{Fields: []Expression{&WildcardExpression{}}, Where: where}
+	if err := e.validateFunctions(stmt); err != nil {
+		return err
+	}
+
+	plan := e.Plan(stmt)
+	err := e.scan(plan, func(key, value string) bool {
+		row := map[string]string{
+			"key":   key,
+			"value": value,
+		}
+		if plan.Residual != nil && !e.evaluateExpression(plan.Residual, row) {
+			return true
+		}
+		return visit(row)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan keys: %v", err)
+	}
+	return nil
+}
+
+// applyWrites writes the changes of a statement, in one batch when the store supports it
+func (e *Executor) applyWrites(ops []kvstore.WriteOp) error {
+	if e.dryRun || len(ops) == 0 {
+		return nil
+	}
+	if batch, ok := e.store.(BatchWriter); ok {
+		return batch.ApplyBatch(ops)
+	}
+
+	writer, ok := e.store.(Writer)
+	if !ok {
+		return fmt.Errorf("store does not support data-modifying statements")
+	}
+	for i, op := range ops {
+		var err error
+		if op.Delete {
+			err = writer.Delete(op.Key)
+		} else {
+			err = writer.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			// Without batches the writes before the failure remain applied
+			return fmt.Errorf("write %d of %d failed: %w", i+1, len(ops), err)
+		}
+	}
+	return nil
+}
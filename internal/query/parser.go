@@ -14,6 +14,10 @@ type Parser struct {
 	peekToken Token
 
 	errors []string
+
+	parameters int  // Highest parameter number seen
+	positional bool // The query uses ? placeholders
+	numbered   bool // The query uses $1 placeholders
 }
 
 // NewParser creates a new parser instance
@@ -35,6 +39,11 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ParameterCount returns the number of arguments the parsed query takes
+func (p *Parser) ParameterCount() int {
+	return p.parameters
+}
+
 // nextToken advances token positions
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
@@ -46,12 +55,152 @@ func (p *Parser) ParseQuery() Statement {
 	switch p.curToken.Type {
 	case SELECT:
 		return p.parseSelectStatement()
+	case INSERT:
+		return p.parseInsertStatement()
+	case UPDATE:
+		return p.parseUpdateStatement()
+	case DELETE:
+		return p.parseDeleteStatement()
+	case EXPLAIN:
+		return p.parseExplainStatement()
 	default:
-		p.addError(fmt.Sprintf("unexpected token %s, expected SELECT", p.curToken.Type))
+		p.addError(fmt.Sprintf("unexpected token %s, expected SELECT, INSERT, UPDATE, DELETE or EXPLAIN", p.curToken.Type))
 		return nil
 	}
 }
 
+// parseExplainStatement parses EXPLAIN [ANALYZE] SELECT ...
+func (p *Parser) parseExplainStatement() *ExplainStatement {
+	stmt := &ExplainStatement{}
+
+	if p.peekToken.Type == ANALYZE {
+		p.nextToken()
+		stmt.Analyze = true
+	}
+	if !p.expectPeek(SELECT) {
+		return nil
+	}
+
+	selectStmt := p.parseSelectStatement()
+	if selectStmt == nil {
+		return nil
+	}
+	stmt.Statement = selectStmt
+	return stmt
+}
+
+// parseInsertStatement parses INSERT INTO table [(key, value)] VALUES (...), (...)
+func (p *Parser) parseInsertStatement() *InsertStatement {
+	stmt := &InsertStatement{Columns: []string{"key", "value"}}
+
+	if !p.expectPeek(INTO) {
+		return nil
+	}
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	stmt.Table = p.curToken.Literal
+
+	// Parse optional column list
+	if p.peekToken.Type == LPAREN {
+		p.nextToken()
+		stmt.Columns = nil
+		for {
+			if !p.expectPeek(IDENT) {
+				return nil
+			}
+			stmt.Columns = append(stmt.Columns, p.curToken.Literal)
+			if p.peekToken.Type != COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(VALUES) {
+		return nil
+	}
+	for {
+		if !p.expectPeek(LPAREN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Rows = append(stmt.Rows, p.parseExpressionList())
+		if !p.expectPeek(RPAREN) {
+			return nil
+		}
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseUpdateStatement parses UPDATE table SET field = expression [WHERE ...]
+func (p *Parser) parseUpdateStatement() *UpdateStatement {
+	stmt := &UpdateStatement{}
+
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	stmt.Table = p.curToken.Literal
+
+	if !p.expectPeek(SET) {
+		return nil
+	}
+	for {
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		field := p.curToken.Literal
+		if !p.expectPeek(ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Set = append(stmt.Set, Assignment{Field: field, Value: p.parseArithmeticExpression()})
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	// Parse optional WHERE clause
+	if p.peekToken.Type == WHERE {
+		p.nextToken()
+		p.nextToken()
+		stmt.Where = p.parseExpression()
+	}
+
+	return stmt
+}
+
+// parseDeleteStatement parses DELETE FROM table [WHERE ...]
+func (p *Parser) parseDeleteStatement() *DeleteStatement {
+	stmt := &DeleteStatement{}
+
+	if !p.expectPeek(FROM) {
+		return nil
+	}
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	stmt.Table = p.curToken.Literal
+
+	// Parse optional WHERE clause
+	if p.peekToken.Type == WHERE {
+		p.nextToken()
+		p.nextToken()
+		stmt.Where = p.parseExpression()
+	}
+
+	return stmt
+}
+
 // parseSelectStatement parses SELECT statements
 func (p *Parser) parseSelectStatement() *SelectStatement {
 	stmt := &SelectStatement{}
@@ -82,6 +231,23 @@ func (p *Parser) parseSelectStatement() *SelectStatement {
 		stmt.Where = p.parseExpression()
 	}
 
+	// Parse optional GROUP BY clause
+	if p.peekToken.Type == GROUP {
+		p.nextToken()
+		if !p.expectPeek(BY) {
+			return nil
+		}
+		p.nextToken()
+		stmt.GroupBy = p.parseExpressionList()
+	}
+
+	// Parse optional HAVING clause
+	if p.peekToken.Type == HAVING {
+		p.nextToken()
+		p.nextToken()
+		stmt.Having = p.parseExpression()
+	}
+
 	// Parse optional ORDER BY clause
 	if p.peekToken.Type == ORDER {
 		stmt.OrderBy = p.parseOrderClause()
@@ -107,38 +273,35 @@ func (p *Parser) parseSelectFields() []Expression {
 		return fields
 	}
 
-	// Handle function calls (COUNT, etc.)
-	if (p.curToken.Type == IDENT || p.curToken.Type == COUNT) && p.peekToken.Type == LPAREN {
-		fields = append(fields, p.parseFunctionExpression())
-
-		// Handle comma-separated fields after function
-		for p.peekToken.Type == COMMA {
-			p.nextToken()
+	// Handle comma-separated fields, function calls and arithmetic, each optionally
+	// renamed with AS
+	for {
+		field := p.parseArithmeticExpression()
+		if p.peekToken.Type == AS {
 			p.nextToken()
-			if (p.curToken.Type == IDENT || p.curToken.Type == COUNT) && p.peekToken.Type == LPAREN {
-				fields = append(fields, p.parseFunctionExpression())
-			} else if p.curToken.Type == IDENT {
-				fields = append(fields, &Identifier{Value: p.curToken.Literal})
+			if !p.expectPeek(IDENT) {
+				return fields
 			}
+			field = &AliasExpression{Expression: field, Alias: p.curToken.Literal}
 		}
-		return fields
-	}
-
-	// Handle regular identifiers
-	if p.curToken.Type == IDENT {
-		fields = append(fields, &Identifier{Value: p.curToken.Literal})
+		fields = append(fields, field)
+		if p.peekToken.Type != COMMA {
+			return fields
+		}
+		p.nextToken()
+		p.nextToken()
 	}
+}
 
-	// Handle comma-separated fields
+// parseExpressionList parses comma-separated expressions such as a GROUP BY list
+func (p *Parser) parseExpressionList() []Expression {
+	list := []Expression{p.parseArithmeticExpression()}
 	for p.peekToken.Type == COMMA {
 		p.nextToken()
 		p.nextToken()
-		if p.curToken.Type == IDENT {
-			fields = append(fields, &Identifier{Value: p.curToken.Literal})
-		}
+		list = append(list, p.parseArithmeticExpression())
 	}
-
-	return fields
+	return list
 }
 
 // parseOrderClause parses ORDER BY clause
@@ -149,13 +312,15 @@ func (p *Parser) parseOrderClause() *OrderClause {
 	if !p.expectPeek(BY) {
 		return nil
 	}
-	if !p.expectPeek(IDENT) {
+	p.nextToken()
+	expression := p.parseArithmeticExpression()
+	if expression == nil {
 		return nil
 	}
 
 	orderBy := &OrderClause{
-		Field:     p.curToken.Literal,
-		Direction: "ASC", // default
+		Expression: expression,
+		Direction:  "ASC", // default
 	}
 
 	// Check for ASC/DESC
@@ -250,62 +415,68 @@ func (p *Parser) parseNotExpression() Expression {
 
 // parseComparisonExpression parses comparison expressions
 func (p *Parser) parseComparisonExpression() Expression {
-	left := p.parsePrimaryExpression()
+	left := p.parseArithmeticExpression()
 
 	switch p.peekToken.Type {
 	case ASSIGN:
 		p.nextToken()
 		operator := EQ
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case NOT_EQ:
 		p.nextToken()
 		operator := NEQ
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case LT:
 		p.nextToken()
 		operator := LT_OP
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case GT:
 		p.nextToken()
 		operator := GT_OP
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case LT_EQ:
 		p.nextToken()
 		operator := LTE
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case GT_EQ:
 		p.nextToken()
 		operator := GTE
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case LIKE:
 		p.nextToken()
 		operator := LIKE_OP
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case CONTAINS:
 		p.nextToken()
 		operator := CONTAINS_OP
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
+		return &BinaryExpression{Left: left, Operator: operator, Right: right}
+	case MATCH:
+		p.nextToken()
+		operator := MATCH_OP
+		p.nextToken()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case REGEX:
 		p.nextToken()
 		operator := REGEX_OP
 		p.nextToken()
-		right := p.parsePrimaryExpression()
+		right := p.parseArithmeticExpression()
 		return &BinaryExpression{Left: left, Operator: operator, Right: right}
 	case BETWEEN:
 		return p.parseBetweenExpression(left)
@@ -323,14 +494,14 @@ func (p *Parser) parseBetweenExpression(field Expression) Expression {
 	}
 
 	p.nextToken()
-	start := p.parsePrimaryExpression()
+	start := p.parseArithmeticExpression()
 
 	if !p.expectPeek(AND) {
 		return nil
 	}
 
 	p.nextToken()
-	end := p.parsePrimaryExpression()
+	end := p.parseArithmeticExpression()
 
 	return &BetweenExpression{Field: field, Start: start, End: end}
 }
@@ -349,12 +520,12 @@ func (p *Parser) parseInExpression(field Expression) Expression {
 	p.nextToken()
 
 	if p.curToken.Type != RPAREN {
-		values = append(values, p.parsePrimaryExpression())
+		values = append(values, p.parseArithmeticExpression())
 
 		for p.peekToken.Type == COMMA {
 			p.nextToken()
 			p.nextToken()
-			values = append(values, p.parsePrimaryExpression())
+			values = append(values, p.parseArithmeticExpression())
 		}
 	}
 
@@ -365,9 +536,89 @@ func (p *Parser) parseInExpression(field Expression) Expression {
 	return &InExpression{Field: field, Values: values}
 }
 
+// parseArithmeticExpression parses + and -, the arithmetic operators with the
+// lowest precedence. Arithmetic binds tighter than comparisons.
+func (p *Parser) parseArithmeticExpression() Expression {
+	left := p.parseTermExpression()
+
+	for p.peekToken.Type == PLUS || p.peekToken.Type == MINUS {
+		p.nextToken()
+		operator := ADD_OP
+		if p.curToken.Type == MINUS {
+			operator = SUB_OP
+		}
+		p.nextToken()
+		right := p.parseTermExpression()
+		left = &BinaryExpression{Left: left, Operator: operator, Right: right}
+	}
+
+	return left
+}
+
+// parseTermExpression parses *, / and %
+func (p *Parser) parseTermExpression() Expression {
+	left := p.parseNegationExpression()
+
+	for p.peekToken.Type == ASTERISK || p.peekToken.Type == SLASH || p.peekToken.Type == PERCENT {
+		p.nextToken()
+		operator := MUL_OP
+		switch p.curToken.Type {
+		case SLASH:
+			operator = DIV_OP
+		case PERCENT:
+			operator = MOD_OP
+		}
+		p.nextToken()
+		right := p.parseNegationExpression()
+		left = &BinaryExpression{Left: left, Operator: operator, Right: right}
+	}
+
+	return left
+}
+
+// parseNegationExpression parses unary minus. Negative numbers become number literals.
+func (p *Parser) parseNegationExpression() Expression {
+	if p.curToken.Type != MINUS {
+		return p.parsePrimaryExpression()
+	}
+
+	p.nextToken()
+	right := p.parseNegationExpression()
+	if number, ok := right.(*NumberLiteral); ok && !strings.HasPrefix(number.Value, "-") {
+		return &NumberLiteral{Value: "-" + number.Value}
+	}
+	return &UnaryExpression{Operator: NEG_OP, Right: right}
+}
+
+// parseCastExpression parses CAST(expression AS INT) and CAST(expression AS FLOAT)
+func (p *Parser) parseCastExpression() Expression {
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression := p.parseArithmeticExpression()
+
+	if !p.expectPeek(AS) {
+		return nil
+	}
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	castType := strings.ToUpper(p.curToken.Literal)
+	if castType != "INT" && castType != "FLOAT" {
+		p.addError(fmt.Sprintf("unsupported CAST type %s: expected INT or FLOAT", p.curToken.Literal))
+		return nil
+	}
+
+	if !p.expectPeek(RPAREN) {
+		return nil
+	}
+	return &CastExpression{Expression: expression, Type: castType}
+}
+
 // parseFunctionExpression parses function calls
 func (p *Parser) parseFunctionExpression() Expression {
-	name := p.curToken.Literal
+	name := strings.ToUpper(p.curToken.Literal)
 
 	if !p.expectPeek(LPAREN) {
 		return nil
@@ -376,32 +627,53 @@ func (p *Parser) parseFunctionExpression() Expression {
 	args := []Expression{}
 	p.nextToken()
 
+	distinct := false
+	if p.curToken.Type == DISTINCT {
+		distinct = true
+		p.nextToken()
+	}
+
+	// An empty argument list ends at the current token
 	if p.curToken.Type != RPAREN {
-		args = append(args, p.parsePrimaryExpression())
+		args = append(args, p.parseArithmeticExpression())
 
 		for p.peekToken.Type == COMMA {
 			p.nextToken()
 			p.nextToken()
-			args = append(args, p.parsePrimaryExpression())
+			args = append(args, p.parseArithmeticExpression())
 		}
-	}
 
-	if !p.expectPeek(RPAREN) {
-		return nil
+		if !p.expectPeek(RPAREN) {
+			return nil
+		}
 	}
 
-	return &FunctionExpression{Name: name, Arguments: args}
+	return &FunctionExpression{Name: name, Arguments: args, Distinct: distinct}
 }
 
 // parsePrimaryExpression parses primary expressions (identifiers, literals)
 func (p *Parser) parsePrimaryExpression() Expression {
 	switch p.curToken.Type {
 	case IDENT:
-		return &Identifier{Value: p.curToken.Literal}
+		if p.peekToken.Type == LPAREN {
+			if strings.ToUpper(p.curToken.Literal) == "CAST" {
+				return p.parseCastExpression()
+			}
+			return p.parseFunctionExpression()
+		}
+		return p.parseIdentifier()
+	case COUNT:
+		return p.parseFunctionExpression()
 	case STRING:
 		return &StringLiteral{Value: p.curToken.Literal}
 	case NUMBER:
 		return &NumberLiteral{Value: p.curToken.Literal}
+	case TRUE, FALSE:
+		return &BooleanLiteral{Value: p.curToken.Type == TRUE}
+	case NULL:
+		return &NullLiteral{}
+	case PARAM:
+		return p.parseParameter()
 	case ASTERISK:
 		return &WildcardExpression{}
 	case LPAREN:
@@ -417,6 +689,69 @@ func (p *Parser) parsePrimaryExpression() Expression {
 	}
 }
 
+// parseIdentifier parses a field name, or a JSON path into the value such as
+// value.user.name or value.tags[0]
+func (p *Parser) parseIdentifier() Expression {
+	literal := p.curToken.Literal
+	end := strings.IndexAny(literal, ".[")
+	if end < 0 {
+		return &Identifier{Value: literal}
+	}
+
+	field := literal[:end]
+	if field != "value" {
+		p.addError(fmt.Sprintf("JSON path %s must start with value", literal))
+		return nil
+	}
+
+	path := []string{}
+	for rest := literal[end:]; rest != ""; {
+		var part string
+		if rest[0] == '[' {
+			closing := strings.IndexByte(rest, ']')
+			part, rest = rest[1:closing], rest[closing+1:]
+		} else {
+			rest = rest[1:]
+			next := strings.IndexAny(rest, ".[")
+			if next < 0 {
+				next = len(rest)
+			}
+			part, rest = rest[:next], rest[next:]
+		}
+		path = append(path, part)
+	}
+	return &JSONPathExpression{Field: field, Path: path}
+}
+
+// parseParameter parses a ? or $n placeholder. ? placeholders are numbered in the
+// order they appear; the two styles cannot be mixed in one query.
+func (p *Parser) parseParameter() Expression {
+	if p.curToken.Literal == "?" {
+		p.positional = true
+		p.parameters++
+		if p.numbered {
+			p.addError("cannot mix ? and $n parameters")
+			return nil
+		}
+		return &Parameter{Index: p.parameters}
+	}
+
+	p.numbered = true
+	index, err := strconv.Atoi(p.curToken.Literal[1:])
+	if err != nil || index < 1 {
+		p.addError(fmt.Sprintf("invalid parameter %s: parameters are numbered from $1", p.curToken.Literal))
+		return nil
+	}
+	if p.positional {
+		p.addError("cannot mix ? and $n parameters")
+		return nil
+	}
+	if index > p.parameters {
+		p.parameters = index
+	}
+	return &Parameter{Index: index}
+}
+
 // expectPeek checks if the next token is of expected type
 func (p *Parser) expectPeek(t TokenType) bool {
 	if p.peekToken.Type == t {
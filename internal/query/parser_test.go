@@ -25,10 +25,62 @@ func TestParser_SelectStatement(t *testing.T) {
 			"SELECT COUNT(*) FROM moz WHERE value CONTAINS 'admin'",
 			"SELECT COUNT(*) FROM moz WHERE (value CONTAINS \"admin\")",
 		},
+		{
+			"SELECT key FROM moz WHERE value MATCH 'red shoes'",
+			"SELECT key FROM moz WHERE (value MATCH \"red shoes\")",
+		},
 		{
 			"SELECT * FROM moz WHERE key BETWEEN 'a' AND 'z'",
 			"SELECT * FROM moz WHERE key BETWEEN \"a\" AND \"z\"",
 		},
+		{
+			"SELECT SPLIT_PART(key, ':', 1), count(DISTINCT value.user), AVG(value.age) FROM moz GROUP BY SPLIT_PART(key, ':', 1) HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC",
+			"SELECT SPLIT_PART(key, \":\", 1), COUNT(DISTINCT value.user), AVG(value.age) FROM moz GROUP BY SPLIT_PART(key, \":\", 1) HAVING (COUNT(*) > 1) ORDER BY COUNT(*) DESC",
+		},
+		{
+			"INSERT INTO moz (value, key) VALUES ('v1', 'k1'), ('v2', 'k2')",
+			"INSERT INTO moz (value, key) VALUES (\"v1\", \"k1\"), (\"v2\", \"k2\")",
+		},
+		{
+			"insert into moz values ('k', 42)",
+			"INSERT INTO moz (key, value) VALUES (\"k\", 42)",
+		},
+		{
+			"UPDATE moz SET value = 'done' WHERE key LIKE 'task:%' AND value != 'done'",
+			"UPDATE moz SET value = \"done\" WHERE ((key LIKE \"task:%\") AND (value != \"done\"))",
+		},
+		{
+			"DELETE FROM moz WHERE key LIKE 'tmp:%'",
+			"DELETE FROM moz WHERE (key LIKE \"tmp:%\")",
+		},
+		{
+			"DELETE FROM moz",
+			"DELETE FROM moz",
+		},
+		{
+			"EXPLAIN ANALYZE SELECT key FROM moz WHERE key LIKE 'user:%' LIMIT 2",
+			"EXPLAIN ANALYZE SELECT key FROM moz WHERE (key LIKE \"user:%\") LIMIT 2",
+		},
+		{
+			"SELECT value.user.name FROM moz WHERE value.age >= 30",
+			"SELECT value.user.name FROM moz WHERE (value.age >= 30)",
+		},
+		{
+			"SELECT key FROM moz WHERE value.tags.0 = 'go' AND value.active = TRUE OR value.manager = null",
+			"SELECT key FROM moz WHERE (((value.tags[0] = \"go\") AND (value.active = true)) OR (value.manager = null))",
+		},
+		{
+			"SELECT key, value * 2 + 1 AS score, -value.age, CAST(value.price AS int) FROM moz WHERE value % 3 = -1 ORDER BY score DESC",
+			"SELECT key, ((value * 2) + 1) AS score, (-value.age), CAST(value.price AS INT) FROM moz WHERE ((value % 3) = -1) ORDER BY score DESC",
+		},
+		{
+			"SELECT UPPER(SUBSTR(key, 1, 4)) FROM moz WHERE LENGTH(value) - 1 BETWEEN 2 AND 2 * (1 + 2) ORDER BY CONCAT(key, '!')",
+			"SELECT UPPER(SUBSTR(key, 1, 4)) FROM moz WHERE (LENGTH(value) - 1) BETWEEN 2 AND (2 * (1 + 2)) ORDER BY CONCAT(key, \"!\") ASC",
+		},
+		{
+			"UPDATE moz SET value = value + 1 WHERE key = 'counter'",
+			"UPDATE moz SET value = (value + 1) WHERE (key = \"counter\")",
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,10 +128,62 @@ func TestParser_ComplexQueries(t *testing.T) {
 			"SELECT * FROM moz LIMIT 10 OFFSET 5",
 			false,
 		},
+		{
+			"SELECT value.name FROM moz ORDER BY value.name DESC",
+			false,
+		},
+		{
+			"SELECT * FROM moz WHERE key.name = 'x'",
+			true,
+		},
+		{
+			"INSERT INTO moz (key, value) VALUES ('k', 'v'",
+			true,
+		},
+		{
+			"UPDATE moz value = 'x'",
+			true,
+		},
+		{
+			"DELETE moz WHERE key = 'x'",
+			true,
+		},
+		{
+			"EXPLAIN DELETE FROM moz",
+			true,
+		},
+		{
+			"SELECT * FROM moz WHERE key = ? AND value IN (?, ?)",
+			false,
+		},
+		{
+			"UPDATE moz SET value = $2 WHERE key = $1",
+			false,
+		},
+		{
+			"SELECT * FROM moz WHERE key = ? OR key = $1",
+			true,
+		},
+		{
+			"SELECT * FROM moz WHERE key = $0",
+			true,
+		},
 		{
 			"SELECT INVALID",
 			true,
 		},
+		{
+			"SELECT CAST(value AS BOOL) FROM moz",
+			true,
+		},
+		{
+			"SELECT value AS FROM moz",
+			true,
+		},
+		{
+			"SELECT value * FROM moz",
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,448 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AccessMethod is how a plan finds its candidate rows
+type AccessMethod int
+
+const (
+	FullScan        AccessMethod = iota // List every key
+	KeyLookup                           // Point lookups for key = and key IN
+	KeyRange                            // GetRange for key BETWEEN, >=, >, <= and <
+	KeyPrefix                           // PrefixSearch for key LIKE 'prefix%'
+	FullTextLookup                      // Full-text index for value MATCH 'words'
+	SecondaryLookup                     // Secondary index on value
+)
+
+// String returns the name of the access method
+func (m AccessMethod) String() string {
+	switch m {
+	case KeyLookup:
+		return "KEY LOOKUP"
+	case KeyRange:
+		return "KEY RANGE"
+	case KeyPrefix:
+		return "KEY PREFIX"
+	case FullTextLookup:
+		return "FULL-TEXT INDEX"
+	case SecondaryLookup:
+		return "SECONDARY INDEX"
+	default:
+		return "FULL SCAN"
+	}
+}
+
+// maxKey sorts after every valid UTF-8 key and stands in for an open range end
+const maxKey = "\xff"
+
+// Plan describes how a SELECT statement finds its rows
+type Plan struct {
+	Access     AccessMethod
+	Keys       []string   // Sorted keys for KeyLookup, FullTextLookup and SecondaryLookup
+	Start      string     // Inclusive lower bound for KeyRange; empty if unbounded
+	End        string     // Inclusive upper bound for KeyRange; empty if unbounded
+	Prefix     string     // Key prefix for KeyPrefix
+	Residual   Expression // Predicates evaluated on each candidate row; nil if the access method covers them all
+	EarlyStop  bool       // LIMIT with ORDER BY key, or without ORDER BY or MATCH ranking: stop once enough rows are found
+	Descending bool       // Visit keys in descending order
+
+	Rank *BinaryExpression // MATCH conjunct whose term frequency ranks rows without ORDER BY; nil if none
+}
+
+// Condition describes the rows the access method selects; it is empty for a full scan
+func (p *Plan) Condition() string {
+	quote := func(s string) string { return (&StringLiteral{Value: s}).String() }
+
+	switch p.Access {
+	case KeyLookup:
+		if len(p.Keys) == 1 {
+			return "key = " + quote(p.Keys[0])
+		}
+		keys := make([]string, len(p.Keys))
+		for i, key := range p.Keys {
+			keys[i] = quote(key)
+		}
+		return "key IN (" + strings.Join(keys, ", ") + ")"
+	case KeyRange:
+		var bounds []string
+		if p.Start != "" {
+			bounds = append(bounds, "key >= "+quote(p.Start))
+		}
+		if p.End != "" {
+			bounds = append(bounds, "key <= "+quote(p.End))
+		}
+		return strings.Join(bounds, " AND ")
+	case KeyPrefix:
+		return "key LIKE " + quote(p.Prefix+"%")
+	case FullTextLookup:
+		return fmt.Sprintf("%d keys from the full-text index", len(p.Keys))
+	case SecondaryLookup:
+		return fmt.Sprintf("%d keys from secondary indexes", len(p.Keys))
+	default:
+		return ""
+	}
+}
+
+// keyPredicate is a WHERE conjunct on the key that an access method can answer
+type keyPredicate struct {
+	access     AccessMethod
+	keys       []string
+	start, end string
+	prefix     string
+	exact      bool // The access method returns exactly the matching keys
+}
+
+// Plan chooses the access method for a SELECT statement. Conjuncts of the WHERE
+// clause on the key become point lookups, a key range or a prefix search; the
+// other conjuncts are left in the residual expression.
+func (e *Executor) Plan(stmt *SelectStatement) *Plan {
+	plan := &Plan{Access: FullScan, Residual: stmt.Where}
+
+	if stmt.Where != nil {
+		for _, conjunct := range splitConjuncts(stmt.Where) {
+			if binary, ok := conjunct.(*BinaryExpression); ok && binary.Operator == MATCH_OP {
+				plan.Rank = binary
+				break
+			}
+		}
+	}
+
+	if stmt.Limit != nil && !e.isAggregationQuery(stmt) {
+		if stmt.OrderBy == nil {
+			// Ranked results are only known once every match is scored
+			plan.EarlyStop = plan.Rank == nil
+		} else if isKeyIdentifier(orderExpression(stmt)) {
+			plan.EarlyStop = true
+			plan.Descending = stmt.OrderBy.Direction == "DESC"
+		}
+	}
+
+	if stmt.Where == nil {
+		return plan
+	}
+
+	conjuncts := splitConjuncts(stmt.Where)
+	predicates := make([]*keyPredicate, len(conjuncts))
+	for i, conjunct := range conjuncts {
+		predicates[i] = analyzeKeyPredicate(conjunct)
+	}
+
+	// Point lookups are the most selective, then prefixes, then ranges
+	for _, access := range []AccessMethod{KeyLookup, KeyPrefix, KeyRange} {
+		var used []int
+		for i, pred := range predicates {
+			if pred != nil && pred.access == access {
+				used = append(used, i)
+				if access != KeyRange {
+					break // Ranges are intersected; one lookup or prefix is enough
+				}
+			}
+		}
+		if len(used) == 0 {
+			continue
+		}
+
+		plan.Access = access
+		var residual []Expression
+		for i, conjunct := range conjuncts {
+			if !containsIndex(used, i) || !predicates[i].exact {
+				residual = append(residual, conjunct)
+			}
+		}
+		plan.Residual = joinConjuncts(residual)
+
+		switch access {
+		case KeyLookup:
+			plan.Keys = predicates[used[0]].keys
+		case KeyPrefix:
+			plan.Prefix = predicates[used[0]].prefix
+		case KeyRange:
+			for _, i := range used {
+				if predicates[i].start != "" && predicates[i].start > plan.Start {
+					plan.Start = predicates[i].start
+				}
+				if predicates[i].end != "" && (plan.End == "" || predicates[i].end < plan.End) {
+					plan.End = predicates[i].end
+				}
+			}
+		}
+		return plan
+	}
+
+	// A MATCH on the value can be answered by the full-text index
+	for i, conjunct := range conjuncts {
+		keys, ok := e.fullTextKeys(conjunct)
+		if !ok {
+			continue
+		}
+		plan.Access = FullTextLookup
+		plan.Keys = keys
+		plan.Residual = joinConjuncts(append(append([]Expression{}, conjuncts[:i]...), conjuncts[i+1:]...))
+		return plan
+	}
+
+	// Fall back to the secondary indexes; the whole WHERE clause is still evaluated
+	if keys, ok := e.indexedKeys(stmt.Where); ok {
+		plan.Access = SecondaryLookup
+		plan.Keys = keys
+	}
+	return plan
+}
+
+// fullTextKeys returns the keys matching a "value MATCH 'words'" or "value.path MATCH 'words'"
+// conjunct according to the full-text index, or false if the index does not cover it
+func (e *Executor) fullTextKeys(expr Expression) ([]string, bool) {
+	binary, ok := expr.(*BinaryExpression)
+	if !ok || binary.Operator != MATCH_OP {
+		return nil, false
+	}
+	var field string
+	switch left := binary.Left.(type) {
+	case *Identifier:
+		if left.Value != "value" {
+			return nil, false
+		}
+	case *JSONPathExpression:
+		field = strings.Join(left.Path, ".")
+	default:
+		return nil, false
+	}
+	query, ok := literalValue(binary.Right)
+	if !ok {
+		return nil, false
+	}
+	indexer, ok := e.store.(FullTextIndexer)
+	if !ok {
+		return nil, false
+	}
+	hits, ok := indexer.LookupFullText(field, query)
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, len(hits))
+	for i, hit := range hits {
+		keys[i] = hit.Key
+	}
+	return keys, true
+}
+
+// analyzeKeyPredicate returns the key access a conjunct allows, or nil
+func analyzeKeyPredicate(expr Expression) *keyPredicate {
+	switch exp := expr.(type) {
+	case *BinaryExpression:
+		literal, operator, ok := keyComparison(exp)
+		if !ok {
+			return nil
+		}
+		switch operator {
+		case EQ:
+			return &keyPredicate{access: KeyLookup, keys: []string{literal}, exact: true}
+		case LIKE_OP:
+			return likePrefix(literal)
+		}
+
+		// Numbers compare numerically, which a lexicographic key range does not follow
+		if isNumeric(literal) {
+			return nil
+		}
+		switch operator {
+		case GTE, GT_OP:
+			return &keyPredicate{access: KeyRange, start: literal, exact: operator == GTE}
+		case LTE, LT_OP:
+			return &keyPredicate{access: KeyRange, end: literal, exact: operator == LTE}
+		}
+
+	case *BetweenExpression:
+		if !isKeyIdentifier(exp.Field) {
+			return nil
+		}
+		start, startOK := literalValue(exp.Start)
+		end, endOK := literalValue(exp.End)
+		if !startOK || !endOK || isNumeric(start) || isNumeric(end) {
+			return nil
+		}
+		return &keyPredicate{access: KeyRange, start: start, end: end, exact: true}
+
+	case *InExpression:
+		if !isKeyIdentifier(exp.Field) || len(exp.Values) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(exp.Values))
+		for _, valueExpr := range exp.Values {
+			literal, ok := literalValue(valueExpr)
+			if !ok {
+				return nil
+			}
+			keys = append(keys, literal)
+		}
+		return &keyPredicate{access: KeyLookup, keys: keys, exact: true}
+	}
+	return nil
+}
+
+// keyComparison returns the literal and operator of "key <op> literal", flipping "literal <op> key"
+func keyComparison(expr *BinaryExpression) (string, Operator, bool) {
+	if isKeyIdentifier(expr.Left) {
+		literal, ok := literalValue(expr.Right)
+		return literal, expr.Operator, ok
+	}
+	if isKeyIdentifier(expr.Right) {
+		literal, ok := literalValue(expr.Left)
+		flipped := map[Operator]Operator{EQ: EQ, LT_OP: GT_OP, GT_OP: LT_OP, LTE: GTE, GTE: LTE}
+		operator, known := flipped[expr.Operator]
+		return literal, operator, ok && known
+	}
+	return "", UNKNOWN_OP, false
+}
+
+// likePrefix turns a LIKE pattern with a literal prefix into a prefix search
+func likePrefix(pattern string) *keyPredicate {
+	end := strings.IndexAny(pattern, "%_")
+	if end < 0 {
+		end = len(pattern)
+	}
+	prefix := pattern[:end]
+
+	// LIKE patterns are matched as regular expressions, so metacharacters are not literal
+	if prefix == "" || strings.ContainsAny(prefix, `\.+*?()|[]{}^$`) {
+		return nil
+	}
+	return &keyPredicate{access: KeyPrefix, prefix: prefix, exact: pattern == prefix+"%"}
+}
+
+// isKeyIdentifier reports whether an expression is the key field
+func isKeyIdentifier(expr Expression) bool {
+	ident, ok := expr.(*Identifier)
+	return ok && ident.Value == "key"
+}
+
+// literalValue returns the value of a string or number literal
+func literalValue(expr Expression) (string, bool) {
+	switch literal := expr.(type) {
+	case *StringLiteral:
+		return literal.Value, true
+	case *NumberLiteral:
+		return literal.Value, true
+	default:
+		return "", false
+	}
+}
+
+// isNumeric reports whether compareValues would compare s as a number
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// splitConjuncts flattens a tree of ANDs into its operands
+func splitConjuncts(expr Expression) []Expression {
+	if binary, ok := expr.(*BinaryExpression); ok && binary.Operator == AND_OP {
+		return append(splitConjuncts(binary.Left), splitConjuncts(binary.Right)...)
+	}
+	return []Expression{expr}
+}
+
+// joinConjuncts combines expressions with AND; it returns nil for none
+func joinConjuncts(exprs []Expression) Expression {
+	if len(exprs) == 0 {
+		return nil
+	}
+	result := exprs[0]
+	for _, expr := range exprs[1:] {
+		result = &BinaryExpression{Left: result, Operator: AND_OP, Right: expr}
+	}
+	return result
+}
+
+func containsIndex(indexes []int, i int) bool {
+	for _, index := range indexes {
+		if index == i {
+			return true
+		}
+	}
+	return false
+}
+
+// scan visits the candidate rows of a plan in key order until visit returns false
+func (e *Executor) scan(plan *Plan, visit func(key, value string) bool) error {
+	var keys []string
+	var values map[string]string
+
+	switch plan.Access {
+	case KeyLookup, FullTextLookup, SecondaryLookup:
+		keys = uniqueSorted(plan.Keys)
+	case KeyRange:
+		end := plan.End
+		if end == "" {
+			end = maxKey
+		}
+		result, err := e.store.GetRange(plan.Start, end)
+		if err != nil {
+			return err
+		}
+		keys, values = sortedKeys(result), result
+	case KeyPrefix:
+		result, err := e.store.PrefixSearch(plan.Prefix)
+		if err != nil {
+			return err
+		}
+		keys, values = sortedKeys(result), result
+	default:
+		var err error
+		if keys, err = e.store.List(); err != nil {
+			return err
+		}
+		sort.Strings(keys)
+	}
+
+	for i := range keys {
+		key := keys[i]
+		if plan.Descending {
+			key = keys[len(keys)-1-i]
+		}
+
+		value, exists := values[key]
+		if values == nil {
+			var err error
+			if value, err = e.store.Get(key); err != nil {
+				continue // Skip keys that can't be retrieved
+			}
+		} else if !exists {
+			continue
+		}
+
+		if !visit(key, value) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// uniqueSorted returns the keys sorted without duplicates
+func uniqueSorted(keys []string) []string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	result := sorted[:0]
+	for i, key := range sorted {
+		if i == 0 || key != sorted[i-1] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a result map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nyasuto/moz/internal/kvstore"
+)
+
+func newPlannerTestStore(t *testing.T) *kvstore.KVStore {
+	store := kvstore.NewWithConfig(kvstore.CompactionConfig{Enabled: false}, kvstore.StorageConfig{
+		Format:    "text",
+		TextFile:  "moz.log",
+		IndexFile: "moz.idx",
+		DataDir:   t.TempDir(),
+	})
+	for key, value := range map[string]string{
+		"admin1": "root",
+		"user:1": "Alice",
+		"user:2": "Bob",
+		"user:3": "Carol",
+		"user:4": "Dave",
+		"zeta":   "Zed",
+	} {
+		if err := store.Put(key, value); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	return store
+}
+
+func parseSelect(t *testing.T, query string) *SelectStatement {
+	p := NewParser(NewLexer(query))
+	stmt := p.ParseQuery()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors for %q: %v", query, p.Errors())
+	}
+	selectStmt, ok := stmt.(*SelectStatement)
+	if !ok {
+		t.Fatalf("%q is not a SELECT statement", query)
+	}
+	return selectStmt
+}
+
+func TestPlanner_AccessMethod(t *testing.T) {
+	executor := NewExecutor(newPlannerTestStore(t))
+
+	tests := []struct {
+		query    string
+		access   AccessMethod
+		residual bool
+	}{
+		{"SELECT * FROM moz", FullScan, false},
+		{"SELECT * FROM moz WHERE value = 'Bob'", FullScan, true},
+		{"SELECT * FROM moz WHERE key = 'user:1'", KeyLookup, false},
+		{"SELECT * FROM moz WHERE key IN ('user:1', 'zeta')", KeyLookup, false},
+		{"SELECT * FROM moz WHERE key BETWEEN 'user:2' AND 'user:3'", KeyRange, false},
+		{"SELECT * FROM moz WHERE key > 'user:2'", KeyRange, true},
+		{"SELECT * FROM moz WHERE key LIKE 'user:%'", KeyPrefix, false},
+		{"SELECT * FROM moz WHERE key LIKE 'user:%' AND value = 'Bob'", KeyPrefix, true},
+		{"SELECT * FROM moz WHERE key = 'user:1' OR key = 'user:2'", FullScan, true},
+		{"SELECT * FROM moz WHERE key > 10", FullScan, true},
+	}
+
+	for _, tt := range tests {
+		plan := executor.Plan(parseSelect(t, tt.query))
+		if plan.Access != tt.access {
+			t.Errorf("%s: expected %s, got %s", tt.query, tt.access, plan.Access)
+		}
+		if (plan.Residual != nil) != tt.residual {
+			t.Errorf("%s: expected residual %v, got %v", tt.query, tt.residual, plan.Residual)
+		}
+	}
+}
+
+func TestPlanner_Results(t *testing.T) {
+	executor := NewExecutor(newPlannerTestStore(t))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT key FROM moz WHERE key = 'user:1'", []string{"user:1"}},
+		{"SELECT key FROM moz WHERE key = 'missing'", nil},
+		{"SELECT key FROM moz WHERE key IN ('zeta', 'user:1', 'zeta')", []string{"user:1", "zeta"}},
+		{"SELECT key FROM moz WHERE key BETWEEN 'user:2' AND 'user:3'", []string{"user:2", "user:3"}},
+		{"SELECT key FROM moz WHERE key > 'user:2' AND key < 'zeta'", []string{"user:3", "user:4"}},
+		{"SELECT key FROM moz WHERE key >= 'user:4'", []string{"user:4", "zeta"}},
+		{"SELECT key FROM moz WHERE key LIKE 'user:%' AND value = 'Bob'", []string{"user:2"}},
+		{"SELECT key FROM moz WHERE key LIKE 'user:_'", []string{"user:1", "user:2", "user:3", "user:4"}},
+		{"SELECT key FROM moz WHERE key LIKE 'user:%' ORDER BY key DESC LIMIT 2", []string{"user:4", "user:3"}},
+		{"SELECT key FROM moz ORDER BY key LIMIT 2 OFFSET 1", []string{"user:1", "user:2"}},
+		{"SELECT key FROM moz ORDER BY value DESC LIMIT 1", []string{"admin1"}},
+	}
+
+	for _, tt := range tests {
+		result := executor.Execute(parseSelect(t, tt.query))
+		if result.Error != nil {
+			t.Fatalf("Execution error for %q: %v", tt.query, result.Error)
+		}
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.want, keys)
+		}
+	}
+}
+
+func TestPlanner_EarlyStop(t *testing.T) {
+	executor := NewExecutor(newPlannerTestStore(t))
+
+	plan := executor.Plan(parseSelect(t, "SELECT * FROM moz ORDER BY key DESC LIMIT 3"))
+	if !plan.EarlyStop || !plan.Descending {
+		t.Errorf("expected a descending early-stop plan, got %+v", plan)
+	}
+
+	plan = executor.Plan(parseSelect(t, "SELECT * FROM moz ORDER BY value LIMIT 3"))
+	if plan.EarlyStop {
+		t.Error("ORDER BY value must scan every row")
+	}
+
+	plan = executor.Plan(parseSelect(t, "SELECT COUNT(*) FROM moz LIMIT 1"))
+	if plan.EarlyStop {
+		t.Error("aggregations must scan every row")
+	}
+}
@@ -0,0 +1,203 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PreparedStatement is a query parsed once and executed with different arguments.
+// Arguments replace the ? and $n placeholders as literals, so they are never parsed
+// as query text. The statement is planned on every execution, because the best
+// access path depends on the argument values.
+type PreparedStatement struct {
+	executor   *Executor
+	query      string
+	stmt       Statement
+	parameters int
+}
+
+// Prepare parses a query with ? or $1 placeholders for later execution
+func (e *Executor) Prepare(query string) (*PreparedStatement, error) {
+	parser := NewParser(NewLexer(query))
+	stmt := parser.ParseQuery()
+	if len(parser.Errors()) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(parser.Errors(), "; "))
+	}
+	return &PreparedStatement{executor: e, query: query, stmt: stmt, parameters: parser.ParameterCount()}, nil
+}
+
+// Statement returns the parsed statement, with its placeholders unbound
+func (ps *PreparedStatement) Statement() Statement {
+	return ps.stmt
+}
+
+// ParameterCount returns the number of arguments Execute takes
+func (ps *PreparedStatement) ParameterCount() int {
+	return ps.parameters
+}
+
+// String returns the query text the statement was prepared from
+func (ps *PreparedStatement) String() string {
+	return ps.query
+}
+
+// Execute binds the arguments to the placeholders in order and executes the
+// statement. Arguments may be strings, numbers, booleans or nil (null).
+func (ps *PreparedStatement) Execute(args ...interface{}) *ExecuteResult {
+	if len(args) != ps.parameters {
+		return &ExecuteResult{Error: fmt.Errorf("query takes %d parameters, got %d", ps.parameters, len(args))}
+	}
+
+	values := make([]Expression, len(args))
+	for i, arg := range args {
+		value, err := literalFor(arg)
+		if err != nil {
+			return &ExecuteResult{Error: fmt.Errorf("parameter $%d: %v", i+1, err)}
+		}
+		values[i] = value
+	}
+
+	stmt := rewriteStatement(ps.stmt, func(param *Parameter) Expression {
+		return values[param.Index-1]
+	})
+	return ps.executor.Execute(stmt)
+}
+
+// literalFor converts an argument to the literal it binds as
+func literalFor(arg interface{}) (Expression, error) {
+	switch v := arg.(type) {
+	case nil:
+		return &NullLiteral{}, nil
+	case string:
+		return &StringLiteral{Value: v}, nil
+	case bool:
+		return &BooleanLiteral{Value: v}, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return &NumberLiteral{Value: fmt.Sprint(v)}, nil
+	case float32:
+		return literalFor(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("unsupported number %v", v)
+		}
+		return &NumberLiteral{Value: formatNumber(v)}, nil
+	case json.Number:
+		if _, err := v.Float64(); err != nil {
+			return nil, fmt.Errorf("invalid number %s", v)
+		}
+		return &NumberLiteral{Value: v.String()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", arg)
+	}
+}
+
+// parameterCount returns the number of placeholders in a statement
+func parameterCount(stmt Statement) int {
+	count := 0
+	rewriteStatement(stmt, func(param *Parameter) Expression {
+		count++
+		return param
+	})
+	return count
+}
+
+// rewriteStatement returns a copy of a statement with every placeholder replaced
+// by bind. The original statement is not modified, so it can be bound again.
+func rewriteStatement(stmt Statement, bind func(*Parameter) Expression) Statement {
+	rewrite := func(expr Expression) Expression {
+		return rewriteExpression(expr, bind)
+	}
+	rewriteList := func(list []Expression) []Expression {
+		if list == nil {
+			return nil
+		}
+		rewritten := make([]Expression, len(list))
+		for i, expr := range list {
+			rewritten[i] = rewrite(expr)
+		}
+		return rewritten
+	}
+
+	switch s := stmt.(type) {
+	case *SelectStatement:
+		clone := *s
+		clone.Fields = rewriteList(s.Fields)
+		clone.Where = rewrite(s.Where)
+		clone.GroupBy = rewriteList(s.GroupBy)
+		clone.Having = rewrite(s.Having)
+		if s.OrderBy != nil {
+			orderBy := *s.OrderBy
+			orderBy.Expression = rewrite(s.OrderBy.Expression)
+			clone.OrderBy = &orderBy
+		}
+		return &clone
+	case *InsertStatement:
+		clone := *s
+		clone.Rows = make([][]Expression, len(s.Rows))
+		for i, row := range s.Rows {
+			clone.Rows[i] = rewriteList(row)
+		}
+		return &clone
+	case *UpdateStatement:
+		clone := *s
+		clone.Set = make([]Assignment, len(s.Set))
+		for i, assignment := range s.Set {
+			clone.Set[i] = Assignment{Field: assignment.Field, Value: rewrite(assignment.Value)}
+		}
+		clone.Where = rewrite(s.Where)
+		return &clone
+	case *DeleteStatement:
+		clone := *s
+		clone.Where = rewrite(s.Where)
+		return &clone
+	case *ExplainStatement:
+		clone := *s
+		clone.Statement = rewriteStatement(s.Statement, bind)
+		return &clone
+	default:
+		return stmt
+	}
+}
+
+// rewriteExpression returns a copy of an expression with every placeholder replaced by bind
+func rewriteExpression(expr Expression, bind func(*Parameter) Expression) Expression {
+	switch exp := expr.(type) {
+	case *Parameter:
+		return bind(exp)
+	case *BinaryExpression:
+		return &BinaryExpression{
+			Left:     rewriteExpression(exp.Left, bind),
+			Operator: exp.Operator,
+			Right:    rewriteExpression(exp.Right, bind),
+		}
+	case *UnaryExpression:
+		return &UnaryExpression{Operator: exp.Operator, Right: rewriteExpression(exp.Right, bind)}
+	case *BetweenExpression:
+		return &BetweenExpression{
+			Field: rewriteExpression(exp.Field, bind),
+			Start: rewriteExpression(exp.Start, bind),
+			End:   rewriteExpression(exp.End, bind),
+		}
+	case *InExpression:
+		values := make([]Expression, len(exp.Values))
+		for i, value := range exp.Values {
+			values[i] = rewriteExpression(value, bind)
+		}
+		return &InExpression{Field: rewriteExpression(exp.Field, bind), Values: values}
+	case *CastExpression:
+		return &CastExpression{Expression: rewriteExpression(exp.Expression, bind), Type: exp.Type}
+	case *AliasExpression:
+		return &AliasExpression{Expression: rewriteExpression(exp.Expression, bind), Alias: exp.Alias}
+	case *FunctionExpression:
+		args := make([]Expression, len(exp.Arguments))
+		for i, arg := range exp.Arguments {
+			args[i] = rewriteExpression(arg, bind)
+		}
+		return &FunctionExpression{Name: exp.Name, Arguments: args, Distinct: exp.Distinct}
+	default:
+		// Identifiers, paths and literals have no placeholders and are never modified
+		return expr
+	}
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestPreparedStatement(t *testing.T) {
+	store := newPlannerTestStore(t)
+	executor := NewExecutor(store)
+
+	stmt, err := executor.Prepare("SELECT key FROM moz WHERE key LIKE ? AND value != ? ORDER BY key")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if stmt.ParameterCount() != 2 {
+		t.Fatalf("Expected 2 parameters, got %d", stmt.ParameterCount())
+	}
+
+	// The same statement runs with different arguments, and uses the key prefix each time
+	for _, tt := range []struct {
+		args []interface{}
+		keys []string
+	}{
+		{[]interface{}{"user:%", "Bob"}, []string{"user:1", "user:3", "user:4"}},
+		{[]interface{}{"z%", "Bob"}, []string{"zeta"}},
+		{[]interface{}{"user:%", "Alice"}, []string{"user:2", "user:3", "user:4"}},
+	} {
+		result := stmt.Execute(tt.args...)
+		if result.Error != nil {
+			t.Fatalf("Execute(%v) failed: %v", tt.args, result.Error)
+		}
+		var keys []string
+		for _, row := range result.Rows {
+			keys = append(keys, row["key"])
+		}
+		if len(keys) != len(tt.keys) {
+			t.Fatalf("Execute(%v): expected %v, got %v", tt.args, tt.keys, keys)
+		}
+		for i := range keys {
+			if keys[i] != tt.keys[i] {
+				t.Errorf("Execute(%v): expected %v, got %v", tt.args, tt.keys, keys)
+			}
+		}
+	}
+
+	// Arguments are bound as literals, never parsed as query text
+	if result := stmt.Execute("x' OR key LIKE '%", "Bob"); result.Error != nil || len(result.Rows) != 0 {
+		t.Errorf("Expected an injected condition to match nothing, got %v (%v)", result.Rows, result.Error)
+	}
+
+	if result := stmt.Execute("user:%"); result.Error == nil {
+		t.Error("Expected an error for a missing argument")
+	}
+	if result := stmt.Execute("user:%", []string{"Bob"}); result.Error == nil {
+		t.Error("Expected an error for an unsupported argument type")
+	}
+	if result := executor.Execute(stmt.Statement()); result.Error == nil {
+		t.Error("Expected an error executing a statement with unbound parameters")
+	}
+
+	// Numbered parameters can repeat, and numbers compare as numbers in JSON paths
+	if err := store.Put("user:5", `{"age": 30, "active": true}`); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	stmt, err = executor.Prepare("SELECT key FROM moz WHERE value.age >= $1 AND value.age <= $1 AND value.active = $2")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if result := stmt.Execute(30.0, true); result.Error != nil || len(result.Rows) != 1 {
+		t.Errorf("Expected user:5, got %v (%v)", result.Rows, result.Error)
+	}
+	if result := stmt.Execute("30", true); result.Error != nil || len(result.Rows) != 0 {
+		t.Errorf("Expected a string not to match a JSON number, got %v (%v)", result.Rows, result.Error)
+	}
+
+	// Data-modifying statements bind their values too
+	stmt, err = executor.Prepare("INSERT INTO moz VALUES ($1, $2)")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if result := stmt.Execute("user:6", "Frank's"); result.Error != nil || result.Affected != 1 {
+		t.Fatalf("INSERT failed: %v", result.Error)
+	}
+	if value, _ := store.Get("user:6"); value != "Frank's" {
+		t.Errorf("Expected the inserted value Frank's, got %q", value)
+	}
+
+	// Arguments bind inside computed fields and ORDER BY
+	stmt, err = executor.Prepare("SELECT key, value.age * $1 AS scaled FROM moz WHERE key = 'user:5' ORDER BY value.age - $1")
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if result := stmt.Execute(2); result.Error != nil || len(result.Rows) != 1 || result.Rows[0]["scaled"] != "60" {
+		t.Errorf("Expected scaled=60, got %v (%v)", result.Rows, result.Error)
+	}
+
+	if _, err := executor.Prepare("SELECT * FROM moz WHERE key = ? OR key = $1"); err == nil {
+		t.Error("Expected an error mixing ? and $n parameters")
+	}
+}
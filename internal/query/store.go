@@ -0,0 +1,23 @@
+package query
+
+import "github.com/nyasuto/moz/internal/index"
+
+// Store is the data a query runs against. Every engine's store implements it, so
+// queries work whichever engine is selected. A store that also implements
+// SecondaryIndexer or FullTextIndexer lets the planner use those indexes.
+type Store interface {
+	List() ([]string, error)
+	Get(key string) (string, error)
+	GetRange(start, end string) (map[string]string, error)
+	PrefixSearch(prefix string) (map[string]string, error)
+}
+
+// SecondaryIndexer is implemented by stores with secondary indexes over values
+type SecondaryIndexer interface {
+	LookupSecondary(field, value string) ([]string, bool)
+}
+
+// FullTextIndexer is implemented by stores with a full-text index over values
+type FullTextIndexer interface {
+	LookupFullText(field, query string) ([]index.SearchHit, bool)
+}
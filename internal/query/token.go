@@ -17,6 +17,10 @@ const (
 	IDENT  // field names, table names
 	STRING // "string"
 	NUMBER // 123
+	TRUE   // true
+	FALSE  // false
+	NULL   // null
+	PARAM  // ? or $1
 
 	// Keywords
 	SELECT
@@ -28,6 +32,7 @@ const (
 	LIKE
 	BETWEEN
 	CONTAINS
+	MATCH
 	IN
 	COUNT
 	DISTINCT
@@ -37,6 +42,17 @@ const (
 	DESC
 	LIMIT
 	OFFSET
+	GROUP
+	HAVING
+	INSERT
+	INTO
+	VALUES
+	UPDATE
+	SET
+	DELETE
+	EXPLAIN
+	ANALYZE
+	AS
 
 	// Operators
 	ASSIGN   // =
@@ -81,6 +97,14 @@ func (t TokenType) String() string {
 		return "STRING"
 	case NUMBER:
 		return "NUMBER"
+	case TRUE:
+		return "TRUE"
+	case FALSE:
+		return "FALSE"
+	case NULL:
+		return "NULL"
+	case PARAM:
+		return "PARAM"
 	case SELECT:
 		return "SELECT"
 	case FROM:
@@ -99,6 +123,8 @@ func (t TokenType) String() string {
 		return "BETWEEN"
 	case CONTAINS:
 		return "CONTAINS"
+	case MATCH:
+		return "MATCH"
 	case IN:
 		return "IN"
 	case COUNT:
@@ -117,6 +143,28 @@ func (t TokenType) String() string {
 		return "LIMIT"
 	case OFFSET:
 		return "OFFSET"
+	case GROUP:
+		return "GROUP"
+	case HAVING:
+		return "HAVING"
+	case INSERT:
+		return "INSERT"
+	case INTO:
+		return "INTO"
+	case VALUES:
+		return "VALUES"
+	case UPDATE:
+		return "UPDATE"
+	case SET:
+		return "SET"
+	case DELETE:
+		return "DELETE"
+	case EXPLAIN:
+		return "EXPLAIN"
+	case ANALYZE:
+		return "ANALYZE"
+	case AS:
+		return "AS"
 	case ASSIGN:
 		return "="
 	case NOT_EQ:
@@ -165,6 +213,7 @@ var Keywords = map[string]TokenType{
 	"LIKE":     LIKE,
 	"BETWEEN":  BETWEEN,
 	"CONTAINS": CONTAINS,
+	"MATCH":    MATCH,
 	"IN":       IN,
 	"COUNT":    COUNT,
 	"DISTINCT": DISTINCT,
@@ -174,7 +223,21 @@ var Keywords = map[string]TokenType{
 	"DESC":     DESC,
 	"LIMIT":    LIMIT,
 	"OFFSET":   OFFSET,
+	"GROUP":    GROUP,
+	"HAVING":   HAVING,
+	"INSERT":   INSERT,
+	"INTO":     INTO,
+	"VALUES":   VALUES,
+	"UPDATE":   UPDATE,
+	"SET":      SET,
+	"DELETE":   DELETE,
+	"EXPLAIN":  EXPLAIN,
+	"ANALYZE":  ANALYZE,
+	"AS":       AS,
 	"REGEX":    REGEX,
+	"TRUE":     TRUE,
+	"FALSE":    FALSE,
+	"NULL":     NULL,
 }
 
 // LookupIdent checks if an identifier is a keyword